@@ -1,45 +1,84 @@
 package main
 
-/*
-#cgo CFLAGS: -x objective-c
-#cgo LDFLAGS: -framework Cocoa -framework Carbon
-#import <Cocoa/Cocoa.h>
-#import <Carbon/Carbon.h>
-void SetWindowLevel();
-void EnableMouseEvents();
-void DisableMouseEvents();
-void ReturnFocusToPreviousWindow();
-void SimulateKeyPresses(const char* keyString);
-char* GetPressedKeysString(void);
-void StartKeyMonitoring(const char* callbackName);
-void StopKeyMonitoring(void);
-double GetMousePosX(void);
-double GetMousePosY(void);
-int GetLastShortcutKeyID(void);
-bool GetShiftDoublePressed(void);
-void ResetShiftDoublePressed(void);
-void ClipboardSetText(const char* text);
-char* ClipboardGetText(void);
-void FreeMemory(void* ptr);
-*/
-import "C"
 import (
 	"context"
 	"encoding/base64"
 	"encoding/json" // JSONパーサー用
 	"fmt"
+	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
-	"unsafe"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 type App struct {
 	ctx context.Context
+
+	// ネイティブ層へのアクセス
+	platform Platform
+
+	// 監視ゴルーチンが使う時計
+	clock Clock
+
+	// App が発行するイベントの配送先
+	events *EventBus
+
+	// グローバルホットキーとショートカットイベントの対応
+	hotkeys *HotkeyRegistry
+
+	// プラグインディレクトリのスキャン結果
+	plugins *PluginRegistry
+
+	// プラグインのアーカイブのインストール先
+	installer *PluginInstaller
+
+	// プラグインストアの索引とダウンロード
+	store *StoreClient
+
+	// プラグインの権限の確認と保存された許可
+	permissions *PermissionManager
+
+	// プラグインごとの呼び出し回数の上限
+	quotas *PluginQuota
+
+	// 権限が必要なメソッドの呼び出しの記録
+	audit *AuditLog
+
+	// プラグインごとのキーと値のストレージ
+	storage *PluginStorage
+
+	// プラグインの設定値
+	settings *SettingsStore
+
+	// プラグインの有効・無効と並び順
+	state *PluginStateStore
+
+	// ネイティブ層から届いたホットキーの入力 (押された順)
+	hotkeyPresses chan HotkeyPress
+	// キューが一杯で捨てたホットキーの入力の数
+	droppedHotkeyPresses atomic.Int64
+
+	// キー状態の変化から連打・長押し・シーケンスを検出する
+	keyGestures *KeyGestureEngine
+
+	// カーソル位置の変化からマウスジェスチャーを認識する
+	mouseGestures *MouseGestureTracker
+
+	// フロントエンドへのイベント送信 (通常は runtime.EventsEmit)
+	emitter func(ctx context.Context, eventName string, optionalData ...interface{})
+
+	// バックグラウンド監視ゴルーチンの管理
+	monitors *monitorSupervisor
+
+	// フロントエンドから通知されたゴーストの位置
+	ghostPosMu sync.Mutex
+	ghostPos   MousePosition
 }
 
 type MousePosition struct {
@@ -66,13 +105,23 @@ type DirectoryEntry struct {
 
 // プラグインマニフェスト
 type GhostManifest struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Version     string `json:"version"`
-	Description string `json:"description"`
-	Author      string `json:"author"`
-	Shortcut    string `json:"shortcut"`
-	Icon        string `json:"icon"`
+	// マニフェストの版 (省略時は 1)
+	ManifestVersion int    `json:"manifestVersion"`
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	Version         string `json:"version"`
+	Description     string `json:"description"`
+	Author          string `json:"author"`
+	Shortcut        string `json:"shortcut"`
+	Icon            string `json:"icon"`
+	// プラグインが使う権限 ("clipboard.read" など)
+	Permissions []string `json:"permissions,omitempty"`
+	// 必要なホストのバージョンの範囲 ({"ghostcursor": ">=1.0.0"})
+	Engines map[string]string `json:"engines,omitempty"`
+	// 必要な他のプラグインのIDとバージョンの範囲
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+	// ユーザーが変更できる設定項目
+	Settings []SettingField `json:"settings,omitempty"`
 }
 
 // ログレベル定義
@@ -87,18 +136,60 @@ const (
 
 // プラグインディレクトリの検証結果
 type PluginValidationResult struct {
-	PluginPath    string          `json:"pluginPath"`
-	IsValid       bool            `json:"isValid"`
-	HasManifest   bool            `json:"hasManifest"`
-	HasContent    bool            `json:"hasContent"`
-	HasBackground bool            `json:"hasBackground"`
-	HasIcon       bool            `json:"hasIcon"`
-	Errors        []string        `json:"errors"`
-	Manifest      json.RawMessage `json:"manifest,omitempty"`
+	PluginPath    string            `json:"pluginPath"`
+	IsValid       bool              `json:"isValid"`
+	HasManifest   bool              `json:"hasManifest"`
+	HasContent    bool              `json:"hasContent"`
+	HasBackground bool              `json:"hasBackground"`
+	HasIcon       bool              `json:"hasIcon"`
+	Errors        []ValidationIssue `json:"errors"`
+	Warnings      []ValidationIssue `json:"warnings"`
+	Manifest      json.RawMessage   `json:"manifest,omitempty"`
+	// 署名の確認結果
+	Signature PluginSignature `json:"signature"`
+	// 同じIDのプラグインが優先順位の高いディレクトリにあり、読み込まれない場合はそのディレクトリ
+	ShadowedBy string `json:"shadowedBy,omitempty"`
+	// プラグインのディレクトリの内容 (2階層まで)
+	Files []string `json:"files,omitempty"`
+
+	// 検証済みのマニフェスト (Manifest から読み取れた範囲)
+	manifest GhostManifest
 }
 
 func NewApp() *App {
-	return &App{}
+	return NewAppWithPlatform(newPlatform())
+}
+
+// NewAppWithPlatform は指定した Platform を使う App を生成する
+func NewAppWithPlatform(platform Platform) *App {
+	// 設定ファイルを読むまでは既定のジェスチャーを使う
+	keyGestures, _ := NewKeyGestureEngine(defaultKeyGestures())
+	mouseGestures, _ := NewMouseGestureTracker(defaultMouseGestureConfig())
+
+	clock := realClock{}
+
+	app := &App{
+		platform:  platform,
+		clock:     clock,
+		events:    NewEventBus(),
+		hotkeys:   NewHotkeyRegistry(platform),
+		plugins:   NewPluginRegistry(pluginDirectories),
+		installer: NewPluginInstaller(userPluginDirs, maxPluginArchiveSize, maxPluginArchiveFiles),
+		store:     NewStoreClient(&http.Client{Timeout: storeRequestTimeout}, loadStoreIndexURL, clock),
+		quotas:    NewPluginQuota(clock, pluginQuotaWindow, defaultPluginQuotas),
+		audit:     NewAuditLog(auditLogDir, configDir, clock, maxAuditLogSize, maxAuditLogFiles),
+		storage:   NewPluginStorage(),
+		settings:  NewSettingsStore(),
+		state:     NewPluginStateStore(),
+		emitter:   runtime.EventsEmit,
+
+		hotkeyPresses: make(chan HotkeyPress, hotkeyQueueSize),
+		keyGestures:   keyGestures,
+		mouseGestures: mouseGestures,
+		monitors:      newMonitorSupervisor(),
+	}
+	app.permissions = NewPermissionManager(app.promptPermission)
+	return app
 }
 
 // マウス位置を取得するためのメソッド
@@ -110,116 +201,176 @@ func (a *App) GetMousePosition(x float64, y float64) MousePosition {
 }
 
 func (a *App) EnableMouseEvents() {
-	C.EnableMouseEvents()
+	a.platform.EnableMouseEvents()
 }
 
 func (a *App) DisableMouseEvents() {
-	C.DisableMouseEvents()
+	a.platform.DisableMouseEvents()
 }
 
 func (a *App) ReturnFocusToPreviousWindow() {
-	C.ReturnFocusToPreviousWindow()
+	a.platform.ReturnFocusToPreviousWindow()
 }
 
 // クリップボードから文字列を読み取るメソッド（ネイティブ実装）
-func (a *App) ReadClipboard() (string, error) {
-	// ネイティブAPIを呼び出す
-	cstr := C.ClipboardGetText()
-
-	// 関数終了時にメモリを解放
-	defer C.FreeMemory(unsafe.Pointer(cstr))
-
-	// C文字列をGo文字列に変換
-	goText := C.GoString(cstr)
-
-	return goText, nil
+// token は LoadPlugin で発行されたトークンで、そのプラグインに clipboard.read の権限が必要
+func (a *App) ReadClipboard(token string) (string, error) {
+	if err := a.authorizePluginCall(token, "ReadClipboard", PermissionClipboardRead, ""); err != nil {
+		return "", err
+	}
+	return a.platform.ReadClipboard()
 }
 
 // クリップボードに文字列を書き込むメソッド（ネイティブ実装）
-func (a *App) WriteClipboard(text string) error {
-	// 文字列をC文字列に変換
-	cText := C.CString(text)
-	// 関数終了時にメモリを解放
-	defer C.free(unsafe.Pointer(cText))
-
-	// ネイティブAPIでクリップボードに書き込み
-	C.ClipboardSetText(cText)
-
-	return nil
+// token のプラグインに clipboard.write の権限が必要
+func (a *App) WriteClipboard(token string, text string) error {
+	if err := a.authorizePluginCall(token, "WriteClipboard", PermissionClipboardWrite, auditTextSummary(text)); err != nil {
+		return err
+	}
+	return a.platform.WriteClipboard(text)
 }
 
 // スクリーンショットを撮る関数
-func (a *App) TakeScreenshot() error {
+// token のプラグインに screen.capture の権限が必要
+func (a *App) TakeScreenshot(token string) error {
+	if err := a.authorizePluginCall(token, "TakeScreenshot", PermissionScreenCapture, ""); err != nil {
+		return err
+	}
 	screenshotPath := filepath.Join(os.ExpandEnv("$HOME"), "Desktop", fmt.Sprintf("screenshot_%s.png", time.Now().Format("20060102_150405")))
-	cmd := exec.Command("screencapture", "-i", screenshotPath)
-	return cmd.Run()
+	return a.platform.CaptureScreen(screenshotPath)
 }
 
 // メモを開く関数
-func (a *App) OpenMemo() error {
-	cmd := exec.Command("open", "-a", "Notes")
-	return cmd.Run()
+// token のプラグインに memo.open の権限が必要
+func (a *App) OpenMemo(token string) error {
+	if err := a.authorizePluginCall(token, "OpenMemo", PermissionMemoOpen, ""); err != nil {
+		return err
+	}
+	return a.platform.OpenMemoApp()
 }
 
-func (a *App) SimulateKeyPress(keyString string) error {
+// token のプラグインに keyboard.simulate の権限が必要
+func (a *App) SimulateKeyPress(token string, keyString string) error {
+	if err := a.authorizePluginCall(token, "SimulateKeyPress", PermissionKeyboard, auditKeySummary(keyString)); err != nil {
+		return err
+	}
 	trimmedKeyString := strings.TrimSpace(keyString)
 	fmt.Printf("Sending key string: '%s'\n", trimmedKeyString)
-	cKeyString := C.CString(trimmedKeyString)
-	defer C.free(unsafe.Pointer(cKeyString))
-	C.SimulateKeyPresses(cKeyString)
+	a.platform.SimulateKeyPresses(trimmedKeyString)
 
 	return nil
 }
 
-// GetPressedKeys 現在押されているキーをカンマ区切りの文字列として取得
-// 返り値: カンマ区切りのキー名文字列 (例: "lcommand,space")
-func (a *App) GetPressedKeys() string {
-	// C関数を呼び出して押されているキーの文字列を取得
-	cKeysString := C.GetPressedKeysString()
-	// 関数終了時にメモリを解放
-	defer C.free(unsafe.Pointer(cKeysString))
+// authorizePluginCall は token のプラグインが permission を使う method を呼べるかを確認する
+// 権限と呼び出し回数の上限を確認し、結果を args (引数の要約) とともに監査ログに残す
+func (a *App) authorizePluginCall(token string, method string, permission Permission, args string) error {
+	entry := AuditEntry{Method: method, Permission: permission, Args: args, Result: AuditAllowed}
+	record, ok := a.plugins.LookupToken(token)
+	var err error
+	if ok {
+		entry.PluginID = record.ID
+		err = a.permissions.Check(record, permission)
+		if err == nil {
+			err = a.quotas.Allow(record.ID, permission)
+		}
+	} else {
+		err = fmt.Errorf("%w: unknown or expired plugin token", ErrPermissionDenied)
+	}
+	if err != nil {
+		entry.Result = AuditDenied
+		entry.Error = err.Error()
+		fmt.Printf("Denied %s to plugin %q: %v\n", method, entry.PluginID, err)
+	}
+
+	if auditErr := a.audit.Record(entry); auditErr != nil {
+		// 記録できない呼び出しは許可しない
+		fmt.Printf("Error writing audit log: %v\n", auditErr)
+		if err == nil {
+			err = fmt.Errorf("%w: failed to write audit log: %v", ErrPermissionDenied, auditErr)
+		}
+	}
+	return err
+}
+
+// QueryAuditLog は権限が必要なメソッドの呼び出しの記録を検索する
+func (a *App) QueryAuditLog(query AuditQuery) ([]AuditEntry, error) {
+	return a.audit.Query(query)
+}
+
+// VerifyAuditLog は監査ログが書き換えられていないかを確認する
+func (a *App) VerifyAuditLog() (AuditVerification, error) {
+	return a.audit.Verify()
+}
 
-	// C文字列をGo文字列に変換
-	keysString := C.GoString(cKeysString)
+// promptPermission は初めて権限を使うプラグインを許可するかをダイアログでユーザーに確認する
+func (a *App) promptPermission(request PermissionRequest) (bool, error) {
+	if a.ctx == nil {
+		return false, fmt.Errorf("the app is not running")
+	}
 
-	return keysString
+	name := request.PluginName
+	if name == "" {
+		name = request.PluginID
+	}
+	description := permissionDescriptions[request.Permission]
+	if description == "" {
+		description = string(request.Permission)
+	}
+	selected, err := runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+		Type:          runtime.QuestionDialog,
+		Title:         "Plugin permission",
+		Message:       fmt.Sprintf("%s (%s) wants to %s.\nAllow this plugin to %s?", name, request.PluginID, description, description),
+		Buttons:       []string{"Allow", "Deny"},
+		DefaultButton: "Deny",
+		CancelButton:  "Deny",
+	})
+	if err != nil {
+		return false, err
+	}
+	// Linux ではボタン名を指定できず Yes/No になる
+	return selected == "Allow" || selected == "Yes", nil
 }
 
-// KeyStateCallback は、キーの状態が変化したときにC言語から呼び出されるコールバック関数
-//
-//export KeyStateCallback
-func KeyStateCallback(cKeysString *C.char) {
-	// C文字列をGo文字列に変換
-	keysString := C.GoString(cKeysString)
+// ListPermissionGrants は保存されているプラグインへの許可・拒否を返す
+func (a *App) ListPermissionGrants() []PermissionGrant {
+	return a.permissions.Grants()
+}
 
-	// アプリインスタンスにアクセスして、イベントを発火
-	if app != nil && app.ctx != nil {
-		runtime.EventsEmit(app.ctx, "key-state-changed", keysString)
+// RevokePluginPermission は pluginID のプラグインへの permission の許可・拒否を取り消す
+// 次に使われるときに改めてユーザーに確認する。loaderKey はホストのローダーのキーで、プラグインからは呼べない
+func (a *App) RevokePluginPermission(loaderKey string, pluginID string, permission string) error {
+	if err := a.authorizeHost(loaderKey, "RevokePluginPermission"); err != nil {
+		return err
 	}
+	return a.permissions.Revoke(pluginID, Permission(permission))
 }
 
-// バックグラウンドでのキー状態監視を保持するためのグローバル変数
-var app *App
+// GetPressedKeys 現在押されているキーをカンマ区切りの文字列として取得
+// 返り値: カンマ区切りのキー名文字列 (例: "lcommand,space")
+func (a *App) GetPressedKeys() string {
+	return a.platform.GetPressedKeys()
+}
 
 // StartKeyMonitoring バックグラウンドでのキー監視を開始
 func (a *App) StartKeyMonitoring() error {
-	// グローバル変数にアプリインスタンスを設定
-	app = a
+	// キーの状態が変化したらフロントエンドにイベントを発火
+	return a.platform.StartKeyMonitoring(func(keys string) {
+		a.events.PublishKeyState(keys)
 
-	// コールバック関数名をC文字列に変換
-	cCallbackName := C.CString("KeyStateCallback")
-	defer C.free(unsafe.Pointer(cCallbackName))
+		// キージェスチャーを検出
+		a.publishKeyGestures(a.keyGestures.Feed(keys, a.clock.Now()))
 
-	// キー監視を開始
-	C.StartKeyMonitoring(cCallbackName)
-
-	return nil
+		// 修飾キーが離されたらマウスジェスチャーを認識する
+		if gesture, ok := a.mouseGestures.FeedKeys(keys, a.clock.Now()); ok {
+			a.publishMouseGesture(gesture)
+		}
+	})
 }
 
 // StopKeyMonitoring バックグラウンドでのキー監視を停止
 func (a *App) StopKeyMonitoring() error {
 	// キー監視を停止
-	C.StopKeyMonitoring()
+	a.platform.StopKeyMonitoring()
 
 	return nil
 }
@@ -228,219 +379,168 @@ func (a *App) StopKeyMonitoring() error {
 func (a *App) GetPluginDirectories() []string {
 	fmt.Println("GetPluginDirectories called")
 
-	// ユーザーのホームディレクトリを取得
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Printf("Error getting user home directory: %v\n", err)
-		return []string{}
-	}
-
-	// プラグインディレクトリの優先順位
-	pluginDirs := []string{
-		filepath.Join(homeDir, ".config", "ghostcursor", "plugins"),
-		"/opt/ghostcursor/plugins",
-		filepath.Join(homeDir, "ghostcursor", "ghosts"),
-	}
-
-	fmt.Printf("Checking plugin directories: %v\n", pluginDirs)
-
-	existingDirs := []string{}
-	for _, dir := range pluginDirs {
-		_, err := os.Stat(dir)
-		if err == nil {
-			fmt.Printf("Directory exists: %s\n", dir)
-			existingDirs = append(existingDirs, dir)
-		} else if os.IsNotExist(err) {
-			fmt.Printf("Directory does not exist: %s\n", dir)
-		} else {
-			fmt.Printf("Error checking directory %s: %v\n", dir, err)
-		}
-	}
-
-	fmt.Printf("Returning existing plugin directories: %v\n", existingDirs)
-	return existingDirs
+	pluginDirs := pluginDirectories()
+	fmt.Printf("Returning existing plugin directories: %v\n", pluginDirs)
+	return pluginDirs
 }
 
-// ディレクトリ内のエントリを一覧
-func (a *App) ListPluginEntries(dir string) ([]DirectoryEntry, error) {
+// ListPluginEntries は token のプラグインのディレクトリの中の dir (相対パス) の内容を一覧にする
+func (a *App) ListPluginEntries(token string, dir string) ([]DirectoryEntry, error) {
 	fmt.Printf("ListPluginEntries called for: %s\n", dir)
 
-	// ディレクトリの存在を確認
-	dirInfo, err := os.Stat(dir)
+	root, err := a.pluginFSRoot(token, PluginFSInstall)
 	if err != nil {
-		if os.IsNotExist(err) {
-			fmt.Printf("Directory does not exist: %s\n", dir)
-		} else {
-			fmt.Printf("Error checking directory %s: %v\n", dir, err)
-		}
 		return nil, err
 	}
-
-	if !dirInfo.IsDir() {
-		errMsg := fmt.Sprintf("%s is not a directory", dir)
-		fmt.Println(errMsg)
-		return nil, fmt.Errorf(errMsg)
-	}
-
-	// ディレクトリの内容を読み取る
-	files, err := os.ReadDir(dir)
+	entries, err := listInstallDir(root, dir)
 	if err != nil {
-		fmt.Printf("Error reading directory %s: %v\n", dir, err)
+		fmt.Printf("Rejected ListPluginEntries: %v\n", err)
 		return nil, err
 	}
 
-	entries := make([]DirectoryEntry, 0, len(files))
-	for _, file := range files {
-		entry := DirectoryEntry{
-			Name:        file.Name(),
-			IsDirectory: file.IsDir(),
-		}
-		entries = append(entries, entry)
-	}
-
 	fmt.Printf("Found %d entries in %s\n", len(entries), dir)
 	return entries, nil
 }
 
-// プラグインのマニフェストを読み込む
-func (a *App) ReadPluginManifest(path string) (GhostManifest, error) {
-	fmt.Printf("ReadPluginManifest called for: %s\n", path)
+// ReadPluginManifest は token のプラグインのマニフェストを読み込む
+func (a *App) ReadPluginManifest(token string) (GhostManifest, error) {
 	var manifest GhostManifest
 
-	// ファイルの存在を確認
-	_, err := os.Stat(path)
+	root, err := a.pluginFSRoot(token, PluginFSInstall)
 	if err != nil {
-		if os.IsNotExist(err) {
-			fmt.Printf("Manifest file does not exist: %s\n", path)
-		} else {
-			fmt.Printf("Error checking manifest file %s: %v\n", path, err)
-		}
 		return manifest, err
 	}
+	fmt.Printf("ReadPluginManifest called for: %s\n", root)
 
 	// マニフェストファイルを読み込み
-	data, err := os.ReadFile(path)
+	data, err := readInstallFile(root, "manifest.json")
 	if err != nil {
-		fmt.Printf("Error reading manifest file %s: %v\n", path, err)
+		fmt.Printf("Error reading manifest file in %s: %v\n", root, err)
 		return manifest, err
 	}
 
-	// JSONとしてパース
-	err = json.Unmarshal(data, &manifest)
-	if err != nil {
-		fmt.Printf("Error parsing manifest file %s: %v\n", path, err)
-		return manifest, err
+	// スキーマに従って検証
+	manifest, errs, warnings := validateManifest(data)
+	for _, warning := range warnings {
+		fmt.Printf("Manifest warning in %s: %s\n", root, warning)
+	}
+	if len(errs) > 0 {
+		fmt.Printf("Invalid manifest file in %s: %s\n", root, joinIssues(errs))
+		return manifest, fmt.Errorf("invalid manifest in %s: %s", root, joinIssues(errs))
 	}
 
 	fmt.Printf("Successfully read manifest: %s (id: %s)\n", manifest.Name, manifest.ID)
 	return manifest, nil
 }
 
-// プラグインモジュールを読み込む
-func (a *App) ReadPluginModule(pluginDir string, moduleName string) (string, error) {
-	fmt.Printf("ReadPluginModule called for: %s/%s\n", pluginDir, moduleName)
+// ReadPluginModule は token のプラグインのディレクトリからモジュールを読み込む
+func (a *App) ReadPluginModule(token string, moduleName string) (string, error) {
+	fmt.Printf("ReadPluginModule called for: %s\n", moduleName)
+
+	root, err := a.pluginFSRoot(token, PluginFSInstall)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsLocal(filepath.FromSlash(moduleName)) {
+		err := fmt.Errorf("%w: invalid module name %q", ErrOutsideSandbox, moduleName)
+		fmt.Printf("Rejected ReadPluginModule: %v\n", err)
+		return "", err
+	}
 
-	// 探索する拡張子とパスの優先順位
+	// 探索するパスの優先順位 (プラグインのディレクトリからの相対パス)
 	possiblePaths := []string{
 		// 新しいindex.js/tsファイル
-		filepath.Join(pluginDir, "dist", "index.js"),
-		filepath.Join(pluginDir, "index.js"),
-		filepath.Join(pluginDir, "dist", "index.ts"),
-		filepath.Join(pluginDir, "index.ts"),
+		"dist/index.js",
+		"index.js",
+		"dist/index.ts",
+		"index.ts",
 		// コンパイル済みJavaScript (distディレクトリ)
-		filepath.Join(pluginDir, "dist", moduleName+".js"),
+		"dist/" + moduleName + ".js",
 		// ルートディレクトリのJavaScript
-		filepath.Join(pluginDir, moduleName+".js"),
+		moduleName + ".js",
 		// TypeScriptソースファイル (distディレクトリ)
-		filepath.Join(pluginDir, "dist", moduleName+".ts"),
+		"dist/" + moduleName + ".ts",
 		// ルートディレクトリのTypeScript
-		filepath.Join(pluginDir, moduleName+".ts"),
+		moduleName + ".ts",
 	}
 
 	// 各ファイルの存在を確認して最初に見つかったものを使用
-	var foundPath string
-	for _, path := range possiblePaths {
-		fmt.Printf("Checking for: %s\n", path)
-		_, err := os.Stat(path)
-		if err == nil {
-			foundPath = path
-			fmt.Printf("Found module at: %s\n", foundPath)
-			break
+	// シンボリックリンクでプラグインの外のファイルを読ませない
+	// 署名の対象にならない隠しディレクトリや node_modules のモジュールは読み込まない
+	for _, name := range possiblePaths {
+		path, err := installSandboxPath(root, name)
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			continue
 		}
-	}
-
-	if foundPath == "" {
-		errorMsg := fmt.Sprintf("Module file %s not found in any location", moduleName)
-		fmt.Println(errorMsg)
-		return "", fmt.Errorf(errorMsg)
-	}
 
-	// モジュールファイルを読み込み
-	data, err := os.ReadFile(foundPath)
-	if err != nil {
-		fmt.Printf("Error reading module file %s: %v\n", foundPath, err)
-		return "", err
+		data, err := readInstallFile(root, name)
+		if err != nil {
+			fmt.Printf("Error reading module file %s: %v\n", path, err)
+			return "", err
+		}
+		fmt.Printf("Successfully read module %s from %s\n", moduleName, path)
+		return string(data), nil
 	}
 
-	fmt.Printf("Successfully read module %s from %s\n", moduleName, foundPath)
-	return string(data), nil
+	errorMsg := fmt.Sprintf("Module file %s not found in any location", moduleName)
+	fmt.Println(errorMsg)
+	return "", fmt.Errorf(errorMsg)
 }
 
 // 単一のプラグインディレクトリを検証
-func (a *App) validatePlugin(pluginPath string) PluginValidationResult {
+func validatePlugin(pluginPath string) PluginValidationResult {
 	fmt.Printf("Validating plugin at: %s\n", pluginPath)
 
 	result := PluginValidationResult{
 		PluginPath: pluginPath,
 		IsValid:    false,
-		Errors:     []string{},
+		Errors:     []ValidationIssue{},
+		Warnings:   []ValidationIssue{},
 	}
 
 	// マニフェストファイルの確認
 	manifestPath := filepath.Join(pluginPath, "manifest.json")
 	manifestInfo, err := os.Stat(manifestPath)
 	if err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("Manifest file not found: %v", err))
+		result.Errors = append(result.Errors, ValidationIssue{Code: issueNotFound, Message: fmt.Sprintf("Manifest file not found: %v", err)})
 	} else if manifestInfo.IsDir() {
-		result.Errors = append(result.Errors, "Manifest is a directory, not a file")
+		result.Errors = append(result.Errors, ValidationIssue{Code: issueInvalidType, Message: "Manifest is a directory, not a file"})
 	} else {
 		result.HasManifest = true
 
 		// マニフェストファイルの内容を読み込む
 		manifestData, err := os.ReadFile(manifestPath)
 		if err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("Failed to read manifest: %v", err))
+			result.Errors = append(result.Errors, ValidationIssue{Code: issueNotFound, Message: fmt.Sprintf("Failed to read manifest: %v", err)})
 		} else {
-			// マニフェストがJSONとして解析できるかチェック
-			var manifestObj map[string]interface{}
-			if err := json.Unmarshal(manifestData, &manifestObj); err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("Invalid manifest JSON: %v", err))
-			} else {
+			// マニフェストをスキーマに従って検証
+			manifest, errs, warnings := validateManifest(manifestData)
+			result.Errors = append(result.Errors, errs...)
+			result.Warnings = append(result.Warnings, warnings...)
+			if len(errs) == 0 || errs[0].Code != issueInvalidJSON {
 				result.Manifest = manifestData
+				result.manifest = manifest
+			}
 
-				// アイコンパスの確認
-				if iconPath, ok := manifestObj["icon"].(string); ok {
-					// アイコンパスが相対パスの場合
-					if !filepath.IsAbs(iconPath) {
-						iconFullPath := filepath.Join(pluginPath, iconPath)
-						iconInfo, err := os.Stat(iconFullPath)
-						if err != nil {
-							// assetsディレクトリも確認
-							iconFullPath = filepath.Join(pluginPath, "assets", filepath.Base(iconPath))
-							iconInfo, err = os.Stat(iconFullPath)
-							if err != nil {
-								result.Errors = append(result.Errors, fmt.Sprintf("Icon file not found: %v", err))
-							} else if iconInfo.IsDir() {
-								result.Errors = append(result.Errors, "Icon path points to a directory")
-							} else {
-								result.HasIcon = true
-							}
-						} else if iconInfo.IsDir() {
-							result.Errors = append(result.Errors, "Icon path points to a directory")
-						} else {
-							result.HasIcon = true
-						}
-					}
+			// アイコンパスの確認 (アイコンが見つからなくてもプラグインは読み込むので警告にする)
+			if iconPath := manifest.Icon; iconPath != "" && !filepath.IsAbs(iconPath) &&
+				!strings.HasPrefix(iconPath, "http") && !strings.HasPrefix(iconPath, "data:") {
+				iconFullPath := filepath.Join(pluginPath, iconPath)
+				iconInfo, err := os.Stat(iconFullPath)
+				if err != nil {
+					// assetsディレクトリも確認
+					iconFullPath = filepath.Join(pluginPath, "assets", filepath.Base(iconPath))
+					iconInfo, err = os.Stat(iconFullPath)
+				}
+				if err != nil {
+					result.Warnings = append(result.Warnings, ValidationIssue{Path: "$.icon", Code: issueNotFound, Message: fmt.Sprintf("Icon file not found: %v", err)})
+				} else if iconInfo.IsDir() {
+					result.Warnings = append(result.Warnings, ValidationIssue{Path: "$.icon", Code: issueInvalidType, Message: "Icon path points to a directory"})
+				} else {
+					result.HasIcon = true
 				}
 			}
 		}
@@ -502,11 +602,11 @@ func (a *App) validatePlugin(pluginPath string) PluginValidationResult {
 
 		// どちらのモジュールも見つからない場合はエラー
 		if !contentExists && !backgroundExists {
-			result.Errors = append(result.Errors, "Neither index.js/ts nor content.js/ts and background.js/ts modules found")
+			result.Errors = append(result.Errors, ValidationIssue{Code: issueNotFound, Message: "Neither index.js/ts nor content.js/ts and background.js/ts modules found"})
 		} else if !contentExists {
-			result.Errors = append(result.Errors, "Content module file not found: tried .js and .ts in both dist/ and root directory")
+			result.Errors = append(result.Errors, ValidationIssue{Code: issueNotFound, Message: "Content module file not found: tried .js and .ts in both dist/ and root directory"})
 		} else if !backgroundExists {
-			result.Errors = append(result.Errors, "Background module file not found: tried .js and .ts in both dist/ and root directory")
+			result.Errors = append(result.Errors, ValidationIssue{Code: issueNotFound, Message: "Background module file not found: tried .js and .ts in both dist/ and root directory"})
 		} else {
 			// 両方存在する場合は両方の条件を満たす
 			result.HasContent = true
@@ -518,8 +618,11 @@ func (a *App) validatePlugin(pluginPath string) PluginValidationResult {
 		result.HasBackground = true
 	}
 
-	// 必須ファイルがすべて存在すれば有効とみなす
-	result.IsValid = result.HasManifest && result.HasContent && result.HasBackground
+	// 必須ファイルがすべて存在し、マニフェストに誤りがなければ有効とみなす
+	result.IsValid = result.HasManifest && result.HasContent && result.HasBackground && len(result.Errors) == 0
+	for _, warning := range result.Warnings {
+		fmt.Printf("Plugin warning for %s: %s\n", pluginPath, warning)
+	}
 	if len(result.Errors) > 0 {
 		fmt.Printf("Plugin validation failed for %s:\n", pluginPath)
 		for _, err := range result.Errors {
@@ -529,100 +632,88 @@ func (a *App) validatePlugin(pluginPath string) PluginValidationResult {
 		fmt.Printf("Plugin validation successful for %s\n", pluginPath)
 	}
 
+	result.Files = listPluginFiles(pluginPath)
 	return result
 }
 
-// アイコンファイルのURLを生成
-func (a *App) GetIconURL(path string) (string, error) {
-	// ファイルが存在するか確認
-	_, err := os.Stat(path)
+// listPluginFiles はプラグインのディレクトリの内容を2階層まで返す (ディレクトリは "/" で終わる、診断画面に表示する)
+func listPluginFiles(dir string) []string {
+	entries, err := listSandboxDir(dir, ".")
 	if err != nil {
-		return "", err
+		return nil
 	}
 
-	// ファイルスキームURLを返す
-	absolutePath, err := filepath.Abs(path)
+	files := []string{}
+	for _, entry := range entries {
+		if !entry.IsDirectory {
+			files = append(files, entry.Name)
+			continue
+		}
+		subEntries, err := listSandboxDir(dir, entry.Name)
+		if err != nil {
+			files = append(files, entry.Name+"/")
+			continue
+		}
+		for _, subEntry := range subEntries {
+			name := entry.Name + "/" + subEntry.Name
+			if subEntry.IsDirectory {
+				name += "/"
+			}
+			files = append(files, name)
+		}
+	}
+	return files
+}
+
+// GetIconURL は token のプラグインのアイコンファイル path (プラグインのディレクトリからの相対パス) のURLを返す
+func (a *App) GetIconURL(token string, path string) (string, error) {
+	root, err := a.pluginFSRoot(token, PluginFSInstall)
 	if err != nil {
 		return "", err
 	}
+	iconPath := resolvePluginIcon(root, path)
+	if iconPath == "" {
+		return "", fmt.Errorf("icon %s not found in the plugin directory", path)
+	}
 
-	return "file://" + absolutePath, nil
+	// ファイルスキームURLを返す
+	return "file://" + iconPath, nil
 }
 
-// GetIconData 関数を修正
-func (a *App) GetIconData(path string) (string, error) {
+// GetIconData は token のプラグインのアイコンファイル path (プラグインのディレクトリからの相対パス) を data URL で返す
+// 見つからない場合は既定のアイコンを返す
+func (a *App) GetIconData(token string, path string) (string, error) {
 	fmt.Printf("GetIconData called for: %s\n", path)
 
+	root, err := a.pluginFSRoot(token, PluginFSInstall)
+	if err != nil {
+		return "", err
+	}
+	if iconPath := resolvePluginIcon(root, path); iconPath != "" {
+		if dataURL, err := iconDataURL(iconPath); err == nil {
+			return dataURL, nil
+		}
+	}
+
 	// デフォルトアイコンパス
 	defaultIconPath := filepath.Join("frontend", "dist", "assets", "images", "ghost.png")
-
-	// 読み込む対象のパスを決定
-	targetPath := path
-	var fileData []byte
-	var err error
-
-	// 相対パスの場合は絶対パスに変換を試みる
-	if !filepath.IsAbs(path) {
-		// プラグインディレクトリパスのリストを取得
-		pluginDirs := a.GetPluginDirectories()
-
-		// まずは単純に指定されたパスで読み込み試行
-		fileData, err = os.ReadFile(path)
-		if err != nil {
-			fmt.Printf("Failed to read icon directly from %s, trying plugin directories\n", path)
-
-			// 各プラグインディレクトリで探索
-			for _, dir := range pluginDirs {
-				entries, err := a.ListPluginEntries(dir)
-				if err != nil {
-					continue
-				}
-
-				for _, entry := range entries {
-					if entry.IsDirectory {
-						// 可能なアイコンパスを検索
-						possiblePaths := []string{
-							filepath.Join(dir, entry.Name, path),
-							filepath.Join(dir, entry.Name, "assets", filepath.Base(path)),
-						}
-
-						for _, iconPath := range possiblePaths {
-							fmt.Printf("Trying path: %s\n", iconPath)
-							fileData, err = os.ReadFile(iconPath)
-							if err == nil {
-								fmt.Printf("Successfully read icon from: %s\n", iconPath)
-								targetPath = iconPath
-								break
-							}
-						}
-
-						if err == nil {
-							break
-						}
-					}
-				}
-
-				if err == nil {
-					break
-				}
-			}
-		}
-	} else {
-		// 絶対パスの場合は直接読み込み
-		fileData, err = os.ReadFile(path)
+	fmt.Printf("Failed to read icon from %s, using default: %s\n", path, defaultIconPath)
+	dataURL, err := iconDataURL(defaultIconPath)
+	if err != nil {
+		return "", fmt.Errorf("could not read icon file or default icon: %v", err)
 	}
+	return dataURL, nil
+}
 
-	// 読み込みに失敗した場合はデフォルトアイコンを試行
+// iconDataURL はアイコンファイルを読み込んで data URL にする
+func iconDataURL(path string) (string, error) {
+	fileData, err := os.ReadFile(path)
 	if err != nil {
-		fmt.Printf("Failed to read icon from %s, using default: %s\n", path, defaultIconPath)
-		fileData, err = os.ReadFile(defaultIconPath)
-		if err != nil {
-			return "", fmt.Errorf("could not read icon file or default icon: %v", err)
-		}
+		return "", err
 	}
 
 	// ファイル拡張子からMIMEタイプを特定
-	extension := strings.ToLower(filepath.Ext(targetPath))
+	extension := strings.ToLower(filepath.Ext(path))
 	mimeType := "image/png" // デフォルト
 
 	switch extension {
@@ -638,32 +729,45 @@ func (a *App) GetIconData(path string) (string, error) {
 
 	// Base64でエンコード
 	base64Data := base64.StdEncoding.EncodeToString(fileData)
-	dataURL := fmt.Sprintf("data:%s;base64,%s", mimeType, base64Data)
-
-	return dataURL, nil
+	return fmt.Sprintf("data:%s;base64,%s", mimeType, base64Data), nil
 }
 
 // ゴーストを切り替える
 func (a *App) SwitchGhost(ghostId string) {
 	// フロントエンドにイベントを送信
-	runtime.EventsEmit(a.ctx, "switch-ghost", ghostId)
+	a.events.PublishSwitchGhost(ghostId)
 }
 
-// プラグインからのログを記録する関数
-func (a *App) WritePluginLog(pluginId string, level LogLevel, message string) error {
-	// ログディレクトリの設定 - ~/.ghostcursor/logs/
+// pluginLogPath はプラグインのログファイル (~/.ghostcursor/logs/<id>.log) のパスを返す
+// ログディレクトリの外を指さないよう、id はプラグインIDの書式のものだけを受け付ける
+func pluginLogPath(pluginId string) (string, error) {
+	if issues := validatePluginIDField("$.id", pluginId); len(issues) > 0 {
+		return "", fmt.Errorf("invalid plugin id %q: %s", pluginId, joinIssues(issues))
+	}
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return err
+		return "", err
 	}
+	return filepath.Join(homeDir, ".ghostcursor", "logs", fmt.Sprintf("%s.log", pluginId)), nil
+}
 
-	logDir := filepath.Join(homeDir, ".ghostcursor", "logs")
-	if err := os.MkdirAll(logDir, 0755); err != nil {
+// token のプラグインからのログを記録する関数
+func (a *App) WritePluginLog(token string, level LogLevel, message string) error {
+	pluginId, err := a.pluginForToken(token)
+	if err != nil {
 		return err
 	}
 
 	// プラグイン固有のログファイル
-	logPath := filepath.Join(logDir, fmt.Sprintf("%s.log", pluginId))
+	logPath, err := pluginLogPath(pluginId)
+	if err != nil {
+		return err
+	}
+
+	// ログディレクトリの設定 - ~/.ghostcursor/logs/
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return err
+	}
 
 	// ファイルを開く（存在しない場合は作成し、存在する場合は追記モードで開く）
 	file, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
@@ -685,14 +789,16 @@ func (a *App) WritePluginLog(pluginId string, level LogLevel, message string) er
 	return err
 }
 
-// プラグインのログファイルを読み込む
-func (a *App) ReadPluginLogs(pluginId string, maxLines int) ([]string, error) {
-	homeDir, err := os.UserHomeDir()
+// token のプラグインのログファイルを読み込む (他のプラグインのログは読めない)
+func (a *App) ReadPluginLogs(token string, maxLines int) ([]string, error) {
+	pluginId, err := a.pluginForToken(token)
+	if err != nil {
+		return nil, err
+	}
+	logPath, err := pluginLogPath(pluginId)
 	if err != nil {
 		return nil, err
 	}
-
-	logPath := filepath.Join(homeDir, ".ghostcursor", "logs", fmt.Sprintf("%s.log", pluginId))
 
 	// ファイルが存在しなければ空の配列を返す
 	if _, err := os.Stat(logPath); os.IsNotExist(err) {
@@ -724,14 +830,16 @@ func (a *App) ReadPluginLogs(pluginId string, maxLines int) ([]string, error) {
 	return nonEmptyLines, nil
 }
 
-// プラグインのログファイルをクリア
-func (a *App) ClearPluginLogs(pluginId string) error {
-	homeDir, err := os.UserHomeDir()
+// token のプラグインのログファイルをクリア
+func (a *App) ClearPluginLogs(token string) error {
+	pluginId, err := a.pluginForToken(token)
+	if err != nil {
+		return err
+	}
+	logPath, err := pluginLogPath(pluginId)
 	if err != nil {
 		return err
 	}
-
-	logPath := filepath.Join(homeDir, ".ghostcursor", "logs", fmt.Sprintf("%s.log", pluginId))
 
 	// ファイルが存在しなければ何もしない
 	if _, err := os.Stat(logPath); os.IsNotExist(err) {
@@ -743,194 +851,991 @@ func (a *App) ClearPluginLogs(pluginId string) error {
 }
 
 // プラグインの検証を行う
+// プラグインディレクトリをスキャンし直し、IDが重複したものも含めたすべての検証結果を返す
 func (a *App) ValidatePlugins() []PluginValidationResult {
 	fmt.Println("ValidatePlugins called")
 
-	results := []PluginValidationResult{}
-
-	// プラグインディレクトリを取得
-	pluginDirs := a.GetPluginDirectories()
+	a.publishPluginChanges(a.plugins.Rescan())
+	return a.plugins.Validations()
+}
 
-	for _, dir := range pluginDirs {
-		dirResults := a.ValidatePluginDirectory(dir)
-		results = append(results, dirResults...)
-	}
+// ListPlugins はスキャン済みのプラグインを読み込む順 (依存先が先) に返す
+func (a *App) ListPlugins() []PluginRecord {
+	return a.state.Apply(a.plugins.Plugins())
+}
 
-	return results
+// RescanPlugins はプラグインディレクトリをスキャンし直して結果を返す
+// 前回のスキャンからの変更は plugin-added などのイベントでも通知される
+func (a *App) RescanPlugins() []PluginRecord {
+	a.publishPluginChanges(a.plugins.Rescan())
+	return a.ListPlugins()
 }
 
-// 単一のプラグインディレクトリを検証
-func (a *App) ValidatePluginDirectory(dir string) []PluginValidationResult {
-	fmt.Printf("ValidatePluginDirectory called for: %s\n", dir)
+// EnablePlugin は id のプラグインを有効にする
+// 無効から変わった場合は plugin-enabled を送信する。loaderKey はホストのローダーのキーで、プラグインからは呼べない
+func (a *App) EnablePlugin(loaderKey string, id string) (PluginRecord, error) {
+	if err := a.authorizeHost(loaderKey, "EnablePlugin"); err != nil {
+		return PluginRecord{}, err
+	}
+	return a.setPluginEnabled(id, true)
+}
 
-	results := []PluginValidationResult{}
+// DisablePlugin は id のプラグインを無効にする (次に有効にするまで読み込まれず、ショートカットも登録しない)
+// 有効から変わった場合は plugin-disabled を送信する。loaderKey はホストのローダーのキーで、プラグインからは呼べない
+func (a *App) DisablePlugin(loaderKey string, id string) (PluginRecord, error) {
+	if err := a.authorizeHost(loaderKey, "DisablePlugin"); err != nil {
+		return PluginRecord{}, err
+	}
+	return a.setPluginEnabled(id, false)
+}
 
-	// ディレクトリの存在を確認
-	dirInfo, err := os.Stat(dir)
+func (a *App) setPluginEnabled(id string, enabled bool) (PluginRecord, error) {
+	if _, ok := a.plugins.Lookup(id); !ok {
+		return PluginRecord{}, fmt.Errorf("plugin %q not found", id)
+	}
+	before := a.ListPlugins()
+	changed, err := a.state.SetEnabled(id, enabled)
 	if err != nil {
-		fmt.Printf("Error: Failed to access directory %s: %v\n", dir, err)
-		return results
+		fmt.Printf("Error saving state of plugin %s: %v\n", id, err)
+		return PluginRecord{}, err
+	}
+	record := a.pluginWithState(id)
+	if !changed {
+		return record, nil
 	}
 
-	if !dirInfo.IsDir() {
-		fmt.Printf("Error: %s is not a directory\n", dir)
-		return results
+	if !enabled {
+		// 無効にしたプラグインからは権限が必要なメソッドを呼べなくする
+		a.plugins.RevokeToken(id)
 	}
+	fmt.Printf("Plugin %s enabled: %v\n", id, enabled)
+	a.events.PublishPluginEnabled(record)
 
-	// ディレクトリ内のエントリを取得
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		fmt.Printf("Error: Failed to read directory %s: %v\n", dir, err)
-		return results
+	// 依存先が無効になった (または有効に戻った) プラグインの変更を知らせる
+	valid := make(map[string]bool, len(before))
+	for _, plugin := range before {
+		valid[plugin.ID] = plugin.Valid
 	}
+	for _, plugin := range a.ListPlugins() {
+		if plugin.ID == id || plugin.ID == "" || valid[plugin.ID] == plugin.Valid {
+			continue
+		}
+		if !plugin.Valid {
+			a.plugins.RevokeToken(plugin.ID)
+		}
+		fmt.Printf("Plugin %s valid: %v (dependency %s enabled: %v)\n", plugin.ID, plugin.Valid, id, enabled)
+		a.events.PublishPluginChange(PluginChange{Kind: PluginUpdated, Plugin: plugin})
+	}
+	a.registerPluginHotkeys()
+	return record, nil
+}
 
-	// 各サブディレクトリを検証
-	for _, entry := range entries {
-		if entry.IsDir() {
-			pluginPath := filepath.Join(dir, entry.Name())
-			result := a.validatePlugin(pluginPath)
-			results = append(results, result)
+// ReorderPlugins は ids の順にゴーストを並べ替えて保存し、並び順の位置を埋めたプラグインの一覧 (読み込み順) を返す
+// ids にないプラグインは以前の並び順のまま後ろに並ぶ。先頭のゴーストが既定のゴーストになる
+// loaderKey はホストのローダーのキーで、プラグインからは呼べない
+func (a *App) ReorderPlugins(loaderKey string, ids []string) ([]PluginRecord, error) {
+	if err := a.authorizeHost(loaderKey, "ReorderPlugins"); err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if _, ok := a.plugins.Lookup(id); !ok {
+			return nil, fmt.Errorf("plugin %q not found", id)
 		}
 	}
+	if err := a.state.Reorder(ids); err != nil {
+		return nil, err
+	}
 
-	return results
+	records := a.ListPlugins()
+	// マニフェストが読めずIDのないプラグインは並び順に含めない
+	byPosition := slices.Clone(records)
+	slices.SortFunc(byPosition, func(x, y PluginRecord) int { return x.Position - y.Position })
+	order := make([]string, 0, len(byPosition))
+	for _, record := range byPosition {
+		if record.ID != "" {
+			order = append(order, record.ID)
+		}
+	}
+	a.events.PublishPluginsReordered(order)
+	return records, nil
 }
 
-// GetMousePosX マウスのX座標を取得
-func (a *App) GetMousePosX() float64 {
-	return float64(C.GetMousePosX())
+// pluginWithState は id のプラグインを有効・無効と並び順の位置を埋めて返す
+func (a *App) pluginWithState(id string) PluginRecord {
+	for _, record := range a.ListPlugins() {
+		if record.ID == id {
+			return record
+		}
+	}
+	return PluginRecord{}
 }
 
-// GetMousePosY マウスのY座標を取得
-func (a *App) GetMousePosY() float64 {
-	return float64(C.GetMousePosY())
+// startPluginWatching はプラグインディレクトリの変更の監視を開始する
+func (a *App) startPluginWatching() {
+	a.monitors.Start(monitorPlugins, a.runPluginWatcher)
 }
 
-// startup関数のリファクタリング
-func (a *App) startup(ctx context.Context) {
-	a.ctx = ctx
-
-	// グローバル変数に確実に設定
-	app = a
-
-	fmt.Println("App startup: context and global app variable initialized")
-
-	// マウス位置を定期的に取得して通知するゴルーチン
-	go func() {
-		// 約60FPS (16ms間隔) でマウス位置を更新
-		ticker := time.NewTicker(20 * time.Millisecond)
-		defer ticker.Stop()
-
-		for range ticker.C {
-			// 内製化したC関数を使ってマウス位置を取得
-			x := a.GetMousePosX()
-			y := a.GetMousePosY()
-
+// runPluginWatcher はプラグインディレクトリの変更を監視し、変更が落ち着いたらスキャンし直してフロントエンドに通知する
+func (a *App) runPluginWatcher(ctx context.Context) {
+	watcher, err := newPluginWatcher(pluginRootCandidates)
+	if err != nil {
+		fmt.Printf("Plugin hot reload is disabled: %v\n", err)
+		return
+	}
+	defer watcher.Close()
+
+	// 監視を始めるまでの変更を反映する
+	a.publishPluginChanges(a.plugins.Sync())
+
+	debounce := time.NewTimer(pluginWatchDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events():
+			if !ok {
+				return
+			}
+			if watcher.Relevant(event.Name) {
+				debounce.Reset(pluginWatchDebounce)
+			}
+		case err, ok := <-watcher.Errors():
+			if !ok {
+				return
+			}
+			fmt.Printf("Error watching plugin directories: %v\n", err)
+		case <-debounce.C:
+			// 追加されたディレクトリや plugin-roots.json の変更を監視に反映してからスキャンする
+			watcher.Refresh()
+			a.publishPluginChanges(a.plugins.Sync())
+		}
+	}
+}
+
+// publishPluginChanges はプラグインの変更を通知し、プラグインのショートカットを登録し直す
+func (a *App) publishPluginChanges(changes []PluginChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	for _, change := range changes {
+		fmt.Printf("Plugin %s: %s (%s)\n", change.Kind, change.Plugin.ID, change.Plugin.Dir)
+		if change.Kind != PluginRemoved {
+			change.Plugin = a.pluginWithState(change.Plugin.ID)
+		}
+		a.events.PublishPluginChange(change)
+	}
+	a.registerPluginHotkeys()
+}
+
+// InstallPlugin はプラグインのアーカイブ (manifest.json を直下に置いたzip) をユーザーのプラグインディレクトリにインストールする
+// 同じIDのプラグインがあれば置き換え、以前の版は RollbackPlugin で戻せるように残す
+// loaderKey はホストのローダーのキーで、プラグインからは呼べない
+func (a *App) InstallPlugin(loaderKey string, path string) (PluginRecord, error) {
+	if err := a.authorizeHost(loaderKey, "InstallPlugin"); err != nil {
+		return PluginRecord{}, err
+	}
+	return a.installPluginArchive(path, "")
+}
+
+func (a *App) installPluginArchive(path, expectedID string) (PluginRecord, error) {
+	id, err := a.installer.Install(path, expectedID)
+	if err != nil {
+		fmt.Printf("Error installing plugin %s: %v\n", path, err)
+		return PluginRecord{}, err
+	}
+
+	a.publishPluginChanges(a.plugins.Rescan())
+	record, ok := a.plugins.Lookup(id)
+	if !ok {
+		return PluginRecord{}, fmt.Errorf("plugin %q was installed but not found by the rescan", id)
+	}
+	return record, nil
+}
+
+// UninstallPlugin はユーザーのプラグインディレクトリから id のプラグインを取り除く
+// 取り除いたプラグインは RollbackPlugin で戻せる。loaderKey はホストのローダーのキーで、プラグインからは呼べない
+func (a *App) UninstallPlugin(loaderKey string, id string) error {
+	if err := a.authorizeHost(loaderKey, "UninstallPlugin"); err != nil {
+		return err
+	}
+	if err := a.installer.Uninstall(id); err != nil {
+		fmt.Printf("Error uninstalling plugin %s: %v\n", id, err)
+		return err
+	}
+	a.clearPluginData(id)
+
+	a.publishPluginChanges(a.plugins.Rescan())
+	return nil
+}
+
+// clearPluginData はアンインストールしたプラグインについて保存したものをすべて削除する
+// 同じIDで入れ直したプラグインに、以前の許可やデータを引き継がせない
+func (a *App) clearPluginData(id string) {
+	if err := a.storage.Clear(id); err != nil {
+		fmt.Printf("Error clearing storage of plugin %s: %v\n", id, err)
+	}
+	if err := a.permissions.Clear(id); err != nil {
+		fmt.Printf("Error clearing permissions of plugin %s: %v\n", id, err)
+	}
+	if err := a.settings.Clear(id); err != nil {
+		fmt.Printf("Error clearing settings of plugin %s: %v\n", id, err)
+	}
+	if err := a.state.Forget(id); err != nil {
+		fmt.Printf("Error clearing state of plugin %s: %v\n", id, err)
+	}
+	if dir, err := pluginDataDir(id); err != nil {
+		fmt.Printf("Error clearing data directory of plugin %s: %v\n", id, err)
+	} else if err := os.RemoveAll(dir); err != nil {
+		fmt.Printf("Error clearing data directory of plugin %s: %v\n", id, err)
+	}
+}
+
+// RollbackPlugin は id のプラグインを置き換え・アンインストールする前の版に戻す
+// loaderKey はホストのローダーのキーで、プラグインからは呼べない
+func (a *App) RollbackPlugin(loaderKey string, id string) (PluginRecord, error) {
+	if err := a.authorizeHost(loaderKey, "RollbackPlugin"); err != nil {
+		return PluginRecord{}, err
+	}
+	if err := a.installer.Rollback(id); err != nil {
+		fmt.Printf("Error rolling back plugin %s: %v\n", id, err)
+		return PluginRecord{}, err
+	}
+
+	a.publishPluginChanges(a.plugins.Rescan())
+	record, ok := a.plugins.Lookup(id)
+	if !ok {
+		return PluginRecord{}, fmt.Errorf("plugin %q was restored but not found by the rescan", id)
+	}
+	return record, nil
+}
+
+// ListInstalledPlugins はユーザーのプラグインディレクトリにあるプラグインを返す
+func (a *App) ListInstalledPlugins() []InstalledPlugin {
+	pluginDir, err := a.installer.PluginDir()
+	if err != nil {
+		fmt.Printf("Error getting plugin directory: %v\n", err)
+		return []InstalledPlugin{}
+	}
+
+	installed := []InstalledPlugin{}
+	for _, plugin := range a.plugins.Plugins() {
+		if plugin.Root == pluginDir && plugin.ID != "" {
+			installed = append(installed, InstalledPlugin{Plugin: plugin, PreviousVersion: a.installer.PreviousVersion(plugin.ID)})
+		}
+	}
+	return installed
+}
+
+// SearchStore はプラグインストアから query に一致するプラグインを探す (空なら全件)
+func (a *App) SearchStore(query string) ([]StorePlugin, error) {
+	plugins, err := a.store.Search(query)
+	if err != nil {
+		fmt.Printf("Error searching plugin store: %v\n", err)
+		return nil, err
+	}
+	for i := range plugins {
+		plugins[i].InstalledVersion = a.installedVersion(plugins[i].ID)
+	}
+	return plugins, nil
+}
+
+// GetPluginDetails はプラグインストアの id のプラグインの情報を返す
+func (a *App) GetPluginDetails(id string) (StorePlugin, error) {
+	plugin, err := a.store.Lookup(id)
+	if err != nil {
+		return StorePlugin{}, err
+	}
+	plugin.InstalledVersion = a.installedVersion(id)
+	return plugin, nil
+}
+
+// CheckForUpdates はストアの索引を取得し直し、インストール済みのものより新しく、このバージョンのアプリで動く版があるプラグインを返す
+func (a *App) CheckForUpdates() ([]PluginUpdate, error) {
+	index, err := a.store.Index(true)
+	if err != nil {
+		fmt.Printf("Error checking for plugin updates: %v\n", err)
+		return nil, err
+	}
+	host, _ := parseSemver(hostVersion)
+
+	updates := []PluginUpdate{}
+	for _, latest := range index {
+		record, ok := a.plugins.Lookup(latest.ID)
+		if !ok {
+			continue
+		}
+		installed, err := parseSemver(record.Manifest.Version)
+		if err != nil {
+			continue
+		}
+		version, _ := parseSemver(latest.Version)
+		if version.Compare(installed) <= 0 {
+			continue
+		}
+		if issues := checkPluginEngines(GhostManifest{Engines: latest.Engines}, host); len(issues) > 0 {
+			fmt.Printf("Skipping update of plugin %s to %s: %s\n", latest.ID, latest.Version, joinIssues(issues))
+			continue
+		}
+		updates = append(updates, PluginUpdate{
+			ID:               latest.ID,
+			Name:             latest.Name,
+			InstalledVersion: record.Manifest.Version,
+			LatestVersion:    latest.Version,
+		})
+	}
+	return updates, nil
+}
+
+// InstallFromStore はプラグインストアから id のプラグインをダウンロードし、チェックサムを確かめてからインストールする
+// loaderKey はホストのローダーのキーで、プラグインからは呼べない
+func (a *App) InstallFromStore(loaderKey string, id string) (PluginRecord, error) {
+	if err := a.authorizeHost(loaderKey, "InstallFromStore"); err != nil {
+		return PluginRecord{}, err
+	}
+	plugin, err := a.store.Lookup(id)
+	if err != nil {
+		return PluginRecord{}, err
+	}
+	host, _ := parseSemver(hostVersion)
+	if issues := checkPluginEngines(GhostManifest{Engines: plugin.Engines}, host); len(issues) > 0 {
+		return PluginRecord{}, fmt.Errorf("plugin %s %s is not compatible: %s", id, plugin.Version, joinIssues(issues))
+	}
+
+	path, err := a.store.Download(plugin)
+	if err != nil {
+		fmt.Printf("Error downloading plugin %s: %v\n", id, err)
+		return PluginRecord{}, err
+	}
+	defer os.Remove(path)
+	return a.installPluginArchive(path, id)
+}
+
+// installedVersion は id のプラグインの読み込まれているバージョンを返す (なければ空)
+func (a *App) installedVersion(id string) string {
+	if record, ok := a.plugins.Lookup(id); ok {
+		return record.Manifest.Version
+	}
+	return ""
+}
+
+// PluginEntry は LoadPlugin の結果
+type PluginEntry struct {
+	// 読み込むモジュールのソースコード
+	Code string `json:"code"`
+	// このプラグインからの呼び出しであることを示すトークン (権限が必要なメソッドに渡す)
+	Token string `json:"token"`
+	// アイコンファイルの data URL (アイコンがファイルでない場合は空)
+	Icon string `json:"icon,omitempty"`
+}
+
+// OpenPluginLoader はプラグインを読み込むためのキーを返す
+// フロントエンドのローダーがページを読み込むたびに、plugin-loader-ready を受け取ってからプラグインのコードを実行する前に一度だけ呼ぶ
+// その後にプラグインが呼んでもキーは得られない
+func (a *App) OpenPluginLoader() (string, error) {
+	return a.plugins.OpenLoader()
+}
+
+// authorizeHost は key がホストのローダーのキーかを確かめる
+// プラグインのインストールや権限の取り消しなど、アプリの画面だけが使うメソッドに使う
+func (a *App) authorizeHost(key string, method string) error {
+	if a.plugins.IsLoader(key) {
+		return nil
+	}
+	fmt.Printf("Rejected %s: invalid loader key\n", method)
+	return fmt.Errorf("%w: %s can only be called by the app", ErrPermissionDenied, method)
+}
+
+// resetPluginLoader はフロントエンドのページが読み込まれるたびに呼ばれ、以前のページのキーとトークンを無効にする
+// 新しいページのローダーには plugin-loader-ready でキーを受け取れるようになったことを知らせる
+func (a *App) resetPluginLoader() {
+	a.plugins.ResetLoader()
+	a.events.PublishPluginLoaderReady()
+}
+
+// LoadPlugin は id のプラグインのモジュールを読み込み、プラグインのトークンを発行する
+// loaderKey は OpenPluginLoader で受け取ったキーで、ホストのローダー以外はトークンを得られない
+// 同じプラグインを読み込み直すと、以前のトークンは使えなくなる
+func (a *App) LoadPlugin(loaderKey string, id string) (PluginEntry, error) {
+	if err := a.authorizeHost(loaderKey, "LoadPlugin"); err != nil {
+		return PluginEntry{}, err
+	}
+	// 無効にしたプラグインに依存するプラグインも読み込めないように、有効・無効を反映した情報で確かめる
+	record := a.pluginWithState(id)
+	if record.ID == "" {
+		return PluginEntry{}, fmt.Errorf("plugin %q not found", id)
+	}
+	if !record.Enabled {
+		return PluginEntry{}, fmt.Errorf("plugin %q is disabled", id)
+	}
+	if !record.Valid || record.EntryFile == "" {
+		return PluginEntry{}, fmt.Errorf("plugin %q is not valid: %s", id, joinIssues(record.Errors))
+	}
+
+	data, err := os.ReadFile(record.EntryFile)
+	if err != nil {
+		return PluginEntry{}, fmt.Errorf("failed to read %s: %w", record.EntryFile, err)
+	}
+	entry := PluginEntry{Code: string(data)}
+	if record.IconPath != "" {
+		if entry.Icon, err = iconDataURL(record.IconPath); err != nil {
+			fmt.Printf("Failed to read icon of plugin %s: %v\n", id, err)
+		}
+	}
+	if entry.Token, err = a.plugins.IssueToken(id); err != nil {
+		return PluginEntry{}, err
+	}
+	return entry, nil
+}
+
+// pluginFSRoot は token のプラグインがファイルAPIで使う area のディレクトリを返す
+func (a *App) pluginFSRoot(token string, area string) (string, error) {
+	record, ok := a.plugins.LookupToken(token)
+	if !ok {
+		return "", fmt.Errorf("%w: unknown or expired plugin token", ErrPermissionDenied)
+	}
+
+	switch area {
+	case PluginFSInstall:
+		return record.Dir, nil
+	case PluginFSData:
+		dir, err := pluginDataDir(record.ID)
+		if err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return "", fmt.Errorf("failed to create data directory for plugin %s: %w", record.ID, err)
+		}
+		return dir, nil
+	}
+	return "", fmt.Errorf("unknown plugin file area %q (expected %q or %q)", area, PluginFSInstall, PluginFSData)
+}
+
+// ListPluginFiles は token のプラグインの area (install または data) の中の dir (相対パス) の内容を返す
+// token のプラグインに fs.read の権限が必要
+func (a *App) ListPluginFiles(token string, area string, dir string) ([]DirectoryEntry, error) {
+	if err := a.authorizePluginCall(token, "ListPluginFiles", PermissionFSRead, auditPathSummary(area, dir)); err != nil {
+		return nil, err
+	}
+	root, err := a.pluginFSRoot(token, area)
+	if err != nil {
+		return nil, err
+	}
+	list := listSandboxDir
+	if area == PluginFSInstall {
+		list = listInstallDir
+	}
+	entries, err := list(root, dir)
+	if err != nil {
+		fmt.Printf("ListPluginFiles %s/%s failed: %v\n", area, dir, err)
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ReadPluginFile は token のプラグインの area (install または data) の中のファイル name (相対パス) を読み込む
+// token のプラグインに fs.read の権限が必要
+func (a *App) ReadPluginFile(token string, area string, name string) (string, error) {
+	if err := a.authorizePluginCall(token, "ReadPluginFile", PermissionFSRead, auditPathSummary(area, name)); err != nil {
+		return "", err
+	}
+	root, err := a.pluginFSRoot(token, area)
+	if err != nil {
+		return "", err
+	}
+	read := readSandboxFile
+	if area == PluginFSInstall {
+		read = readInstallFile
+	}
+	data, err := read(root, name)
+	if err != nil {
+		fmt.Printf("ReadPluginFile %s/%s failed: %v\n", area, name, err)
+		return "", err
+	}
+	return string(data), nil
+}
+
+// WritePluginFile は token のプラグインのデータディレクトリにファイル name (相対パス) を書き込む
+// インストール先のディレクトリには書き込めない。token のプラグインに fs.write の権限が必要
+func (a *App) WritePluginFile(token string, name string, content string) error {
+	if err := a.authorizePluginCall(token, "WritePluginFile", PermissionFSWrite, auditPathSummary(PluginFSData, name)); err != nil {
+		return err
+	}
+	root, err := a.pluginFSRoot(token, PluginFSData)
+	if err != nil {
+		return err
+	}
+	if err := writeSandboxFile(root, name, []byte(content)); err != nil {
+		fmt.Printf("WritePluginFile %s failed: %v\n", name, err)
+		return err
+	}
+	return nil
+}
+
+// GetPluginSettings は token のプラグインの設定項目と現在の値を返す
+func (a *App) GetPluginSettings(token string) (PluginSettings, error) {
+	record, ok := a.plugins.LookupToken(token)
+	if !ok {
+		return PluginSettings{}, fmt.Errorf("%w: unknown or expired plugin token", ErrPermissionDenied)
+	}
+	return a.settings.Get(record), nil
+}
+
+// SetPluginSettings は token のプラグインの設定のうち values にある項目を変更する (null の項目は既定値に戻す)
+// 値が変わった項目があれば plugin-settings-changed を送信する
+func (a *App) SetPluginSettings(token string, values map[string]any) (PluginSettings, error) {
+	record, ok := a.plugins.LookupToken(token)
+	if !ok {
+		return PluginSettings{}, fmt.Errorf("%w: unknown or expired plugin token", ErrPermissionDenied)
+	}
+	return a.setPluginSettings(record, values)
+}
+
+// GetPluginSettingsByID は id のプラグインの設定項目と現在の値を返す (アプリの設定画面用)
+// loaderKey はホストのローダーのキーで、プラグインからは呼べない
+func (a *App) GetPluginSettingsByID(loaderKey string, id string) (PluginSettings, error) {
+	if err := a.authorizeHost(loaderKey, "GetPluginSettingsByID"); err != nil {
+		return PluginSettings{}, err
+	}
+	record, ok := a.plugins.Lookup(id)
+	if !ok {
+		return PluginSettings{}, fmt.Errorf("plugin %q not found", id)
+	}
+	return a.settings.Get(record), nil
+}
+
+// SetPluginSettingsByID は id のプラグインの設定を SetPluginSettings と同じ検証をして変更する (アプリの設定画面用)
+// loaderKey はホストのローダーのキーで、プラグインからは呼べない
+func (a *App) SetPluginSettingsByID(loaderKey string, id string, values map[string]any) (PluginSettings, error) {
+	if err := a.authorizeHost(loaderKey, "SetPluginSettingsByID"); err != nil {
+		return PluginSettings{}, err
+	}
+	record, ok := a.plugins.Lookup(id)
+	if !ok {
+		return PluginSettings{}, fmt.Errorf("plugin %q not found", id)
+	}
+	return a.setPluginSettings(record, values)
+}
+
+// setPluginSettings は record のプラグインの設定を変更し、値が変わった項目があれば plugin-settings-changed を送信する
+func (a *App) setPluginSettings(record PluginRecord, values map[string]any) (PluginSettings, error) {
+	id := record.ID
+	settings, changed, err := a.settings.Set(record, values)
+	if err != nil {
+		fmt.Printf("Error saving settings of plugin %s: %v\n", id, err)
+		return PluginSettings{}, err
+	}
+
+	if len(changed) > 0 {
+		fmt.Printf("Settings of plugin %s changed: %v\n", id, changed)
+		a.events.PublishPluginSettingsChanged(PluginSettingsChangedEvent{PluginID: id, Values: settings.Values, Changed: changed})
+	}
+	return settings, nil
+}
+
+// pluginForToken は token を発行したプラグインのIDを返す
+func (a *App) pluginForToken(token string) (string, error) {
+	record, ok := a.plugins.LookupToken(token)
+	if !ok {
+		return "", fmt.Errorf("%w: unknown or expired plugin token", ErrPermissionDenied)
+	}
+	return record.ID, nil
+}
+
+// StorageGet は token のプラグインのストレージから key の値を返す (なければ null)
+func (a *App) StorageGet(token string, key string) (*string, error) {
+	id, err := a.pluginForToken(token)
+	if err != nil {
+		return nil, err
+	}
+	value, ok, err := a.storage.Get(id, key)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// StorageSet は token のプラグインのストレージの key に value を保存する
+func (a *App) StorageSet(token string, key string, value string) error {
+	id, err := a.pluginForToken(token)
+	if err != nil {
+		return err
+	}
+	if err := a.storage.Set(id, key, value); err != nil {
+		fmt.Printf("StorageSet %q for plugin %s failed: %v\n", key, id, err)
+		return err
+	}
+	return nil
+}
+
+// StorageDelete は token のプラグインのストレージから key を削除する
+func (a *App) StorageDelete(token string, key string) error {
+	id, err := a.pluginForToken(token)
+	if err != nil {
+		return err
+	}
+	return a.storage.Delete(id, key)
+}
+
+// StorageList は token のプラグインのストレージのキーを名前順に返す
+func (a *App) StorageList(token string) ([]string, error) {
+	id, err := a.pluginForToken(token)
+	if err != nil {
+		return nil, err
+	}
+	return a.storage.List(id)
+}
+
+// DeletePluginFile は token のプラグインのデータディレクトリからファイルか空のディレクトリ name (相対パス) を削除する
+// token のプラグインに fs.write の権限が必要
+func (a *App) DeletePluginFile(token string, name string) error {
+	if err := a.authorizePluginCall(token, "DeletePluginFile", PermissionFSWrite, auditPathSummary(PluginFSData, name)); err != nil {
+		return err
+	}
+	root, err := a.pluginFSRoot(token, PluginFSData)
+	if err != nil {
+		return err
+	}
+	if err := removeSandboxFile(root, name); err != nil {
+		fmt.Printf("DeletePluginFile %s failed: %v\n", name, err)
+		return err
+	}
+	return nil
+}
+
+// 単一のプラグインディレクトリを検証 (プラグインディレクトリの中だけ)
+func (a *App) ValidatePluginDirectory(dir string) []PluginValidationResult {
+	fmt.Printf("ValidatePluginDirectory called for: %s\n", dir)
+
+	results := []PluginValidationResult{}
+
+	dir, err := confinePath(dir, pluginDirectories())
+	if err != nil {
+		fmt.Printf("Rejected ValidatePluginDirectory: %v\n", err)
+		return results
+	}
+
+	// ディレクトリの存在を確認
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		fmt.Printf("Error: Failed to access directory %s: %v\n", dir, err)
+		return results
+	}
+
+	if !dirInfo.IsDir() {
+		fmt.Printf("Error: %s is not a directory\n", dir)
+		return results
+	}
+
+	// ディレクトリ内のエントリを取得
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		fmt.Printf("Error: Failed to read directory %s: %v\n", dir, err)
+		return results
+	}
+
+	trust, err := loadPluginTrust()
+	if err != nil {
+		fmt.Printf("Error loading plugin trust settings: %v\n", err)
+	}
+
+	// 各サブディレクトリを検証
+	for _, entry := range entries {
+		if entry.IsDir() {
+			pluginPath := filepath.Join(dir, entry.Name())
+			result := validatePlugin(pluginPath)
+			applyPluginTrust(&result, trust)
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// GetMousePosX マウスのX座標を取得
+func (a *App) GetMousePosX() float64 {
+	return a.platform.GetMousePosX()
+}
+
+// GetMousePosY マウスのY座標を取得
+func (a *App) GetMousePosY() float64 {
+	return a.platform.GetMousePosY()
+}
+
+// startup関数のリファクタリング
+func (a *App) startup(ctx context.Context) {
+	a.ctx = ctx
+
+	// イベントバスの購読者としてフロントエンドを登録
+	a.events.SubscribeAll(newWailsSubscriber(ctx, a.emitter))
+
+	fmt.Println("App startup: context initialized")
+
+	// マウス位置を定期的に取得して通知する
+	a.monitors.Start(monitorMouse, a.runMouseMonitor)
+}
+
+// runMouseMonitor はマウス位置を定期的に取得してフロントエンドに通知する
+func (a *App) runMouseMonitor(ctx context.Context) {
+	// 約60FPS (16ms間隔) でマウス位置を更新
+	ticker := a.clock.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			// ネイティブ層からマウス位置を取得
+			x := a.GetMousePosX()
+			y := a.GetMousePosY()
+
 			// フロントエンドにイベントを発行（元の実装と同様に-40のオフセット）
-			runtime.EventsEmit(a.ctx, "mouse-move", MousePosition{X: x, Y: y - 40})
+			a.events.PublishMouseMove(MousePosition{X: x, Y: y - 40})
+
+			// マウスジェスチャーはスクリーン座標のまま認識する
+			if gesture, ok := a.mouseGestures.FeedPosition(MousePosition{X: x, Y: y}, a.clock.Now()); ok {
+				a.publishMouseGesture(gesture)
+			}
 		}
-	}()
+	}
 }
 
-// GetLastShortcutKeyID はCライブラリから最後に検出されたショートカットキーのIDを取得
-func (a *App) GetLastShortcutKeyID() int {
-	return int(C.GetLastShortcutKeyID())
+// registerKeyGestures は設定ファイルのキージェスチャーを読み込む
+func (a *App) registerKeyGestures() error {
+	gestures, err := loadKeyGestures()
+	if err != nil {
+		fmt.Printf("Failed to load key gesture config, using defaults: %v\n", err)
+	}
+
+	if err := a.keyGestures.SetGestures(gestures); err != nil {
+		fmt.Printf("Invalid key gestures were skipped: %v\n", err)
+		return err
+	}
+	return nil
 }
 
-// GetShiftDoublePressed はCライブラリからShiftキーの二重押しが検出されたかを取得
-func (a *App) GetShiftDoublePressed() bool {
-	return bool(C.GetShiftDoublePressed())
+// GetKeyGestures は検出中のキージェスチャーを返す
+func (a *App) GetKeyGestures() []KeyGesture {
+	return a.keyGestures.Gestures()
 }
 
-// ResetShiftDoublePressed はCライブラリのShiftキーの二重押しフラグをリセット
-func (a *App) ResetShiftDoublePressed() {
-	C.ResetShiftDoublePressed()
+// ReloadKeyGestures は設定ファイルを読み直してキージェスチャーを置き換える
+// 不正なジェスチャーがあった場合はそれ以外を有効にしたうえでエラーを返す
+func (a *App) ReloadKeyGestures() error {
+	return a.registerKeyGestures()
+}
+
+// publishKeyGestures は検出したキージェスチャーのイベントを送信する
+func (a *App) publishKeyGestures(events []ShortcutID) {
+	for _, event := range events {
+		fmt.Printf("Key gesture detected: %s\n", event)
+		a.events.PublishShortcut(event)
+	}
+}
+
+// registerMouseGestures は設定ファイルのマウスジェスチャーを読み込む
+func (a *App) registerMouseGestures() error {
+	config, err := loadMouseGestureConfig()
+	if err != nil {
+		fmt.Printf("Failed to load mouse gesture config, using defaults: %v\n", err)
+	}
+
+	if err := a.mouseGestures.SetConfig(config); err != nil {
+		fmt.Printf("Invalid mouse gesture settings were skipped: %v\n", err)
+		return err
+	}
+	return nil
 }
 
-// ショートカットを監視するゴルーチン
+// GetMouseGestures はマウスジェスチャーの設定と、認識に使うテンプレートを返す
+func (a *App) GetMouseGestures() MouseGestureConfig {
+	return a.mouseGestures.Config()
+}
+
+// ReloadMouseGestures は設定ファイルを読み直してマウスジェスチャーの設定を置き換える
+// 不正なテンプレートがあった場合はそれ以外を有効にしたうえでエラーを返す
+func (a *App) ReloadMouseGestures() error {
+	return a.registerMouseGestures()
+}
+
+// publishMouseGesture は認識したマウスジェスチャーをアクティブなゴーストに送信する
+func (a *App) publishMouseGesture(gesture GestureEvent) {
+	fmt.Printf("Mouse gesture detected: %s (score %.2f)\n", gesture.Name, gesture.Score)
+	a.events.PublishGesture(gesture)
+}
+
+// registerHotkeys は設定ファイルのホットキーを登録し、失敗したものをフロントエンドに通知する
+func (a *App) registerHotkeys() []HotkeyStatus {
+	bindings, err := loadHotkeyBindings()
+	if err != nil {
+		fmt.Printf("Failed to load hotkey config, using defaults: %v\n", err)
+	}
+
+	statuses := a.hotkeys.Apply(bindings)
+	for _, status := range statuses {
+		if status.Registered {
+			fmt.Printf("Hotkey %s registered for %s\n", status.Hotkey, status.Event)
+			continue
+		}
+		fmt.Printf("Failed to register hotkey %s for %s: %s\n", status.Hotkey, status.Event, status.Error)
+		a.events.PublishHotkeyRegistrationFailed(status)
+	}
+	return statuses
+}
+
+// registerPluginHotkeys はマニフェストの shortcut を読み込んでプラグインごとのホットキーを登録する
+func (a *App) registerPluginHotkeys() []HotkeyStatus {
+	var shortcuts []PluginShortcut
+	for _, plugin := range a.ListPlugins() {
+		if !plugin.Valid || !plugin.Enabled || plugin.Manifest.Shortcut == "" {
+			continue
+		}
+		shortcuts = append(shortcuts, PluginShortcut{PluginID: plugin.ID, Shortcut: plugin.Manifest.Shortcut})
+	}
+
+	statuses := a.hotkeys.ApplyPluginShortcuts(shortcuts)
+	for _, status := range statuses {
+		if status.Registered {
+			fmt.Printf("Hotkey %s registered for plugin %s\n", status.Hotkey, status.PluginID)
+			continue
+		}
+		fmt.Printf("Failed to register hotkey %s for plugin %s: %s\n", status.Hotkey, status.PluginID, status.Error)
+		a.events.PublishHotkeyRegistrationFailed(status)
+	}
+	return statuses
+}
+
+// dispatchHotkey は押されたホットキーに割り当てられたイベントを送信する
+func (a *App) dispatchHotkey(press HotkeyPress) {
+	latency := a.clock.Now().Sub(press.PressedAt)
+
+	status, ok := a.hotkeys.Lookup(press.ID)
+	switch {
+	case !ok:
+		fmt.Printf("Shortcut detected: %s (ID: %d, latency: %v)\n", ShortcutUnknown, press.ID, latency)
+		a.events.PublishShortcut(ShortcutUnknown)
+	case status.PluginID != "":
+		fmt.Printf("Plugin shortcut detected: %s (plugin: %s, latency: %v)\n", status.Hotkey, status.PluginID, latency)
+		a.events.PublishPluginShortcut(PluginShortcutEvent{
+			PluginID:  status.PluginID,
+			Shortcut:  status.Hotkey,
+			PressedAt: press.PressedAt.UnixMilli(),
+		})
+	default:
+		fmt.Printf("Shortcut detected: %s (ID: %d, latency: %v)\n", status.Event, press.ID, latency)
+		a.events.PublishShortcut(ShortcutID(status.Event))
+	}
+}
+
+// GetHotkeyBindings はグローバルホットキーの登録状態を返す
+func (a *App) GetHotkeyBindings() []HotkeyStatus {
+	return a.hotkeys.Statuses()
+}
+
+// ReloadHotkeyBindings は設定ファイルとマニフェストを読み直してグローバルホットキーを登録し直す
+func (a *App) ReloadHotkeyBindings() []HotkeyStatus {
+	a.registerHotkeys()
+	a.registerPluginHotkeys()
+	return a.hotkeys.Statuses()
+}
+
+// ショートカットの監視を開始 (既に動いている場合は何もしない)
 func (a *App) startShortcutMonitoring() {
-	// 既存の監視ゴルーチンがあれば停止
-	if shortcutMonitoringRunning {
+	if !a.monitors.Start(monitorShortcut, a.runShortcutMonitor) {
 		return
 	}
 
-	shortcutMonitoringRunning = true
-
-	// 別のゴルーチンでショートカットを監視
-	go func() {
-		ticker := time.NewTicker(100 * time.Millisecond) // 100msごとに確認
-		defer ticker.Stop()
-
-		for shortcutMonitoringRunning {
-			select {
-			case <-ticker.C:
-				// Alt+数字のショートカットをチェック
-				scID := a.GetLastShortcutKeyID()
-				if scID > 0 {
-					var eventType string
-
-					// IDに基づいてイベントタイプを決定
-					switch scID {
-					case 1:
-						eventType = "pushSC1"
-					case 2:
-						eventType = "pushSC2"
-					case 3:
-						eventType = "pushSC3"
-					case 4:
-						eventType = "pushSC4"
-					default:
-						eventType = "unknown"
-					}
-
-					fmt.Printf("Shortcut detected: %s (ID: %d)\n", eventType, scID)
-
-					// フロントエンドにイベントを送信
-					if a.ctx != nil {
-						runtime.EventsEmit(a.ctx, "shortcut-event", eventType)
-					}
-				}
+	// ホットキーはネイティブ層から押されるたびに通知される
+	a.platform.SetHotKeyHandler(a.onHotKeyPressed)
+}
 
-				// Shiftキーの二重押しをチェック
-				if a.GetShiftDoublePressed() {
-					fmt.Println("Shift double-press detected")
+// runShortcutMonitor はホットキーの入力を押された順に送信し、時間経過で確定するキージェスチャーを監視する
+func (a *App) runShortcutMonitor(ctx context.Context) {
+	ticker := a.clock.NewTicker(50 * time.Millisecond) // キー状態の監視と同じ50msごとに確認
+	defer ticker.Stop()
 
-					// フロントエンドにイベントを送信
-					if a.ctx != nil {
-						runtime.EventsEmit(a.ctx, "shortcut-event", "pushSub")
-					}
+	for {
+		select {
+		case <-ctx.Done():
+			return
 
-					// フラグをリセット
-					a.ResetShiftDoublePressed()
-				}
+		case press := <-a.hotkeyPresses:
+			// 押された順にイベントを送信
+			a.dispatchHotkey(press)
+
+		case <-ticker.C():
+			// 長押しと、回数の多い連打を待っていた連打を確定させる
+			a.publishKeyGestures(a.keyGestures.Tick(a.clock.Now()))
+		}
+	}
+}
+
+// ネイティブ側で選択されたゴーストの変化の監視を開始
+func (a *App) startGhostMonitoring() {
+	a.monitors.Start(monitorGhost, a.runGhostMonitor)
+}
+
+// runGhostMonitor はネイティブ側で選択されたゴーストが変わったら通知する
+func (a *App) runGhostMonitor(ctx context.Context) {
+	ticker := a.clock.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	var lastGhostId string
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			ghostId := a.platform.GetLastGhostId()
+			if ghostId != lastGhostId {
+				lastGhostId = ghostId
+				fmt.Printf("Ghost changed: %s\n", ghostId)
+				a.events.PublishSwitchGhost(ghostId)
 			}
 		}
-	}()
+	}
 }
 
-// ショートカット監視を停止
+// ショートカット監視を停止し、監視ゴルーチンの終了を待つ
 func (a *App) stopShortcutMonitoring() {
-	shortcutMonitoringRunning = false
+	a.platform.SetHotKeyHandler(nil)
+	a.monitors.Stop(monitorShortcut)
 }
 
-// 監視状態を追跡する変数
-var shortcutMonitoringRunning bool
-
-var ghostPosX float64
-var ghostPosY float64
+// onHotKeyPressed はネイティブ層のスレッドから呼ばれ、押された時刻とともに入力をキューに積む
+// ネイティブ層のスレッド (macOS ではメインスレッド) を止めないよう、キューが一杯の場合は待たずに捨てて数える
+// ディスパッチャがホットキーの登録の完了を待っている間に、登録するスレッドがここで待つとデッドロックするため
+func (a *App) onHotKeyPressed(id int) {
+	select {
+	case a.hotkeyPresses <- HotkeyPress{ID: id, PressedAt: a.clock.Now()}:
+	default:
+		dropped := a.droppedHotkeyPresses.Add(1)
+		fmt.Printf("Dropped hotkey %d: input queue is full (%d dropped so far)\n", id, dropped)
+	}
+}
 
 // GetGhostPosX はゴーストのX座標を返す
 func (a *App) GetGhostPosX() float64 {
-	return ghostPosX
+	a.ghostPosMu.Lock()
+	defer a.ghostPosMu.Unlock()
+	return a.ghostPos.X
 }
 
 // GetGhostPosY はゴーストのY座標を返す
 func (a *App) GetGhostPosY() float64 {
-	return ghostPosY
+	a.ghostPosMu.Lock()
+	defer a.ghostPosMu.Unlock()
+	return a.ghostPos.Y
 }
 
 // SetGhostPos はゴーストの位置を設定
 func (a *App) SetGhostPos(x float64, y float64) {
-	ghostPosX = x
-	ghostPosY = y
+	a.ghostPosMu.Lock()
+	defer a.ghostPosMu.Unlock()
+	a.ghostPos = MousePosition{X: x, Y: y}
 }
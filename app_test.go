@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// 監視ゴルーチンのイベントを待つ時間 (フェイクの時計とは別の実時間)
+const fakeEventTimeout = 2 * time.Second
+
+// startFakeApp は設定ディレクトリを一時ディレクトリにして FakeEnvironment の App を起動する
+func startFakeApp(t *testing.T) *FakeEnvironment {
+	t.Helper()
+	t.Setenv(configDirEnv, t.TempDir())
+
+	env := NewFakeEnvironment()
+	env.App.startup(context.Background())
+	t.Cleanup(func() { env.App.shutdown(context.Background()) })
+	return env
+}
+
+// openTestLoader はページが読み込まれたときと同じようにローダーのキーを受け取る
+func openTestLoader(t *testing.T, app *App) string {
+	t.Helper()
+	app.resetPluginLoader()
+	key, err := app.OpenPluginLoader()
+	if err != nil {
+		t.Fatalf("OpenPluginLoader: %v", err)
+	}
+	return key
+}
+
+func TestMouseMoveEvents(t *testing.T) {
+	env := startFakeApp(t)
+	if !env.Clock.WaitForTickers(1, fakeEventTimeout) {
+		t.Fatal("mouse monitor did not start")
+	}
+
+	positions := []struct{ x, y float64 }{{100, 200}, {640, 40}, {0, 0}}
+	for i, pos := range positions {
+		env.Platform.MoveMouse(pos.x, pos.y)
+		env.Clock.Advance(20 * time.Millisecond)
+		if !env.Events.WaitFor(EventMouseMove, i+1, fakeEventTimeout) {
+			t.Fatalf("mouse-move for %v was not emitted", pos)
+		}
+	}
+
+	events := env.Events.EventsNamed(EventMouseMove)
+	for i, pos := range positions {
+		got, ok := events[i].Data[0].(MousePosition)
+		if !ok {
+			t.Fatalf("mouse-move payload = %#v", events[i].Data)
+		}
+		// フロントエンドにはメニューバーの分だけ上にずらした位置が送られる
+		if want := (MousePosition{X: pos.x, Y: pos.y - 40}); got != want {
+			t.Errorf("mouse-move %d = %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestShortcutEvents(t *testing.T) {
+	env := startFakeApp(t)
+	env.App.registerHotkeys()
+	env.App.startShortcutMonitoring()
+
+	ids := make(map[string]int)
+	for id, hotkey := range env.Platform.RegisteredHotKeys() {
+		ids[hotkey] = id
+	}
+
+	tests := []struct {
+		hotkey string
+		want   ShortcutID
+	}{
+		{"Option+1", ShortcutSC1},
+		{"Option+4", ShortcutSC4},
+		{"Option+2", ShortcutSC2},
+	}
+	for i, tt := range tests {
+		id, ok := ids[tt.hotkey]
+		if !ok {
+			t.Fatalf("%s is not registered: %v", tt.hotkey, ids)
+		}
+		env.Platform.PressHotKey(id)
+		if !env.Events.WaitFor(EventShortcut, i+1, fakeEventTimeout) {
+			t.Fatalf("shortcut-event for %s was not emitted", tt.hotkey)
+		}
+	}
+
+	// 押された順に送信される
+	for i, event := range env.Events.EventsNamed(EventShortcut) {
+		if got := event.Data[0]; got != tests[i].want {
+			t.Errorf("shortcut-event %d = %v, want %v", i, got, tests[i].want)
+		}
+	}
+}
+
+func TestHotkeyPressesDroppedWhenQueueIsFull(t *testing.T) {
+	// ディスパッチャを起動せず、キューが一杯になったままにする
+	env := NewFakeEnvironment()
+	env.Platform.SetHotKeyHandler(env.App.onHotKeyPressed)
+
+	const extra = 3
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < hotkeyQueueSize+extra; i++ {
+			env.Platform.PressHotKey(i)
+		}
+	}()
+	// ネイティブ層のスレッドを止めない
+	select {
+	case <-done:
+	case <-time.After(fakeEventTimeout):
+		t.Fatal("onHotKeyPressed blocked on a full queue")
+	}
+
+	if got := env.App.droppedHotkeyPresses.Load(); got != extra {
+		t.Errorf("dropped %d presses, want %d", got, extra)
+	}
+	// 捨てるのは後から届いた入力で、キューの中は押された順のまま
+	if len(env.App.hotkeyPresses) != hotkeyQueueSize {
+		t.Fatalf("queue has %d presses, want %d", len(env.App.hotkeyPresses), hotkeyQueueSize)
+	}
+	for i := 0; i < hotkeyQueueSize; i++ {
+		if press := <-env.App.hotkeyPresses; press.ID != i {
+			t.Fatalf("press %d has id %d", i, press.ID)
+		}
+	}
+
+	env.Platform.PressHotKey(99)
+	if press := <-env.App.hotkeyPresses; press.ID != 99 || env.App.droppedHotkeyPresses.Load() != extra {
+		t.Errorf("press after draining = %d (dropped %d), want it queued", press.ID, env.App.droppedHotkeyPresses.Load())
+	}
+}
+
+func TestSwitchGhostEvents(t *testing.T) {
+	env := startFakeApp(t)
+	env.App.startGhostMonitoring()
+	// マウスとゴーストの監視
+	if !env.Clock.WaitForTickers(2, fakeEventTimeout) {
+		t.Fatal("ghost monitor did not start")
+	}
+
+	env.Clock.Advance(100 * time.Millisecond)
+	if !env.Events.WaitFor(EventSwitchGhost, 1, fakeEventTimeout) {
+		t.Fatal("switch-ghost for the initial ghost was not emitted")
+	}
+
+	// 変わっていなければ送信しない
+	env.Clock.Advance(100 * time.Millisecond)
+	env.Platform.SetLastGhostId("clock")
+	env.Clock.Advance(100 * time.Millisecond)
+	if !env.Events.WaitFor(EventSwitchGhost, 2, fakeEventTimeout) {
+		t.Fatal("switch-ghost for the changed ghost was not emitted")
+	}
+
+	var got []interface{}
+	for _, event := range env.Events.EventsNamed(EventSwitchGhost) {
+		got = append(got, event.Data[0])
+	}
+	if len(got) != 2 || got[0] != "default" || got[1] != "clock" {
+		t.Errorf("switch-ghost = %v, want [default clock]", got)
+	}
+}
+
+func TestKeyStateEvents(t *testing.T) {
+	env := startFakeApp(t)
+	if err := env.App.StartKeyMonitoring(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Shift の連打は既定のキージェスチャーで pushSub になる
+	steps := []struct {
+		keys    []string
+		advance time.Duration
+	}{
+		{[]string{"shift"}, 50 * time.Millisecond},
+		{nil, 50 * time.Millisecond},
+		{[]string{"shift"}, 50 * time.Millisecond},
+		{nil, 0},
+	}
+	for _, step := range steps {
+		env.Platform.SetPressedKeys(step.keys...)
+		env.Clock.Advance(step.advance)
+	}
+
+	var states []interface{}
+	for _, event := range env.Events.EventsNamed(EventKeyStateChanged) {
+		states = append(states, event.Data[0])
+	}
+	want := []interface{}{"shift", "", "shift", ""}
+	if len(states) != len(want) {
+		t.Fatalf("key-state-changed = %q, want %q", states, want)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Errorf("key-state-changed %d = %q, want %q", i, states[i], want[i])
+		}
+	}
+
+	shortcuts := env.Events.EventsNamed(EventShortcut)
+	if len(shortcuts) != 1 || shortcuts[0].Data[0] != ShortcutSub {
+		t.Errorf("shortcut-event = %v, want [%s]", shortcuts, ShortcutSub)
+	}
+
+	if err := env.App.StopKeyMonitoring(); err != nil {
+		t.Fatal(err)
+	}
+	if env.Platform.KeyMonitoring() {
+		t.Error("key monitoring is still running after StopKeyMonitoring")
+	}
+}
@@ -0,0 +1,560 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 監査ログのファイル名 (ローテーションしたものは audit.log.1, audit.log.2, ... で、数字が大きいほど古い)
+const auditLogFile = "audit.log"
+
+// 監査ログとは別のディレクトリに置く、エントリに署名する鍵と最新のエントリの記録
+// 監査ログのディレクトリだけを書き換えられても検出できるようにする
+const (
+	auditKeyFile  = "audit.key"
+	auditHeadFile = "audit-head.json"
+)
+
+const (
+	// この大きさを超えたらローテーションする
+	maxAuditLogSize = 1 << 20
+	// 現在のファイルを含めて残すファイルの数
+	maxAuditLogFiles = 5
+)
+
+// 監査ログの結果
+const (
+	AuditAllowed = "allowed"
+	AuditDenied  = "denied"
+)
+
+// AuditEntry は権限が必要なメソッドの呼び出し1回分の記録
+// 各エントリは直前のエントリのハッシュを含み、監査ログの外に置いた鍵で署名するため、途中を書き換えたり削除したりすると Verify で検出できる
+type AuditEntry struct {
+	Seq        int64      `json:"seq"`
+	Time       time.Time  `json:"time"`
+	PluginID   string     `json:"pluginId"`
+	Method     string     `json:"method"`
+	Permission Permission `json:"permission"`
+	// 引数の要約 (クリップボードの内容や入力した文字は含めない)
+	Args   string `json:"args,omitempty"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+	// 直前のエントリのハッシュと、このエントリのハッシュ (HMAC-SHA256、16進数)
+	PrevHash string `json:"prevHash"`
+	Hash     string `json:"hash"`
+}
+
+// AuditQuery は監査ログを検索する条件 (空の条件は絞り込まない)
+type AuditQuery struct {
+	PluginID string    `json:"pluginId,omitempty"`
+	Method   string    `json:"method,omitempty"`
+	Since    time.Time `json:"since,omitempty"`
+	// 新しいものから最大何件返すか (0 なら全件)
+	Limit int `json:"limit,omitempty"`
+}
+
+// AuditVerification は監査ログのハッシュの連鎖を確認した結果
+type AuditVerification struct {
+	Valid   bool `json:"valid"`
+	Entries int  `json:"entries"`
+	// 最初に不整合が見つかったエントリの連番 (Valid が false のとき)
+	BrokenSeq int64  `json:"brokenSeq,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// auditHead は最後に記録したエントリと、残っている最も古いエントリの連番
+// 末尾や古いファイルを削除されても Verify で検出できるよう、監査ログの外に鍵で署名して保存する
+type auditHead struct {
+	Seq  int64  `json:"seq"`
+	Hash string `json:"hash"`
+	// 0 は記録が失われて、どこから残っているか分からないことを表す
+	FirstSeq int64  `json:"firstSeq"`
+	MAC      string `json:"mac"`
+}
+
+// AuditLog は権限が必要なメソッドの呼び出しを追記専用のファイルに記録する
+type AuditLog struct {
+	mu    sync.Mutex
+	clock Clock
+	// 監査ログを置くディレクトリを返す
+	dir func() (string, error)
+	// 鍵と最新のエントリの記録を置くディレクトリを返す (dir とは別のディレクトリ)
+	keyDir   func() (string, error)
+	maxSize  int64
+	maxFiles int
+
+	loaded   bool
+	key      []byte
+	seq      int64
+	lastHash string
+	first    int64
+}
+
+// NewAuditLog は dir に監査ログを書き、maxSize を超えたら maxFiles 個までのファイルでローテーションする AuditLog を生成する
+// エントリに署名する鍵と最新のエントリの記録は keyDir に置く
+func NewAuditLog(dir, keyDir func() (string, error), clock Clock, maxSize int64, maxFiles int) *AuditLog {
+	return &AuditLog{dir: dir, keyDir: keyDir, clock: clock, maxSize: maxSize, maxFiles: maxFiles}
+}
+
+// auditLogDir は監査ログのディレクトリ (~/.config/ghostcursor/audit) を返す
+func auditLogDir() (string, error) {
+	return configPath("audit")
+}
+
+// Record は entry に連番、時刻、ハッシュを付けて監査ログに追記する
+func (l *AuditLog) Record(entry AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	dir, err := l.dir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create audit log directory %s: %w", dir, err)
+	}
+	if !l.loaded {
+		if err := l.load(dir); err != nil {
+			return err
+		}
+		l.loaded = true
+	}
+
+	entry.Seq = l.seq + 1
+	entry.Time = l.clock.Now().UTC()
+	entry.PrevHash = l.lastHash
+	entry.Hash = ""
+	hash, err := auditEntryHash(l.key, entry)
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	path := filepath.Join(dir, auditLogFile)
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 && info.Size()+int64(len(line)) > l.maxSize {
+		if err := l.rotate(dir); err != nil {
+			return err
+		}
+		// 記録が失われている場合は、ローテーションで分からなくなったことを隠さない
+		if l.first != 0 {
+			first, err := l.oldestSeq(dir)
+			if err != nil {
+				return err
+			}
+			if first == 0 {
+				first = entry.Seq
+			}
+			l.first = first
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	_, err = f.Write(line)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	l.seq = entry.Seq
+	l.lastHash = entry.Hash
+	// 追記の後に更新するため、途中で終了しても記録がエントリより古くなるだけで Verify は通る
+	return l.saveHead()
+}
+
+// Query は条件に一致するエントリを古い順に返す
+func (l *AuditLog) Query(query AuditQuery) ([]AuditEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, err := l.readAll()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []AuditEntry{}
+	for _, entry := range entries {
+		if query.PluginID != "" && entry.PluginID != query.PluginID {
+			continue
+		}
+		if query.Method != "" && entry.Method != query.Method {
+			continue
+		}
+		if !query.Since.IsZero() && entry.Time.Before(query.Since) {
+			continue
+		}
+		results = append(results, entry)
+	}
+	if query.Limit > 0 && len(results) > query.Limit {
+		results = results[len(results)-query.Limit:]
+	}
+	return results, nil
+}
+
+// Verify は残っているすべてのエントリについて署名と連鎖を確認し、監査ログの外に保存した最新のエントリの記録と照合する
+// ローテーションで削除された範囲の内容は確認できないが、記録より古いエントリが欠けていれば検出する
+func (l *AuditLog) Verify() (AuditVerification, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, err := l.readAll()
+	if err != nil {
+		return AuditVerification{}, err
+	}
+	key, err := l.readKey()
+	if err != nil {
+		return AuditVerification{}, err
+	}
+	head, err := l.readHead(key)
+	if err != nil {
+		return AuditVerification{}, err
+	}
+
+	result := AuditVerification{Valid: true, Entries: len(entries)}
+	fail := func(seq int64, format string, args ...interface{}) (AuditVerification, error) {
+		result.Valid = false
+		result.BrokenSeq = seq
+		result.Message = fmt.Sprintf(format, args...)
+		return result, nil
+	}
+
+	if key == nil && len(entries) > 0 {
+		return fail(entries[0].Seq, "audit log key is missing")
+	}
+	if head == nil {
+		if len(entries) == 0 {
+			return result, nil
+		}
+		return fail(entries[0].Seq, "audit log head record is missing or has been modified")
+	}
+	if head.FirstSeq == 0 {
+		return fail(head.Seq, "audit log head record was lost before entry %d; the log cannot be verified", head.Seq)
+	}
+	if len(entries) == 0 {
+		return fail(head.FirstSeq, "audit log is empty but entries %d to %d were recorded", head.FirstSeq, head.Seq)
+	}
+	if entries[0].Seq != head.FirstSeq {
+		return fail(entries[0].Seq, "audit log starts at entry %d, expected entry %d", entries[0].Seq, head.FirstSeq)
+	}
+
+	for i, entry := range entries {
+		var problem string
+		if hash, err := auditEntryHash(key, entry); err != nil || !hmac.Equal([]byte(hash), []byte(entry.Hash)) {
+			problem = "hash does not match the entry"
+		} else if i == 0 && entry.Seq == 1 && entry.PrevHash != "" {
+			problem = "first entry has a previous hash"
+		} else if i > 0 && entry.Seq != entries[i-1].Seq+1 {
+			problem = fmt.Sprintf("expected entry %d, found %d", entries[i-1].Seq+1, entry.Seq)
+		} else if i > 0 && entry.PrevHash != entries[i-1].Hash {
+			problem = "previous hash does not match the preceding entry"
+		}
+		if problem != "" {
+			return fail(entry.Seq, "audit log entry %d: %s", entry.Seq, problem)
+		}
+	}
+
+	// 記録より後のエントリは、記録を更新する前に終了した場合にだけ存在する (署名と連鎖は確認済み)
+	last := entries[len(entries)-1]
+	if last.Seq < head.Seq {
+		return fail(last.Seq+1, "audit log ends at entry %d, expected at least %d entries up to entry %d", last.Seq, head.Seq-head.FirstSeq+1, head.Seq)
+	}
+	if entry := entries[head.Seq-head.FirstSeq]; entry.Hash != head.Hash {
+		return fail(entry.Seq, "audit log entry %d does not match the head record", entry.Seq)
+	}
+	return result, nil
+}
+
+// load は鍵を読み込み (なければ作成し)、最新のエントリの記録から連番と直前のハッシュを読み込む (l.mu を持って呼ぶ)
+func (l *AuditLog) load(dir string) error {
+	key, err := l.readKey()
+	if err != nil {
+		return err
+	}
+	keyExisted := key != nil
+	if key == nil {
+		if key, err = l.createKey(); err != nil {
+			return err
+		}
+	}
+	l.key = key
+
+	head, err := l.readHead(key)
+	if err != nil {
+		return err
+	}
+	var last *AuditEntry
+	for _, path := range l.files(dir) {
+		entries, err := readAuditFile(path)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			last = &entries[len(entries)-1]
+			break
+		}
+	}
+
+	switch {
+	case head != nil:
+		l.seq, l.lastHash, l.first = head.Seq, head.Hash, head.FirstSeq
+		// 記録を更新する前に終了していた場合は、追記済みのエントリから続ける
+		if last != nil && last.Seq > head.Seq {
+			l.seq, l.lastHash = last.Seq, last.Hash
+		}
+	case last != nil:
+		// 記録がないのにエントリが残っている場合は続きから記録するが、確認できないことは記録に残す
+		l.seq, l.lastHash = last.Seq, last.Hash
+		l.first = 0
+		if !keyExisted {
+			fmt.Printf("Audit log key was missing; existing audit log entries can no longer be verified\n")
+		}
+	default:
+		l.first = 1
+	}
+	return nil
+}
+
+// rotate は audit.log を audit.log.1 に、audit.log.N を audit.log.N+1 にずらし、最も古いものを削除する (l.mu を持って呼ぶ)
+func (l *AuditLog) rotate(dir string) error {
+	files := l.files(dir)
+	if err := os.Remove(files[len(files)-1]); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove old audit log: %w", err)
+	}
+	for i := len(files) - 2; i >= 0; i-- {
+		if err := os.Rename(files[i], files[i+1]); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+	return nil
+}
+
+// oldestSeq は残っている最も古いエントリの連番を返す (エントリがなければ 0)
+func (l *AuditLog) oldestSeq(dir string) (int64, error) {
+	files := l.files(dir)
+	for i := len(files) - 1; i >= 0; i-- {
+		entries, err := readAuditFile(files[i])
+		if err != nil {
+			return 0, err
+		}
+		if len(entries) > 0 {
+			return entries[0].Seq, nil
+		}
+	}
+	return 0, nil
+}
+
+// files は監査ログのファイルを新しい順に返す (存在しないものも含む)
+func (l *AuditLog) files(dir string) []string {
+	files := []string{filepath.Join(dir, auditLogFile)}
+	for i := 1; i < l.maxFiles; i++ {
+		files = append(files, filepath.Join(dir, fmt.Sprintf("%s.%d", auditLogFile, i)))
+	}
+	return files
+}
+
+// readAll はすべてのファイルのエントリを古い順に返す (l.mu を持って呼ぶ)
+func (l *AuditLog) readAll() ([]AuditEntry, error) {
+	dir, err := l.dir()
+	if err != nil {
+		return nil, err
+	}
+
+	files := l.files(dir)
+	var entries []AuditEntry
+	for i := len(files) - 1; i >= 0; i-- {
+		fileEntries, err := readAuditFile(files[i])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	return entries, nil
+}
+
+// readAuditFile は監査ログのファイルを読み込む (存在しなければ空)
+// 壊れた行は Verify で検出できるよう、ハッシュが一致しないエントリとして返す
+func readAuditFile(path string) ([]AuditEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxAuditLogSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			entry = AuditEntry{Error: fmt.Sprintf("unreadable entry: %v", err), Hash: "invalid"}
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// auditEntryHash は Hash を除いたエントリのJSONの、key による HMAC-SHA256 を返す
+func auditEntryHash(key []byte, entry AuditEntry) (string, error) {
+	entry.Hash = ""
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	return auditMAC(key, data), nil
+}
+
+// auditMAC は data の key による HMAC-SHA256 を16進数で返す
+func auditMAC(key, data []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// readKey は鍵を読み込む (存在しなければ nil)
+func (l *AuditLog) readKey() ([]byte, error) {
+	dir, err := l.keyDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, auditKeyFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit key: %w", err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("audit key %s is corrupted", path)
+	}
+	return key, nil
+}
+
+// createKey は新しい鍵を作成して保存する
+func (l *AuditLog) createKey() ([]byte, error) {
+	dir, err := l.keyDir()
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate audit key: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, auditKeyFile), []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// readHead は最新のエントリの記録を読み込む (存在しないか署名が一致しなければ nil)
+func (l *AuditLog) readHead(key []byte) (*auditHead, error) {
+	dir, err := l.keyDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, auditHeadFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log head record: %w", err)
+	}
+	var head auditHead
+	if json.Unmarshal(data, &head) != nil || key == nil || !hmac.Equal([]byte(head.MAC), []byte(auditHeadMAC(key, head))) {
+		return nil, nil
+	}
+	return &head, nil
+}
+
+// saveHead は最新のエントリの記録を署名して保存する (l.mu を持って呼ぶ)
+func (l *AuditLog) saveHead() error {
+	dir, err := l.keyDir()
+	if err != nil {
+		return err
+	}
+	head := auditHead{Seq: l.seq, Hash: l.lastHash, FirstSeq: l.first}
+	head.MAC = auditHeadMAC(l.key, head)
+	data, err := json.Marshal(head)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit log head record: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(dir, auditHeadFile), data, 0600)
+}
+
+// auditHeadMAC は MAC を除いた記録の署名を返す
+func auditHeadMAC(key []byte, head auditHead) string {
+	head.MAC = ""
+	data, _ := json.Marshal(head)
+	return auditMAC(key, data)
+}
+
+// 入力した文字を記録しないよう、これらの修飾キーとの組み合わせでない1文字のキーは伏せる (shift は文字の入力に使われるため含めない)
+var auditShortcutModifiers = map[string]bool{
+	"cmd": true, "command": true, "lcmd": true, "rcmd": true, "lcommand": true, "rcommand": true, "super": true,
+	"ctrl": true, "control": true, "lctrl": true, "rctrl": true, "lcontrol": true, "rcontrol": true,
+	"alt": true, "option": true, "lalt": true, "ralt": true, "loption": true, "roption": true,
+}
+
+// auditKeySummary は SimulateKeyPress のキーの組み合わせを、修飾キーのないときは文字を伏せて要約する
+func auditKeySummary(keyString string) string {
+	var keys []string
+	shortcut := false
+	for _, name := range strings.Split(keyString, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if auditShortcutModifiers[name] {
+			shortcut = true
+		}
+		keys = append(keys, name)
+	}
+	for i, key := range keys {
+		if !shortcut && utf8.RuneCountInString(key) == 1 {
+			keys[i] = "*"
+		}
+	}
+	return "keys: " + strings.Join(keys, "+")
+}
+
+// auditPathSummary はプラグインのファイルAPIの対象を要約する
+func auditPathSummary(area, name string) string {
+	return fmt.Sprintf("path: %s/%s", area, name)
+}
+
+// auditTextSummary は書き込む文字列を内容を含めずに要約する
+func auditTextSummary(text string) string {
+	return fmt.Sprintf("text: %d chars", utf8.RuneCountInString(text))
+}
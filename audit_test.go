@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestAuditLog は一時ディレクトリに監査ログを書く AuditLog を生成し、監査ログと鍵のディレクトリを返す
+func newTestAuditLog(t *testing.T, maxSize int64, maxFiles int) (*AuditLog, string, string) {
+	t.Helper()
+	logDir := filepath.Join(t.TempDir(), "audit")
+	keyDir := t.TempDir()
+	log := reopenTestAuditLog(logDir, keyDir, maxSize, maxFiles)
+	return log, logDir, keyDir
+}
+
+// reopenTestAuditLog はアプリを起動し直したときのように、既存の監査ログを使う AuditLog を生成する
+func reopenTestAuditLog(logDir, keyDir string, maxSize int64, maxFiles int) *AuditLog {
+	dir := func() (string, error) { return logDir, nil }
+	key := func() (string, error) { return keyDir, nil }
+	return NewAuditLog(dir, key, NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)), maxSize, maxFiles)
+}
+
+func recordTestEntries(t *testing.T, log *AuditLog, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		entry := AuditEntry{PluginID: "clock", Method: "ReadClipboard", Permission: PermissionClipboardRead, Result: AuditAllowed}
+		if err := log.Record(entry); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+}
+
+func readAuditLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func writeAuditLines(t *testing.T, path string, lines []string) {
+	t.Helper()
+	data := strings.Join(lines, "\n")
+	if len(lines) > 0 {
+		data += "\n"
+	}
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func verifyTestAuditLog(t *testing.T, log *AuditLog) AuditVerification {
+	t.Helper()
+	result, err := log.Verify()
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	return result
+}
+
+func TestAuditLogVerify(t *testing.T) {
+	log, logDir, keyDir := newTestAuditLog(t, maxAuditLogSize, maxAuditLogFiles)
+
+	if result := verifyTestAuditLog(t, log); !result.Valid || result.Entries != 0 {
+		t.Fatalf("empty log: %+v, want valid with 0 entries", result)
+	}
+	recordTestEntries(t, log, 3)
+
+	// 起動し直しても連鎖を続ける
+	log = reopenTestAuditLog(logDir, keyDir, maxAuditLogSize, maxAuditLogFiles)
+	recordTestEntries(t, log, 2)
+	result := verifyTestAuditLog(t, log)
+	if !result.Valid || result.Entries != 5 {
+		t.Fatalf("Verify = %+v, want valid with 5 entries", result)
+	}
+
+	entries, err := log.Query(AuditQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, entry := range entries {
+		if entry.Seq != int64(i+1) {
+			t.Errorf("entry %d has seq %d", i, entry.Seq)
+		}
+	}
+
+	// 鍵と記録は監査ログのディレクトリの外に置く
+	for _, name := range []string{auditKeyFile, auditHeadFile} {
+		if _, err := os.Stat(filepath.Join(keyDir, name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(logDir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s is in the audit log directory", name)
+		}
+	}
+}
+
+func TestAuditLogDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name string
+		// 5件記録した後の監査ログを書き換える
+		tamper    func(t *testing.T, logPath, keyDir string)
+		brokenSeq int64
+	}{
+		{
+			name: "edited entry",
+			tamper: func(t *testing.T, logPath, keyDir string) {
+				lines := readAuditLines(t, logPath)
+				lines[1] = strings.Replace(lines[1], `"result":"allowed"`, `"result":"denied"`, 1)
+				writeAuditLines(t, logPath, lines)
+			},
+			brokenSeq: 2,
+		},
+		{
+			// 鍵を知らなければハッシュを計算し直しても一致しない
+			name: "edited entry with recomputed hash",
+			tamper: func(t *testing.T, logPath, keyDir string) {
+				lines := readAuditLines(t, logPath)
+				var entry AuditEntry
+				if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+					t.Fatal(err)
+				}
+				entry.Result = AuditDenied
+				entry.Hash, _ = auditEntryHash([]byte("guessed key"), entry)
+				data, _ := json.Marshal(entry)
+				lines[1] = string(data)
+				writeAuditLines(t, logPath, lines)
+			},
+			brokenSeq: 2,
+		},
+		{
+			name: "reordered entries",
+			tamper: func(t *testing.T, logPath, keyDir string) {
+				lines := readAuditLines(t, logPath)
+				lines[1], lines[2] = lines[2], lines[1]
+				writeAuditLines(t, logPath, lines)
+			},
+			brokenSeq: 3,
+		},
+		{
+			name: "deleted entry",
+			tamper: func(t *testing.T, logPath, keyDir string) {
+				lines := readAuditLines(t, logPath)
+				writeAuditLines(t, logPath, append(lines[:2:2], lines[3:]...))
+			},
+			brokenSeq: 4,
+		},
+		{
+			name: "deleted oldest entry",
+			tamper: func(t *testing.T, logPath, keyDir string) {
+				writeAuditLines(t, logPath, readAuditLines(t, logPath)[1:])
+			},
+			brokenSeq: 2,
+		},
+		{
+			name: "truncated tail",
+			tamper: func(t *testing.T, logPath, keyDir string) {
+				lines := readAuditLines(t, logPath)
+				writeAuditLines(t, logPath, lines[:3])
+			},
+			brokenSeq: 4,
+		},
+		{
+			name: "emptied log",
+			tamper: func(t *testing.T, logPath, keyDir string) {
+				writeAuditLines(t, logPath, nil)
+			},
+			brokenSeq: 1,
+		},
+		{
+			name: "deleted head record",
+			tamper: func(t *testing.T, logPath, keyDir string) {
+				if err := os.Remove(filepath.Join(keyDir, auditHeadFile)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			brokenSeq: 1,
+		},
+		{
+			name: "edited head record",
+			tamper: func(t *testing.T, logPath, keyDir string) {
+				path := filepath.Join(keyDir, auditHeadFile)
+				var head auditHead
+				data, _ := os.ReadFile(path)
+				if err := json.Unmarshal(data, &head); err != nil {
+					t.Fatal(err)
+				}
+				head.Seq = 3
+				data, _ = json.Marshal(head)
+				if err := os.WriteFile(path, data, 0600); err != nil {
+					t.Fatal(err)
+				}
+				lines := readAuditLines(t, logPath)
+				writeAuditLines(t, logPath, lines[:3])
+			},
+			brokenSeq: 1,
+		},
+		{
+			name: "deleted key",
+			tamper: func(t *testing.T, logPath, keyDir string) {
+				if err := os.Remove(filepath.Join(keyDir, auditKeyFile)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			brokenSeq: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, logDir, keyDir := newTestAuditLog(t, maxAuditLogSize, maxAuditLogFiles)
+			recordTestEntries(t, log, 5)
+			tt.tamper(t, filepath.Join(logDir, auditLogFile), keyDir)
+
+			result := verifyTestAuditLog(t, log)
+			if result.Valid {
+				t.Fatalf("Verify = %+v, want invalid", result)
+			}
+			if result.BrokenSeq != tt.brokenSeq {
+				t.Errorf("BrokenSeq = %d, want %d (%s)", result.BrokenSeq, tt.brokenSeq, result.Message)
+			}
+		})
+	}
+}
+
+func TestAuditLogRotation(t *testing.T) {
+	// 1件ごとにローテーションし、3ファイル (3件) まで残す
+	newRotatedLog := func(t *testing.T) (*AuditLog, string) {
+		log, logDir, _ := newTestAuditLog(t, 1, 3)
+		recordTestEntries(t, log, 6)
+		return log, logDir
+	}
+
+	t.Run("rotated away", func(t *testing.T) {
+		log, _ := newRotatedLog(t)
+		result := verifyTestAuditLog(t, log)
+		if !result.Valid || result.Entries != 3 {
+			t.Fatalf("Verify = %+v, want valid with 3 entries", result)
+		}
+		entries, err := log.Query(AuditQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 3 || entries[0].Seq != 4 || entries[2].Seq != 6 {
+			t.Errorf("entries after rotation = %+v, want 4 to 6", entries)
+		}
+	})
+
+	t.Run("gap between rotated files", func(t *testing.T) {
+		log, logDir := newRotatedLog(t)
+		if err := os.Remove(filepath.Join(logDir, auditLogFile+".1")); err != nil {
+			t.Fatal(err)
+		}
+		if result := verifyTestAuditLog(t, log); result.Valid || result.BrokenSeq != 6 {
+			t.Errorf("Verify = %+v, want broken at entry 6", result)
+		}
+	})
+
+	t.Run("deleted oldest file", func(t *testing.T) {
+		log, logDir := newRotatedLog(t)
+		if err := os.Remove(filepath.Join(logDir, auditLogFile+".2")); err != nil {
+			t.Fatal(err)
+		}
+		if result := verifyTestAuditLog(t, log); result.Valid || result.BrokenSeq != 5 {
+			t.Errorf("Verify = %+v, want broken at entry 5", result)
+		}
+	})
+}
+
+func TestAuditLogHeadBehindLastEntry(t *testing.T) {
+	log, logDir, keyDir := newTestAuditLog(t, maxAuditLogSize, maxAuditLogFiles)
+	recordTestEntries(t, log, 3)
+	headPath := filepath.Join(keyDir, auditHeadFile)
+	head, err := os.ReadFile(headPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// エントリを追記した後、記録を更新する前に終了した状態
+	recordTestEntries(t, log, 1)
+	if err := os.WriteFile(headPath, head, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if result := verifyTestAuditLog(t, log); !result.Valid || result.Entries != 4 {
+		t.Fatalf("Verify = %+v, want valid with 4 entries", result)
+	}
+
+	log = reopenTestAuditLog(logDir, keyDir, maxAuditLogSize, maxAuditLogFiles)
+	recordTestEntries(t, log, 1)
+	if result := verifyTestAuditLog(t, log); !result.Valid || result.Entries != 5 {
+		t.Fatalf("Verify after restart = %+v, want valid with 5 entries", result)
+	}
+}
+
+func TestAuditLogLostHeadStaysInvalid(t *testing.T) {
+	log, logDir, keyDir := newTestAuditLog(t, maxAuditLogSize, maxAuditLogFiles)
+	recordTestEntries(t, log, 3)
+
+	// 末尾を削除して記録も消しても、起動し直して記録を続けたときに整合した状態には戻らない
+	logPath := filepath.Join(logDir, auditLogFile)
+	writeAuditLines(t, logPath, readAuditLines(t, logPath)[:2])
+	if err := os.Remove(filepath.Join(keyDir, auditHeadFile)); err != nil {
+		t.Fatal(err)
+	}
+	log = reopenTestAuditLog(logDir, keyDir, maxAuditLogSize, maxAuditLogFiles)
+	recordTestEntries(t, log, 2)
+	if result := verifyTestAuditLog(t, log); result.Valid {
+		t.Fatalf("Verify = %+v, want invalid", result)
+	}
+}
@@ -7,17 +7,10 @@
 #import "keyboard.h"
 #import "logger.h"
 #import "mouse.h"
-// 最後に検出されたショートカットキーIDを保持
-static int lastShortcutKeyID = 0;
-// Shiftキーの二重押し検出用
-static NSTimeInterval lastShiftKeyTime = 0;
-static bool shiftDoublePressed = false;
-static bool lastShiftState = false;
-static const NSTimeInterval DOUBLE_PRESS_THRESHOLD = 0.5; // 0.5秒以内の2回押し
+// ホットキーが押されたことをGoに通知するコールバック
+extern void HotKeyCallback(int hotKeyID);
 // ホットキーハンドラー
 OSStatus HotKeyHandler(EventHandlerCallRef nextHandler, EventRef theEvent, void *userData) {
-    writeToLogFile("HotKey handler called!");
-    
     EventHotKeyID hotKeyID;
     OSStatus status = GetEventParameter(theEvent, kEventParamDirectObject, typeEventHotKeyID, NULL, 
                                       sizeof(EventHotKeyID), NULL, &hotKeyID);
@@ -31,103 +24,42 @@ OSStatus HotKeyHandler(EventHandlerCallRef nextHandler, EventRef theEvent, void
     snprintf(buffer, sizeof(buffer), "Hot key pressed with ID: %d", hotKeyID.id);
     writeToLogFile(buffer);
     
-    // ショートカットIDを保存
-    lastShortcutKeyID = hotKeyID.id;
-    
-    // イベント別のログ
-    switch (hotKeyID.id) {
-        case kHotKeyID_SC1:
-            writeToLogFile("Option+1 pressed - SC1 Event");
-            break;
-        case kHotKeyID_SC2:
-            writeToLogFile("Option+2 pressed - SC2 Event");
-            break;
-        case kHotKeyID_SC3:
-            writeToLogFile("Option+3 pressed - SC3 Event");
-            break;
-        case kHotKeyID_SC4:
-            writeToLogFile("Option+4 pressed - SC4 Event");
-            break;
-        default:
-            snprintf(buffer, sizeof(buffer), "Unknown hot key ID: %d", hotKeyID.id);
-            writeToLogFile(buffer);
-            break;
-    }
+    // 押されるたびにGoに通知する (IDとイベントの対応はGo側で管理する)
+    HotKeyCallback(hotKeyID.id);
     
     return noErr;
 }
-// 最新のショートカットキーIDを取得
-int GetLastShortcutKeyID() {
-    int id = lastShortcutKeyID;
-    lastShortcutKeyID = 0; // 読み取り後はリセット
-    return id;
-}
-// Shiftキーの二重押しが検出されたかどうか
-bool GetShiftDoublePressed() {
-    return shiftDoublePressed;
-}
-// Shiftキーの二重押しフラグをリセット
-void ResetShiftDoublePressed() {
-    shiftDoublePressed = false;
+// 登録済みホットキーの参照 (ID -> EventHotKeyRef)
+static NSMutableDictionary<NSNumber*, NSValue*> *hotKeyRefs = nil;
+static bool hotKeyHandlerInstalled = false;
+
+// メインスレッドでブロックを同期実行する
+static void runOnMainThread(void (^block)(void)) {
+    if ([NSThread isMainThread]) {
+        block();
+    } else {
+        dispatch_sync(dispatch_get_main_queue(), block);
+    }
 }
+
+// ホットキーのイベントハンドラーを登録する (複数回呼んでも一度だけ登録する)
 void RegisterGlobalHotKey() {
-    writeToLogFile("Registering global hotkeys...");
-    EventHotKeyRef hotKeyRef;
-    EventHotKeyID hotKeyID;
+    if (hotKeyHandlerInstalled) {
+        return;
+    }
     EventTypeSpec eventType;
-    OSStatus status;
     eventType.eventClass = kEventClassKeyboard;
     eventType.eventKind = kEventHotKeyPressed;
-    // イベントハンドラーの登録
-    status = InstallEventHandler(GetApplicationEventTarget(),
-                               NewEventHandlerUPP(HotKeyHandler),
-                               1, &eventType, NULL, NULL);
+    OSStatus status = InstallEventHandler(GetApplicationEventTarget(),
+                                        NewEventHandlerUPP(HotKeyHandler),
+                                        1, &eventType, NULL, NULL);
     if (status != noErr) {
         writeToLogFile("Failed to install event handler");
         return;
     }
-    // Option+1 (ショートカット1)
-    hotKeyID.signature = 'GHST';
-    hotKeyID.id = kHotKeyID_SC1;
-    status = RegisterEventHotKey(kVK_ANSI_1, optionKey, hotKeyID,
-                              GetApplicationEventTarget(), 0, &hotKeyRef);
-    
-    if (status != noErr) {
-        writeToLogFile("Failed to register Option+1 hotkey");
-    } else {
-        writeToLogFile("Option+1 hotkey registered for SC1");
-    }
-    // Option+2 (ショートカット2)
-    hotKeyID.id = kHotKeyID_SC2;
-    status = RegisterEventHotKey(kVK_ANSI_2, optionKey, hotKeyID,
-                              GetApplicationEventTarget(), 0, &hotKeyRef);
-    
-    if (status != noErr) {
-        writeToLogFile("Failed to register Option+2 hotkey");
-    } else {
-        writeToLogFile("Option+2 hotkey registered for SC2");
-    }
-    // Option+3 (ショートカット3)
-    hotKeyID.id = kHotKeyID_SC3;
-    status = RegisterEventHotKey(kVK_ANSI_3, optionKey, hotKeyID,
-                              GetApplicationEventTarget(), 0, &hotKeyRef);
-    
-    if (status != noErr) {
-        writeToLogFile("Failed to register Option+3 hotkey");
-    } else {
-        writeToLogFile("Option+3 hotkey registered for SC3");
-    }
-    // Option+4 (予備)
-    hotKeyID.id = kHotKeyID_SC4;
-    status = RegisterEventHotKey(kVK_ANSI_4, optionKey, hotKeyID,
-                              GetApplicationEventTarget(), 0, &hotKeyRef);
-    
-    if (status != noErr) {
-        writeToLogFile("Failed to register Option+4 hotkey");
-    } else {
-        writeToLogFile("Option+4 hotkey registered for SC4");
-    }
-    writeToLogFile("Global hotkeys registration completed");
+    hotKeyRefs = [[NSMutableDictionary alloc] init];
+    hotKeyHandlerInstalled = true;
+    writeToLogFile("Hotkey event handler installed");
 }
 // 文字列キー名からmacOSのキーコードに変換する関数
 static int getKeyCodeFromName(const char* keyName) {
@@ -220,9 +152,20 @@ if (strlen(keyName) == 1 && ((keyName[0] >= 'a' && keyName[0] <= 'z') || (keyNam
 }
     
     // 数字キー (0-9)
+    // 仮想キーコードは数字順に並んでいないため個別に対応させる
     if (strlen(keyName) == 1 && keyName[0] >= '0' && keyName[0] <= '9') {
-        if (keyName[0] == '0') return kVK_ANSI_0;
-        return kVK_ANSI_1 + (keyName[0] - '1');
+        switch (keyName[0]) {
+            case '0': return kVK_ANSI_0;
+            case '1': return kVK_ANSI_1;
+            case '2': return kVK_ANSI_2;
+            case '3': return kVK_ANSI_3;
+            case '4': return kVK_ANSI_4;
+            case '5': return kVK_ANSI_5;
+            case '6': return kVK_ANSI_6;
+            case '7': return kVK_ANSI_7;
+            case '8': return kVK_ANSI_8;
+            default: return kVK_ANSI_9;
+        }
     }
     
     // 記号キー
@@ -240,6 +183,50 @@ if (strlen(keyName) == 1 && ((keyName[0] >= 'a' && keyName[0] <= 'z') || (keyNam
     
     return -1;
 }
+// modifiers + keyName をグローバルホットキーとして登録する
+// 成功時は noErr、キー名が不明な場合は -1、それ以外は RegisterEventHotKey の OSStatus を返す
+int RegisterHotKeyWithID(int hotKeyID, UInt32 modifiers, const char* keyName) {
+    int keyCode = getKeyCodeFromName(keyName);
+    if (keyCode == -1) {
+        char buffer[100];
+        snprintf(buffer, sizeof(buffer), "Unknown hotkey key: %s", keyName);
+        writeToLogFile(buffer);
+        return -1;
+    }
+    
+    __block OSStatus status = noErr;
+    runOnMainThread(^{
+        RegisterGlobalHotKey();
+        EventHotKeyID eventHotKeyID;
+        eventHotKeyID.signature = 'GHST';
+        eventHotKeyID.id = hotKeyID;
+        EventHotKeyRef hotKeyRef = NULL;
+        status = RegisterEventHotKey(keyCode, modifiers, eventHotKeyID,
+                                     GetApplicationEventTarget(), 0, &hotKeyRef);
+        if (status == noErr) {
+            hotKeyRefs[@(hotKeyID)] = [NSValue valueWithPointer:hotKeyRef];
+        }
+    });
+    
+    char buffer[100];
+    if (status == noErr) {
+        snprintf(buffer, sizeof(buffer), "Hotkey %d registered (key: %s)", hotKeyID, keyName);
+    } else {
+        snprintf(buffer, sizeof(buffer), "Failed to register hotkey %d (key: %s, status: %d)", hotKeyID, keyName, (int)status);
+    }
+    writeToLogFile(buffer);
+    return status;
+}
+// ホットキーの登録を解除する
+void UnregisterHotKeyWithID(int hotKeyID) {
+    runOnMainThread(^{
+        NSValue *value = hotKeyRefs[@(hotKeyID)];
+        if (value != nil) {
+            UnregisterEventHotKey((EventHotKeyRef)[value pointerValue]);
+            [hotKeyRefs removeObjectForKey:@(hotKeyID)];
+        }
+    });
+}
 // キーコードから文字列キー名に変換する関数
 static const char* getKeyNameFromCode(int keyCode) {
     switch (keyCode) {
@@ -500,26 +487,6 @@ char* GetPressedKeysString() {
     snprintf(logBuffer, sizeof(logBuffer), "Found %d pressed keys: %s", keysFound, buffer);
     writeToLogFile(logBuffer);
     
-    // Shiftキーの二重押し検出ロジック
-    bool isShiftPressed = (strstr(buffer, "lshift") != NULL || 
-                         strstr(buffer, "rshift") != NULL);
-    
-    if (isShiftPressed && !lastShiftState) {
-        // Shiftキーが押された時
-        NSTimeInterval currentTime = [[NSDate date] timeIntervalSince1970];
-        NSTimeInterval timeDiff = currentTime - lastShiftKeyTime;
-        
-        if (timeDiff < DOUBLE_PRESS_THRESHOLD && timeDiff > 0.05) {
-            // 前回のShiftキー押下から閾値時間以内、かつちゃんと離した後に押した場合
-            shiftDoublePressed = true;
-            writeToLogFile("Double Shift detected!");
-        }
-        
-        lastShiftKeyTime = currentTime;
-    }
-    
-    lastShiftState = isShiftPressed;
-    
     // 結果の文字列を動的に割り当て
     return strdup(buffer);
 }
@@ -611,10 +578,5 @@ void StopKeyMonitoring() {
         callbackFunctionName = NULL;
     }
     
-    // Shiftキー状態をリセット
-    lastShiftKeyTime = 0;
-    shiftDoublePressed = false;
-    lastShiftState = false;
-    
     writeToLogFile("Key monitoring stopped");
 }
\ No newline at end of file
@@ -0,0 +1,88 @@
+package x11
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Xauthority のアドレスファミリ
+const (
+	familyLocal = 256
+	familyWild  = 65535
+)
+
+const authProtocol = "MIT-MAGIC-COOKIE-1"
+
+// readAuthority は Xauthority ファイルから接続先に対応する認証情報を探す
+// 見つからない場合は空の認証情報で接続を試みる
+func readAuthority(host, displayNum string) (name string, data []byte) {
+	path := os.Getenv("XAUTHORITY")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", nil
+		}
+		path = filepath.Join(home, ".Xauthority")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil
+	}
+	defer f.Close()
+
+	if host == "" || host == "unix" || host == "localhost" {
+		host, err = os.Hostname()
+		if err != nil {
+			return "", nil
+		}
+	}
+
+	for {
+		family, err := readUint16(f)
+		if err != nil {
+			return "", nil
+		}
+		address, err1 := readCounted(f)
+		number, err2 := readCounted(f)
+		entryName, err3 := readCounted(f)
+		entryData, err4 := readCounted(f)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+			return "", nil
+		}
+
+		if family != familyWild && !(family == familyLocal && string(address) == host) {
+			continue
+		}
+		if len(number) > 0 && string(number) != displayNum {
+			continue
+		}
+		if string(entryName) != authProtocol {
+			continue
+		}
+		return string(entryName), entryData
+	}
+}
+
+// Xauthority ファイルはビッグエンディアンで書かれている
+func readUint16(r io.Reader) (uint16, error) {
+	var buf [2]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(buf[:]), nil
+}
+
+func readCounted(r io.Reader) ([]byte, error) {
+	n, err := readUint16(r)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
@@ -0,0 +1,489 @@
+// Package x11 はX11プロトコルを直接話す最小限のクライアント
+//
+// Linux向けのネイティブ層が必要とするリクエスト (ポインタ位置、キーボード状態、
+// キーグラブ、セレクション、XTEST拡張) だけを実装している。
+// $DISPLAY で指定されたサーバーに接続するため、Xvfbなどのローカルサーバーに対しても動作する。
+package x11
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// クライアントのバイトオーダーはリトルエンディアンで固定する
+var order = binary.LittleEndian
+
+// replyTimeout はリプライを待つ最大時間 (テストで短くするため変数にしている)
+var replyTimeout = 5 * time.Second
+
+// ErrClosed は切断済みの接続を使おうとしたときに返される
+var ErrClosed = errors.New("x11: connection closed")
+
+type (
+	Window  uint32
+	Atom    uint32
+	Keycode uint8
+	Keysym  uint32
+)
+
+// 予約済みの値
+const (
+	None        = 0
+	CurrentTime = 0
+)
+
+// 修飾キーのマスク
+const (
+	ModMaskShift   uint16 = 1 << 0
+	ModMaskLock    uint16 = 1 << 1
+	ModMaskControl uint16 = 1 << 2
+	ModMask1       uint16 = 1 << 3
+	ModMask2       uint16 = 1 << 4
+	ModMask3       uint16 = 1 << 5
+	ModMask4       uint16 = 1 << 6
+	ModMask5       uint16 = 1 << 7
+	ModMaskAny     uint16 = 1 << 15
+)
+
+// Screen は接続時にサーバーから通知されるスクリーン情報
+type Screen struct {
+	Root           Window
+	WidthInPixels  uint16
+	HeightInPixels uint16
+	RootVisual     uint32
+	RootDepth      uint8
+}
+
+// Error はサーバーから返されたエラー
+type Error struct {
+	Code        uint8
+	Sequence    uint16
+	BadValue    uint32
+	MinorOpcode uint16
+	MajorOpcode uint8
+}
+
+func (e *Error) Error() string {
+	name, ok := errorNames[e.Code]
+	if !ok {
+		name = "Unknown"
+	}
+	return fmt.Sprintf("x11: %s error (code %d, opcode %d.%d, value 0x%x)", name, e.Code, e.MajorOpcode, e.MinorOpcode, e.BadValue)
+}
+
+// エラーコード
+const (
+	BadRequest        = 1
+	BadValue          = 2
+	BadWindow         = 3
+	BadAtom           = 5
+	BadMatch          = 8
+	BadAccess         = 10
+	BadAlloc          = 11
+	BadLength         = 16
+	BadImplementation = 17
+)
+
+var errorNames = map[uint8]string{
+	BadRequest:        "BadRequest",
+	BadValue:          "BadValue",
+	BadWindow:         "BadWindow",
+	BadAtom:           "BadAtom",
+	BadMatch:          "BadMatch",
+	BadAccess:         "BadAccess",
+	BadAlloc:          "BadAlloc",
+	BadLength:         "BadLength",
+	BadImplementation: "BadImplementation",
+}
+
+type response struct {
+	data []byte
+	err  error
+}
+
+// Conn はX11サーバーへの接続
+type Conn struct {
+	conn net.Conn
+
+	// リクエストの書き込みとシーケンス番号の採番
+	writeMu sync.Mutex
+	seq     uint16
+
+	// リプライ待ちのリクエスト
+	pendingMu sync.Mutex
+	pending   map[uint16]chan response
+
+	// リソースIDの採番
+	idMu   sync.Mutex
+	idBase uint32
+	idMask uint32
+	idNext uint32
+
+	// Screens は接続先のスクリーン一覧
+	Screens []Screen
+	// MinKeycode と MaxKeycode はサーバーが使うキーコードの範囲
+	MinKeycode Keycode
+	MaxKeycode Keycode
+
+	events    chan Event
+	closed    chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Dial は display (空の場合は $DISPLAY) のX11サーバーに接続する
+func Dial(display string) (*Conn, error) {
+	if display == "" {
+		display = os.Getenv("DISPLAY")
+	}
+	if display == "" {
+		return nil, errors.New("x11: DISPLAY is not set")
+	}
+
+	host, displayNum, err := parseDisplay(display)
+	if err != nil {
+		return nil, err
+	}
+
+	var netConn net.Conn
+	if host == "" || host == "unix" {
+		netConn, err = net.Dial("unix", "/tmp/.X11-unix/X"+displayNum)
+	} else {
+		port, _ := strconv.Atoi(displayNum)
+		netConn, err = net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(6000+port)))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("x11: failed to connect to %s: %w", display, err)
+	}
+
+	authName, authData := readAuthority(host, displayNum)
+
+	c := &Conn{
+		conn:    netConn,
+		pending: make(map[uint16]chan response),
+		events:  make(chan Event, 256),
+		closed:  make(chan struct{}),
+	}
+	if err := c.handshake(authName, authData); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	go c.readLoop()
+	return c, nil
+}
+
+// parseDisplay は "[host]:display[.screen]" 形式の文字列を分解する
+func parseDisplay(display string) (host, displayNum string, err error) {
+	colon := strings.LastIndex(display, ":")
+	if colon < 0 {
+		return "", "", fmt.Errorf("x11: invalid DISPLAY %q", display)
+	}
+	host = display[:colon]
+	displayNum = display[colon+1:]
+	if dot := strings.Index(displayNum, "."); dot >= 0 {
+		displayNum = displayNum[:dot]
+	}
+	if _, err := strconv.Atoi(displayNum); err != nil {
+		return "", "", fmt.Errorf("x11: invalid DISPLAY %q", display)
+	}
+	return host, displayNum, nil
+}
+
+func (c *Conn) handshake(authName string, authData []byte) error {
+	buf := make([]byte, 12, 12+pad(len(authName))+pad(len(authData)))
+	buf[0] = 'l' // リトルエンディアン
+	order.PutUint16(buf[2:], 11)
+	order.PutUint16(buf[4:], 0)
+	order.PutUint16(buf[6:], uint16(len(authName)))
+	order.PutUint16(buf[8:], uint16(len(authData)))
+	buf = appendPadded(buf, []byte(authName))
+	buf = appendPadded(buf, authData)
+	if _, err := c.conn.Write(buf); err != nil {
+		return fmt.Errorf("x11: failed to send setup: %w", err)
+	}
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return fmt.Errorf("x11: failed to read setup reply: %w", err)
+	}
+	body := make([]byte, int(order.Uint16(header[6:]))*4)
+	if _, err := io.ReadFull(c.conn, body); err != nil {
+		return fmt.Errorf("x11: failed to read setup reply: %w", err)
+	}
+
+	switch header[0] {
+	case 1:
+	case 0:
+		reasonLen := int(header[1])
+		if reasonLen > len(body) {
+			reasonLen = len(body)
+		}
+		return fmt.Errorf("x11: connection refused: %s", string(body[:reasonLen]))
+	default:
+		return errors.New("x11: server requested further authentication")
+	}
+
+	if len(body) < 32 {
+		return errors.New("x11: setup reply is too short")
+	}
+	c.idBase = order.Uint32(body[4:])
+	c.idMask = order.Uint32(body[8:])
+	vendorLen := int(order.Uint16(body[16:]))
+	numScreens := int(body[20])
+	numFormats := int(body[21])
+	c.MinKeycode = Keycode(body[26])
+	c.MaxKeycode = Keycode(body[27])
+
+	off := 32 + pad(vendorLen) + 8*numFormats
+	for i := 0; i < numScreens; i++ {
+		if off+40 > len(body) {
+			return errors.New("x11: setup reply is truncated")
+		}
+		s := body[off:]
+		c.Screens = append(c.Screens, Screen{
+			Root:           Window(order.Uint32(s[0:])),
+			WidthInPixels:  order.Uint16(s[20:]),
+			HeightInPixels: order.Uint16(s[22:]),
+			RootVisual:     order.Uint32(s[32:]),
+			RootDepth:      s[38],
+		})
+
+		// 深度とビジュアルの一覧を読み飛ばす
+		numDepths := int(s[39])
+		off += 40
+		for d := 0; d < numDepths; d++ {
+			if off+8 > len(body) {
+				return errors.New("x11: setup reply is truncated")
+			}
+			numVisuals := int(order.Uint16(body[off+2:]))
+			off += 8 + 24*numVisuals
+		}
+	}
+	if len(c.Screens) == 0 {
+		return errors.New("x11: server reported no screens")
+	}
+	return nil
+}
+
+// DefaultScreen は最初のスクリーンを返す
+func (c *Conn) DefaultScreen() Screen {
+	return c.Screens[0]
+}
+
+// Events はサーバーから届いたイベントを受け取るチャネルを返す
+// リプライを伴わないリクエストのエラーも *Error として届く
+func (c *Conn) Events() <-chan Event {
+	return c.events
+}
+
+// Close は接続を閉じる
+func (c *Conn) Close() error {
+	c.shutdown(ErrClosed)
+	return nil
+}
+
+func (c *Conn) shutdown(err error) {
+	c.closeOnce.Do(func() {
+		c.closeErr = err
+		close(c.closed)
+		c.conn.Close()
+
+		c.pendingMu.Lock()
+		for seq, ch := range c.pending {
+			ch <- response{err: err}
+			delete(c.pending, seq)
+		}
+		c.pendingMu.Unlock()
+	})
+}
+
+// NewID は新しいリソースIDを採番する
+func (c *Conn) NewID() (uint32, error) {
+	c.idMu.Lock()
+	defer c.idMu.Unlock()
+
+	inc := c.idMask & -c.idMask
+	if c.idNext > c.idMask-inc {
+		return 0, errors.New("x11: resource IDs exhausted")
+	}
+	id := c.idBase | c.idNext
+	c.idNext += inc
+	return id, nil
+}
+
+func (c *Conn) readLoop() {
+	for {
+		buf := make([]byte, 32)
+		if _, err := io.ReadFull(c.conn, buf); err != nil {
+			c.shutdown(fmt.Errorf("x11: connection lost: %w", err))
+			close(c.events)
+			return
+		}
+
+		seq := order.Uint16(buf[2:])
+		switch buf[0] {
+		case 0:
+			xerr := &Error{
+				Code:        buf[1],
+				Sequence:    seq,
+				BadValue:    order.Uint32(buf[4:]),
+				MinorOpcode: order.Uint16(buf[8:]),
+				MajorOpcode: buf[10],
+			}
+			if !c.deliver(seq, response{err: xerr}) {
+				c.pushEvent(xerr)
+			}
+		case 1:
+			extra := int(order.Uint32(buf[4:])) * 4
+			if extra > 0 {
+				more := make([]byte, extra)
+				if _, err := io.ReadFull(c.conn, more); err != nil {
+					c.shutdown(fmt.Errorf("x11: connection lost: %w", err))
+					close(c.events)
+					return
+				}
+				buf = append(buf, more...)
+			}
+			c.deliver(seq, response{data: buf})
+		default:
+			c.pushEvent(parseEvent(buf))
+		}
+	}
+}
+
+func (c *Conn) pushEvent(ev Event) {
+	select {
+	case c.events <- ev:
+	case <-c.closed:
+	}
+}
+
+func (c *Conn) deliver(seq uint16, resp response) bool {
+	c.pendingMu.Lock()
+	ch, ok := c.pending[seq]
+	if ok {
+		delete(c.pending, seq)
+	}
+	c.pendingMu.Unlock()
+
+	if ok {
+		ch <- resp
+	}
+	return ok
+}
+
+// send はリクエストを送信し、そのシーケンス番号を返す
+// wait が true の場合はリプライ (またはエラー) を受け取るチャネルも登録する
+func (c *Conn) send(req []byte, wait bool) (uint16, chan response, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	select {
+	case <-c.closed:
+		return 0, nil, c.closeErr
+	default:
+	}
+
+	c.seq++
+	var ch chan response
+	if wait {
+		ch = make(chan response, 1)
+		c.pendingMu.Lock()
+		c.pending[c.seq] = ch
+		c.pendingMu.Unlock()
+	}
+
+	if _, err := c.conn.Write(req); err != nil {
+		if wait {
+			c.pendingMu.Lock()
+			delete(c.pending, c.seq)
+			c.pendingMu.Unlock()
+		}
+		c.shutdown(fmt.Errorf("x11: connection lost: %w", err))
+		return 0, nil, err
+	}
+	return c.seq, ch, nil
+}
+
+// await は seq のリプライを待つ
+// タイムアウトした場合は待機を取り消し、遅れて届いたリプライは捨てる
+func (c *Conn) await(seq uint16, ch chan response) ([]byte, error) {
+	timer := time.NewTimer(replyTimeout)
+	defer timer.Stop()
+
+	select {
+	case resp := <-ch:
+		return resp.data, resp.err
+	case <-timer.C:
+		c.pendingMu.Lock()
+		delete(c.pending, seq)
+		c.pendingMu.Unlock()
+		return nil, errors.New("x11: timed out waiting for reply")
+	}
+}
+
+// roundTrip はリプライを返すリクエストを送信し、リプライを待つ
+func (c *Conn) roundTrip(req []byte) ([]byte, error) {
+	seq, ch, err := c.send(req, true)
+	if err != nil {
+		return nil, err
+	}
+	return c.await(seq, ch)
+}
+
+// sendVoid はリプライのないリクエストを送信する
+// エラーが発生した場合は Events に *Error として届く
+func (c *Conn) sendVoid(req []byte) error {
+	_, _, err := c.send(req, false)
+	return err
+}
+
+// sendChecked はリプライのないリクエストを送信し、サーバーがエラーを返したかを確認する
+func (c *Conn) sendChecked(req []byte) error {
+	seq, ch, err := c.send(req, true)
+	if err != nil {
+		return err
+	}
+
+	// リクエストは順番に処理されるため、後続のリプライが届いた時点でエラーの有無が確定する
+	if err := c.Sync(); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp.err
+	default:
+		c.pendingMu.Lock()
+		delete(c.pending, seq)
+		c.pendingMu.Unlock()
+		return nil
+	}
+}
+
+// Sync はサーバーとの往復を1回行い、それまでのリクエストが処理されたことを保証する
+func (c *Conn) Sync() error {
+	_, err := c.roundTrip(newRequest(opGetInputFocus, 0, 0).bytes())
+	return err
+}
+
+func pad(n int) int {
+	return (n + 3) &^ 3
+}
+
+func appendPadded(buf, data []byte) []byte {
+	buf = append(buf, data...)
+	for i := len(data); i < pad(len(data)); i++ {
+		buf = append(buf, 0)
+	}
+	return buf
+}
@@ -0,0 +1,79 @@
+package x11
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseDisplay(t *testing.T) {
+	tests := []struct {
+		display    string
+		host       string
+		displayNum string
+		wantErr    bool
+	}{
+		{display: ":0", displayNum: "0"},
+		{display: ":1.0", displayNum: "1"},
+		{display: "unix:2", host: "unix", displayNum: "2"},
+		{display: "localhost:10.1", host: "localhost", displayNum: "10"},
+		{display: "[::1]:3", host: "[::1]", displayNum: "3"},
+		{display: "0", wantErr: true},
+		{display: ":x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.display, func(t *testing.T) {
+			host, displayNum, err := parseDisplay(tt.display)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseDisplay(%q) succeeded", tt.display)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if host != tt.host || displayNum != tt.displayNum {
+				t.Errorf("parseDisplay(%q) = %q, %q, want %q, %q", tt.display, host, displayNum, tt.host, tt.displayNum)
+			}
+		})
+	}
+}
+
+// TestRoundTripTimeout はリプライが届かない場合に待機が取り消されることを確かめる
+func TestRoundTripTimeout(t *testing.T) {
+	old := replyTimeout
+	replyTimeout = 20 * time.Millisecond
+	t.Cleanup(func() { replyTimeout = old })
+
+	// リクエストを読み捨てるだけで何も返さないサーバー
+	client, server := net.Pipe()
+	go io.Copy(io.Discard, server)
+	c := &Conn{
+		conn:    client,
+		pending: make(map[uint16]chan response),
+		events:  make(chan Event, 1),
+		closed:  make(chan struct{}),
+	}
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.QueryPointer(1); err == nil {
+			t.Fatal("QueryPointer succeeded without a reply")
+		}
+	}
+
+	c.pendingMu.Lock()
+	pending := len(c.pending)
+	c.pendingMu.Unlock()
+	if pending != 0 {
+		t.Errorf("%d requests are still pending after timing out", pending)
+	}
+
+	// 遅れて届いたリプライは待っている呼び出しがないので捨てられる
+	if c.deliver(c.seq, response{}) {
+		t.Error("late reply was delivered")
+	}
+}
@@ -0,0 +1,130 @@
+package x11
+
+// Event はサーバーから届くイベント
+type Event interface {
+	// EventCode はイベント種別 (SendEvent で送られたかを示す最上位ビットは除く)
+	EventCode() uint8
+}
+
+// イベントコード
+const (
+	KeyPress         = 2
+	KeyRelease       = 3
+	SelectionClear   = 29
+	SelectionRequest = 30
+	SelectionNotify  = 31
+)
+
+// KeyEvent は KeyPress / KeyRelease イベント
+type KeyEvent struct {
+	Code   uint8
+	Detail Keycode
+	Time   uint32
+	Root   Window
+	Event  Window
+	Child  Window
+	RootX  int16
+	RootY  int16
+	State  uint16
+}
+
+func (e *KeyEvent) EventCode() uint8 { return e.Code }
+
+// SelectionClearEvent はセレクションの所有権を失ったときに届く
+type SelectionClearEvent struct {
+	Time      uint32
+	Owner     Window
+	Selection Atom
+}
+
+func (e *SelectionClearEvent) EventCode() uint8 { return SelectionClear }
+
+// SelectionRequestEvent は他のクライアントが所有中のセレクションを要求したときに届く
+type SelectionRequestEvent struct {
+	Time      uint32
+	Owner     Window
+	Requestor Window
+	Selection Atom
+	Target    Atom
+	Property  Atom
+}
+
+func (e *SelectionRequestEvent) EventCode() uint8 { return SelectionRequest }
+
+// SelectionNotifyEvent は ConvertSelection の結果として届く
+// 変換に失敗した場合 Property は None になる
+type SelectionNotifyEvent struct {
+	Time      uint32
+	Requestor Window
+	Selection Atom
+	Target    Atom
+	Property  Atom
+}
+
+func (e *SelectionNotifyEvent) EventCode() uint8 { return SelectionNotify }
+
+// bytes は SendEvent で送るための32バイトの表現を返す
+func (e *SelectionNotifyEvent) bytes() []byte {
+	buf := make([]byte, 32)
+	buf[0] = SelectionNotify
+	order.PutUint32(buf[4:], e.Time)
+	order.PutUint32(buf[8:], uint32(e.Requestor))
+	order.PutUint32(buf[12:], uint32(e.Selection))
+	order.PutUint32(buf[16:], uint32(e.Target))
+	order.PutUint32(buf[20:], uint32(e.Property))
+	return buf
+}
+
+// UnknownEvent はこのパッケージが解釈しないイベント
+type UnknownEvent struct {
+	Code uint8
+	Raw  []byte
+}
+
+func (e *UnknownEvent) EventCode() uint8 { return e.Code }
+
+// Error はリプライを伴わないリクエストのエラーとしてイベントチャネルに届く
+func (e *Error) EventCode() uint8 { return 0 }
+
+func parseEvent(buf []byte) Event {
+	code := buf[0] & 0x7f
+	switch code {
+	case KeyPress, KeyRelease:
+		return &KeyEvent{
+			Code:   code,
+			Detail: Keycode(buf[1]),
+			Time:   order.Uint32(buf[4:]),
+			Root:   Window(order.Uint32(buf[8:])),
+			Event:  Window(order.Uint32(buf[12:])),
+			Child:  Window(order.Uint32(buf[16:])),
+			RootX:  int16(order.Uint16(buf[20:])),
+			RootY:  int16(order.Uint16(buf[22:])),
+			State:  order.Uint16(buf[28:]),
+		}
+	case SelectionClear:
+		return &SelectionClearEvent{
+			Time:      order.Uint32(buf[4:]),
+			Owner:     Window(order.Uint32(buf[8:])),
+			Selection: Atom(order.Uint32(buf[12:])),
+		}
+	case SelectionRequest:
+		return &SelectionRequestEvent{
+			Time:      order.Uint32(buf[4:]),
+			Owner:     Window(order.Uint32(buf[8:])),
+			Requestor: Window(order.Uint32(buf[12:])),
+			Selection: Atom(order.Uint32(buf[16:])),
+			Target:    Atom(order.Uint32(buf[20:])),
+			Property:  Atom(order.Uint32(buf[24:])),
+		}
+	case SelectionNotify:
+		return &SelectionNotifyEvent{
+			Time:      order.Uint32(buf[4:]),
+			Requestor: Window(order.Uint32(buf[8:])),
+			Selection: Atom(order.Uint32(buf[12:])),
+			Target:    Atom(order.Uint32(buf[16:])),
+			Property:  Atom(order.Uint32(buf[20:])),
+		}
+	default:
+		return &UnknownEvent{Code: code, Raw: buf}
+	}
+}
@@ -0,0 +1,351 @@
+package x11
+
+import (
+	"errors"
+	"fmt"
+)
+
+// コアプロトコルのオペコード
+const (
+	opCreateWindow       = 1
+	opDestroyWindow      = 4
+	opInternAtom         = 16
+	opChangeProperty     = 18
+	opGetProperty        = 20
+	opSetSelectionOwner  = 22
+	opGetSelectionOwner  = 23
+	opConvertSelection   = 24
+	opSendEvent          = 25
+	opGrabKey            = 33
+	opUngrabKey          = 34
+	opQueryPointer       = 38
+	opGetInputFocus      = 43
+	opQueryKeymap        = 44
+	opQueryExtension     = 98
+	opGetKeyboardMapping = 101
+)
+
+// ウィンドウクラス
+const (
+	WindowClassInputOutput = 1
+	WindowClassInputOnly   = 2
+)
+
+// ChangeProperty のモード
+const (
+	PropModeReplace = 0
+	PropModePrepend = 1
+	PropModeAppend  = 2
+)
+
+// GrabKey のモード
+const (
+	GrabModeSync  = 0
+	GrabModeAsync = 1
+)
+
+// 定義済みアトム
+const (
+	AtomPrimary Atom = 1
+	AtomAtom    Atom = 4
+	AtomString  Atom = 31
+)
+
+// request はリクエストの組み立てを補助する
+type request struct {
+	buf []byte
+}
+
+func newRequest(opcode, data uint8, bodyLen int) *request {
+	r := &request{buf: make([]byte, 4, 4+bodyLen)}
+	r.buf[0] = opcode
+	r.buf[1] = data
+	return r
+}
+
+func (r *request) u8(v uint8) *request {
+	r.buf = append(r.buf, v)
+	return r
+}
+
+func (r *request) u16(v uint16) *request {
+	r.buf = order.AppendUint16(r.buf, v)
+	return r
+}
+
+func (r *request) u32(v uint32) *request {
+	r.buf = order.AppendUint32(r.buf, v)
+	return r
+}
+
+func (r *request) skip(n int) *request {
+	for i := 0; i < n; i++ {
+		r.buf = append(r.buf, 0)
+	}
+	return r
+}
+
+func (r *request) raw(data []byte) *request {
+	r.buf = appendPadded(r.buf, data)
+	return r
+}
+
+// bytes は長さフィールドを埋めたリクエストを返す
+func (r *request) bytes() []byte {
+	for len(r.buf)%4 != 0 {
+		r.buf = append(r.buf, 0)
+	}
+	order.PutUint16(r.buf[2:], uint16(len(r.buf)/4))
+	return r.buf
+}
+
+// CreateWindow は parent の子ウィンドウを作成する
+// セレクションの受け渡し用など、表示しないウィンドウには WindowClassInputOnly を使う
+func (c *Conn) CreateWindow(parent Window, x, y int16, width, height uint16, class uint16) (Window, error) {
+	id, err := c.NewID()
+	if err != nil {
+		return 0, err
+	}
+	req := newRequest(opCreateWindow, 0, 28).
+		u32(id).u32(uint32(parent)).
+		u16(uint16(x)).u16(uint16(y)).u16(width).u16(height).
+		u16(0). // border width
+		u16(class).
+		u32(0). // visual: CopyFromParent
+		u32(0)  // value-mask
+	if err := c.sendChecked(req.bytes()); err != nil {
+		return 0, err
+	}
+	return Window(id), nil
+}
+
+// DestroyWindow はウィンドウを破棄する
+func (c *Conn) DestroyWindow(w Window) error {
+	return c.sendVoid(newRequest(opDestroyWindow, 0, 4).u32(uint32(w)).bytes())
+}
+
+// InternAtom は名前に対応するアトムを取得する
+func (c *Conn) InternAtom(name string, onlyIfExists bool) (Atom, error) {
+	var flag uint8
+	if onlyIfExists {
+		flag = 1
+	}
+	req := newRequest(opInternAtom, flag, 4+pad(len(name))).
+		u16(uint16(len(name))).skip(2).raw([]byte(name))
+	reply, err := c.roundTrip(req.bytes())
+	if err != nil {
+		return 0, err
+	}
+	return Atom(order.Uint32(reply[8:])), nil
+}
+
+// ChangeProperty はウィンドウのプロパティを書き換える
+// format は 8, 16, 32 のいずれかで、data の長さはその倍数である必要がある
+func (c *Conn) ChangeProperty(mode uint8, w Window, property, typ Atom, format uint8, data []byte) error {
+	if format != 8 && format != 16 && format != 32 {
+		return fmt.Errorf("x11: invalid property format %d", format)
+	}
+	unit := int(format / 8)
+	if len(data)%unit != 0 {
+		return errors.New("x11: property data length does not match format")
+	}
+	req := newRequest(opChangeProperty, mode, 20+pad(len(data))).
+		u32(uint32(w)).u32(uint32(property)).u32(uint32(typ)).
+		u8(format).skip(3).
+		u32(uint32(len(data) / unit)).
+		raw(data)
+	return c.sendVoid(req.bytes())
+}
+
+// PropertyValue は GetProperty の結果
+type PropertyValue struct {
+	Type       Atom
+	Format     uint8
+	BytesAfter uint32
+	Value      []byte
+}
+
+// GetProperty はウィンドウのプロパティを読み出す
+// offset と length は4バイト単位で指定する
+func (c *Conn) GetProperty(del bool, w Window, property, typ Atom, offset, length uint32) (*PropertyValue, error) {
+	var flag uint8
+	if del {
+		flag = 1
+	}
+	req := newRequest(opGetProperty, flag, 20).
+		u32(uint32(w)).u32(uint32(property)).u32(uint32(typ)).
+		u32(offset).u32(length)
+	reply, err := c.roundTrip(req.bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	format := reply[1]
+	valueLen := int(order.Uint32(reply[16:]))
+	if format > 0 {
+		valueLen *= int(format / 8)
+	}
+	if 32+valueLen > len(reply) {
+		return nil, errors.New("x11: GetProperty reply is truncated")
+	}
+	return &PropertyValue{
+		Type:       Atom(order.Uint32(reply[8:])),
+		Format:     format,
+		BytesAfter: order.Uint32(reply[12:]),
+		Value:      reply[32 : 32+valueLen],
+	}, nil
+}
+
+// SetSelectionOwner はセレクションの所有者を設定する
+func (c *Conn) SetSelectionOwner(owner Window, selection Atom, time uint32) error {
+	req := newRequest(opSetSelectionOwner, 0, 12).
+		u32(uint32(owner)).u32(uint32(selection)).u32(time)
+	return c.sendVoid(req.bytes())
+}
+
+// GetSelectionOwner はセレクションの所有者を返す
+func (c *Conn) GetSelectionOwner(selection Atom) (Window, error) {
+	reply, err := c.roundTrip(newRequest(opGetSelectionOwner, 0, 4).u32(uint32(selection)).bytes())
+	if err != nil {
+		return 0, err
+	}
+	return Window(order.Uint32(reply[8:])), nil
+}
+
+// ConvertSelection はセレクションの内容を target 形式で requestor の property に書き込むよう要求する
+// 結果は SelectionNotifyEvent として届く
+func (c *Conn) ConvertSelection(requestor Window, selection, target, property Atom, time uint32) error {
+	req := newRequest(opConvertSelection, 0, 20).
+		u32(uint32(requestor)).u32(uint32(selection)).u32(uint32(target)).u32(uint32(property)).u32(time)
+	return c.sendVoid(req.bytes())
+}
+
+// SendSelectionNotify は SelectionRequest への応答を requestor に送る
+func (c *Conn) SendSelectionNotify(ev *SelectionNotifyEvent) error {
+	req := newRequest(opSendEvent, 0, 40).
+		u32(uint32(ev.Requestor)).
+		u32(0). // event-mask: 空の場合は requestor を作成したクライアントに届く
+		raw(ev.bytes())
+	return c.sendVoid(req.bytes())
+}
+
+// GrabKey は grabWindow 上で modifiers + key の組み合わせをグラブする
+// 他のクライアントが同じ組み合わせをグラブ済みの場合は BadAccess エラーを返す
+func (c *Conn) GrabKey(ownerEvents bool, grabWindow Window, modifiers uint16, key Keycode, pointerMode, keyboardMode uint8) error {
+	var flag uint8
+	if ownerEvents {
+		flag = 1
+	}
+	req := newRequest(opGrabKey, flag, 12).
+		u32(uint32(grabWindow)).u16(modifiers).u8(uint8(key)).
+		u8(pointerMode).u8(keyboardMode).skip(3)
+	return c.sendChecked(req.bytes())
+}
+
+// UngrabKey は GrabKey で設定したグラブを解除する
+func (c *Conn) UngrabKey(key Keycode, grabWindow Window, modifiers uint16) error {
+	req := newRequest(opUngrabKey, uint8(key), 8).
+		u32(uint32(grabWindow)).u16(modifiers).skip(2)
+	return c.sendVoid(req.bytes())
+}
+
+// PointerPosition は QueryPointer の結果
+type PointerPosition struct {
+	SameScreen bool
+	Root       Window
+	Child      Window
+	RootX      int16
+	RootY      int16
+	Mask       uint16
+}
+
+// QueryPointer は w のスクリーン上のポインタ位置を返す
+func (c *Conn) QueryPointer(w Window) (*PointerPosition, error) {
+	reply, err := c.roundTrip(newRequest(opQueryPointer, 0, 4).u32(uint32(w)).bytes())
+	if err != nil {
+		return nil, err
+	}
+	return &PointerPosition{
+		SameScreen: reply[1] != 0,
+		Root:       Window(order.Uint32(reply[8:])),
+		Child:      Window(order.Uint32(reply[12:])),
+		RootX:      int16(order.Uint16(reply[16:])),
+		RootY:      int16(order.Uint16(reply[18:])),
+		Mask:       order.Uint16(reply[24:]),
+	}, nil
+}
+
+// QueryKeymap は押されているキーをキーコードごとのビットマップ (32バイト) で返す
+func (c *Conn) QueryKeymap() ([32]byte, error) {
+	var keys [32]byte
+	reply, err := c.roundTrip(newRequest(opQueryKeymap, 0, 0).bytes())
+	if err != nil {
+		return keys, err
+	}
+	if len(reply) < 40 {
+		return keys, errors.New("x11: QueryKeymap reply is truncated")
+	}
+	copy(keys[:], reply[8:40])
+	return keys, nil
+}
+
+// KeyboardMapping は GetKeyboardMapping の結果
+// Keysyms[i*KeysymsPerKeycode+j] が FirstKeycode+i の j 番目のキーシンボル
+type KeyboardMapping struct {
+	FirstKeycode      Keycode
+	KeysymsPerKeycode int
+	Keysyms           []Keysym
+}
+
+// GetKeyboardMapping は first から count 個のキーコードに割り当てられたキーシンボルを返す
+func (c *Conn) GetKeyboardMapping(first Keycode, count uint8) (*KeyboardMapping, error) {
+	req := newRequest(opGetKeyboardMapping, 0, 4).u8(uint8(first)).u8(count).skip(2)
+	reply, err := c.roundTrip(req.bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	perKeycode := int(reply[1])
+	n := int(order.Uint32(reply[4:]))
+	if 32+4*n > len(reply) {
+		return nil, errors.New("x11: GetKeyboardMapping reply is truncated")
+	}
+	syms := make([]Keysym, n)
+	for i := range syms {
+		syms[i] = Keysym(order.Uint32(reply[32+4*i:]))
+	}
+	return &KeyboardMapping{FirstKeycode: first, KeysymsPerKeycode: perKeycode, Keysyms: syms}, nil
+}
+
+// Extension は QueryExtension の結果
+type Extension struct {
+	Present     bool
+	MajorOpcode uint8
+	FirstEvent  uint8
+	FirstError  uint8
+}
+
+// QueryExtension は拡張がサーバーで利用可能かを問い合わせる
+func (c *Conn) QueryExtension(name string) (*Extension, error) {
+	req := newRequest(opQueryExtension, 0, 4+pad(len(name))).
+		u16(uint16(len(name))).skip(2).raw([]byte(name))
+	reply, err := c.roundTrip(req.bytes())
+	if err != nil {
+		return nil, err
+	}
+	return &Extension{
+		Present:     reply[8] != 0,
+		MajorOpcode: reply[9],
+		FirstEvent:  reply[10],
+		FirstError:  reply[11],
+	}, nil
+}
+
+// EncodeAtoms は ATOM 型のプロパティ値 (フォーマット32) としてアトムの列をエンコードする
+func EncodeAtoms(atoms []Atom) []byte {
+	buf := make([]byte, 0, 4*len(atoms))
+	for _, a := range atoms {
+		buf = order.AppendUint32(buf, uint32(a))
+	}
+	return buf
+}
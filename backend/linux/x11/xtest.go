@@ -0,0 +1,41 @@
+package x11
+
+import "errors"
+
+// XTEST 拡張のマイナーオペコード
+const xtestFakeInput = 2
+
+// XTest はキー入力を合成するための XTEST 拡張
+type XTest struct {
+	conn        *Conn
+	majorOpcode uint8
+}
+
+// NewXTest は XTEST 拡張が利用可能か確認し、操作用の XTest を返す
+func (c *Conn) NewXTest() (*XTest, error) {
+	ext, err := c.QueryExtension("XTEST")
+	if err != nil {
+		return nil, err
+	}
+	if !ext.Present {
+		return nil, errors.New("x11: XTEST extension is not available")
+	}
+	return &XTest{conn: c, majorOpcode: ext.MajorOpcode}, nil
+}
+
+// FakeKey はキーの押下 (press=true) または解放を合成する
+func (x *XTest) FakeKey(key Keycode, press bool) error {
+	eventType := uint8(KeyRelease)
+	if press {
+		eventType = KeyPress
+	}
+	req := newRequest(x.majorOpcode, xtestFakeInput, 32).
+		u8(eventType).u8(uint8(key)).skip(2).
+		u32(CurrentTime).
+		u32(None). // root
+		skip(8).
+		u16(0).u16(0). // rootX, rootY
+		skip(7).
+		u8(0) // deviceid
+	return x.conn.sendVoid(req.bytes())
+}
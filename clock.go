@@ -0,0 +1,33 @@
+package main
+
+import "time"
+
+// Clock は時刻の取得と定期実行を抽象化するインターフェース
+// 監視ゴルーチンはこれを通して時間を扱うため、テストでは FakeClock で時間を進められる
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker は time.Ticker と同等の振る舞いをするインターフェース
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// realClock は実時間を使う Clock
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{ticker: time.NewTicker(d)}
+}
+
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t *realTicker) C() <-chan time.Time { return t.ticker.C }
+
+func (t *realTicker) Stop() { t.ticker.Stop() }
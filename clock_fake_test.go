@@ -0,0 +1,116 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// FakeClock は Advance を呼んだときだけ時間が進む Clock
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+// NewFakeClock は start を現在時刻とする FakeClock を生成する
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for FakeClock.NewTicker")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTicker{
+		clock:    c,
+		interval: d,
+		next:     c.now.Add(d),
+		ch:       make(chan time.Time, 1),
+	}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Advance は時間を d だけ進め、その間に発火するティッカーを時刻順に発火させる
+// time.Ticker と同様に、受信側が追いつかない場合のティックは捨てられる
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	target := c.now.Add(d)
+	for {
+		// 次に発火するティッカーを探す
+		active := make([]*fakeTicker, 0, len(c.tickers))
+		for _, t := range c.tickers {
+			if !t.stopped {
+				active = append(active, t)
+			}
+		}
+		c.tickers = active
+		sort.SliceStable(active, func(i, j int) bool { return active[i].next.Before(active[j].next) })
+
+		if len(active) == 0 || active[0].next.After(target) {
+			break
+		}
+
+		t := active[0]
+		c.now = t.next
+		select {
+		case t.ch <- t.next:
+		default:
+		}
+		t.next = t.next.Add(t.interval)
+	}
+	c.now = target
+}
+
+type fakeTicker struct {
+	clock    *FakeClock
+	interval time.Duration
+	next     time.Time
+	ch       chan time.Time
+	stopped  bool
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.stopped = true
+}
+
+// WaitForTickers は動いているティッカーが n 個以上になるまで最大 timeout 待つ
+// 監視ゴルーチンがティッカーを作る前に Advance してティックを取りこぼさないために使う
+func (c *FakeClock) WaitForTickers(n int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		c.mu.Lock()
+		active := 0
+		for _, t := range c.tickers {
+			if !t.stopped {
+				active++
+			}
+		}
+		c.mu.Unlock()
+
+		if active >= n {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
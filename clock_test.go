@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClockAdvance(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		advance  []time.Duration
+		ticks    []time.Duration // 受け取るティックの start からの経過時間
+	}{
+		{
+			name:     "before first tick",
+			interval: 20 * time.Millisecond,
+			advance:  []time.Duration{19 * time.Millisecond},
+		},
+		{
+			name:     "exactly one interval",
+			interval: 20 * time.Millisecond,
+			advance:  []time.Duration{20 * time.Millisecond},
+			ticks:    []time.Duration{20 * time.Millisecond},
+		},
+		{
+			name:     "ticks accumulate over small advances",
+			interval: 50 * time.Millisecond,
+			advance:  []time.Duration{30 * time.Millisecond, 30 * time.Millisecond, 50 * time.Millisecond},
+			ticks:    []time.Duration{50 * time.Millisecond, 100 * time.Millisecond},
+		},
+		{
+			// time.Ticker と同様に、受信されていないティックは1つだけ残る
+			name:     "missed ticks are dropped",
+			interval: 10 * time.Millisecond,
+			advance:  []time.Duration{35 * time.Millisecond},
+			ticks:    []time.Duration{10 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewFakeClock(start)
+			ticker := clock.NewTicker(tt.interval)
+			defer ticker.Stop()
+
+			var got []time.Duration
+			var elapsed time.Duration
+			for _, d := range tt.advance {
+				clock.Advance(d)
+				elapsed += d
+				select {
+				case tick := <-ticker.C():
+					got = append(got, tick.Sub(start))
+				default:
+				}
+			}
+
+			if len(got) != len(tt.ticks) {
+				t.Fatalf("ticks = %v, want %v", got, tt.ticks)
+			}
+			for i := range got {
+				if got[i] != tt.ticks[i] {
+					t.Errorf("tick %d = %v, want %v", i, got[i], tt.ticks[i])
+				}
+			}
+			if now := clock.Now(); !now.Equal(start.Add(elapsed)) {
+				t.Errorf("Now() = %v, want %v", now, start.Add(elapsed))
+			}
+		})
+	}
+}
+
+func TestFakeClockStoppedTicker(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ticker := clock.NewTicker(10 * time.Millisecond)
+	ticker.Stop()
+
+	clock.Advance(time.Second)
+	select {
+	case tick := <-ticker.C():
+		t.Fatalf("stopped ticker fired at %v", tick)
+	default:
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ホスト (このアプリ) のバージョン
+// マニフェストの engines.ghostcursor と比べる
+const hostVersion = "1.0.0"
+
+// engines でホストを表すキー
+const hostEngineName = "ghostcursor"
+
+// resolvePluginLoadOrder はプラグインの engines と dependencies を確認し、依存先が先に来る読み込み順に並べ替える
+// 条件を満たさないプラグイン (依存先が無効なものを含む) は無効にし、問題を validations の該当する結果にも追加する
+// 依存関係のないプラグインの順序と無効なプラグインの位置はスキャンした順のまま保つ
+func resolvePluginLoadOrder(records []PluginRecord, validations []PluginValidationResult, host Semver) []PluginRecord {
+	byID := make(map[string]int, len(records))
+	for i, record := range records {
+		if record.ID != "" {
+			byID[record.ID] = i
+		}
+	}
+	report := func(i int, issues []ValidationIssue) {
+		records[i].Valid = false
+		records[i].Errors = append(records[i].Errors, issues...)
+		for j := range validations {
+			if validations[j].PluginPath == records[i].Dir {
+				validations[j].IsValid = false
+				validations[j].Errors = append(validations[j].Errors, issues...)
+			}
+		}
+	}
+
+	// ホストのバージョン
+	for i, record := range records {
+		if record.Valid {
+			if issues := checkPluginEngines(record.Manifest, host); len(issues) > 0 {
+				report(i, issues)
+			}
+		}
+	}
+
+	// 依存先が無効になると依存元も無効になるので、変化がなくなるまで繰り返す
+	for changed := true; changed; {
+		changed = false
+		for i, record := range records {
+			if !record.Valid {
+				continue
+			}
+			if issues := checkPluginDependencies(record.Manifest, records, byID); len(issues) > 0 {
+				report(i, issues)
+				changed = true
+			}
+		}
+	}
+
+	// 依存先がすべて並んだものから、スキャンした順に並べる
+	ordered := make([]PluginRecord, 0, len(records))
+	placed := make([]bool, len(records))
+	for progress := true; progress; {
+		progress = false
+		for i, record := range records {
+			if placed[i] || (record.Valid && !dependenciesPlaced(record.Manifest, byID, placed)) {
+				continue
+			}
+			ordered = append(ordered, records[i])
+			placed[i] = true
+			progress = true
+		}
+	}
+
+	// 残ったプラグインは循環する依存関係に含まれるか、それに依存している
+	var cycle []string
+	for i, record := range records {
+		if !placed[i] {
+			cycle = append(cycle, record.ID)
+		}
+	}
+	for i := range records {
+		if placed[i] {
+			continue
+		}
+		report(i, []ValidationIssue{{
+			Path:    "$.dependencies",
+			Code:    issueDependencyCycle,
+			Message: fmt.Sprintf("circular dependency among plugins %s", strings.Join(cycle, ", ")),
+		}})
+		ordered = append(ordered, records[i])
+	}
+	return ordered
+}
+
+// checkPluginEngines はホストのバージョンが manifest の engines の範囲に含まれるかを確認する
+func checkPluginEngines(manifest GhostManifest, host Semver) []ValidationIssue {
+	constraint, ok := manifest.Engines[hostEngineName]
+	if !ok {
+		return nil
+	}
+	path := jsonPath(jsonPath("$", "engines"), hostEngineName)
+	r, err := parseSemverRange(constraint)
+	if err != nil {
+		return []ValidationIssue{{Path: path, Code: issueInvalidFormat, Message: err.Error()}}
+	}
+	if !r.Contains(host) {
+		return []ValidationIssue{{
+			Path:    path,
+			Code:    issueIncompatibleEngine,
+			Message: fmt.Sprintf("requires %s %s, but this is version %s", hostEngineName, r, host),
+		}}
+	}
+	return nil
+}
+
+// checkPluginDependencies は manifest の dependencies がすべて有効なプラグインで満たされるかを確認する
+func checkPluginDependencies(manifest GhostManifest, records []PluginRecord, byID map[string]int) []ValidationIssue {
+	var issues []ValidationIssue
+	for _, id := range sortedKeys(manifest.Dependencies) {
+		constraint := manifest.Dependencies[id]
+		path := jsonPath(jsonPath("$", "dependencies"), id)
+		unsatisfied := func(format string, args ...any) {
+			issues = append(issues, ValidationIssue{Path: path, Code: issueUnsatisfiedDep, Message: fmt.Sprintf(format, args...)})
+		}
+
+		i, ok := byID[id]
+		if !ok {
+			unsatisfied("requires plugin %s %s, which is not installed", id, constraint)
+			continue
+		}
+		dependency := records[i]
+		if !dependency.Valid {
+			unsatisfied("requires plugin %s, which is not valid", id)
+			continue
+		}
+
+		r, err := parseSemverRange(constraint)
+		if err != nil {
+			issues = append(issues, ValidationIssue{Path: path, Code: issueInvalidFormat, Message: err.Error()})
+			continue
+		}
+		version, err := parseSemver(dependency.Manifest.Version)
+		if err != nil || !r.Contains(version) {
+			unsatisfied("requires plugin %s %s, but version %s is installed", id, r, dependency.Manifest.Version)
+		}
+	}
+	return issues
+}
+
+// checkDisabledDependencies は無効にされたプラグインに (間接的に) 依存するプラグインを、依存関係を満たさないものとして無効にする
+// records は依存先が先に並んだ読み込み順であること
+func checkDisabledDependencies(records []PluginRecord) {
+	byID := make(map[string]int, len(records))
+	for i, record := range records {
+		if record.ID != "" {
+			byID[record.ID] = i
+		}
+	}
+
+	for i := range records {
+		if !records[i].Valid {
+			continue
+		}
+		for _, id := range sortedKeys(records[i].Manifest.Dependencies) {
+			j, ok := byID[id]
+			if !ok {
+				continue
+			}
+			var reason string
+			switch {
+			case !records[j].Enabled:
+				reason = "disabled"
+			case !records[j].Valid:
+				reason = "not valid"
+			default:
+				continue
+			}
+			records[i].Valid = false
+			records[i].Errors = append(records[i].Errors, ValidationIssue{
+				Path:    jsonPath(jsonPath("$", "dependencies"), id),
+				Code:    issueUnsatisfiedDep,
+				Message: fmt.Sprintf("requires plugin %s, which is %s", id, reason),
+			})
+		}
+	}
+}
+
+// dependenciesPlaced は manifest の依存先がすべて並べ終わっているかを返す
+func dependenciesPlaced(manifest GhostManifest, byID map[string]int, placed []bool) bool {
+	for id := range manifest.Dependencies {
+		if i, ok := byID[id]; !ok || !placed[i] {
+			return false
+		}
+	}
+	return true
+}
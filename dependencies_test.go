@@ -0,0 +1,232 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testDependencyPlugin は依存関係の確認に使う、有効で検証に成功したプラグイン
+type testDependencyPlugin struct {
+	id, version  string
+	dependencies map[string]string
+	engine       string
+}
+
+func testDependencyRecords(plugins []testDependencyPlugin) ([]PluginRecord, []PluginValidationResult) {
+	var records []PluginRecord
+	var validations []PluginValidationResult
+	for _, p := range plugins {
+		manifest := GhostManifest{ID: p.id, Name: p.id, Version: p.version, Dependencies: p.dependencies}
+		if p.engine != "" {
+			manifest.Engines = map[string]string{hostEngineName: p.engine}
+		}
+		dir := "/plugins/" + p.id
+		records = append(records, PluginRecord{ID: p.id, Manifest: manifest, Dir: dir, Valid: true, Enabled: true})
+		validations = append(validations, PluginValidationResult{PluginPath: dir, IsValid: true})
+	}
+	return records, validations
+}
+
+func TestResolvePluginLoadOrder(t *testing.T) {
+	type issue struct{ path, code string }
+	tests := []struct {
+		name    string
+		plugins []testDependencyPlugin
+		host    string
+		// 並べ替えた後のIDの順序と、無効になったプラグインの問題
+		wantOrder  []string
+		wantIssues map[string][]issue
+	}{
+		{
+			name:      "no dependencies keeps the scan order",
+			plugins:   []testDependencyPlugin{{id: "b", version: "1.0.0"}, {id: "a", version: "1.0.0"}},
+			wantOrder: []string{"b", "a"},
+		},
+		{
+			name: "dependency before dependent",
+			plugins: []testDependencyPlugin{
+				{id: "app", version: "1.0.0", dependencies: map[string]string{"lib": "^1.0.0"}},
+				{id: "lib", version: "1.4.0"},
+				{id: "other", version: "1.0.0"},
+			},
+			wantOrder: []string{"lib", "other", "app"},
+		},
+		{
+			name: "chain",
+			plugins: []testDependencyPlugin{
+				{id: "a", version: "1.0.0", dependencies: map[string]string{"b": "*"}},
+				{id: "b", version: "1.0.0", dependencies: map[string]string{"c": "*"}},
+				{id: "c", version: "1.0.0"},
+			},
+			wantOrder: []string{"c", "b", "a"},
+		},
+		{
+			name: "shared dependency",
+			plugins: []testDependencyPlugin{
+				{id: "a", version: "1.0.0", dependencies: map[string]string{"lib": ">=1.0.0", "util": "~2.1.0"}},
+				{id: "b", version: "1.0.0", dependencies: map[string]string{"lib": "1.0.0"}},
+				{id: "util", version: "2.1.3", dependencies: map[string]string{"lib": "^1.0.0"}},
+				{id: "lib", version: "1.0.0"},
+			},
+			wantOrder: []string{"lib", "b", "util", "a"},
+		},
+		{
+			name: "missing dependency",
+			plugins: []testDependencyPlugin{
+				{id: "app", version: "1.0.0", dependencies: map[string]string{"ghost-lib": "^1.0.0"}},
+				{id: "other", version: "1.0.0"},
+			},
+			wantOrder:  []string{"app", "other"},
+			wantIssues: map[string][]issue{"app": {{`$.dependencies["ghost-lib"]`, issueUnsatisfiedDep}}},
+		},
+		{
+			name: "version mismatch",
+			plugins: []testDependencyPlugin{
+				{id: "app", version: "1.0.0", dependencies: map[string]string{"lib": "^2.0.0"}},
+				{id: "lib", version: "1.5.0"},
+			},
+			wantOrder:  []string{"app", "lib"},
+			wantIssues: map[string][]issue{"app": {{"$.dependencies.lib", issueUnsatisfiedDep}}},
+		},
+		{
+			name: "prerelease does not satisfy the release",
+			plugins: []testDependencyPlugin{
+				{id: "app", version: "1.0.0", dependencies: map[string]string{"lib": ">=2.0.0"}},
+				{id: "lib", version: "2.0.0-rc.1"},
+			},
+			wantOrder:  []string{"app", "lib"},
+			wantIssues: map[string][]issue{"app": {{"$.dependencies.lib", issueUnsatisfiedDep}}},
+		},
+		{
+			name: "invalid dependency invalidates dependents",
+			plugins: []testDependencyPlugin{
+				{id: "top", version: "1.0.0", dependencies: map[string]string{"mid": "*"}},
+				{id: "mid", version: "1.0.0", dependencies: map[string]string{"lib": "^3.0.0"}},
+				{id: "lib", version: "1.0.0"},
+			},
+			wantOrder: []string{"top", "mid", "lib"},
+			wantIssues: map[string][]issue{
+				"top": {{"$.dependencies.mid", issueUnsatisfiedDep}},
+				"mid": {{"$.dependencies.lib", issueUnsatisfiedDep}},
+			},
+		},
+		{
+			name: "incompatible engine",
+			plugins: []testDependencyPlugin{
+				{id: "future", version: "1.0.0", engine: ">=2.0.0"},
+				{id: "app", version: "1.0.0", dependencies: map[string]string{"future": "*"}},
+				{id: "current", version: "1.0.0", engine: "^1.0.0"},
+			},
+			host:      "1.0.0",
+			wantOrder: []string{"future", "app", "current"},
+			wantIssues: map[string][]issue{
+				"future": {{"$.engines.ghostcursor", issueIncompatibleEngine}},
+				"app":    {{"$.dependencies.future", issueUnsatisfiedDep}},
+			},
+		},
+		{
+			name: "cycle",
+			plugins: []testDependencyPlugin{
+				{id: "a", version: "1.0.0", dependencies: map[string]string{"b": "*"}},
+				{id: "b", version: "1.0.0", dependencies: map[string]string{"a": "*"}},
+				{id: "c", version: "1.0.0"},
+			},
+			wantOrder: []string{"c", "a", "b"},
+			wantIssues: map[string][]issue{
+				"a": {{"$.dependencies", issueDependencyCycle}},
+				"b": {{"$.dependencies", issueDependencyCycle}},
+			},
+		},
+		{
+			name: "depends on a cycle",
+			plugins: []testDependencyPlugin{
+				{id: "app", version: "1.0.0", dependencies: map[string]string{"a": "*"}},
+				{id: "a", version: "1.0.0", dependencies: map[string]string{"b": "*"}},
+				{id: "b", version: "1.0.0", dependencies: map[string]string{"a": "*"}},
+			},
+			wantOrder: []string{"app", "a", "b"},
+			wantIssues: map[string][]issue{
+				"app": {{"$.dependencies", issueDependencyCycle}},
+				"a":   {{"$.dependencies", issueDependencyCycle}},
+				"b":   {{"$.dependencies", issueDependencyCycle}},
+			},
+		},
+		{
+			name:       "self dependency",
+			plugins:    []testDependencyPlugin{{id: "a", version: "1.0.0", dependencies: map[string]string{"a": "*"}}},
+			wantOrder:  []string{"a"},
+			wantIssues: map[string][]issue{"a": {{"$.dependencies", issueDependencyCycle}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host := tt.host
+			if host == "" {
+				host = hostVersion
+			}
+			records, validations := testDependencyRecords(tt.plugins)
+			ordered := resolvePluginLoadOrder(records, validations, mustParseSemver(t, host))
+
+			var order []string
+			issues := make(map[string][]issue)
+			for _, record := range ordered {
+				order = append(order, record.ID)
+				for _, i := range record.Errors {
+					issues[record.ID] = append(issues[record.ID], issue{i.Path, i.Code})
+				}
+				if record.Valid != (len(record.Errors) == 0) {
+					t.Errorf("%s: Valid = %v with errors %v", record.ID, record.Valid, record.Errors)
+				}
+			}
+			if !reflect.DeepEqual(order, tt.wantOrder) {
+				t.Errorf("order = %v, want %v", order, tt.wantOrder)
+			}
+			want := tt.wantIssues
+			if want == nil {
+				want = map[string][]issue{}
+			}
+			if !reflect.DeepEqual(issues, want) {
+				t.Errorf("issues = %v, want %v", issues, want)
+			}
+
+			// 診断画面の結果にも同じ問題を載せる
+			for i, validation := range validations {
+				id := tt.plugins[i].id
+				if validation.IsValid != (len(want[id]) == 0) || len(validation.Errors) != len(want[id]) {
+					t.Errorf("validation of %s = valid %v, errors %v, want %v", id, validation.IsValid, validation.Errors, want[id])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckDisabledDependencies(t *testing.T) {
+	records, _ := testDependencyRecords([]testDependencyPlugin{
+		{id: "lib", version: "1.0.0"},
+		{id: "mid", version: "1.0.0", dependencies: map[string]string{"lib": "*"}},
+		{id: "top", version: "1.0.0", dependencies: map[string]string{"mid": "*"}},
+		{id: "other", version: "1.0.0"},
+	})
+	records[0].Enabled = false
+
+	checkDisabledDependencies(records)
+	want := map[string]string{
+		"lib":   "",
+		"mid":   "requires plugin lib, which is disabled",
+		"top":   "requires plugin mid, which is not valid",
+		"other": "",
+	}
+	for _, record := range records {
+		var got string
+		if len(record.Errors) > 0 {
+			got = record.Errors[0].Message
+			if record.Errors[0].Code != issueUnsatisfiedDep {
+				t.Errorf("%s: code = %s, want %s", record.ID, record.Errors[0].Code, issueUnsatisfiedDep)
+			}
+		}
+		if got != want[record.ID] || record.Valid != (got == "") {
+			t.Errorf("%s: valid %v, error %q, want %q", record.ID, record.Valid, got, want[record.ID])
+		}
+	}
+}
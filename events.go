@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// フロントエンドに送信するイベント名
+// frontend/src 側の EventsOn と一致させること
+const (
+	EventMouseMove       = "mouse-move"
+	EventShortcut        = "shortcut-event"
+	EventSwitchGhost     = "switch-ghost"
+	EventKeyStateChanged = "key-state-changed"
+
+	EventHotkeyRegistrationFailed = "hotkey-registration-failed"
+	EventPluginShortcut           = "plugin-shortcut-event"
+	EventGesture                  = "gesture-event"
+
+	EventPluginAdded   = "plugin-added"
+	EventPluginUpdated = "plugin-updated"
+	EventPluginRemoved = "plugin-removed"
+
+	EventPluginSettingsChanged = "plugin-settings-changed"
+	EventPluginEnabled         = "plugin-enabled"
+	EventPluginDisabled        = "plugin-disabled"
+	EventPluginsReordered      = "plugins-reordered"
+	EventPluginLoaderReady     = "plugin-loader-ready"
+)
+
+// ShortcutID は shortcut-event のペイロード
+type ShortcutID string
+
+const (
+	ShortcutSC1     ShortcutID = "pushSC1"
+	ShortcutSC2     ShortcutID = "pushSC2"
+	ShortcutSC3     ShortcutID = "pushSC3"
+	ShortcutSC4     ShortcutID = "pushSC4"
+	ShortcutSub     ShortcutID = "pushSub"
+	ShortcutUnknown ShortcutID = "unknown"
+)
+
+// PluginShortcutEvent は plugin-shortcut-event のペイロード
+// アクティブなゴーストに関係なく PluginID のプラグインに届ける
+type PluginShortcutEvent struct {
+	PluginID string `json:"pluginId"`
+	Shortcut string `json:"shortcut"`
+	// 押された時刻 (Unixミリ秒)
+	PressedAt int64 `json:"pressedAt"`
+}
+
+// Event はイベントバスを流れるイベント
+type Event struct {
+	Name    string
+	Payload interface{}
+}
+
+// EventHandler はイベントバスの購読者
+type EventHandler func(event Event)
+
+type subscription struct {
+	// 空の場合はすべてのイベントを受け取る
+	name    string
+	handler EventHandler
+}
+
+// EventBus は App が発行するイベントを購読者に配送する
+// Wailsランタイムへの送信も購読者の一つとして扱う
+type EventBus struct {
+	mu          sync.RWMutex
+	nextID      int
+	subscribers map[int]subscription
+}
+
+// NewEventBus は購読者のいない EventBus を生成する
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[int]subscription)}
+}
+
+// Subscribe は name のイベントを購読し、購読を解除する関数を返す
+func (b *EventBus) Subscribe(name string, handler EventHandler) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = subscription{name: name, handler: handler}
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
+// SubscribeAll はすべてのイベントを購読し、購読を解除する関数を返す
+func (b *EventBus) SubscribeAll(handler EventHandler) (unsubscribe func()) {
+	return b.Subscribe("", handler)
+}
+
+// Publish はイベントを購読者に登録順で同期的に配送する
+func (b *EventBus) Publish(name string, payload interface{}) {
+	b.mu.RLock()
+	ids := make([]int, 0, len(b.subscribers))
+	for id, sub := range b.subscribers {
+		if sub.name == "" || sub.name == name {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	handlers := make([]EventHandler, len(ids))
+	for i, id := range ids {
+		handlers[i] = b.subscribers[id].handler
+	}
+	b.mu.RUnlock()
+
+	event := Event{Name: name, Payload: payload}
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// PublishMouseMove はカーソル位置の変化を通知する
+func (b *EventBus) PublishMouseMove(pos MousePosition) {
+	b.Publish(EventMouseMove, pos)
+}
+
+// PublishShortcut はショートカットの入力を通知する
+func (b *EventBus) PublishShortcut(id ShortcutID) {
+	b.Publish(EventShortcut, id)
+}
+
+// PublishPluginShortcut はプラグインが宣言したショートカットの入力を通知する
+func (b *EventBus) PublishPluginShortcut(event PluginShortcutEvent) {
+	b.Publish(EventPluginShortcut, event)
+}
+
+// PublishGesture は認識したマウスジェスチャーを通知する
+func (b *EventBus) PublishGesture(event GestureEvent) {
+	b.Publish(EventGesture, event)
+}
+
+// PublishPluginChange はプラグインの追加・更新・削除を通知する
+func (b *EventBus) PublishPluginChange(change PluginChange) {
+	switch change.Kind {
+	case PluginAdded:
+		b.Publish(EventPluginAdded, change.Plugin)
+	case PluginUpdated:
+		b.Publish(EventPluginUpdated, change.Plugin)
+	case PluginRemoved:
+		b.Publish(EventPluginRemoved, change.Plugin)
+	}
+}
+
+// PublishPluginSettingsChanged はプラグインの設定の変更を通知する
+func (b *EventBus) PublishPluginSettingsChanged(event PluginSettingsChangedEvent) {
+	b.Publish(EventPluginSettingsChanged, event)
+}
+
+// PublishPluginEnabled はプラグインの有効・無効の変更を通知する
+func (b *EventBus) PublishPluginEnabled(plugin PluginRecord) {
+	if plugin.Enabled {
+		b.Publish(EventPluginEnabled, plugin)
+	} else {
+		b.Publish(EventPluginDisabled, plugin)
+	}
+}
+
+// PublishPluginsReordered はゴーストの並び順 (プラグインIDの配列) の変更を通知する
+func (b *EventBus) PublishPluginsReordered(order []string) {
+	b.Publish(EventPluginsReordered, order)
+}
+
+// PublishPluginLoaderReady はページのローダーがキーを受け取れるようになったことを通知する
+func (b *EventBus) PublishPluginLoaderReady() {
+	b.Publish(EventPluginLoaderReady, nil)
+}
+
+// PublishSwitchGhost はゴーストの切り替えを通知する
+func (b *EventBus) PublishSwitchGhost(ghostID string) {
+	b.Publish(EventSwitchGhost, ghostID)
+}
+
+// PublishKeyState は押されているキー (カンマ区切り) の変化を通知する
+func (b *EventBus) PublishKeyState(keys string) {
+	b.Publish(EventKeyStateChanged, keys)
+}
+
+// PublishHotkeyRegistrationFailed はグローバルホットキーの登録失敗を通知する
+func (b *EventBus) PublishHotkeyRegistrationFailed(status HotkeyStatus) {
+	b.Publish(EventHotkeyRegistrationFailed, status)
+}
+
+// newWailsSubscriber はイベントをWailsランタイム経由でフロントエンドに送信する購読者を返す
+func newWailsSubscriber(ctx context.Context, emit func(ctx context.Context, eventName string, optionalData ...interface{})) EventHandler {
+	return func(event Event) {
+		if event.Payload == nil {
+			emit(ctx, event.Name)
+			return
+		}
+		emit(ctx, event.Name, event.Payload)
+	}
+}
@@ -4,7 +4,7 @@ import { EnableMouseEvents, DisableMouseEvents, ReturnFocusToPreviousWindow } fr
 import { GhostManager } from './core/GhostManager';
 import { GhostRenderer } from './components/GhostRenderer';
 import { PluginDiagnostic } from './components/PluginDiagnostic';
-import { LoadedGhost } from './core/types';
+import { LoadedGhost, MouseGesture, PluginRecord, PluginSettingsChangedEvent } from './core/types';
 
 interface Position {
     x: number;
@@ -378,6 +378,124 @@ export default function App() {
         };
     }, [ghostManager, currentGhost]);
 
+    // プラグイン固有のショートカットの監視
+    useEffect(() => {
+        let unsubscribe: () => void;
+
+        try {
+            unsubscribe = EventsOn('plugin-shortcut-event', (event: { pluginId: string; shortcut: string; pressedAt: number }) => {
+                console.log(`Plugin shortcut event received: ${event.shortcut} -> ${event.pluginId}`);
+                ghostManager.handlePluginShortcut(event.pluginId);
+            });
+        } catch (error) {
+            console.error('Failed to register plugin-shortcut-event handler:', error);
+            unsubscribe = () => { };
+        }
+
+        return () => {
+            if (unsubscribe) unsubscribe();
+        };
+    }, [ghostManager]);
+
+    // マウスジェスチャーの監視 (アクティブなゴーストに届ける)
+    useEffect(() => {
+        let unsubscribe: () => void;
+
+        try {
+            unsubscribe = EventsOn('gesture-event', (gesture: MouseGesture) => {
+                console.log(`Gesture event received: ${gesture.name} (${gesture.score.toFixed(2)})`);
+                ghostManager.handleGesture(gesture);
+            });
+        } catch (error) {
+            console.error('Failed to register gesture-event handler:', error);
+            unsubscribe = () => { };
+        }
+
+        return () => {
+            if (unsubscribe) unsubscribe();
+        };
+    }, [ghostManager]);
+
+    // プラグインの設定の変更 (対象のゴーストに届ける)
+    useEffect(() => {
+        let unsubscribe: () => void;
+
+        try {
+            unsubscribe = EventsOn('plugin-settings-changed', (event: PluginSettingsChangedEvent) => {
+                console.log(`Plugin settings changed: ${event.pluginId}`);
+                ghostManager.handleSettingsChanged(event);
+            });
+        } catch (error) {
+            console.error('Failed to register plugin-settings-changed handler:', error);
+            unsubscribe = () => { };
+        }
+
+        return () => {
+            if (unsubscribe) unsubscribe();
+        };
+    }, [ghostManager]);
+
+    // プラグインのホットリロード (変更のあったゴーストだけを読み込み直す)
+    useEffect(() => {
+        const refreshGhosts = async () => {
+            setAvailableGhosts(ghostManager.getGhosts());
+
+            let current = ghostManager.getCurrentGhost();
+            if (!current) {
+                // アクティブなゴーストが削除された場合は先頭のゴーストに切り替える
+                const first = ghostManager.getGhosts()[0];
+                if (first && await ghostManager.switchGhost(first.manifest.id)) {
+                    current = ghostManager.getCurrentGhost();
+                }
+            }
+            setCurrentGhost(current ?? null);
+        };
+
+        const reload = async (plugin: PluginRecord) => {
+            try {
+                await ghostManager.reloadGhost(plugin);
+            } catch (error) {
+                console.error(`Failed to reload plugin ${plugin.id}:`, error);
+            }
+            await refreshGhosts();
+        };
+
+        const unsubscribers: (() => void)[] = [];
+        try {
+            unsubscribers.push(EventsOn('plugin-added', (plugin: PluginRecord) => {
+                console.log(`Plugin added: ${plugin.id}`);
+                reload(plugin);
+            }));
+            unsubscribers.push(EventsOn('plugin-updated', (plugin: PluginRecord) => {
+                console.log(`Plugin updated: ${plugin.id}`);
+                reload(plugin);
+            }));
+            unsubscribers.push(EventsOn('plugin-removed', (plugin: PluginRecord) => {
+                console.log(`Plugin removed: ${plugin.id}`);
+                ghostManager.unloadGhost(plugin.id).then(refreshGhosts);
+            }));
+            unsubscribers.push(EventsOn('plugin-enabled', (plugin: PluginRecord) => {
+                console.log(`Plugin enabled: ${plugin.id}`);
+                reload(plugin);
+            }));
+            unsubscribers.push(EventsOn('plugin-disabled', (plugin: PluginRecord) => {
+                console.log(`Plugin disabled: ${plugin.id}`);
+                ghostManager.unloadGhost(plugin.id).then(refreshGhosts);
+            }));
+            unsubscribers.push(EventsOn('plugins-reordered', (order: string[]) => {
+                console.log('Plugins reordered:', order);
+                ghostManager.setOrder(order);
+                refreshGhosts();
+            }));
+        } catch (error) {
+            console.error('Failed to register plugin reload handlers:', error);
+        }
+
+        return () => {
+            unsubscribers.forEach(unsubscribe => unsubscribe());
+        };
+    }, [ghostManager]);
+
     useEffect(() => {
         const updateGhosts = () => {
             const ghosts = ghostManager.getGhosts();
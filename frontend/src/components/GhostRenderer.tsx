@@ -1,6 +1,10 @@
 import React, { useState, useEffect, useRef, useCallback } from 'react';
 import { LoadedGhost, Position } from '../core/types';
-import { GetIconData } from '../../wailsjs/go/main/App';
+
+const defaultIcon = './assets/images/ghost.png';
+
+// アイコンは LoadPlugin で data URL として読み込まれる (URLのアイコンはそのまま使う)
+const iconSource = (icon: string) => icon.startsWith('data:') || icon.startsWith('http') ? icon : defaultIcon;
 interface GhostRendererProps {
     ghost: LoadedGhost | null;
     position: Position;
@@ -45,23 +49,10 @@ export const GhostRenderer: React.FC<GhostRendererProps> = ({
 
             for (const g of allGhosts) {
                 if (g.manifest.icon) {
-                    try {
-                        console.log(`Loading icon for ${g.manifest.name} from ${g.manifest.icon}`);
-                        const dataUrl = await GetIconData(g.manifest.icon);
-                        if (dataUrl) {
-                            newIcons[g.manifest.id] = dataUrl;
-                            console.log(`Successfully loaded icon for ${g.manifest.name}`);
-                        } else {
-                            newIcons[g.manifest.id] = './assets/images/ghost.png';
-                            console.log(`Using default icon for ${g.manifest.name} (no data returned)`);
-                        }
-                    } catch (error) {
-                        console.error(`Failed to load icon for ${g.manifest.name}:`, error);
-                        newIcons[g.manifest.id] = './assets/images/ghost.png';
-                    }
+                    newIcons[g.manifest.id] = iconSource(g.manifest.icon);
                 } else {
                     console.log(`No icon specified for ${g.manifest.name}, using default`);
-                    newIcons[g.manifest.id] = './assets/images/ghost.png';
+                    newIcons[g.manifest.id] = defaultIcon;
                 }
             }
 
@@ -152,23 +143,7 @@ export const GhostRenderer: React.FC<GhostRendererProps> = ({
         currentIconPathRef.current = iconPath;
 
         try {
-            if (iconPath.startsWith('data:') || iconPath.startsWith('http')) {
-                setIconSrc(iconPath);
-                iconLoadingRef.current = false;
-                return;
-            }
-
-            const dataUrl = await GetIconData(iconPath);
-
-            if (!dataUrl) {
-                console.error('Received empty icon data');
-                setIconSrc('./assets/images/ghost.png');
-            } else {
-                setIconSrc(dataUrl);
-            }
-        } catch (error) {
-            console.error('Failed to load ghost icon:', error);
-            setIconSrc('./assets/images/ghost.png');
+            setIconSrc(iconSource(iconPath));
         } finally {
             iconLoadingRef.current = false;
         }
@@ -1,5 +1,9 @@
 import React, { useState, useEffect } from 'react';
-import { ValidatePlugins } from '../../wailsjs/go/main/App';
+import { ValidatePlugins, GetHotkeyBindings, ReloadHotkeyBindings, ListPermissionGrants, QueryAuditLog, VerifyAuditLog } from '../../wailsjs/go/main/App';
+import { callHost } from '../core/host';
+import { PluginSettingsForm } from './PluginSettingsForm';
+import { EventsOn } from '../../wailsjs/runtime/runtime';
+import { main } from '../../wailsjs/go/models';
 interface PluginValidationResult {
     pluginPath: string;
     isValid: boolean;
@@ -7,14 +11,97 @@ interface PluginValidationResult {
     hasContent: boolean;
     hasBackground: boolean;
     hasIcon: boolean;
-    errors: string[];
+    errors: ValidationIssue[];
+    warnings: ValidationIssue[];
     manifest?: any;
+    signature?: {
+        status: string;
+        publisher?: string;
+        message?: string;
+    };
+    shadowedBy?: string;  // 同じIDのプラグインが優先されて読み込まれない場合、そのディレクトリ
+    files?: string[];     // プラグインのディレクトリの内容 (2階層まで、ディレクトリは "/" で終わる)
+}
+interface ValidationIssue {
+    path: string;
+    code: string;
+    message: string;
+}
+const formatIssue = (issue: ValidationIssue) => issue.path ? `${issue.path}: ${issue.message}` : issue.message;
+interface HotkeyStatus {
+    id: number;
+    hotkey: string;
+    modifiers: string[];
+    key: string;
+    event?: string;
+    pluginId?: string;
+    registered: boolean;
+    error?: string;
+}
+interface PermissionGrant {
+    pluginId: string;
+    permission: string;
+    granted: boolean;
+}
+interface AuditEntry {
+    seq: number;
+    time: string;
+    pluginId: string;
+    method: string;
+    args?: string;
+    result: string;
+    error?: string;
+}
+interface AuditVerification {
+    valid: boolean;
+    entries: number;
+    message?: string;
 }
 export const PluginDiagnostic: React.FC = () => {
     const [isOpen, setIsOpen] = useState(false);
     const [validationResults, setValidationResults] = useState<PluginValidationResult[]>([]);
     const [isLoading, setIsLoading] = useState(false);
     const [fileDetails, setFileDetails] = useState<Record<string, string[]>>({});
+    const [hotkeys, setHotkeys] = useState<HotkeyStatus[]>([]);
+    const [grants, setGrants] = useState<PermissionGrant[]>([]);
+    const [auditEntries, setAuditEntries] = useState<AuditEntry[]>([]);
+    const [auditVerification, setAuditVerification] = useState<AuditVerification | null>(null);
+
+    const loadAuditLog = async () => {
+        try {
+            const entries = (await QueryAuditLog(main.AuditQuery.createFrom({ limit: 50 }))) || [];
+            setAuditEntries(entries.reverse());
+            setAuditVerification(await VerifyAuditLog());
+        } catch (error) {
+            console.error('Failed to read audit log:', error);
+        }
+    };
+
+    const loadGrants = async () => {
+        try {
+            setGrants((await ListPermissionGrants()) || []);
+        } catch (error) {
+            console.error('Failed to get permission grants:', error);
+        }
+    };
+
+    const revokeGrant = async (grant: PermissionGrant) => {
+        try {
+            await callHost('RevokePluginPermission', grant.pluginId, grant.permission);
+        } catch (error) {
+            console.error(`Failed to revoke ${grant.permission} of ${grant.pluginId}:`, error);
+        }
+        loadGrants();
+    };
+
+    const loadHotkeys = async (reload = false) => {
+        try {
+            const statuses = reload ? await ReloadHotkeyBindings() : await GetHotkeyBindings();
+            setHotkeys(statuses || []);
+        } catch (error) {
+            console.error('Failed to get hotkey bindings:', error);
+        }
+    };
 
     const runDiagnostic = async () => {
         try {
@@ -24,36 +111,8 @@ export const PluginDiagnostic: React.FC = () => {
 
 
             const details: Record<string, string[]> = {};
-
             for (const result of results) {
-                try {
-                    if (typeof window !== 'undefined' && window.go && window.go.main && window.go.main.App) {
-
-                        const entries = await window.go.main.App.ListPluginEntries(result.pluginPath);
-                        const files: string[] = [];
-
-
-                        for (const entry of entries) {
-                            if (entry.isDirectory) {
-
-                                try {
-                                    const subEntries = await window.go.main.App.ListPluginEntries(`${result.pluginPath}/${entry.name}`);
-                                    for (const subEntry of subEntries) {
-                                        files.push(`${entry.name}/${subEntry.name}${subEntry.isDirectory ? '/' : ''}`);
-                                    }
-                                } catch (error) {
-                                    files.push(`${entry.name}/`);
-                                }
-                            } else {
-                                files.push(entry.name);
-                            }
-                        }
-
-                        details[result.pluginPath] = files;
-                    }
-                } catch (error) {
-                    console.error('Failed to get file details:', error);
-                }
+                details[result.pluginPath] = result.files ?? [];
             }
 
             setFileDetails(details);
@@ -66,6 +125,29 @@ export const PluginDiagnostic: React.FC = () => {
 
     useEffect(() => {
         runDiagnostic();
+        loadHotkeys();
+        loadGrants();
+        loadAuditLog();
+    }, []);
+
+    // ホットキーの登録に失敗したら診断画面を開いて知らせる
+    useEffect(() => {
+        let unsubscribe: () => void;
+
+        try {
+            unsubscribe = EventsOn('hotkey-registration-failed', (status: HotkeyStatus) => {
+                console.warn(`Failed to register hotkey ${status.hotkey} (${status.pluginId || status.event}): ${status.error}`);
+                loadHotkeys();
+                setIsOpen(true);
+            });
+        } catch (error) {
+            console.error('Failed to register hotkey-registration-failed handler:', error);
+            unsubscribe = () => { };
+        }
+
+        return () => {
+            if (unsubscribe) unsubscribe();
+        };
     }, []);
 
     useEffect(() => {
@@ -130,6 +212,85 @@ export const PluginDiagnostic: React.FC = () => {
                     {isLoading ? 'Running...' : 'Run Diagnostic'}
                 </button>
             </div>
+            <div style={{ marginBottom: '20px' }}>
+                <div style={{ display: 'flex', alignItems: 'center', gap: '10px' }}>
+                    <h3 style={{ margin: 0 }}>Hotkeys:</h3>
+                    <button
+                        style={{
+                            backgroundColor: '#4299e1',
+                            color: 'white',
+                            border: 'none',
+                            padding: '4px 12px',
+                            borderRadius: '4px',
+                            cursor: 'pointer',
+                        }}
+                        onClick={() => loadHotkeys(true)}
+                    >
+                        Reload
+                    </button>
+                </div>
+                {hotkeys.length === 0 ? (
+                    <div>No hotkeys registered</div>
+                ) : (
+                    <ul style={{ paddingLeft: '20px' }}>
+                        {hotkeys.map((hotkey) => (
+                            <li key={hotkey.id} style={{ color: hotkey.registered ? 'white' : '#f87171' }}>
+                                <code>{hotkey.hotkey}</code> → {hotkey.pluginId ? `plugin: ${hotkey.pluginId}` : hotkey.event}{' '}
+                                {hotkey.registered ? '✅' : `❌ ${hotkey.error}`}
+                            </li>
+                        ))}
+                    </ul>
+                )}
+            </div>
+            <div style={{ marginBottom: '20px' }}>
+                <h3 style={{ margin: 0 }}>Permissions:</h3>
+                {grants.length === 0 ? (
+                    <div>No permissions granted or denied yet</div>
+                ) : (
+                    <ul style={{ paddingLeft: '20px' }}>
+                        {grants.map((grant) => (
+                            <li key={`${grant.pluginId}/${grant.permission}`}>
+                                {grant.pluginId}: <code>{grant.permission}</code> {grant.granted ? '✅ allowed' : '❌ denied'}{' '}
+                                <button
+                                    style={{
+                                        backgroundColor: 'transparent',
+                                        color: '#4299e1',
+                                        border: '1px solid #4299e1',
+                                        padding: '0 8px',
+                                        borderRadius: '4px',
+                                        cursor: 'pointer',
+                                    }}
+                                    onClick={() => revokeGrant(grant)}
+                                >
+                                    Revoke
+                                </button>
+                            </li>
+                        ))}
+                    </ul>
+                )}
+            </div>
+            <div style={{ marginBottom: '20px' }}>
+                <h3 style={{ margin: 0 }}>Audit log:</h3>
+                {auditVerification && (
+                    <div style={{ color: auditVerification.valid ? '#68d391' : '#fc8181' }}>
+                        {auditVerification.valid
+                            ? `✅ ${auditVerification.entries} entries, not modified`
+                            : `❌ ${auditVerification.message}`}
+                    </div>
+                )}
+                {auditEntries.length === 0 ? (
+                    <div>No privileged calls recorded yet</div>
+                ) : (
+                    <ul style={{ paddingLeft: '20px' }}>
+                        {auditEntries.map((entry) => (
+                            <li key={entry.seq}>
+                                {new Date(entry.time).toLocaleString()} {entry.pluginId || '(unknown)'}: <code>{entry.method}</code>
+                                {entry.args ? ` (${entry.args})` : ''} {entry.result === 'allowed' ? '✅' : `❌ ${entry.error || ''}`}
+                            </li>
+                        ))}
+                    </ul>
+                )}
+            </div>
             {isLoading ? (
                 <div>Loading...</div>
             ) : (
@@ -185,6 +346,24 @@ export const PluginDiagnostic: React.FC = () => {
                                         }}>
                                             Icon: {result.hasIcon ? '✓' : '✗'}
                                         </div>
+                                        {result.signature && (
+                                            <div style={{
+                                                backgroundColor: result.signature.status === 'valid' ? 'rgba(74, 222, 128, 0.2)' : 'rgba(251, 191, 36, 0.2)',
+                                                padding: '4px 8px',
+                                                borderRadius: '4px'
+                                            }} title={result.signature.message}>
+                                                Signature: {result.signature.status}{result.signature.publisher ? ` (${result.signature.publisher})` : ''}
+                                            </div>
+                                        )}
+                                        {result.shadowedBy && (
+                                            <div style={{
+                                                backgroundColor: 'rgba(251, 191, 36, 0.2)',
+                                                padding: '4px 8px',
+                                                borderRadius: '4px'
+                                            }} title={result.shadowedBy}>
+                                                Shadowed: not loaded
+                                            </div>
+                                        )}
                                     </div>
 
                                     {/* ファイル構造表示 */}
@@ -218,6 +397,11 @@ export const PluginDiagnostic: React.FC = () => {
                                         </div>
                                     )}
 
+                                    {/* 読み込まれるプラグインだけ設定を変更できる */}
+                                    {result.isValid && !result.shadowedBy && result.manifest?.settings?.length > 0 && (
+                                        <PluginSettingsForm pluginId={result.manifest.id} />
+                                    )}
+
                                     {result.manifest && (
                                         <div style={{ marginBottom: '10px' }}>
                                             <h5 style={{ marginBottom: '5px' }}>Manifest:</h5>
@@ -237,7 +421,17 @@ export const PluginDiagnostic: React.FC = () => {
                                             <h5 style={{ color: '#f87171', marginBottom: '5px' }}>Errors:</h5>
                                             <ul style={{ margin: 0, paddingLeft: '20px' }}>
                                                 {result.errors.map((error, i) => (
-                                                    <li key={i}>{error}</li>
+                                                    <li key={i}>{formatIssue(error)}</li>
+                                                ))}
+                                            </ul>
+                                        </div>
+                                    )}
+                                    {result.warnings?.length > 0 && (
+                                        <div>
+                                            <h5 style={{ color: '#fbbf24', marginBottom: '5px' }}>Warnings:</h5>
+                                            <ul style={{ margin: 0, paddingLeft: '20px' }}>
+                                                {result.warnings.map((warning, i) => (
+                                                    <li key={i}>{formatIssue(warning)}</li>
                                                 ))}
                                             </ul>
                                         </div>
@@ -0,0 +1,186 @@
+import React, { useState, useEffect } from 'react';
+import { callHost } from '../core/host';
+
+// マニフェストの settings の1項目 (Go の SettingField)
+interface SettingField {
+    key: string;
+    type: 'string' | 'number' | 'integer' | 'boolean' | 'shortcut' | 'stringList';
+    title?: string;
+    description?: string;
+    default?: any;
+    enum?: any[];
+    minimum?: number;
+    maximum?: number;
+}
+interface PluginSettings {
+    pluginId: string;
+    fields: SettingField[];
+    values: Record<string, any>;
+}
+
+const inputStyle: React.CSSProperties = {
+    backgroundColor: 'rgba(255, 255, 255, 0.1)',
+    color: 'white',
+    border: '1px solid rgba(255, 255, 255, 0.3)',
+    borderRadius: '4px',
+    padding: '2px 6px',
+};
+
+const buttonStyle: React.CSSProperties = {
+    backgroundColor: 'transparent',
+    color: '#4299e1',
+    border: '1px solid #4299e1',
+    padding: '0 8px',
+    borderRadius: '4px',
+    cursor: 'pointer',
+};
+
+// 入力欄の文字列を項目の型の値にする (正しくない値はそのまま Go 側の検証に任せる)
+const parseInput = (field: SettingField, text: string): any => {
+    switch (field.type) {
+        case 'number':
+        case 'integer':
+            return text.trim() === '' || isNaN(Number(text)) ? text : Number(text);
+        case 'stringList':
+            return text.split('\n').map(line => line.trim()).filter(line => line !== '');
+        default:
+            return text;
+    }
+};
+
+// SettingInput は項目の型に合わせた入力欄を表示する
+const SettingInput: React.FC<{ field: SettingField; value: any; onChange: (value: any) => void }> = ({ field, value, onChange }) => {
+    if (field.type === 'boolean') {
+        return <input type="checkbox" checked={value === true} onChange={(e) => onChange(e.target.checked)} />;
+    }
+    if (field.enum && field.enum.length > 0) {
+        const index = field.enum.findIndex(option => option === value);
+        return (
+            <select style={inputStyle} value={index} onChange={(e) => onChange(field.enum![Number(e.target.value)])}>
+                {index === -1 && <option value={-1}>{String(value)}</option>}
+                {field.enum.map((option, i) => (
+                    <option key={i} value={i}>{String(option)}</option>
+                ))}
+            </select>
+        );
+    }
+    if (field.type === 'stringList') {
+        return (
+            <textarea
+                style={{ ...inputStyle, minWidth: '240px' }}
+                rows={3}
+                placeholder="one item per line"
+                value={Array.isArray(value) ? value.join('\n') : String(value ?? '')}
+                onChange={(e) => onChange(e.target.value.split('\n'))}
+                onBlur={(e) => onChange(parseInput(field, e.target.value))}
+            />
+        );
+    }
+    if (field.type === 'number' || field.type === 'integer') {
+        return (
+            <input
+                type="number"
+                style={inputStyle}
+                min={field.minimum}
+                max={field.maximum}
+                step={field.type === 'integer' ? 1 : 'any'}
+                value={value ?? ''}
+                onChange={(e) => onChange(parseInput(field, e.target.value))}
+            />
+        );
+    }
+    return (
+        <input
+            type="text"
+            style={{ ...inputStyle, minWidth: '240px' }}
+            placeholder={field.type === 'shortcut' ? 'e.g. Alt+1' : undefined}
+            value={value ?? ''}
+            onChange={(e) => onChange(e.target.value)}
+        />
+    );
+};
+
+// PluginSettingsForm はマニフェストの settings から生成した、pluginId のプラグインの設定画面
+// 値の検証は Go 側でプラグイン自身の SetPluginSettings と同じスキーマで行い、正しくない値があれば何も保存しない
+export const PluginSettingsForm: React.FC<{ pluginId: string }> = ({ pluginId }) => {
+    const [settings, setSettings] = useState<PluginSettings | null>(null);
+    const [draft, setDraft] = useState<Record<string, any>>({});
+    const [error, setError] = useState<string | null>(null);
+    const [saved, setSaved] = useState(false);
+
+    const apply = (next: PluginSettings) => {
+        setSettings(next);
+        setDraft({ ...next.values });
+    };
+
+    useEffect(() => {
+        callHost<PluginSettings>('GetPluginSettingsByID', pluginId)
+            .then(apply)
+            .catch((err) => setError(String(err)));
+    }, [pluginId]);
+
+    if (!settings || settings.fields.length === 0) {
+        return error ? <div style={{ color: '#fc8181' }}>❌ {error}</div> : null;
+    }
+
+    const save = async (values: Record<string, any>) => {
+        setSaved(false);
+        try {
+            apply(await callHost<PluginSettings>('SetPluginSettingsByID', pluginId, values));
+            setError(null);
+            setSaved(true);
+        } catch (err) {
+            setError(String(err));
+        }
+    };
+
+    // 変更した項目だけを送る (stringList は入力途中の空行を取り除く)
+    const changedValues = () => {
+        const values: Record<string, any> = {};
+        for (const field of settings.fields) {
+            const value = field.type === 'stringList' && Array.isArray(draft[field.key])
+                ? parseInput(field, draft[field.key].join('\n'))
+                : draft[field.key];
+            if (JSON.stringify(value) !== JSON.stringify(settings.values[field.key])) {
+                values[field.key] = value;
+            }
+        }
+        return values;
+    };
+
+    return (
+        <div style={{ marginBottom: '10px' }}>
+            <h5 style={{ marginBottom: '5px' }}>Settings:</h5>
+            <table style={{ borderSpacing: '8px 4px' }}>
+                <tbody>
+                    {settings.fields.map((field) => (
+                        <tr key={field.key}>
+                            <td title={field.description} style={{ verticalAlign: 'top' }}>
+                                {field.title || field.key} <code style={{ opacity: 0.6 }}>{field.type}</code>
+                            </td>
+                            <td>
+                                <SettingInput
+                                    field={field}
+                                    value={draft[field.key]}
+                                    onChange={(value) => setDraft({ ...draft, [field.key]: value })}
+                                />
+                                {field.description && <div style={{ opacity: 0.7, fontSize: '12px' }}>{field.description}</div>}
+                            </td>
+                            <td style={{ verticalAlign: 'top' }}>
+                                {/* null を送ると既定値に戻る */}
+                                <button style={buttonStyle} onClick={() => save({ [field.key]: null })}>
+                                    Default
+                                </button>
+                            </td>
+                        </tr>
+                    ))}
+                </tbody>
+            </table>
+            <button style={buttonStyle} onClick={() => save(changedValues())}>
+                Save
+            </button>{' '}
+            {error && <span style={{ color: '#fc8181' }}>❌ {error}</span>}
+            {saved && !error && <span style={{ color: '#68d391' }}>✅ saved</span>}
+        </div>
+    );
+};
@@ -1,6 +1,7 @@
-import { LoadedGhost, GhostEvent, GhostEventType, GhostManifest, Ghost } from './types';
+import { LoadedGhost, GhostEvent, GhostEventType, GhostManifest, Ghost, MouseGesture, PluginRecord, PluginSettingsChangedEvent } from './types';
 import { EventEmitter } from './events';
 import { createPluginContext, PluginContext } from '../utils/plugin-utils';
+import { hostApp, loadPlugin, openPluginLoader } from './host';
 
 export class GhostManager {
     private ghosts: Map<string, LoadedGhost> = new Map();
@@ -8,6 +9,8 @@ export class GhostManager {
     private eventEmitter: EventEmitter;
     private isLoading: boolean = false;
     private pluginContexts: Map<string, PluginContext> = new Map();
+    // ユーザーが決めたゴーストの並び順 (プラグインID)
+    private order: string[] = [];
 
     
     private isSwitching: boolean = false;
@@ -29,30 +32,31 @@ export class GhostManager {
 
             try {
                 
-                if (typeof window !== 'undefined' && window.go && window.go.main && window.go.main.App) {
-                    const pluginDirs = await window.go.main.App.GetPluginDirectories();
-                    console.log("Plugin directories:", pluginDirs);
+                if (hostApp.ListPlugins) {
+                    // Go 側でスキャン・検証・ID の重複排除まで済ませたプラグインの一覧
+                    const plugins: PluginRecord[] = await hostApp.ListPlugins();
+                    console.log(`Found ${plugins.length} plugins:`, plugins.map(p => p.id || p.dir));
+                    // プラグインのコードを実行する前にキーを受け取っておく
+                    await openPluginLoader();
+                    // 一覧は読み込み順 (依存先が先) なので、表示の並び順は position から作る
+                    this.order = [...plugins].sort((a, b) => a.position - b.position).map(p => p.id);
+
+                    for (const plugin of plugins) {
+                        if (!plugin.valid) {
+                            console.error(`Skipping invalid plugin at ${plugin.dir}:`, plugin.errors);
+                            continue;
+                        }
+                        if (!plugin.enabled) {
+                            console.log(`Skipping disabled plugin ${plugin.id}`);
+                            continue;
+                        }
 
-                    for (const dir of pluginDirs) {
                         try {
-                            console.log(`Scanning directory: ${dir}`);
-                            
-                            const entries = await window.go.main.App.ListPluginEntries(dir);
-                            console.log(`Found ${entries.length} entries in ${dir}:`, entries);
-
-                            for (const entry of entries) {
-                                if (entry.isDirectory) {
-                                    try {
-                                        console.log(`Loading plugin from: ${dir}/${entry.name}`);
-                                        await this.loadSingleGhost(`${dir}/${entry.name}`);
-                                        console.log(`Successfully loaded plugin from: ${dir}/${entry.name}`);
-                                    } catch (error) {
-                                        console.error(`Failed to load plugin from ${dir}/${entry.name}:`, error);
-                                    }
-                                }
-                            }
+                            console.log(`Loading plugin ${plugin.id} from: ${plugin.dir}`);
+                            await this.loadSingleGhost(plugin);
+                            console.log(`Successfully loaded plugin from: ${plugin.dir}`);
                         } catch (error) {
-                            console.error(`Failed to read plugin directory ${dir}:`, error);
+                            console.error(`Failed to load plugin from ${plugin.dir}:`, error);
                         }
                     }
 
@@ -71,29 +75,34 @@ export class GhostManager {
         }
     }
 
-    private async loadSingleGhost(pluginDir: string) {
+    private async loadSingleGhost(plugin: PluginRecord) {
+        const pluginDir = plugin.dir;
         try {
-            if (typeof window === 'undefined' || !window.go || !window.go.main || !window.go.main.App) {
+            if (!hostApp.LoadPlugin) {
                 throw new Error("Wails API not available");
             }
 
             
-            const manifest = await window.go.main.App.ReadPluginManifest(`${pluginDir}/manifest.json`);
+            const manifest: GhostManifest = { ...plugin.manifest };
+
+            
+            // モジュールの読み込みと同時に、権限が必要な呼び出しに使うトークンが発行される
+            console.log(`Loading entry module ${plugin.entryFile}`);
+            const entry = await loadPlugin(plugin.id);
 
             
-            const context = await createPluginContext(manifest.id);
+            const context = await createPluginContext(manifest.id, entry.token);
             this.pluginContexts.set(manifest.id, context);
 
             
-            if (manifest.icon && !manifest.icon.startsWith('http') && !manifest.icon.startsWith('data:')) {
-                
-                manifest.icon = `${pluginDir}/${manifest.icon}`;
+            // アイコンファイルは LoadPlugin が data URL にして返す
+            if (entry.icon) {
+                manifest.icon = entry.icon;
             }
 
             
             try {
-                console.log(`Loading index module from ${pluginDir}`);
-                const indexCode = await window.go.main.App.ReadPluginModule(pluginDir, 'index');
+                const indexCode = entry.code;
 
                 
                 const isTypeScript = this.isTypeScriptCode(indexCode);
@@ -140,6 +149,62 @@ export class GhostManager {
     }
 
 
+    // 追加・更新されたプラグインだけを読み込み直す (アクティブなゴーストは読み込み後に再度アクティブにする)
+    async reloadGhost(plugin: PluginRecord) {
+        if (!plugin.valid) {
+            // 壊れた状態に更新されたプラグインは読み込み済みのものも取り除く
+            console.error(`Not reloading invalid plugin at ${plugin.dir}:`, plugin.errors);
+            await this.unloadGhost(plugin.id);
+            return;
+        }
+        if (!plugin.enabled) {
+            await this.unloadGhost(plugin.id);
+            return;
+        }
+
+        const wasCurrent = this.currentGhostId === plugin.id;
+        const oldGhost = this.ghosts.get(plugin.id);
+        if (wasCurrent && oldGhost) {
+            try {
+                await oldGhost.ghost.onDeactivate();
+                this.emitEvent('deactivate', plugin.id);
+            } catch (error) {
+                console.error(`Error deactivating ghost "${plugin.id}" before reload:`, error);
+            }
+        }
+
+        await this.loadSingleGhost(plugin);
+        console.log(`Reloaded plugin ${plugin.id} from: ${plugin.dir}`);
+
+        if (wasCurrent) {
+            const newGhost = this.ghosts.get(plugin.id)!;
+            await newGhost.ghost.onActivate();
+            this.emitEvent('activate', plugin.id);
+        }
+    }
+
+    // 削除されたプラグインを取り除く (アクティブなゴーストだった場合はアクティブなゴーストがなくなる)
+    async unloadGhost(pluginId: string) {
+        const ghost = this.ghosts.get(pluginId);
+        if (!ghost) return;
+
+        try {
+            if (this.currentGhostId === pluginId) {
+                await ghost.ghost.onDeactivate();
+                this.currentGhostId = null;
+                this.emitEvent('deactivate', pluginId);
+            }
+            await ghost.ghost.onCleanup();
+        } catch (error) {
+            console.error(`Error unloading ghost "${pluginId}":`, error);
+        } finally {
+            this.ghosts.delete(pluginId);
+            this.pluginContexts.delete(pluginId);
+            console.log(`Unloaded plugin ${pluginId}`);
+        }
+    }
+
+
     
     private isTypeScriptCode(code: string): boolean {
         
@@ -238,9 +303,9 @@ export class GhostManager {
             this.emitEvent('activate', ghostId);
 
             
-            if (typeof window !== 'undefined' && window.go && window.go.main && window.go.main.App && window.go.main.App.SwitchGhost) {
+            if (hostApp.SwitchGhost) {
                 try {
-                    await window.go.main.App.SwitchGhost(ghostId);
+                    await hostApp.SwitchGhost(ghostId);
                 } catch (error) {
                     console.error("Failed to call native SwitchGhost:", error);
                 }
@@ -366,6 +431,59 @@ export class GhostManager {
     }
 
     
+    async handleGesture(gesture: MouseGesture) {
+        if (!this.currentGhostId) return;
+
+        const ghost = this.ghosts.get(this.currentGhostId);
+        if (ghost && ghost.ghost.onGesture) {
+            try {
+                console.log(`Handling gesture "${gesture.name}" for ghost: ${this.currentGhostId}`);
+                await ghost.ghost.onGesture(gesture);
+                this.emitEvent('gesture', this.currentGhostId, gesture);
+            } catch (error) {
+                console.error(`Error handling gesture for ghost "${this.currentGhostId}":`, error);
+            }
+        }
+    }
+
+    
+    // マニフェストの shortcut はアクティブかどうかに関係なく対象のゴーストを呼び出す
+    async handlePluginShortcut(pluginId: string) {
+        const ghost = this.ghosts.get(pluginId);
+        if (!ghost) {
+            console.warn(`Shortcut received for unknown ghost: ${pluginId}`);
+            return;
+        }
+
+        try {
+            console.log(`Handling shortcut for ghost: ${pluginId}`);
+            if (ghost.ghost.onShortcut) {
+                await ghost.ghost.onShortcut();
+            } else {
+                await ghost.ghost.onClick();
+            }
+            this.emitEvent('shortcut', pluginId);
+        } catch (error) {
+            console.error(`Error handling shortcut for ghost "${pluginId}":`, error);
+        }
+    }
+
+    
+    // 設定の変更はアクティブかどうかに関係なく対象のゴーストに届ける
+    async handleSettingsChanged(event: PluginSettingsChangedEvent) {
+        const ghost = this.ghosts.get(event.pluginId);
+        if (!ghost || !ghost.ghost.onSettingsChanged) return;
+
+        try {
+            console.log(`Settings changed for ghost ${event.pluginId}: ${event.changed.join(', ')}`);
+            await ghost.ghost.onSettingsChanged(event.values, event.changed);
+            this.emitEvent('settingsChanged', event.pluginId, event.changed);
+        } catch (error) {
+            console.error(`Error handling settings change for ghost "${event.pluginId}":`, error);
+        }
+    }
+
+    
     private emitEvent(type: GhostEventType, ghostId: string, data?: any) {
         this.eventEmitter.emit({ type, ghostId, data });
     }
@@ -379,8 +497,18 @@ export class GhostManager {
         return this.currentGhostId ? this.ghosts.get(this.currentGhostId) : null;
     }
 
+    // ゴーストをユーザーが決めた並び順で返す (並び順にないものは読み込み順で後ろに並ぶ)
     getGhosts() {
-        return Array.from(this.ghosts.values());
+        const rank = (ghost: LoadedGhost) => {
+            const index = this.order.indexOf(ghost.manifest.id);
+            return index === -1 ? this.order.length : index;
+        };
+        return Array.from(this.ghosts.values()).sort((a, b) => rank(a) - rank(b));
+    }
+
+    // Go 側で並び替えられたゴーストの並び順を反映する
+    setOrder(ids: string[]) {
+        this.order = [...ids];
     }
 
     
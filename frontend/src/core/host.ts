@@ -0,0 +1,88 @@
+import { EventsOn } from '../../wailsjs/runtime/runtime';
+
+type HostMethod = (...args: any[]) => Promise<any>;
+
+// アプリのメソッド (window.go.main.App) への参照
+// プラグインのコードを実行する前に一度だけ取り出す。呼び出すたびに window.go を参照すると、
+// 先に読み込んだプラグインが LoadPlugin などを差し替えて、後から読み込むプラグインのトークンやローダーのキーを集められるため
+export const hostApp: Readonly<Record<string, HostMethod>> = captureHostApp();
+
+function captureHostApp(): Readonly<Record<string, HostMethod>> {
+    const captured: Record<string, HostMethod> = {};
+    if (typeof window === 'undefined' || !window.go || !window.go.main || !window.go.main.App) {
+        return Object.freeze(captured);
+    }
+
+    const app = window.go.main.App;
+    for (const name of Object.keys(app)) {
+        if (typeof app[name] === 'function') {
+            captured[name] = app[name];
+        }
+    }
+
+    // 取り出した後もプラグインが Wails の呼び出しの経路を書き換えられないようにする
+    // (window.go のメソッド、呼び出しを送る WailsInvoke、結果を受け取る window.wails.Callback)
+    lockGlobal('go', deepFreeze(window.go));
+    lockGlobal('wails', Object.freeze((window as any).wails));
+    lockGlobal('runtime', Object.freeze(window.runtime));
+    lockGlobal('WailsInvoke', (window as any).WailsInvoke);
+    return Object.freeze(captured);
+}
+
+function deepFreeze<T>(value: T): T {
+    if (value && typeof value === 'object' && !Object.isFrozen(value)) {
+        Object.freeze(value);
+        for (const key of Object.keys(value)) {
+            deepFreeze((value as any)[key]);
+        }
+    }
+    return value;
+}
+
+// window の name を書き換えも削除もできないプロパティにする
+function lockGlobal(name: string, value: unknown) {
+    if (value === undefined) return;
+    Object.defineProperty(window, name, { value, writable: false, configurable: false, enumerable: true });
+}
+
+
+// LoadPlugin に渡すキー (Go 側はページを読み込むたびに一度しか発行しない)
+// キーはこのモジュールの外には渡さず、プラグインのコードからは届かない
+let loaderKey: Promise<string> | null = null;
+
+// Go 側はページの読み込みが終わると以前のページのキーを無効にし、plugin-loader-ready を送ってからキーを発行する
+// プラグインのコードを実行する前に待ち始めるよう、モジュールの読み込み時に購読する
+const loaderReady = new Promise<void>(resolve => {
+    if (typeof window !== 'undefined' && window.runtime) {
+        const unsubscribe = EventsOn('plugin-loader-ready', () => {
+            unsubscribe();
+            resolve();
+        });
+    }
+});
+
+function pluginLoaderKey(): Promise<string> {
+    if (!loaderKey) {
+        // plugin-loader-ready が先に届いていればすぐに受け取れる
+        loaderKey = hostApp.OpenPluginLoader().catch(async () => {
+            await loaderReady;
+            return hostApp.OpenPluginLoader();
+        });
+    }
+    return loaderKey;
+}
+
+// openPluginLoader はプラグインのコードを実行する前に呼び、ローダーのキーを受け取っておく
+export async function openPluginLoader(): Promise<void> {
+    await pluginLoaderKey();
+}
+
+// loadPlugin は id のプラグインのモジュールを読み込み、そのプラグインのトークンを受け取る
+export async function loadPlugin(id: string): Promise<{ code: string; token: string; icon?: string }> {
+    return hostApp.LoadPlugin(await pluginLoaderKey(), id);
+}
+
+// callHost はプラグインのインストールや権限の取り消しなど、アプリの画面だけが使うメソッドをローダーのキーを付けて呼ぶ
+export async function callHost<T = any>(method: string, ...args: any[]): Promise<T> {
+    return hostApp[method](await pluginLoaderKey(), ...args);
+}
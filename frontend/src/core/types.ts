@@ -12,6 +12,7 @@ export interface Velocity {
 
 // プラグインのマニフェスト定義
 export interface GhostManifest {
+    manifestVersion?: number; // マニフェストの版 (省略時は 1)
     id: string;               // プラグインの一意なID
     name: string;             // 表示名
     version: string;          // バージョン
@@ -19,6 +20,29 @@ export interface GhostManifest {
     author: string;           // 作者
     shortcut: string;         // ショートカットキー (e.g., "Alt+1")
     icon: string;             // アイコンのパス
+    engines?: Record<string, string>;       // 必要なホストのバージョン (e.g., { "ghostcursor": ">=1.0.0" })
+    dependencies?: Record<string, string>;  // 必要なプラグインのIDとバージョンの範囲 (e.g., { "clipboard-core": "^1.2.0" })
+    permissions?: string[];   // 使う権限 (e.g., ["clipboard.read", "keyboard.simulate"])
+    settings?: SettingField[];  // ユーザーが変更できる設定項目
+}
+
+// マニフェストの settings の1項目
+export interface SettingField {
+    key: string;
+    type: 'string' | 'number' | 'integer' | 'boolean' | 'shortcut' | 'stringList';
+    title?: string;
+    description?: string;
+    default?: any;            // 省略時は型の空の値 (enum があればその先頭)
+    enum?: any[];             // 選べる値 (string, number, integer のみ)
+    minimum?: number;         // 値の範囲 (number, integer のみ)
+    maximum?: number;
+}
+
+// plugin-settings-changed のペイロード
+export interface PluginSettingsChangedEvent {
+    pluginId: string;
+    values: Record<string, any>;  // すべての項目の現在の値
+    changed: string[];            // 値が変わった項目のキー
 }
 
 // 統合されたGhostインターフェース（contentとbackgroundを統合）
@@ -50,6 +74,15 @@ export interface Ghost {
     // サブショートカットが押されたときの処理
     onPushSub?: () => Promise<void>;
     
+    // マニフェストの shortcut が押されたときの処理 (未定義の場合は onClick が呼ばれる)
+    onShortcut?: () => Promise<void>;
+    
+    // マウスジェスチャーが認識されたときの処理 (アクティブなゴーストのみ)
+    onGesture?: (gesture: MouseGesture) => Promise<void>;
+
+    // 設定画面などで設定が変更されたときの処理 (values はすべての項目の現在の値)
+    onSettingsChanged?: (values: Record<string, any>, changed: string[]) => Promise<void>;
+    
     // ボタンのテキストを取得
     getButtonText: () => string;
     
@@ -60,6 +93,46 @@ export interface Ghost {
     onCleanup: () => Promise<void | boolean>;
 }
 
+// Go 側でスキャン・検証済みのプラグイン (ListPlugins の戻り値)
+export interface PluginRecord {
+    id: string;
+    manifest: GhostManifest;
+    root: string;             // プラグインが見つかったプラグインディレクトリ
+    dir: string;              // プラグイン自身のディレクトリ
+    entryFile: string;        // 読み込むモジュールのパス
+    backgroundFile?: string;  // 旧式のプラグインの background.js/ts
+    iconPath?: string;        // アイコンファイルのパス (icon がURLの場合はなし)
+    valid: boolean;
+    errors: ValidationIssue[];
+    warnings: ValidationIssue[];
+    signature: PluginSignature;
+    enabled: boolean;         // 無効にしたプラグインは読み込まない
+    position: number;         // ユーザーが決めた並び順での位置 (先頭が既定のゴースト)
+}
+
+// プラグインの署名の確認結果
+export interface PluginSignature {
+    status: 'valid' | 'unsigned' | 'untrusted' | 'invalid';
+    publisher?: string;       // signature.json の発行者
+    message?: string;
+}
+
+// プラグインの検証で見つかった問題
+export interface ValidationIssue {
+    path: string;             // マニフェスト内のJSONパス (e.g., "$.id")、ファイルに関する問題では空
+    code: string;             // 問題の種類 (e.g., "required", "invalid_format", "unknown_field")
+    message: string;
+}
+
+// 認識されたマウスジェスチャー (gesture-event のペイロード)
+export interface MouseGesture {
+    name: string;             // テンプレート名 (e.g., "L", "circle", "flick-left")
+    score: number;            // 一致度 (0〜1)
+    durationMs: number;       // 描き始めから描き終わりまでの時間
+    start: Position;          // 描き始めのスクリーン座標
+    end: Position;            // 描き終わりのスクリーン座標
+}
+
 // ロードされたプラグインの完全な形
 export interface LoadedGhost {
     manifest: GhostManifest;
@@ -67,7 +140,7 @@ export interface LoadedGhost {
 }
 
 // イベント型定義
-export type GhostEventType = 'activate' | 'deactivate' | 'click' | 'move' | 'rightClick' | 'pushSC1' | 'pushSC2' | 'pushSC3' | 'pushSub';
+export type GhostEventType = 'activate' | 'deactivate' | 'click' | 'move' | 'rightClick' | 'pushSC1' | 'pushSC2' | 'pushSC3' | 'pushSub' | 'shortcut' | 'gesture' | 'settingsChanged';
 
 export interface GhostEvent {
     type: GhostEventType;
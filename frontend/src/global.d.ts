@@ -6,7 +6,8 @@ declare global {
     // Wails フレームワークのAPI
     go?: {
       main: {
-        App: WailsBindings;
+        // アプリのすべてのメソッド (プラグインに渡すのは WailsBindings の範囲だけ)
+        App: WailsBindings & { [key: string]: any };
       };
     };
     runtime?: {
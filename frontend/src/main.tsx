@@ -1,3 +1,5 @@
+// ほかのモジュールやプラグインのコードより先に、アプリのメソッドを取り出して window.go を固定する
+import './core/host'
 import React from 'react'
 import {createRoot} from 'react-dom/client'
 import './style.css'
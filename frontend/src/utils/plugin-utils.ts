@@ -2,10 +2,14 @@
 
 
 import { createLogger, Logger } from './logger';
+import { hostApp } from '../core/host';
 
 
+export type PluginFileArea = 'install' | 'data';
+
+
+// プラグインが使えるメソッド (これ以外のアプリのメソッドはプラグインに渡さない)
 export interface WailsBindings {
-  [key: string]: any;
   WritePluginLog: (pluginId: string, level: string, message: string) => Promise<void>;
   ReadPluginLogs: (pluginId: string, maxLines: number) => Promise<string[]>;
   ClearPluginLogs: (pluginId: string) => Promise<void>;
@@ -13,11 +17,21 @@ export interface WailsBindings {
   WriteClipboard: (text: string) => Promise<void>;
   ReturnFocusToPreviousWindow: () => Promise<void>;
   TakeScreenshot: () => Promise<void>;
+  ListPlugins: () => Promise<any[]>;
   GetPluginDirectories: () => Promise<string[]>;
-  ListPluginEntries: (dir: string) => Promise<{name: string, isDirectory: boolean}[]>;
-  ReadPluginManifest: (path: string) => Promise<any>;
-  ReadPluginModule: (path: string, moduleName: string) => Promise<string>;
-  GetIconData: (path: string) => Promise<Uint8Array>;
+  // area は 'install' (プラグインのディレクトリ、読み取り専用) か 'data' (プラグインごとのデータディレクトリ)
+  ListPluginFiles: (area: PluginFileArea, dir: string) => Promise<{name: string, isDirectory: boolean}[]>;
+  ReadPluginFile: (area: PluginFileArea, path: string) => Promise<string>;
+  WritePluginFile: (path: string, content: string) => Promise<void>;
+  DeletePluginFile: (path: string) => Promise<void>;
+  // プラグインごとのキーと文字列の値のストレージ (StorageGet は値がなければ null)
+  StorageGet: (key: string) => Promise<string | null>;
+  StorageSet: (key: string, value: string) => Promise<void>;
+  StorageDelete: (key: string) => Promise<void>;
+  StorageList: () => Promise<string[]>;
+  // マニフェストの settings の項目と現在の値 (値の変更は Ghost.onSettingsChanged で通知される)
+  GetPluginSettings: () => Promise<{fields: any[], values: Record<string, any>}>;
+  SetPluginSettings: (values: Record<string, any>) => Promise<{fields: any[], values: Record<string, any>}>;
   OpenMemo: () => Promise<void>;
   SimulateKeyPress: (keyString: string) => Promise<void>;
   GetPressedKeys: () => Promise<string[]>;
@@ -42,13 +56,49 @@ export interface PluginContext {
 }
 
 
-export async function createPluginContext(pluginId: string): Promise<PluginContext> {
+// プラグインには WailsBindings に挙げたメソッドだけを渡し、インストールや権限の取り消し、任意のパスを受け取るメソッドなどアプリ用のメソッドは渡さない
+// 権限の確認が必要なメソッドは、Go 側がどのプラグインの呼び出しかを判断できるよう LoadPlugin で発行されたトークンを付けて呼ぶ (トークンはプラグインには渡さない)
+// ログも他のプラグインのものを扱えないよう、渡されたIDではなく自分のトークンで呼ぶ
+// app はプラグインのコードを実行する前に取り出したメソッドの参照 (hostApp) で、プラグインが window.go を書き換えても影響しない
+function scopeBindings(token: string, app: Readonly<Record<string, (...args: any[]) => Promise<any>>>): WailsBindings {
+  return {
+    WritePluginLog: (_pluginId: string, level: string, message: string) => app.WritePluginLog(token, level, message),
+    ReadPluginLogs: (_pluginId: string, maxLines: number) => app.ReadPluginLogs(token, maxLines),
+    ClearPluginLogs: (_pluginId: string) => app.ClearPluginLogs(token),
+    ReturnFocusToPreviousWindow: () => app.ReturnFocusToPreviousWindow(),
+    ListPlugins: () => app.ListPlugins(),
+    GetPluginDirectories: () => app.GetPluginDirectories(),
+    GetPressedKeys: () => app.GetPressedKeys(),
+    GetMousePosX: () => app.GetMousePosX(),
+    GetMousePosY: () => app.GetMousePosY(),
+    GetGhostPosX: () => app.GetGhostPosX(),
+    GetGhostPosY: () => app.GetGhostPosY(),
+    ListPluginFiles: (area: PluginFileArea, dir: string) => app.ListPluginFiles(token, area, dir),
+    ReadPluginFile: (area: PluginFileArea, path: string) => app.ReadPluginFile(token, area, path),
+    WritePluginFile: (path: string, content: string) => app.WritePluginFile(token, path, content),
+    DeletePluginFile: (path: string) => app.DeletePluginFile(token, path),
+    StorageGet: (key: string) => app.StorageGet(token, key),
+    StorageSet: (key: string, value: string) => app.StorageSet(token, key, value),
+    StorageDelete: (key: string) => app.StorageDelete(token, key),
+    StorageList: () => app.StorageList(token),
+    GetPluginSettings: () => app.GetPluginSettings(token),
+    SetPluginSettings: (values: Record<string, any>) => app.SetPluginSettings(token, values),
+    ReadClipboard: () => app.ReadClipboard(token),
+    WriteClipboard: (text: string) => app.WriteClipboard(token, text),
+    TakeScreenshot: () => app.TakeScreenshot(token),
+    OpenMemo: () => app.OpenMemo(token),
+    SimulateKeyPress: (keyString: string) => app.SimulateKeyPress(token, keyString),
+  };
+}
+
+
+export async function createPluginContext(pluginId: string, token: string): Promise<PluginContext> {
   let wailsBindings: WailsBindings | undefined;
   let wailsRuntime: WailsRuntime | undefined;
   
   
-  if (typeof window !== 'undefined' && window.go && window.go.main && window.go.main.App) {
-    wailsBindings = window.go.main.App;
+  if (hostApp.LoadPlugin) {
+    wailsBindings = Object.freeze(scopeBindings(token, hostApp));
   }
   
   
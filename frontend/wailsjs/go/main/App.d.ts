@@ -2,21 +2,33 @@
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
 
+export function CheckForUpdates():Promise<Array<main.PluginUpdate>>;
+
 export function ClearPluginLogs(arg1:string):Promise<void>;
 
+export function DeletePluginFile(arg1:string,arg2:string):Promise<void>;
+
 export function DisableMouseEvents():Promise<void>;
 
+export function DisablePlugin(arg1:string,arg2:string):Promise<main.PluginRecord>;
+
 export function EnableMouseEvents():Promise<void>;
 
+export function EnablePlugin(arg1:string,arg2:string):Promise<main.PluginRecord>;
+
 export function GetGhostPosX():Promise<number>;
 
 export function GetGhostPosY():Promise<number>;
 
-export function GetIconData(arg1:string):Promise<string>;
+export function GetHotkeyBindings():Promise<Array<main.HotkeyStatus>>;
+
+export function GetIconData(arg1:string,arg2:string):Promise<string>;
 
-export function GetIconURL(arg1:string):Promise<string>;
+export function GetIconURL(arg1:string,arg2:string):Promise<string>;
 
-export function GetLastShortcutKeyID():Promise<number>;
+export function GetKeyGestures():Promise<Array<main.KeyGesture>>;
+
+export function GetMouseGestures():Promise<main.MouseGestureConfig>;
 
 export function GetMousePosX():Promise<number>;
 
@@ -24,17 +36,41 @@ export function GetMousePosY():Promise<number>;
 
 export function GetMousePosition(arg1:number,arg2:number):Promise<main.MousePosition>;
 
+export function GetPluginDetails(arg1:string):Promise<main.StorePlugin>;
+
 export function GetPluginDirectories():Promise<Array<string>>;
 
+export function GetPluginSettings(arg1:string):Promise<main.PluginSettings>;
+
+export function GetPluginSettingsByID(arg1:string,arg2:string):Promise<main.PluginSettings>;
+
 export function GetPressedKeys():Promise<string>;
 
-export function GetShiftDoublePressed():Promise<boolean>;
+export function InstallFromStore(arg1:string,arg2:string):Promise<main.PluginRecord>;
+
+export function InstallPlugin(arg1:string,arg2:string):Promise<main.PluginRecord>;
+
+export function ListInstalledPlugins():Promise<Array<main.InstalledPlugin>>;
+
+export function ListPermissionGrants():Promise<Array<main.PermissionGrant>>;
+
+export function ListPluginEntries(arg1:string,arg2:string):Promise<Array<main.DirectoryEntry>>;
+
+export function ListPluginFiles(arg1:string,arg2:string,arg3:string):Promise<Array<main.DirectoryEntry>>;
+
+export function ListPlugins():Promise<Array<main.PluginRecord>>;
+
+export function LoadPlugin(arg1:string,arg2:string):Promise<main.PluginEntry>;
+
+export function OpenMemo(arg1:string):Promise<void>;
 
-export function ListPluginEntries(arg1:string):Promise<Array<main.DirectoryEntry>>;
+export function OpenPluginLoader():Promise<string>;
 
-export function OpenMemo():Promise<void>;
+export function QueryAuditLog(arg1:main.AuditQuery):Promise<Array<main.AuditEntry>>;
 
-export function ReadClipboard():Promise<string>;
+export function ReadClipboard(arg1:string):Promise<string>;
+
+export function ReadPluginFile(arg1:string,arg2:string,arg3:string):Promise<string>;
 
 export function ReadPluginLogs(arg1:string,arg2:number):Promise<Array<string>>;
 
@@ -42,26 +78,58 @@ export function ReadPluginManifest(arg1:string):Promise<main.GhostManifest>;
 
 export function ReadPluginModule(arg1:string,arg2:string):Promise<string>;
 
-export function ResetShiftDoublePressed():Promise<void>;
+export function ReloadHotkeyBindings():Promise<Array<main.HotkeyStatus>>;
+
+export function ReloadKeyGestures():Promise<void>;
+
+export function ReloadMouseGestures():Promise<void>;
+
+export function ReorderPlugins(arg1:string,arg2:Array<string>):Promise<Array<main.PluginRecord>>;
+
+export function RescanPlugins():Promise<Array<main.PluginRecord>>;
 
 export function ReturnFocusToPreviousWindow():Promise<void>;
 
+export function RevokePluginPermission(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function RollbackPlugin(arg1:string,arg2:string):Promise<main.PluginRecord>;
+
+export function SearchStore(arg1:string):Promise<Array<main.StorePlugin>>;
+
 export function SetGhostPos(arg1:number,arg2:number):Promise<void>;
 
-export function SimulateKeyPress(arg1:string):Promise<void>;
+export function SetPluginSettings(arg1:string,arg2:{[key: string]: any}):Promise<main.PluginSettings>;
+
+export function SetPluginSettingsByID(arg1:string,arg2:string,arg3:{[key: string]: any}):Promise<main.PluginSettings>;
+
+export function SimulateKeyPress(arg1:string,arg2:string):Promise<void>;
 
 export function StartKeyMonitoring():Promise<void>;
 
 export function StopKeyMonitoring():Promise<void>;
 
+export function StorageDelete(arg1:string,arg2:string):Promise<void>;
+
+export function StorageGet(arg1:string,arg2:string):Promise<string>;
+
+export function StorageList(arg1:string):Promise<Array<string>>;
+
+export function StorageSet(arg1:string,arg2:string,arg3:string):Promise<void>;
+
 export function SwitchGhost(arg1:string):Promise<void>;
 
-export function TakeScreenshot():Promise<void>;
+export function TakeScreenshot(arg1:string):Promise<void>;
+
+export function UninstallPlugin(arg1:string,arg2:string):Promise<void>;
 
 export function ValidatePluginDirectory(arg1:string):Promise<Array<main.PluginValidationResult>>;
 
 export function ValidatePlugins():Promise<Array<main.PluginValidationResult>>;
 
-export function WriteClipboard(arg1:string):Promise<void>;
+export function VerifyAuditLog():Promise<main.AuditVerification>;
+
+export function WriteClipboard(arg1:string,arg2:string):Promise<void>;
+
+export function WritePluginFile(arg1:string,arg2:string,arg3:string):Promise<void>;
 
 export function WritePluginLog(arg1:string,arg2:main.LogLevel,arg3:string):Promise<void>;
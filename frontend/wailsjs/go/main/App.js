@@ -2,18 +2,34 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function CheckForUpdates() {
+  return window['go']['main']['App']['CheckForUpdates']();
+}
+
 export function ClearPluginLogs(arg1) {
   return window['go']['main']['App']['ClearPluginLogs'](arg1);
 }
 
+export function DeletePluginFile(arg1, arg2) {
+  return window['go']['main']['App']['DeletePluginFile'](arg1, arg2);
+}
+
 export function DisableMouseEvents() {
   return window['go']['main']['App']['DisableMouseEvents']();
 }
 
+export function DisablePlugin(arg1, arg2) {
+  return window['go']['main']['App']['DisablePlugin'](arg1, arg2);
+}
+
 export function EnableMouseEvents() {
   return window['go']['main']['App']['EnableMouseEvents']();
 }
 
+export function EnablePlugin(arg1, arg2) {
+  return window['go']['main']['App']['EnablePlugin'](arg1, arg2);
+}
+
 export function GetGhostPosX() {
   return window['go']['main']['App']['GetGhostPosX']();
 }
@@ -22,16 +38,24 @@ export function GetGhostPosY() {
   return window['go']['main']['App']['GetGhostPosY']();
 }
 
-export function GetIconData(arg1) {
-  return window['go']['main']['App']['GetIconData'](arg1);
+export function GetHotkeyBindings() {
+  return window['go']['main']['App']['GetHotkeyBindings']();
 }
 
-export function GetIconURL(arg1) {
-  return window['go']['main']['App']['GetIconURL'](arg1);
+export function GetIconData(arg1, arg2) {
+  return window['go']['main']['App']['GetIconData'](arg1, arg2);
 }
 
-export function GetLastShortcutKeyID() {
-  return window['go']['main']['App']['GetLastShortcutKeyID']();
+export function GetIconURL(arg1, arg2) {
+  return window['go']['main']['App']['GetIconURL'](arg1, arg2);
+}
+
+export function GetKeyGestures() {
+  return window['go']['main']['App']['GetKeyGestures']();
+}
+
+export function GetMouseGestures() {
+  return window['go']['main']['App']['GetMouseGestures']();
 }
 
 export function GetMousePosX() {
@@ -46,28 +70,76 @@ export function GetMousePosition(arg1, arg2) {
   return window['go']['main']['App']['GetMousePosition'](arg1, arg2);
 }
 
+export function GetPluginDetails(arg1) {
+  return window['go']['main']['App']['GetPluginDetails'](arg1);
+}
+
 export function GetPluginDirectories() {
   return window['go']['main']['App']['GetPluginDirectories']();
 }
 
+export function GetPluginSettings(arg1) {
+  return window['go']['main']['App']['GetPluginSettings'](arg1);
+}
+
+export function GetPluginSettingsByID(arg1, arg2) {
+  return window['go']['main']['App']['GetPluginSettingsByID'](arg1, arg2);
+}
+
 export function GetPressedKeys() {
   return window['go']['main']['App']['GetPressedKeys']();
 }
 
-export function GetShiftDoublePressed() {
-  return window['go']['main']['App']['GetShiftDoublePressed']();
+export function InstallFromStore(arg1, arg2) {
+  return window['go']['main']['App']['InstallFromStore'](arg1, arg2);
+}
+
+export function InstallPlugin(arg1, arg2) {
+  return window['go']['main']['App']['InstallPlugin'](arg1, arg2);
+}
+
+export function ListInstalledPlugins() {
+  return window['go']['main']['App']['ListInstalledPlugins']();
+}
+
+export function ListPermissionGrants() {
+  return window['go']['main']['App']['ListPermissionGrants']();
+}
+
+export function ListPluginEntries(arg1, arg2) {
+  return window['go']['main']['App']['ListPluginEntries'](arg1, arg2);
+}
+
+export function ListPluginFiles(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ListPluginFiles'](arg1, arg2, arg3);
+}
+
+export function ListPlugins() {
+  return window['go']['main']['App']['ListPlugins']();
+}
+
+export function LoadPlugin(arg1, arg2) {
+  return window['go']['main']['App']['LoadPlugin'](arg1, arg2);
+}
+
+export function OpenMemo(arg1) {
+  return window['go']['main']['App']['OpenMemo'](arg1);
 }
 
-export function ListPluginEntries(arg1) {
-  return window['go']['main']['App']['ListPluginEntries'](arg1);
+export function OpenPluginLoader() {
+  return window['go']['main']['App']['OpenPluginLoader']();
 }
 
-export function OpenMemo() {
-  return window['go']['main']['App']['OpenMemo']();
+export function QueryAuditLog(arg1) {
+  return window['go']['main']['App']['QueryAuditLog'](arg1);
 }
 
-export function ReadClipboard() {
-  return window['go']['main']['App']['ReadClipboard']();
+export function ReadClipboard(arg1) {
+  return window['go']['main']['App']['ReadClipboard'](arg1);
+}
+
+export function ReadPluginFile(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ReadPluginFile'](arg1, arg2, arg3);
 }
 
 export function ReadPluginLogs(arg1, arg2) {
@@ -82,20 +154,56 @@ export function ReadPluginModule(arg1, arg2) {
   return window['go']['main']['App']['ReadPluginModule'](arg1, arg2);
 }
 
-export function ResetShiftDoublePressed() {
-  return window['go']['main']['App']['ResetShiftDoublePressed']();
+export function ReloadHotkeyBindings() {
+  return window['go']['main']['App']['ReloadHotkeyBindings']();
+}
+
+export function ReloadKeyGestures() {
+  return window['go']['main']['App']['ReloadKeyGestures']();
+}
+
+export function ReloadMouseGestures() {
+  return window['go']['main']['App']['ReloadMouseGestures']();
+}
+
+export function ReorderPlugins(arg1, arg2) {
+  return window['go']['main']['App']['ReorderPlugins'](arg1, arg2);
+}
+
+export function RescanPlugins() {
+  return window['go']['main']['App']['RescanPlugins']();
 }
 
 export function ReturnFocusToPreviousWindow() {
   return window['go']['main']['App']['ReturnFocusToPreviousWindow']();
 }
 
+export function RevokePluginPermission(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RevokePluginPermission'](arg1, arg2, arg3);
+}
+
+export function RollbackPlugin(arg1, arg2) {
+  return window['go']['main']['App']['RollbackPlugin'](arg1, arg2);
+}
+
+export function SearchStore(arg1) {
+  return window['go']['main']['App']['SearchStore'](arg1);
+}
+
 export function SetGhostPos(arg1, arg2) {
   return window['go']['main']['App']['SetGhostPos'](arg1, arg2);
 }
 
-export function SimulateKeyPress(arg1) {
-  return window['go']['main']['App']['SimulateKeyPress'](arg1);
+export function SetPluginSettings(arg1, arg2) {
+  return window['go']['main']['App']['SetPluginSettings'](arg1, arg2);
+}
+
+export function SetPluginSettingsByID(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetPluginSettingsByID'](arg1, arg2, arg3);
+}
+
+export function SimulateKeyPress(arg1, arg2) {
+  return window['go']['main']['App']['SimulateKeyPress'](arg1, arg2);
 }
 
 export function StartKeyMonitoring() {
@@ -106,12 +214,32 @@ export function StopKeyMonitoring() {
   return window['go']['main']['App']['StopKeyMonitoring']();
 }
 
+export function StorageDelete(arg1, arg2) {
+  return window['go']['main']['App']['StorageDelete'](arg1, arg2);
+}
+
+export function StorageGet(arg1, arg2) {
+  return window['go']['main']['App']['StorageGet'](arg1, arg2);
+}
+
+export function StorageList(arg1) {
+  return window['go']['main']['App']['StorageList'](arg1);
+}
+
+export function StorageSet(arg1, arg2, arg3) {
+  return window['go']['main']['App']['StorageSet'](arg1, arg2, arg3);
+}
+
 export function SwitchGhost(arg1) {
   return window['go']['main']['App']['SwitchGhost'](arg1);
 }
 
-export function TakeScreenshot() {
-  return window['go']['main']['App']['TakeScreenshot']();
+export function TakeScreenshot(arg1) {
+  return window['go']['main']['App']['TakeScreenshot'](arg1);
+}
+
+export function UninstallPlugin(arg1, arg2) {
+  return window['go']['main']['App']['UninstallPlugin'](arg1, arg2);
 }
 
 export function ValidatePluginDirectory(arg1) {
@@ -122,8 +250,16 @@ export function ValidatePlugins() {
   return window['go']['main']['App']['ValidatePlugins']();
 }
 
-export function WriteClipboard(arg1) {
-  return window['go']['main']['App']['WriteClipboard'](arg1);
+export function VerifyAuditLog() {
+  return window['go']['main']['App']['VerifyAuditLog']();
+}
+
+export function WriteClipboard(arg1, arg2) {
+  return window['go']['main']['App']['WriteClipboard'](arg1, arg2);
+}
+
+export function WritePluginFile(arg1, arg2, arg3) {
+  return window['go']['main']['App']['WritePluginFile'](arg1, arg2, arg3);
 }
 
 export function WritePluginLog(arg1, arg2, arg3) {
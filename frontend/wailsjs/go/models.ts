@@ -1,5 +1,107 @@
 export namespace main {
 	
+	export class AuditEntry {
+	    seq: number;
+	    time: any;
+	    pluginId: string;
+	    method: string;
+	    permission: string;
+	    args?: string;
+	    result: string;
+	    error?: string;
+	    prevHash: string;
+	    hash: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AuditEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.seq = source["seq"];
+	        this.time = this.convertValues(source["time"], null);
+	        this.pluginId = source["pluginId"];
+	        this.method = source["method"];
+	        this.permission = source["permission"];
+	        this.args = source["args"];
+	        this.result = source["result"];
+	        this.error = source["error"];
+	        this.prevHash = source["prevHash"];
+	        this.hash = source["hash"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class AuditQuery {
+	    pluginId?: string;
+	    method?: string;
+	    since?: any;
+	    limit?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new AuditQuery(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.pluginId = source["pluginId"];
+	        this.method = source["method"];
+	        this.since = this.convertValues(source["since"], null);
+	        this.limit = source["limit"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class AuditVerification {
+	    valid: boolean;
+	    entries: number;
+	    brokenSeq?: number;
+	    message?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AuditVerification(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.valid = source["valid"];
+	        this.entries = source["entries"];
+	        this.brokenSeq = source["brokenSeq"];
+	        this.message = source["message"];
+	    }
+	}
 	export class DirectoryEntry {
 	    name: string;
 	    isDirectory: boolean;
@@ -14,7 +116,58 @@ export namespace main {
 	        this.isDirectory = source["isDirectory"];
 	    }
 	}
+	export class GesturePoint {
+	    x: number;
+	    y: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new GesturePoint(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.x = source["x"];
+	        this.y = source["y"];
+	    }
+	}
+	export class GestureTemplate {
+	    name: string;
+	    points: GesturePoint[];
+	    rotationInvariant?: boolean;
+	    maxDurationMs?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new GestureTemplate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.points = this.convertValues(source["points"], GesturePoint);
+	        this.rotationInvariant = source["rotationInvariant"];
+	        this.maxDurationMs = source["maxDurationMs"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class GhostManifest {
+	    manifestVersion: number;
 	    id: string;
 	    name: string;
 	    version: string;
@@ -22,6 +175,10 @@ export namespace main {
 	    author: string;
 	    shortcut: string;
 	    icon: string;
+	    engines?: Record<string, string>;
+	    dependencies?: Record<string, string>;
+	    permissions?: string[];
+	    settings?: SettingField[];
 	
 	    static createFrom(source: any = {}) {
 	        return new GhostManifest(source);
@@ -29,6 +186,7 @@ export namespace main {
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.manifestVersion = source["manifestVersion"];
 	        this.id = source["id"];
 	        this.name = source["name"];
 	        this.version = source["version"];
@@ -36,7 +194,151 @@ export namespace main {
 	        this.author = source["author"];
 	        this.shortcut = source["shortcut"];
 	        this.icon = source["icon"];
+	        this.engines = source["engines"];
+	        this.dependencies = source["dependencies"];
+	        this.permissions = source["permissions"];
+	        this.settings = this.convertValues(source["settings"], SettingField);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class HotkeyStatus {
+	    id: number;
+	    hotkey: string;
+	    modifiers: string[];
+	    key: string;
+	    event?: string;
+	    pluginId?: string;
+	    registered: boolean;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new HotkeyStatus(source);
 	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.hotkey = source["hotkey"];
+	        this.modifiers = source["modifiers"];
+	        this.key = source["key"];
+	        this.event = source["event"];
+	        this.pluginId = source["pluginId"];
+	        this.registered = source["registered"];
+	        this.error = source["error"];
+	    }
+	}
+	export class InstalledPlugin {
+	    plugin: PluginRecord;
+	    previousVersion?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new InstalledPlugin(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.plugin = this.convertValues(source["plugin"], PluginRecord);
+	        this.previousVersion = source["previousVersion"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class KeyGesture {
+	    kind: string;
+	    key?: string;
+	    count?: number;
+	    keys?: string[];
+	    intervalMs?: number;
+	    durationMs?: number;
+	    event: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new KeyGesture(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.key = source["key"];
+	        this.count = source["count"];
+	        this.keys = source["keys"];
+	        this.intervalMs = source["intervalMs"];
+	        this.durationMs = source["durationMs"];
+	        this.event = source["event"];
+	    }
+	}
+	export class MouseGestureConfig {
+	    modifiers: string[];
+	    threshold: number;
+	    minDistance: number;
+	    idleMs: number;
+	    maxDurationMs: number;
+	    templates?: GestureTemplate[];
+	
+	    static createFrom(source: any = {}) {
+	        return new MouseGestureConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.modifiers = source["modifiers"];
+	        this.threshold = source["threshold"];
+	        this.minDistance = source["minDistance"];
+	        this.idleMs = source["idleMs"];
+	        this.maxDurationMs = source["maxDurationMs"];
+	        this.templates = this.convertValues(source["templates"], GestureTemplate);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class MousePosition {
 	    x: number;
@@ -52,6 +354,160 @@ export namespace main {
 	        this.y = source["y"];
 	    }
 	}
+	export class PermissionGrant {
+	    pluginId: string;
+	    permission: string;
+	    granted: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new PermissionGrant(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.pluginId = source["pluginId"];
+	        this.permission = source["permission"];
+	        this.granted = source["granted"];
+	    }
+	}
+	export class PluginEntry {
+	    code: string;
+	    token: string;
+	    icon?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PluginEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.code = source["code"];
+	        this.token = source["token"];
+	        this.icon = source["icon"];
+	    }
+	}
+	export class PluginRecord {
+	    id: string;
+	    manifest: GhostManifest;
+	    root: string;
+	    dir: string;
+	    entryFile: string;
+	    backgroundFile?: string;
+	    iconPath?: string;
+	    valid: boolean;
+	    errors: ValidationIssue[];
+	    warnings: ValidationIssue[];
+	    signature: PluginSignature;
+	    enabled: boolean;
+	    position: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PluginRecord(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.manifest = this.convertValues(source["manifest"], GhostManifest);
+	        this.root = source["root"];
+	        this.dir = source["dir"];
+	        this.entryFile = source["entryFile"];
+	        this.backgroundFile = source["backgroundFile"];
+	        this.iconPath = source["iconPath"];
+	        this.valid = source["valid"];
+	        this.errors = this.convertValues(source["errors"], ValidationIssue);
+	        this.warnings = this.convertValues(source["warnings"], ValidationIssue);
+	        this.signature = this.convertValues(source["signature"], PluginSignature);
+	        this.enabled = source["enabled"];
+	        this.position = source["position"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PluginSettings {
+	    pluginId: string;
+	    fields: SettingField[];
+	    values: Record<string, any>;
+	
+	    static createFrom(source: any = {}) {
+	        return new PluginSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.pluginId = source["pluginId"];
+	        this.fields = this.convertValues(source["fields"], SettingField);
+	        this.values = source["values"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PluginSignature {
+	    status: string;
+	    publisher?: string;
+	    message?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PluginSignature(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.status = source["status"];
+	        this.publisher = source["publisher"];
+	        this.message = source["message"];
+	    }
+	}
+	export class PluginUpdate {
+	    id: string;
+	    name: string;
+	    installedVersion: string;
+	    latestVersion: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PluginUpdate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.installedVersion = source["installedVersion"];
+	        this.latestVersion = source["latestVersion"];
+	    }
+	}
 	export class PluginValidationResult {
 	    pluginPath: string;
 	    isValid: boolean;
@@ -59,8 +515,12 @@ export namespace main {
 	    hasContent: boolean;
 	    hasBackground: boolean;
 	    hasIcon: boolean;
-	    errors: string[];
+	    errors: ValidationIssue[];
+	    warnings: ValidationIssue[];
 	    manifest?: number[];
+	    signature: PluginSignature;
+	    shadowedBy?: string;
+	    files?: string[];
 	
 	    static createFrom(source: any = {}) {
 	        return new PluginValidationResult(source);
@@ -74,8 +534,106 @@ export namespace main {
 	        this.hasContent = source["hasContent"];
 	        this.hasBackground = source["hasBackground"];
 	        this.hasIcon = source["hasIcon"];
-	        this.errors = source["errors"];
+	        this.errors = this.convertValues(source["errors"], ValidationIssue);
+	        this.warnings = this.convertValues(source["warnings"], ValidationIssue);
 	        this.manifest = source["manifest"];
+	        this.signature = this.convertValues(source["signature"], PluginSignature);
+	        this.shadowedBy = source["shadowedBy"];
+	        this.files = source["files"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SettingField {
+	    key: string;
+	    type: string;
+	    title?: string;
+	    description?: string;
+	    default?: any;
+	    enum?: any[];
+	    minimum?: number;
+	    maximum?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SettingField(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = source["key"];
+	        this.type = source["type"];
+	        this.title = source["title"];
+	        this.description = source["description"];
+	        this.default = source["default"];
+	        this.enum = source["enum"];
+	        this.minimum = source["minimum"];
+	        this.maximum = source["maximum"];
+	    }
+	}
+	export class StorePlugin {
+	    id: string;
+	    name: string;
+	    version: string;
+	    description: string;
+	    author: string;
+	    icon?: string;
+	    tags?: string[];
+	    engines?: Record<string, string>;
+	    downloadUrl: string;
+	    sha256: string;
+	    size?: number;
+	    installedVersion?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new StorePlugin(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.version = source["version"];
+	        this.description = source["description"];
+	        this.author = source["author"];
+	        this.icon = source["icon"];
+	        this.tags = source["tags"];
+	        this.engines = source["engines"];
+	        this.downloadUrl = source["downloadUrl"];
+	        this.sha256 = source["sha256"];
+	        this.size = source["size"];
+	        this.installedVersion = source["installedVersion"];
+	    }
+	}
+	export class ValidationIssue {
+	    path: string;
+	    code: string;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ValidationIssue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.code = source["code"];
+	        this.message = source["message"];
 	    }
 	}
 
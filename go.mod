@@ -4,7 +4,10 @@ go 1.23
 
 toolchain go1.23.6
 
-require github.com/wailsapp/wails/v2 v2.9.2
+require (
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/wailsapp/wails/v2 v2.9.2
+)
 
 require (
 	github.com/bep/debounce v1.2.1 // indirect
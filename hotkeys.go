@@ -0,0 +1,350 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ホットキー設定ファイル (設定ディレクトリ内)
+const hotkeyConfigFile = "hotkeys.json"
+
+// errHotkeyInUse は同じ組み合わせが他のアプリケーションに登録済みの場合のエラー
+var errHotkeyInUse = errors.New("hotkey is already in use by another application")
+
+// HotkeyModifiers はホットキーの修飾キーの組み合わせ
+type HotkeyModifiers uint8
+
+const (
+	ModControl HotkeyModifiers = 1 << iota
+	ModOption
+	ModShift
+	ModCommand
+)
+
+// 修飾キー名 (表示順)
+var hotkeyModifierNames = []struct {
+	mod  HotkeyModifiers
+	name string
+}{
+	{ModControl, "Control"},
+	{ModOption, "Option"},
+	{ModShift, "Shift"},
+	{ModCommand, "Command"},
+}
+
+// 設定ファイルで使える修飾キー名 (小文字)
+var hotkeyModifierAliases = map[string]HotkeyModifiers{
+	"control": ModControl,
+	"ctrl":    ModControl,
+	"option":  ModOption,
+	"opt":     ModOption,
+	"alt":     ModOption,
+	"shift":   ModShift,
+	"command": ModCommand,
+	"cmd":     ModCommand,
+	"super":   ModCommand,
+	"meta":    ModCommand,
+}
+
+// parseHotkeyModifiers は修飾キー名の一覧を HotkeyModifiers に変換する
+func parseHotkeyModifiers(names []string) (HotkeyModifiers, error) {
+	var mods HotkeyModifiers
+	for _, name := range names {
+		mod, ok := hotkeyModifierAliases[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return 0, fmt.Errorf("unknown modifier %q", name)
+		}
+		mods |= mod
+	}
+	return mods, nil
+}
+
+// modifierNames は修飾キーの表示名を表示順に返す
+func modifierNames(mods HotkeyModifiers) []string {
+	var names []string
+	for _, m := range hotkeyModifierNames {
+		if mods&m.mod != 0 {
+			names = append(names, m.name)
+		}
+	}
+	return names
+}
+
+// formatHotkey は "Option+1" の形式でホットキーを表す
+func formatHotkey(mods HotkeyModifiers, key string) string {
+	return strings.Join(append(modifierNames(mods), key), "+")
+}
+
+// HotkeyBinding は修飾キー + キーと、押されたときに送る shortcut-event の対応
+type HotkeyBinding struct {
+	Modifiers []string `json:"modifiers"`
+	Key       string   `json:"key"`
+	Event     string   `json:"event"`
+}
+
+// ネイティブ層からの入力を溜めておけるホットキーの数
+const hotkeyQueueSize = 64
+
+// HotkeyPress はネイティブ層から届いたホットキーの入力
+type HotkeyPress struct {
+	ID        int
+	PressedAt time.Time
+}
+
+// hotkeyConfig は hotkeys.json の内容
+type hotkeyConfig struct {
+	Hotkeys []HotkeyBinding `json:"hotkeys"`
+}
+
+// HotkeyStatus はホットキーの登録結果
+// 設定ファイルの割り当ては Event、プラグインのショートカットは PluginID を持つ
+type HotkeyStatus struct {
+	ID         int      `json:"id"`
+	Hotkey     string   `json:"hotkey"`
+	Modifiers  []string `json:"modifiers"`
+	Key        string   `json:"key"`
+	Event      string   `json:"event,omitempty"`
+	PluginID   string   `json:"pluginId,omitempty"`
+	Registered bool     `json:"registered"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// defaultHotkeyBindings は設定ファイルがない場合の割り当て (従来の Option+1..4)
+func defaultHotkeyBindings() []HotkeyBinding {
+	return []HotkeyBinding{
+		{Modifiers: []string{"option"}, Key: "1", Event: string(ShortcutSC1)},
+		{Modifiers: []string{"option"}, Key: "2", Event: string(ShortcutSC2)},
+		{Modifiers: []string{"option"}, Key: "3", Event: string(ShortcutSC3)},
+		{Modifiers: []string{"option"}, Key: "4", Event: string(ShortcutSC4)},
+	}
+}
+
+// loadHotkeyBindings は設定ファイルからホットキーの割り当てを読み込む
+// ファイルがない場合は既定の割り当てを返す
+func loadHotkeyBindings() ([]HotkeyBinding, error) {
+	var config hotkeyConfig
+	found, err := readConfigFile(hotkeyConfigFile, &config)
+	if err != nil {
+		return defaultHotkeyBindings(), err
+	}
+	if !found {
+		return defaultHotkeyBindings(), nil
+	}
+	return config.Hotkeys, nil
+}
+
+// parseShortcut はマニフェストの shortcut ("Alt+1" の形式) を修飾キーとキー名に分解する
+func parseShortcut(shortcut string) (HotkeyModifiers, string, error) {
+	parts := strings.Split(shortcut, "+")
+	key := strings.ToLower(strings.TrimSpace(parts[len(parts)-1]))
+	if key == "" {
+		return 0, "", fmt.Errorf("invalid shortcut %q: key is empty", shortcut)
+	}
+
+	mods, err := parseHotkeyModifiers(parts[:len(parts)-1])
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid shortcut %q: %w", shortcut, err)
+	}
+	if mods == 0 {
+		return 0, "", fmt.Errorf("invalid shortcut %q: at least one modifier is required", shortcut)
+	}
+	return mods, key, nil
+}
+
+// PluginShortcut はマニフェストで宣言されたプラグインのショートカット
+type PluginShortcut struct {
+	PluginID string
+	Shortcut string
+}
+
+// プラグインのホットキーIDの開始値 (設定ファイルの割り当てと重ならないようにする)
+const pluginHotkeyIDBase = 1000
+
+// hotkeyCombo はホットキーの重複検出に使うキー
+type hotkeyCombo struct {
+	mods HotkeyModifiers
+	key  string
+}
+
+// hotkeyEntry は登録を試みたホットキー
+type hotkeyEntry struct {
+	combo  hotkeyCombo
+	status HotkeyStatus
+}
+
+// HotkeyRegistry はホットキーIDと送信するイベントの対応を管理し、
+// Platform を通してグローバルホットキーを登録する
+// 設定ファイルの割り当てがプラグインのショートカットより優先される
+type HotkeyRegistry struct {
+	mu       sync.RWMutex
+	platform HotkeyController
+
+	global  []hotkeyEntry
+	plugins []hotkeyEntry
+
+	// 設定ファイルを読み直したときに登録し直すためのプラグインのショートカット
+	pluginShortcuts []PluginShortcut
+}
+
+// NewHotkeyRegistry は何も登録されていない HotkeyRegistry を生成する
+func NewHotkeyRegistry(platform HotkeyController) *HotkeyRegistry {
+	return &HotkeyRegistry{platform: platform}
+}
+
+// Apply は登録済みのホットキーをすべて解除してから bindings を登録し、各割り当ての結果を返す
+// プラグインのショートカットも登録し直される
+func (r *HotkeyRegistry) Apply(bindings []HotkeyBinding) []HotkeyStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.unregisterLocked(r.plugins)
+	r.unregisterLocked(r.global)
+	r.plugins = nil
+	r.global = make([]hotkeyEntry, 0, len(bindings))
+
+	seen := make(map[hotkeyCombo]string)
+	for i, binding := range bindings {
+		// IDはネイティブ層に渡すため 1 から振る (0 は「押されていない」)
+		id := i + 1
+		key := strings.ToLower(strings.TrimSpace(binding.Key))
+		entry := hotkeyEntry{status: HotkeyStatus{
+			ID:        id,
+			Hotkey:    binding.Key,
+			Modifiers: binding.Modifiers,
+			Key:       binding.Key,
+			Event:     binding.Event,
+		}}
+
+		mods, err := parseHotkeyModifiers(binding.Modifiers)
+		switch {
+		case err != nil:
+		case key == "":
+			err = errors.New("key is empty")
+		case binding.Event == "":
+			err = errors.New("event is empty")
+		case mods == 0:
+			err = errors.New("at least one modifier is required")
+		}
+		if err == nil {
+			entry.combo = hotkeyCombo{mods: mods, key: key}
+			err = r.registerLocked(&entry, seen, fmt.Sprintf("hotkey #%d", id))
+		}
+		if err != nil {
+			entry.status.Error = err.Error()
+		}
+		r.global = append(r.global, entry)
+	}
+
+	r.applyPluginsLocked(seen)
+	return r.statusesLocked(r.global)
+}
+
+// ApplyPluginShortcuts はプラグインのショートカットを登録し直し、各プラグインの結果を返す
+// 同じ組み合わせを宣言したプラグインが複数ある場合は先に宣言したものが優先される
+func (r *HotkeyRegistry) ApplyPluginShortcuts(shortcuts []PluginShortcut) []HotkeyStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.unregisterLocked(r.plugins)
+	r.pluginShortcuts = append([]PluginShortcut(nil), shortcuts...)
+
+	seen := make(map[hotkeyCombo]string)
+	for _, entry := range r.global {
+		if entry.status.Registered {
+			seen[entry.combo] = fmt.Sprintf("hotkey #%d", entry.status.ID)
+		}
+	}
+	r.applyPluginsLocked(seen)
+	return r.statusesLocked(r.plugins)
+}
+
+func (r *HotkeyRegistry) applyPluginsLocked(seen map[hotkeyCombo]string) {
+	r.plugins = make([]hotkeyEntry, 0, len(r.pluginShortcuts))
+	for i, shortcut := range r.pluginShortcuts {
+		entry := hotkeyEntry{status: HotkeyStatus{
+			ID:       pluginHotkeyIDBase + i + 1,
+			Hotkey:   shortcut.Shortcut,
+			PluginID: shortcut.PluginID,
+		}}
+
+		mods, key, err := parseShortcut(shortcut.Shortcut)
+		if err == nil {
+			entry.combo = hotkeyCombo{mods: mods, key: key}
+			entry.status.Modifiers = modifierNames(mods)
+			entry.status.Key = key
+			err = r.registerLocked(&entry, seen, fmt.Sprintf("plugin %q", shortcut.PluginID))
+		}
+		if err != nil {
+			entry.status.Error = err.Error()
+		}
+		r.plugins = append(r.plugins, entry)
+	}
+}
+
+// registerLocked は重複を確認してからホットキーを登録する
+// seen には登録済みの組み合わせと、その持ち主の説明が入る
+func (r *HotkeyRegistry) registerLocked(entry *hotkeyEntry, seen map[hotkeyCombo]string, owner string) error {
+	entry.status.Hotkey = formatHotkey(entry.combo.mods, entry.combo.key)
+	if other, ok := seen[entry.combo]; ok {
+		return fmt.Errorf("conflicts with %s", other)
+	}
+	if err := r.platform.RegisterHotKey(entry.status.ID, entry.combo.mods, entry.combo.key); err != nil {
+		return err
+	}
+	seen[entry.combo] = owner
+	entry.status.Registered = true
+	return nil
+}
+
+// Lookup はホットキーIDの登録内容を返す
+func (r *HotkeyRegistry) Lookup(id int) (HotkeyStatus, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := r.global
+	if id > pluginHotkeyIDBase {
+		entries = r.plugins
+	}
+	for _, entry := range entries {
+		if entry.status.ID == id && entry.status.Registered {
+			return entry.status, true
+		}
+	}
+	return HotkeyStatus{}, false
+}
+
+// Statuses は設定ファイルの割り当てとプラグインのショートカットの登録結果を返す
+func (r *HotkeyRegistry) Statuses() []HotkeyStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append(r.statusesLocked(r.global), r.statusesLocked(r.plugins)...)
+}
+
+func (r *HotkeyRegistry) statusesLocked(entries []hotkeyEntry) []HotkeyStatus {
+	statuses := make([]HotkeyStatus, len(entries))
+	for i, entry := range entries {
+		statuses[i] = entry.status
+	}
+	return statuses
+}
+
+// Unregister は登録済みのホットキーをすべて解除する
+func (r *HotkeyRegistry) Unregister() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unregisterLocked(r.plugins)
+	r.unregisterLocked(r.global)
+	r.plugins = nil
+	r.global = nil
+}
+
+func (r *HotkeyRegistry) unregisterLocked(entries []hotkeyEntry) {
+	for _, entry := range entries {
+		if entry.status.Registered {
+			r.platform.UnregisterHotKey(entry.status.ID)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestHotkeyRegistryApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		bindings []HotkeyBinding
+		blocked  []string
+		plugins  []PluginShortcut
+		// 登録されたホットキーとその持ち主 (イベント名またはプラグインID)
+		want map[string]string
+		// 登録に失敗した割り当てのIDとエラー
+		failed map[int]string
+	}{
+		{
+			name:     "defaults",
+			bindings: defaultHotkeyBindings(),
+			want: map[string]string{
+				"Option+1": "pushSC1", "Option+2": "pushSC2", "Option+3": "pushSC3", "Option+4": "pushSC4",
+			},
+		},
+		{
+			name: "invalid bindings",
+			bindings: []HotkeyBinding{
+				{Modifiers: []string{"hyper"}, Key: "1", Event: "a"},
+				{Modifiers: []string{"ctrl"}, Key: " ", Event: "b"},
+				{Modifiers: []string{"ctrl"}, Key: "k", Event: ""},
+				{Key: "k", Event: "d"},
+				{Modifiers: []string{"Ctrl", "alt"}, Key: "K", Event: "e"},
+			},
+			want: map[string]string{"Control+Option+k": "e"},
+			failed: map[int]string{
+				1: `unknown modifier "hyper"`,
+				2: "key is empty",
+				3: "event is empty",
+				4: "at least one modifier is required",
+			},
+		},
+		{
+			name: "conflicting bindings",
+			bindings: []HotkeyBinding{
+				{Modifiers: []string{"alt"}, Key: "1", Event: "first"},
+				{Modifiers: []string{"option"}, Key: "1", Event: "second"},
+			},
+			want:   map[string]string{"Option+1": "first"},
+			failed: map[int]string{2: "conflicts with hotkey #1"},
+		},
+		{
+			name: "blocked by another application",
+			bindings: []HotkeyBinding{
+				{Modifiers: []string{"cmd"}, Key: "space", Event: "search"},
+				{Modifiers: []string{"cmd", "shift"}, Key: "space", Event: "other"},
+			},
+			blocked: []string{"Command+space"},
+			want:    map[string]string{"Shift+Command+space": "other"},
+			failed:  map[int]string{1: errHotkeyInUse.Error()},
+		},
+		{
+			name:     "config takes precedence over plugins",
+			bindings: defaultHotkeyBindings()[:1],
+			plugins: []PluginShortcut{
+				{PluginID: "clock", Shortcut: "Alt+1"},
+				{PluginID: "memo", Shortcut: "Ctrl+M"},
+				{PluginID: "notes", Shortcut: "Control+m"},
+				{PluginID: "broken", Shortcut: "M"},
+			},
+			want: map[string]string{"Option+1": "pushSC1", "Control+m": "memo"},
+			failed: map[int]string{
+				pluginHotkeyIDBase + 1: "conflicts with hotkey #1",
+				pluginHotkeyIDBase + 3: `conflicts with plugin "memo"`,
+				pluginHotkeyIDBase + 4: `invalid shortcut "M": at least one modifier is required`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			platform := NewFakePlatform()
+			for _, hotkey := range tt.blocked {
+				mods, key, err := parseShortcut(hotkey)
+				if err != nil {
+					t.Fatal(err)
+				}
+				platform.BlockHotKey(mods, key)
+			}
+
+			registry := NewHotkeyRegistry(platform)
+			registry.ApplyPluginShortcuts(tt.plugins)
+			registry.Apply(tt.bindings)
+
+			registered := platform.RegisteredHotKeys()
+			if len(registered) != len(tt.want) {
+				t.Errorf("registered = %v, want %v", registered, tt.want)
+			}
+			for id, hotkey := range registered {
+				status, ok := registry.Lookup(id)
+				if !ok {
+					t.Errorf("Lookup(%d) for %s failed", id, hotkey)
+					continue
+				}
+				owner := status.Event + status.PluginID
+				if want := tt.want[hotkey]; owner != want {
+					t.Errorf("%s belongs to %q, want %q", hotkey, owner, want)
+				}
+			}
+
+			for _, status := range registry.Statuses() {
+				want, failed := tt.failed[status.ID]
+				if status.Registered == failed {
+					t.Errorf("hotkey #%d (%s) registered = %v, want %v", status.ID, status.Hotkey, status.Registered, !failed)
+				}
+				if failed && status.Error != want {
+					t.Errorf("hotkey #%d error = %q, want %q", status.ID, status.Error, want)
+				}
+			}
+		})
+	}
+}
+
+func TestHotkeyRegistryUnregister(t *testing.T) {
+	platform := NewFakePlatform()
+	registry := NewHotkeyRegistry(platform)
+	registry.ApplyPluginShortcuts([]PluginShortcut{{PluginID: "clock", Shortcut: "Ctrl+T"}})
+	registry.Apply(defaultHotkeyBindings())
+
+	// 割り当てを減らすと以前のホットキーは解除される
+	registry.Apply(defaultHotkeyBindings()[:2])
+	if got := len(platform.RegisteredHotKeys()); got != 3 {
+		t.Errorf("registered %d hotkeys after reapply, want 3", got)
+	}
+
+	registry.Unregister()
+	if hotkeys := platform.RegisteredHotKeys(); len(hotkeys) != 0 {
+		t.Errorf("hotkeys still registered: %v", hotkeys)
+	}
+	if _, ok := registry.Lookup(1); ok {
+		t.Error("Lookup succeeded after Unregister")
+	}
+}
+
+// TestHotkeyRegistryConcurrent は go test -race で登録し直しとホットキーの入力の競合を検出する
+func TestHotkeyRegistryConcurrent(t *testing.T) {
+	platform := NewFakePlatform()
+	registry := NewHotkeyRegistry(platform)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				registry.Apply(defaultHotkeyBindings()[:1+j%4])
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				registry.ApplyPluginShortcuts([]PluginShortcut{{PluginID: "p", Shortcut: fmt.Sprintf("Ctrl+%d", (i+j)%10)}})
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				registry.Lookup(1 + j%4)
+				registry.Lookup(pluginHotkeyIDBase + 1)
+				registry.Statuses()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 最後に登録した状態と Platform の登録が一致する
+	registered := platform.RegisteredHotKeys()
+	for _, status := range registry.Statuses() {
+		if status.Registered && registered[status.ID] != status.Hotkey {
+			t.Errorf("hotkey #%d is %q in the platform, want %q", status.ID, registered[status.ID], status.Hotkey)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// プラグインのアーカイブを展開するときの上限 (展開後の合計サイズとファイル数)
+const (
+	maxPluginArchiveSize  = 256 << 20
+	maxPluginArchiveFiles = 10000
+)
+
+// InstalledPlugin はユーザーのプラグインディレクトリにインストールされたプラグイン
+type InstalledPlugin struct {
+	Plugin PluginRecord `json:"plugin"`
+	// RollbackPlugin で戻せる以前の版 (なければ空)
+	PreviousVersion string `json:"previousVersion,omitempty"`
+}
+
+// PluginInstaller はプラグインのアーカイブ (manifest.json を直下に置いたzip) をユーザーのプラグインディレクトリに展開する
+// 置き換えたりアンインストールしたりしたプラグインは、プラグインごとに1つ前の版を退避しておく
+type PluginInstaller struct {
+	mu sync.Mutex
+	// インストール先のプラグインディレクトリと、以前の版の退避先を返す
+	dirs func() (pluginDir, backupDir string, err error)
+	// アーカイブを展開するときの上限
+	maxSize  int64
+	maxFiles int
+}
+
+// NewPluginInstaller は dirs のディレクトリにインストールし、展開後の合計が maxSize バイトまたは maxFiles 個を超えるアーカイブを拒否する PluginInstaller を生成する
+func NewPluginInstaller(dirs func() (pluginDir, backupDir string, err error), maxSize int64, maxFiles int) *PluginInstaller {
+	return &PluginInstaller{dirs: dirs, maxSize: maxSize, maxFiles: maxFiles}
+}
+
+// userPluginDirs はユーザーのプラグインディレクトリ (~/.config/ghostcursor/plugins) と退避先を返す
+func userPluginDirs() (pluginDir, backupDir string, err error) {
+	if pluginDir, err = configPath("plugins"); err != nil {
+		return "", "", err
+	}
+	if backupDir, err = configPath("plugin-backups"); err != nil {
+		return "", "", err
+	}
+	return pluginDir, backupDir, nil
+}
+
+// PluginDir はインストール先のプラグインディレクトリを返す
+func (i *PluginInstaller) PluginDir() (string, error) {
+	pluginDir, _, err := i.dirs()
+	return pluginDir, err
+}
+
+// Install はアーカイブを展開して検証し、問題がなければ同じIDのプラグインと置き換えて、インストールしたプラグインのIDを返す
+// expectedID が空でなければ、マニフェストの id が一致しないアーカイブは拒否する
+// 展開と検証はプラグインディレクトリ内の隠しディレクトリで行い、最後に名前を変更して有効にする
+func (i *PluginInstaller) Install(archivePath, expectedID string) (string, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	pluginDir, backupDir, err := i.dirs()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create plugin directory %s: %w", pluginDir, err)
+	}
+
+	staging, err := os.MkdirTemp(pluginDir, ".install-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	// 成功時は名前を変更済みのため何もしない
+	defer os.RemoveAll(staging)
+
+	if err := extractPluginArchive(archivePath, staging, i.maxSize, i.maxFiles); err != nil {
+		return "", err
+	}
+	// 署名の扱いが block なら、署名を確認できないプラグインはここで拒否する
+	trust, err := loadPluginTrust()
+	if err != nil {
+		fmt.Printf("Error loading plugin trust settings: %v\n", err)
+	}
+	validation := validatePlugin(staging)
+	applyPluginTrust(&validation, trust)
+	if !validation.IsValid {
+		return "", fmt.Errorf("invalid plugin archive %s: %s", archivePath, joinIssues(validation.Errors))
+	}
+	id := validation.manifest.ID
+	if expectedID != "" && id != expectedID {
+		return "", fmt.Errorf("plugin archive %s contains plugin %q, expected %q", archivePath, id, expectedID)
+	}
+
+	// 以前の版を退避してから入れ替え、失敗したら元に戻す
+	current := findInstalledPlugin(pluginDir, id)
+	if current != "" {
+		if err := moveToBackup(current, backupDir, id); err != nil {
+			return "", err
+		}
+	}
+	target := filepath.Join(pluginDir, id)
+	if err := os.Rename(staging, target); err != nil {
+		if current != "" {
+			os.Rename(filepath.Join(backupDir, id), current)
+		}
+		return "", fmt.Errorf("failed to install plugin %s: %w", id, err)
+	}
+
+	fmt.Printf("Installed plugin %s (%s) to %s\n", id, validation.manifest.Version, target)
+	return id, nil
+}
+
+// Uninstall は id のプラグインをプラグインディレクトリから退避先に移す
+func (i *PluginInstaller) Uninstall(id string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	pluginDir, backupDir, err := i.dirs()
+	if err != nil {
+		return err
+	}
+	current := findInstalledPlugin(pluginDir, id)
+	if current == "" {
+		return fmt.Errorf("plugin %q is not installed in %s", id, pluginDir)
+	}
+	if err := moveToBackup(current, backupDir, id); err != nil {
+		return err
+	}
+
+	fmt.Printf("Uninstalled plugin %s from %s\n", id, current)
+	return nil
+}
+
+// Rollback は id のプラグインを退避しておいた以前の版に戻す
+// 現在の版があれば、それが新たに退避される
+func (i *PluginInstaller) Rollback(id string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	pluginDir, backupDir, err := i.dirs()
+	if err != nil {
+		return err
+	}
+	backup := filepath.Join(backupDir, id)
+	if info, err := os.Stat(backup); err != nil || !info.IsDir() {
+		return fmt.Errorf("no previous version of plugin %q to roll back to", id)
+	}
+
+	current := findInstalledPlugin(pluginDir, id)
+	if current == "" {
+		if err := os.MkdirAll(pluginDir, 0755); err != nil {
+			return fmt.Errorf("failed to create plugin directory %s: %w", pluginDir, err)
+		}
+		if err := os.Rename(backup, filepath.Join(pluginDir, id)); err != nil {
+			return fmt.Errorf("failed to restore plugin %s: %w", id, err)
+		}
+		fmt.Printf("Restored plugin %s from %s\n", id, backup)
+		return nil
+	}
+
+	// 現在の版と以前の版を入れ替える
+	swap, err := os.MkdirTemp(backupDir, ".rollback-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(swap)
+	if err := os.Rename(current, filepath.Join(swap, id)); err != nil {
+		return fmt.Errorf("failed to move %s: %w", current, err)
+	}
+	if err := os.Rename(backup, filepath.Join(pluginDir, id)); err != nil {
+		os.Rename(filepath.Join(swap, id), current)
+		return fmt.Errorf("failed to restore plugin %s: %w", id, err)
+	}
+	if err := os.Rename(filepath.Join(swap, id), backup); err != nil {
+		return fmt.Errorf("failed to keep the replaced version of plugin %s: %w", id, err)
+	}
+
+	fmt.Printf("Rolled back plugin %s\n", id)
+	return nil
+}
+
+// PreviousVersion は id のプラグインの退避しておいた版のバージョンを返す (なければ空)
+func (i *PluginInstaller) PreviousVersion(id string) string {
+	_, backupDir, err := i.dirs()
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(backupDir, id, "manifest.json"))
+	if err != nil {
+		return ""
+	}
+	manifest, _, _ := validateManifest(data)
+	return manifest.Version
+}
+
+// moveToBackup は dir を backupDir/id に移す (以前に退避したものは削除する)
+func moveToBackup(dir, backupDir, id string) error {
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory %s: %w", backupDir, err)
+	}
+	backup := filepath.Join(backupDir, id)
+	if err := os.RemoveAll(backup); err != nil {
+		return fmt.Errorf("failed to remove old backup %s: %w", backup, err)
+	}
+	if err := os.Rename(dir, backup); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", dir, err)
+	}
+	return nil
+}
+
+// findInstalledPlugin は pluginDir の直下からマニフェストの id が一致するディレクトリを探す (なければ空)
+// ディレクトリ名が id と同じものを優先する
+func findInstalledPlugin(pluginDir, id string) string {
+	if readPluginID(filepath.Join(pluginDir, id)) == id {
+		return filepath.Join(pluginDir, id)
+	}
+
+	entries, err := os.ReadDir(pluginDir)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		dir := filepath.Join(pluginDir, entry.Name())
+		if readPluginID(dir) == id {
+			return dir
+		}
+	}
+	return ""
+}
+
+// readPluginID は dir の manifest.json の id を返す (読めなければ空)
+func readPluginID(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return ""
+	}
+	manifest, _, _ := validateManifest(data)
+	return manifest.ID
+}
+
+// extractPluginArchive はプラグインのアーカイブを dest に展開する
+// dest の外を指すパス、シンボリックリンク、直下に manifest.json のないアーカイブ、上限を超えるアーカイブは拒否する
+func extractPluginArchive(archivePath, dest string, maxSize int64, maxFiles int) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open plugin archive %s: %w", archivePath, err)
+	}
+	defer r.Close()
+
+	if len(r.File) > maxFiles {
+		return fmt.Errorf("plugin archive %s has too many entries (%d, max %d)", archivePath, len(r.File), maxFiles)
+	}
+
+	hasManifest := false
+	for _, f := range r.File {
+		name, err := pluginArchiveEntryPath(f)
+		if err != nil {
+			return fmt.Errorf("invalid plugin archive %s: %w", archivePath, err)
+		}
+		if name == "manifest.json" && !f.FileInfo().IsDir() {
+			hasManifest = true
+		}
+	}
+	if !hasManifest {
+		return fmt.Errorf("invalid plugin archive %s: manifest.json must be at the root of the archive", archivePath)
+	}
+
+	remaining := maxSize
+	for _, f := range r.File {
+		name, _ := pluginArchiveEntryPath(f)
+		path := filepath.Join(dest, name)
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return fmt.Errorf("failed to create %s: %w", path, err)
+			}
+			continue
+		}
+
+		written, err := extractPluginArchiveFile(f, path, remaining)
+		if err != nil {
+			return fmt.Errorf("failed to extract %s from %s: %w", f.Name, archivePath, err)
+		}
+		remaining -= written
+	}
+	return nil
+}
+
+// pluginArchiveEntryPath はアーカイブ内のエントリの展開先の相対パスを返す
+func pluginArchiveEntryPath(f *zip.File) (string, error) {
+	if strings.Contains(f.Name, `\`) {
+		return "", fmt.Errorf("entry %q contains a backslash", f.Name)
+	}
+	name := filepath.FromSlash(strings.TrimSuffix(f.Name, "/"))
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("entry %q points outside the plugin directory", f.Name)
+	}
+	if mode := f.Mode(); mode&fs.ModeType != 0 && !mode.IsDir() {
+		return "", fmt.Errorf("entry %q is not a regular file or directory", f.Name)
+	}
+	return name, nil
+}
+
+// extractPluginArchiveFile は f を path に書き出し、書き込んだバイト数を返す
+// limit を超える場合はエラーにする (ヘッダーのサイズは信用しない)
+func extractPluginArchiveFile(f *zip.File, path string, limit int64) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, err
+	}
+	src, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(dst, io.LimitReader(src, limit+1))
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return written, err
+	}
+	if written > limit {
+		return written, errors.New("plugin archive is too large when extracted")
+	}
+	return written, nil
+}
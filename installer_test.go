@@ -0,0 +1,270 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testArchiveEntry はテスト用のアーカイブの1エントリ (mode が 0 なら通常のファイル)
+type testArchiveEntry struct {
+	name string
+	body string
+	mode fs.FileMode
+}
+
+func testManifestEntry(id, version string) testArchiveEntry {
+	return testArchiveEntry{name: "manifest.json", body: `{"id": "` + id + `", "name": "` + id + `", "version": "` + version + `"}`}
+}
+
+// writeTestArchive は entries のアーカイブを一時ディレクトリに書き、そのパスを返す
+func writeTestArchive(t *testing.T, entries ...testArchiveEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+		if entry.mode != 0 {
+			header.SetMode(entry.mode)
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entry.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return writeTestArchiveBytes(t, buf.Bytes())
+}
+
+func writeTestArchiveBytes(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "plugin.zip")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newTestInstaller は一時ディレクトリにインストールする PluginInstaller を生成し、そのディレクトリを返す
+func newTestInstaller(t *testing.T, maxSize int64, maxFiles int) (*PluginInstaller, string) {
+	t.Helper()
+	t.Setenv(configDirEnv, t.TempDir())
+	root := t.TempDir()
+	dirs := func() (string, string, error) {
+		return filepath.Join(root, "plugins"), filepath.Join(root, "backups"), nil
+	}
+	return NewPluginInstaller(dirs, maxSize, maxFiles), root
+}
+
+// installedVersion は dir のプラグインのバージョンを返す (なければ空)
+func installedVersion(t *testing.T, dir string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return ""
+	}
+	manifest, _, _ := validateManifest(data)
+	return manifest.Version
+}
+
+func TestPluginInstallerRejectsArchives(t *testing.T) {
+	index := testArchiveEntry{name: "index.js", body: "export default {};"}
+	valid := writeTestArchive(t, testManifestEntry("clock", "1.0.0"), index)
+	validData, err := os.ReadFile(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		archive string
+		wantErr string
+	}{
+		{
+			name:    "parent traversal",
+			archive: writeTestArchive(t, testManifestEntry("clock", "1.0.0"), testArchiveEntry{name: "../evil.js", body: "x"}),
+			wantErr: "outside the plugin directory",
+		},
+		{
+			name:    "nested traversal",
+			archive: writeTestArchive(t, testManifestEntry("clock", "1.0.0"), testArchiveEntry{name: "lib/../../../evil.js", body: "x"}),
+			wantErr: "outside the plugin directory",
+		},
+		{
+			name:    "absolute path",
+			archive: writeTestArchive(t, testManifestEntry("clock", "1.0.0"), testArchiveEntry{name: "/tmp/evil.js", body: "x"}),
+			wantErr: "outside the plugin directory",
+		},
+		{
+			name:    "backslash",
+			archive: writeTestArchive(t, testManifestEntry("clock", "1.0.0"), testArchiveEntry{name: `..\evil.js`, body: "x"}),
+			wantErr: "backslash",
+		},
+		{
+			name:    "symlink",
+			archive: writeTestArchive(t, testManifestEntry("clock", "1.0.0"), testArchiveEntry{name: "index.js", body: "/etc/passwd", mode: fs.ModeSymlink | 0o777}),
+			wantErr: "not a regular file or directory",
+		},
+		{
+			name:    "too many entries",
+			archive: writeTestArchive(t, testManifestEntry("clock", "1.0.0"), index, testArchiveEntry{name: "a.js"}, testArchiveEntry{name: "b.js"}),
+			wantErr: "too many entries",
+		},
+		{
+			name:    "too large when extracted",
+			archive: writeTestArchive(t, testManifestEntry("clock", "1.0.0"), testArchiveEntry{name: "big.js", body: strings.Repeat("a", 4096)}),
+			wantErr: "too large",
+		},
+		{
+			name:    "manifest not at the root",
+			archive: writeTestArchive(t, testArchiveEntry{name: "clock/manifest.json", body: `{"id": "clock", "name": "clock", "version": "1.0.0"}`}, index),
+			wantErr: "manifest.json must be at the root",
+		},
+		{
+			name:    "corrupt archive",
+			archive: writeTestArchiveBytes(t, []byte("this is not a zip file")),
+			wantErr: "failed to open plugin archive",
+		},
+		{
+			name:    "truncated archive",
+			archive: writeTestArchiveBytes(t, validData[:len(validData)/2]),
+			wantErr: "failed to open plugin archive",
+		},
+		{
+			name:    "invalid manifest",
+			archive: writeTestArchive(t, testManifestEntry("clock", "latest"), index),
+			wantErr: "invalid plugin archive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installer, root := newTestInstaller(t, 1024, 3)
+			_, err := installer.Install(tt.archive, "")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Install error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			// 展開途中のファイルも含めて何も残さない
+			filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					t.Errorf("file left after a rejected install: %s", path)
+				}
+				return nil
+			})
+		})
+	}
+
+	// 上限ちょうどのアーカイブはインストールできる
+	installer, root := newTestInstaller(t, 1024, 3)
+	archive := writeTestArchive(t, testManifestEntry("clock", "1.0.0"), index, testArchiveEntry{name: "lib/util.js", body: "export {};"})
+	if id, err := installer.Install(archive, "clock"); err != nil || id != "clock" {
+		t.Fatalf("Install = %q, %v", id, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "plugins", "clock", "lib", "util.js")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPluginInstallerReplaceAndRollback(t *testing.T) {
+	installer, root := newTestInstaller(t, maxPluginArchiveSize, maxPluginArchiveFiles)
+	index := testArchiveEntry{name: "index.js", body: "export default {};"}
+	target := filepath.Join(root, "plugins", "clock")
+	backup := filepath.Join(root, "backups", "clock")
+
+	if _, err := installer.Install(writeTestArchive(t, testManifestEntry("clock", "1.0.0"), index), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := installer.Install(writeTestArchive(t, testManifestEntry("memo", "1.0.0"), index), "clock"); err == nil {
+		t.Fatal("Install accepted an archive with a different id")
+	}
+	if _, err := installer.Install(writeTestArchive(t, testManifestEntry("clock", "2.0.0"), index), "clock"); err != nil {
+		t.Fatal(err)
+	}
+	if got := installedVersion(t, target); got != "2.0.0" {
+		t.Errorf("installed version = %q, want 2.0.0", got)
+	}
+	if got := installer.PreviousVersion("clock"); got != "1.0.0" {
+		t.Errorf("PreviousVersion = %q, want 1.0.0", got)
+	}
+
+	// 正しくないアーカイブでは置き換えず、退避した版もそのまま
+	if _, err := installer.Install(writeTestArchive(t, testManifestEntry("clock", "latest"), index), "clock"); err == nil {
+		t.Fatal("Install accepted an invalid manifest")
+	}
+	if got := installedVersion(t, target); got != "2.0.0" {
+		t.Errorf("installed version after a rejected install = %q, want 2.0.0", got)
+	}
+	if got := installedVersion(t, backup); got != "1.0.0" {
+		t.Errorf("backup version after a rejected install = %q, want 1.0.0", got)
+	}
+
+	// ロールバックすると現在の版と以前の版が入れ替わる
+	if err := installer.Rollback("clock"); err != nil {
+		t.Fatal(err)
+	}
+	if got, prev := installedVersion(t, target), installedVersion(t, backup); got != "1.0.0" || prev != "2.0.0" {
+		t.Errorf("after Rollback: installed %q, backup %q, want 1.0.0 and 2.0.0", got, prev)
+	}
+
+	if err := installer.Uninstall("clock"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("plugin directory remains after Uninstall: %v", err)
+	}
+	if err := installer.Rollback("clock"); err != nil {
+		t.Fatal(err)
+	}
+	if got := installedVersion(t, target); got != "1.0.0" {
+		t.Errorf("restored version = %q, want 1.0.0", got)
+	}
+}
+
+func TestPluginInstallerRestoresBackupOnFailure(t *testing.T) {
+	installer, root := newTestInstaller(t, maxPluginArchiveSize, maxPluginArchiveFiles)
+	pluginDir := filepath.Join(root, "plugins")
+
+	// ディレクトリ名が id と異なるプラグインがあり、id の名前のディレクトリは別のもので使われている
+	current := filepath.Join(pluginDir, "clock-dev")
+	writeTestPlugin(t, current, "clock", "1.0.0")
+	blocker := filepath.Join(pluginDir, "clock")
+	if err := os.MkdirAll(blocker, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(blocker, "notes.txt"), []byte("not a plugin"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 現在の版を退避した後、名前の変更に失敗する
+	archive := writeTestArchive(t, testManifestEntry("clock", "2.0.0"), testArchiveEntry{name: "index.js", body: "export default {};"})
+	if _, err := installer.Install(archive, "clock"); err == nil || !strings.Contains(err.Error(), "failed to install plugin clock") {
+		t.Fatalf("Install error = %v, want a failure to install", err)
+	}
+	if got := installedVersion(t, current); got != "1.0.0" {
+		t.Errorf("version at %s after the failed install = %q, want 1.0.0 restored from the backup", current, got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "backups", "clock")); !os.IsNotExist(err) {
+		t.Errorf("backup remains after it was restored: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(blocker, "notes.txt")); err != nil {
+		t.Errorf("unrelated directory was changed: %v", err)
+	}
+	entries, err := os.ReadDir(pluginDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			t.Errorf("staging directory left behind: %s", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,450 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// キージェスチャー設定ファイル (設定ディレクトリ内)
+const keyGestureConfigFile = "keygestures.json"
+
+// KeyGestureKind はキージェスチャーの種類
+type KeyGestureKind string
+
+const (
+	// KeyGestureTap は同じキーを Count 回続けて押す (2 で二重押し、3 で三重押し)
+	KeyGestureTap KeyGestureKind = "tap"
+	// KeyGestureLongPress はキーを Duration 以上押し続ける
+	KeyGestureLongPress KeyGestureKind = "longPress"
+	// KeyGestureSequence は Keys を順番に押す (例: control, control, k)
+	KeyGestureSequence KeyGestureKind = "sequence"
+)
+
+// 省略時の間隔
+const (
+	defaultKeyGestureInterval  = 500 * time.Millisecond
+	defaultKeyGestureLongPress = 800 * time.Millisecond
+)
+
+// KeyGesture はキー入力のパターンと、検出したときに送る shortcut-event の対応
+type KeyGesture struct {
+	Kind  KeyGestureKind `json:"kind"`
+	Key   string         `json:"key,omitempty"`
+	Count int            `json:"count,omitempty"`
+	Keys  []string       `json:"keys,omitempty"`
+	// 押下の間隔の上限 (tap, sequence)
+	IntervalMs int `json:"intervalMs,omitempty"`
+	// 押し続ける時間 (longPress)
+	DurationMs int    `json:"durationMs,omitempty"`
+	Event      string `json:"event"`
+}
+
+// keyGestureConfig は keygestures.json の内容
+type keyGestureConfig struct {
+	Gestures []KeyGesture `json:"gestures"`
+}
+
+// defaultKeyGestures は設定ファイルがない場合のジェスチャー (従来のShift二重押し)
+func defaultKeyGestures() []KeyGesture {
+	return []KeyGesture{
+		{Kind: KeyGestureTap, Key: "shift", Count: 2, IntervalMs: 500, Event: string(ShortcutSub)},
+	}
+}
+
+// loadKeyGestures は設定ファイルからキージェスチャーを読み込む
+// ファイルがない場合は既定のジェスチャーを返す
+func loadKeyGestures() ([]KeyGesture, error) {
+	var config keyGestureConfig
+	found, err := readConfigFile(keyGestureConfigFile, &config)
+	if err != nil {
+		return defaultKeyGestures(), err
+	}
+	if !found {
+		return defaultKeyGestures(), nil
+	}
+	return config.Gestures, nil
+}
+
+// キー状態の左右の区別をなくした名前
+var keyGestureLogicalNames = map[string]string{
+	"lshift": "shift", "rshift": "shift",
+	"lcontrol": "control", "rcontrol": "control",
+	"loption": "option", "roption": "option",
+	"lcommand": "command", "rcommand": "command",
+}
+
+// 設定ファイルで使えるキー名の別名
+var keyGestureAliases = map[string]string{
+	"ctrl": "control", "alt": "option", "cmd": "command", "super": "command",
+	"lctrl": "lcontrol", "rctrl": "rcontrol", "lalt": "loption", "ralt": "roption",
+	"lcmd": "lcommand", "rcmd": "rcommand",
+	"enter": "return", "esc": "escape", "del": "delete", "backspace": "delete",
+}
+
+// normalizeGestureKey は設定ファイルのキー名を正規化する
+func normalizeGestureKey(key string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if alias, ok := keyGestureAliases[key]; ok {
+		return alias
+	}
+	return key
+}
+
+// gestureKeyMatches は押されたキー (キー状態の名前) がジェスチャーのキーに当てはまるかを返す
+// "shift" は左右どちらのShiftにも当てはまり、"lshift" は左のShiftだけに当てはまる
+func gestureKeyMatches(gestureKey, pressed string) bool {
+	return gestureKey == pressed || gestureKey == keyGestureLogicalNames[pressed]
+}
+
+// validate はジェスチャーを検証し、省略された値を補った正規化済みのコピーを返す
+func (g KeyGesture) validate() (KeyGesture, error) {
+	if g.Event == "" {
+		return g, errors.New("event is empty")
+	}
+	if g.IntervalMs < 0 || g.DurationMs < 0 {
+		return g, errors.New("intervalMs and durationMs must not be negative")
+	}
+	if g.IntervalMs == 0 {
+		g.IntervalMs = int(defaultKeyGestureInterval / time.Millisecond)
+	}
+	if g.DurationMs == 0 {
+		g.DurationMs = int(defaultKeyGestureLongPress / time.Millisecond)
+	}
+
+	switch g.Kind {
+	case KeyGestureTap:
+		if g.Count < 2 {
+			return g, fmt.Errorf("tap count must be at least 2, got %d", g.Count)
+		}
+		fallthrough
+	case KeyGestureLongPress:
+		g.Key = normalizeGestureKey(g.Key)
+		if g.Key == "" {
+			return g, errors.New("key is empty")
+		}
+	case KeyGestureSequence:
+		if len(g.Keys) < 2 {
+			return g, errors.New("sequence needs at least 2 keys")
+		}
+		keys := make([]string, len(g.Keys))
+		for i, key := range g.Keys {
+			keys[i] = normalizeGestureKey(key)
+			if keys[i] == "" {
+				return g, fmt.Errorf("keys[%d] is empty", i)
+			}
+		}
+		g.Keys = keys
+	default:
+		return g, fmt.Errorf("unknown kind %q", g.Kind)
+	}
+	return g, nil
+}
+
+func (g KeyGesture) interval() time.Duration {
+	return time.Duration(g.IntervalMs) * time.Millisecond
+}
+
+func (g KeyGesture) duration() time.Duration {
+	return time.Duration(g.DurationMs) * time.Millisecond
+}
+
+// tapCounter は同じキーの連続した押下を数える
+type tapCounter struct {
+	count int
+	last  time.Time
+	// 回数の多いジェスチャーを待っている間、保留しているジェスチャー
+	pending *KeyGesture
+}
+
+// keyPress は押下の履歴
+type keyPress struct {
+	key string
+	at  time.Time
+	seq int
+}
+
+// heldKey は押され続けているキー
+type heldKey struct {
+	since time.Time
+	// 押している間に他のキーが押された場合は長押しとみなさない
+	interrupted bool
+	fired       map[int]bool
+}
+
+// KeyGestureEngine はキー状態の変化からキージェスチャーを検出する
+// ネイティブ層に依存しないため、時刻を指定したキー状態の列で検証できる
+type KeyGestureEngine struct {
+	mu       sync.Mutex
+	gestures []KeyGesture
+
+	pressed map[string]*heldKey
+	taps    map[string]*tapCounter
+
+	// キーシーケンスの検出に使う直近の押下
+	history []keyPress
+	pressNo int
+	// キーシーケンスごとの、最後に検出したときの押下の番号 (同じ押下を二度使わない)
+	sequenceFired map[int]int
+}
+
+// NewKeyGestureEngine は gestures を検出する KeyGestureEngine を生成する
+// 不正なジェスチャーは取り除かれ、その理由がエラーとして返される
+func NewKeyGestureEngine(gestures []KeyGesture) (*KeyGestureEngine, error) {
+	e := &KeyGestureEngine{}
+	err := e.SetGestures(gestures)
+	return e, err
+}
+
+// SetGestures は検出するジェスチャーを置き換え、検出途中の状態を破棄する
+func (e *KeyGestureEngine) SetGestures(gestures []KeyGesture) error {
+	var valid []KeyGesture
+	var errs []error
+	for i, g := range gestures {
+		normalized, err := g.validate()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("gesture #%d (%s): %w", i+1, g.Event, err))
+			continue
+		}
+		valid = append(valid, normalized)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.gestures = valid
+	e.pressed = make(map[string]*heldKey)
+	e.taps = make(map[string]*tapCounter)
+	e.history = nil
+	e.sequenceFired = make(map[int]int)
+	return errors.Join(errs...)
+}
+
+// Gestures は検出中のジェスチャー (正規化済み) を返す
+func (e *KeyGestureEngine) Gestures() []KeyGesture {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]KeyGesture(nil), e.gestures...)
+}
+
+// Feed はカンマ区切りのキー状態 (KeyStateCallback と同じ形式) を時刻 at の状態として受け取り、
+// 検出したジェスチャーのイベントを検出順に返す
+func (e *KeyGestureEngine) Feed(keys string, at time.Time) []ShortcutID {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	current := make(map[string]bool)
+	for _, key := range strings.Split(keys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			current[key] = true
+		}
+	}
+
+	// 離されたキー
+	for key := range e.pressed {
+		if !current[key] {
+			delete(e.pressed, key)
+		}
+	}
+
+	// 新しく押されたキー (キー状態の並びに依存しないよう名前順に処理する)
+	var pressed []string
+	for key := range current {
+		if _, ok := e.pressed[key]; !ok {
+			pressed = append(pressed, key)
+		}
+	}
+	sort.Strings(pressed)
+
+	events := e.expireLocked(at)
+	for _, key := range pressed {
+		for _, held := range e.pressed {
+			held.interrupted = true
+		}
+		e.pressed[key] = &heldKey{since: at, fired: make(map[int]bool)}
+
+		events = append(events, e.pressTapsLocked(key, at)...)
+		events = append(events, e.pressSequencesLocked(key, at)...)
+	}
+	return append(events, e.longPressLocked(at)...)
+}
+
+// Tick は時刻 at までに確定した長押しと保留中の連打を返す
+// キー状態が変化しなくても定期的に呼び出す必要がある
+func (e *KeyGestureEngine) Tick(at time.Time) []ShortcutID {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append(e.expireLocked(at), e.longPressLocked(at)...)
+}
+
+// pressTapsLocked は key の押下を連打のカウンタに反映する
+func (e *KeyGestureEngine) pressTapsLocked(key string, at time.Time) []ShortcutID {
+	var events []ShortcutID
+
+	for _, tapKey := range e.tapKeysLocked() {
+		counter := e.taps[tapKey]
+		if !gestureKeyMatches(tapKey, key) {
+			// 別のキーが押されたら連打は途切れる
+			if counter != nil {
+				if counter.pending != nil {
+					events = append(events, ShortcutID(counter.pending.Event))
+				}
+				delete(e.taps, tapKey)
+			}
+			continue
+		}
+
+		if counter == nil {
+			counter = &tapCounter{}
+			e.taps[tapKey] = counter
+		}
+		if counter.count > 0 && at.Sub(counter.last) > e.tapIntervalLocked(tapKey) {
+			if counter.pending != nil {
+				events = append(events, ShortcutID(counter.pending.Event))
+			}
+			counter.count = 0
+		}
+		counter.count++
+		counter.last = at
+		counter.pending = nil
+
+		match, maxCount := e.tapGestureLocked(tapKey, counter.count)
+		switch {
+		case counter.count >= maxCount:
+			// これ以上回数の多いジェスチャーはないのですぐに確定する
+			if match != nil {
+				events = append(events, ShortcutID(match.Event))
+			}
+			delete(e.taps, tapKey)
+		case match == nil:
+		default:
+			// 回数の多いジェスチャーの途中かもしれないので間隔が過ぎるまで保留する
+			counter.pending = match
+		}
+	}
+	return events
+}
+
+// expireLocked は間隔が過ぎた連打を確定させる
+func (e *KeyGestureEngine) expireLocked(at time.Time) []ShortcutID {
+	var events []ShortcutID
+	for _, tapKey := range e.tapKeysLocked() {
+		counter := e.taps[tapKey]
+		if counter == nil || at.Sub(counter.last) <= e.tapIntervalLocked(tapKey) {
+			continue
+		}
+		if counter.pending != nil {
+			events = append(events, ShortcutID(counter.pending.Event))
+		}
+		delete(e.taps, tapKey)
+	}
+	return events
+}
+
+// pressSequencesLocked は key の押下を履歴に加え、直近の押下と一致するキーシーケンスを返す
+func (e *KeyGestureEngine) pressSequencesLocked(key string, at time.Time) []ShortcutID {
+	maxLen := 0
+	for _, g := range e.gestures {
+		if g.Kind == KeyGestureSequence && len(g.Keys) > maxLen {
+			maxLen = len(g.Keys)
+		}
+	}
+	if maxLen == 0 {
+		return nil
+	}
+
+	e.pressNo++
+	e.history = append(e.history, keyPress{key: key, at: at, seq: e.pressNo})
+	if len(e.history) > maxLen {
+		e.history = e.history[len(e.history)-maxLen:]
+	}
+
+	var events []ShortcutID
+	for i, g := range e.gestures {
+		if g.Kind != KeyGestureSequence || len(e.history) < len(g.Keys) {
+			continue
+		}
+
+		window := e.history[len(e.history)-len(g.Keys):]
+		if window[0].seq <= e.sequenceFired[i] {
+			continue
+		}
+		matched := true
+		for j, press := range window {
+			if !gestureKeyMatches(g.Keys[j], press.key) || (j > 0 && press.at.Sub(window[j-1].at) > g.interval()) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			e.sequenceFired[i] = e.pressNo
+			events = append(events, ShortcutID(g.Event))
+		}
+	}
+	return events
+}
+
+// longPressLocked は Duration 以上押し続けられているキーの長押しを確定させる
+func (e *KeyGestureEngine) longPressLocked(at time.Time) []ShortcutID {
+	var events []ShortcutID
+	for i, g := range e.gestures {
+		if g.Kind != KeyGestureLongPress {
+			continue
+		}
+		for key, held := range e.pressed {
+			if held.interrupted || held.fired[i] || !gestureKeyMatches(g.Key, key) {
+				continue
+			}
+			if at.Sub(held.since) >= g.duration() {
+				held.fired[i] = true
+				events = append(events, ShortcutID(g.Event))
+			}
+		}
+	}
+	return events
+}
+
+// tapKeysLocked は連打のジェスチャーが設定されているキーを返す
+func (e *KeyGestureEngine) tapKeysLocked() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, g := range e.gestures {
+		if g.Kind == KeyGestureTap && !seen[g.Key] {
+			seen[g.Key] = true
+			keys = append(keys, g.Key)
+		}
+	}
+	return keys
+}
+
+// tapIntervalLocked は key の連打のうち最も長い間隔を返す
+func (e *KeyGestureEngine) tapIntervalLocked(key string) time.Duration {
+	var interval time.Duration
+	for _, g := range e.gestures {
+		if g.Kind == KeyGestureTap && g.Key == key && g.interval() > interval {
+			interval = g.interval()
+		}
+	}
+	return interval
+}
+
+// tapGestureLocked は key を count 回押したときのジェスチャーと、key の連打の最大回数を返す
+func (e *KeyGestureEngine) tapGestureLocked(key string, count int) (*KeyGesture, int) {
+	var match *KeyGesture
+	maxCount := 0
+	for i := range e.gestures {
+		g := &e.gestures[i]
+		if g.Kind != KeyGestureTap || g.Key != key {
+			continue
+		}
+		if g.Count == count && match == nil {
+			match = g
+		}
+		if g.Count > maxCount {
+			maxCount = g.Count
+		}
+	}
+	return match, maxCount
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// keyStep はキー状態の列の1つ
+// tick が true なら keys を送らずに Tick だけ呼ぶ
+type keyStep struct {
+	ms   int
+	keys string
+	tick bool
+}
+
+// runKeyTimeline は steps を順に engine に送り、検出したイベントを返す
+func runKeyTimeline(engine *KeyGestureEngine, steps []keyStep) []ShortcutID {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var events []ShortcutID
+	for _, step := range steps {
+		at := start.Add(time.Duration(step.ms) * time.Millisecond)
+		if step.tick {
+			events = append(events, engine.Tick(at)...)
+		} else {
+			events = append(events, engine.Feed(step.keys, at)...)
+		}
+	}
+	return events
+}
+
+// taps は key を at の各時刻に 50ms ずつ押して離すキー状態の列を返す
+func taps(key string, at ...int) []keyStep {
+	var steps []keyStep
+	for _, ms := range at {
+		steps = append(steps, keyStep{ms: ms, keys: key}, keyStep{ms: ms + 50})
+	}
+	return steps
+}
+
+func TestKeyGestureEngineTimelines(t *testing.T) {
+	tapGestures := []KeyGesture{
+		{Kind: KeyGestureTap, Key: "shift", Count: 2, Event: "double"},
+		{Kind: KeyGestureTap, Key: "shift", Count: 3, Event: "triple"},
+	}
+	longPress := []KeyGesture{
+		{Kind: KeyGestureLongPress, Key: "ctrl", DurationMs: 800, Event: "long"},
+	}
+	sequence := []KeyGesture{
+		{Kind: KeyGestureSequence, Keys: []string{"ctrl", "ctrl", "k"}, IntervalMs: 400, Event: "palette"},
+	}
+
+	tests := []struct {
+		name     string
+		gestures []KeyGesture
+		steps    []keyStep
+		want     []ShortcutID
+	}{
+		{
+			name:     "default shift double press",
+			gestures: defaultKeyGestures(),
+			steps:    taps("lshift", 0, 200),
+			want:     []ShortcutID{ShortcutSub},
+		},
+		{
+			name:     "left and right shift count as the same key",
+			gestures: defaultKeyGestures(),
+			steps:    append(taps("lshift", 0), taps("rshift", 300)...),
+			want:     []ShortcutID{ShortcutSub},
+		},
+		{
+			name:     "double press too slow",
+			gestures: defaultKeyGestures(),
+			steps:    taps("lshift", 0, 600),
+		},
+		{
+			name:     "other key breaks the taps",
+			gestures: defaultKeyGestures(),
+			steps:    append(append(taps("lshift", 0), taps("a", 100)...), taps("lshift", 200)...),
+		},
+		{
+			name:     "triple press wins over double",
+			gestures: tapGestures,
+			steps:    taps("lshift", 0, 200, 400),
+			want:     []ShortcutID{"triple"},
+		},
+		{
+			name:     "double press waits for the interval",
+			gestures: tapGestures,
+			steps: append(taps("lshift", 0, 200),
+				keyStep{ms: 600, tick: true},
+				keyStep{ms: 701, tick: true},
+			),
+			want: []ShortcutID{"double"},
+		},
+		{
+			name:     "pending double press is confirmed by another key",
+			gestures: tapGestures,
+			steps:    append(taps("lshift", 0, 200), taps("a", 300)...),
+			want:     []ShortcutID{"double"},
+		},
+		{
+			name:     "long press",
+			gestures: longPress,
+			steps: []keyStep{
+				{ms: 0, keys: "lcontrol"},
+				{ms: 500, tick: true},
+				{ms: 850, tick: true},
+				{ms: 900, tick: true},
+				{ms: 1000},
+			},
+			want: []ShortcutID{"long"},
+		},
+		{
+			name:     "released before the duration",
+			gestures: longPress,
+			steps: []keyStep{
+				{ms: 0, keys: "lcontrol"},
+				{ms: 700},
+				{ms: 900, tick: true},
+			},
+		},
+		{
+			name:     "long press interrupted by another key",
+			gestures: longPress,
+			steps: []keyStep{
+				{ms: 0, keys: "lcontrol"},
+				{ms: 300, keys: "lcontrol,c"},
+				{ms: 400, keys: "lcontrol"},
+				{ms: 1000, tick: true},
+			},
+		},
+		{
+			name:     "key sequence",
+			gestures: sequence,
+			steps:    append(taps("rcontrol", 0, 200), taps("k", 400)...),
+			want:     []ShortcutID{"palette"},
+		},
+		{
+			name:     "key sequence too slow",
+			gestures: sequence,
+			steps:    append(taps("rcontrol", 0, 200), taps("k", 700)...),
+		},
+		{
+			name:     "key sequence presses are not reused",
+			gestures: sequence,
+			steps:    append(append(taps("lcontrol", 0, 100), taps("k", 200)...), taps("k", 300)...),
+			want:     []ShortcutID{"palette"},
+		},
+		{
+			name:     "simultaneous presses are ordered by name",
+			gestures: []KeyGesture{{Kind: KeyGestureSequence, Keys: []string{"a", "b"}, Event: "ab"}},
+			steps:    []keyStep{{ms: 0, keys: "b,a"}, {ms: 50}},
+			want:     []ShortcutID{"ab"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewKeyGestureEngine(tt.gestures)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := runKeyTimeline(engine, tt.steps); !slices.Equal(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyGestureEngineSetGestures(t *testing.T) {
+	tests := []struct {
+		name    string
+		gesture KeyGesture
+		err     string
+	}{
+		{name: "no event", gesture: KeyGesture{Kind: KeyGestureTap, Key: "a", Count: 2}, err: "event is empty"},
+		{name: "single tap", gesture: KeyGesture{Kind: KeyGestureTap, Key: "a", Count: 1, Event: "e"}, err: "tap count must be at least 2"},
+		{name: "no key", gesture: KeyGesture{Kind: KeyGestureLongPress, Event: "e"}, err: "key is empty"},
+		{name: "short sequence", gesture: KeyGesture{Kind: KeyGestureSequence, Keys: []string{"a"}, Event: "e"}, err: "at least 2 keys"},
+		{name: "empty sequence key", gesture: KeyGesture{Kind: KeyGestureSequence, Keys: []string{"a", " "}, Event: "e"}, err: "keys[1] is empty"},
+		{name: "negative interval", gesture: KeyGesture{Kind: KeyGestureTap, Key: "a", Count: 2, IntervalMs: -1, Event: "e"}, err: "must not be negative"},
+		{name: "unknown kind", gesture: KeyGesture{Kind: "swipe", Event: "e"}, err: `unknown kind "swipe"`},
+		{name: "valid", gesture: KeyGesture{Kind: KeyGestureLongPress, Key: "Alt", Event: "e"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewKeyGestureEngine([]KeyGesture{tt.gesture})
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				// 省略された値は既定値で補われ、キー名は正規化される
+				got := engine.Gestures()[0]
+				if got.Key != "option" || got.DurationMs != 800 || got.IntervalMs != 500 {
+					t.Errorf("normalized gesture = %+v", got)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+			if len(engine.Gestures()) != 0 {
+				t.Errorf("invalid gesture was kept: %+v", engine.Gestures())
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+//go:build linux
+
+package main
+
+import (
+	"strings"
+
+	"tmp/backend/linux/x11"
+)
+
+// linuxKey はキー名とX11のキーシンボルの対応
+type linuxKey struct {
+	name   string
+	keysym x11.Keysym
+}
+
+// linuxKeys は macOS 版 (backend/darwin/keyboard) と同じキー名をX11のキーシンボルに対応付ける
+// GetPressedKeys はこの順番でキー名を並べる
+var linuxKeys = []linuxKey{
+	// 修飾キー (command は Super、option は Alt に対応させる)
+	{"lcommand", 0xffeb}, {"rcommand", 0xffec}, {"lshift", 0xffe1}, {"rshift", 0xffe2},
+	{"loption", 0xffe9}, {"roption", 0xffea}, {"lcontrol", 0xffe3}, {"rcontrol", 0xffe4},
+
+	// 特殊キー
+	{"space", 0x0020}, {"return", 0xff0d}, {"tab", 0xff09}, {"escape", 0xff1b},
+	{"delete", 0xff08}, {"forwarddelete", 0xffff},
+	{"left", 0xff51}, {"right", 0xff53}, {"up", 0xff52}, {"down", 0xff54},
+	{"home", 0xff50}, {"end", 0xff57}, {"pageup", 0xff55}, {"pagedown", 0xff56}, {"help", 0xff6a},
+
+	// ファンクションキー
+	{"f1", 0xffbe}, {"f2", 0xffbf}, {"f3", 0xffc0}, {"f4", 0xffc1}, {"f5", 0xffc2},
+	{"f6", 0xffc3}, {"f7", 0xffc4}, {"f8", 0xffc5}, {"f9", 0xffc6}, {"f10", 0xffc7},
+	{"f11", 0xffc8}, {"f12", 0xffc9}, {"f13", 0xffca}, {"f14", 0xffcb}, {"f15", 0xffcc},
+	{"f16", 0xffcd}, {"f17", 0xffce}, {"f18", 0xffcf}, {"f19", 0xffd0}, {"f20", 0xffd1},
+
+	// 英字キー
+	{"a", 'a'}, {"b", 'b'}, {"c", 'c'}, {"d", 'd'}, {"e", 'e'}, {"f", 'f'}, {"g", 'g'},
+	{"h", 'h'}, {"i", 'i'}, {"j", 'j'}, {"k", 'k'}, {"l", 'l'}, {"m", 'm'}, {"n", 'n'},
+	{"o", 'o'}, {"p", 'p'}, {"q", 'q'}, {"r", 'r'}, {"s", 's'}, {"t", 't'}, {"u", 'u'},
+	{"v", 'v'}, {"w", 'w'}, {"x", 'x'}, {"y", 'y'}, {"z", 'z'},
+
+	// 数字キー
+	{"0", '0'}, {"1", '1'}, {"2", '2'}, {"3", '3'}, {"4", '4'},
+	{"5", '5'}, {"6", '6'}, {"7", '7'}, {"8", '8'}, {"9", '9'},
+
+	// 記号キー
+	{"minus", '-'}, {"equal", '='}, {"leftbracket", '['}, {"rightbracket", ']'},
+	{"semicolon", ';'}, {"quote", '\''}, {"backslash", '\\'}, {"comma", ','},
+	{"period", '.'}, {"slash", '/'}, {"grave", '`'},
+}
+
+// linuxKeyAliases は入力として受け付ける別名
+var linuxKeyAliases = map[string]string{
+	"cmd": "lcommand", "command": "lcommand", "lcmd": "lcommand", "rcmd": "rcommand", "super": "lcommand",
+	"shift": "lshift",
+	"alt":   "loption", "option": "loption", "lalt": "loption", "ralt": "roption",
+	"ctrl": "lcontrol", "control": "lcontrol", "lctrl": "lcontrol", "rctrl": "rcontrol",
+	"enter": "return",
+	"esc":   "escape",
+	"del":   "delete", "backspace": "delete",
+	"-": "minus", "=": "equal", "[": "leftbracket", "]": "rightbracket", ";": "semicolon",
+	"'": "quote", "\\": "backslash", ",": "comma", ".": "period", "/": "slash", "`": "grave",
+}
+
+// linuxKeysymForName はキー名 (大文字小文字は区別しない) に対応するキーシンボルを返す
+func linuxKeysymForName(name string) (x11.Keysym, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := linuxKeyAliases[name]; ok {
+		name = alias
+	}
+	for _, k := range linuxKeys {
+		if k.name == name {
+			return k.keysym, true
+		}
+	}
+	return 0, false
+}
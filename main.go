@@ -1,63 +1,40 @@
 package main
 
-/*
-#cgo CFLAGS: -x objective-c -I${SRCDIR}/backend/darwin/utils -I${SRCDIR}/backend/darwin/mouse -I${SRCDIR}/backend/darwin/keyboard -I${SRCDIR}/backend/darwin/window -I${SRCDIR}/backend/darwin/logger
-#cgo LDFLAGS: -framework Cocoa -framework Carbon
-#include "utils.h"
-#include "mouse.h"
-#include "keyboard.h"
-#include "window.h"
-#include "logger.h"
-
-#include "backend/darwin/utils/utils.m"
-#include "backend/darwin/mouse/mouse.m"
-#include "backend/darwin/keyboard/keyboard.m"
-#include "backend/darwin/window/window.m"
-#include "backend/darwin/logger/logger.m"
-*/
-import "C"
-
 import (
 	"context"
 	"embed"
 	"log"
-	"time"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
 	"github.com/wailsapp/wails/v2/pkg/options/mac"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 //go:embed all:frontend/dist
 var assets embed.FS
 
-// グローバルなアプリインスタンスへの参照
-var globalApp *App
-
-func getScreenSize() (width, height int) {
-	var w, h C.int
-	C.GetMainScreenSize(&w, &h)
-	return int(w), int(h)
-}
-
 func (a *App) shutdown(ctx context.Context) {
 	// ショートカット監視を停止
 	a.stopShortcutMonitoring()
 
+	// グローバルホットキーの登録を解除
+	a.hotkeys.Unregister()
+
 	// キー監視を停止
 	a.StopKeyMonitoring()
 
+	// マウス位置・ゴーストの監視を停止し、すべての監視ゴルーチンの終了を待つ
+	a.monitors.Shutdown()
+
 	// モニタリングを停止
-	C.StopMonitoring()
+	a.platform.StopMonitoring()
 }
 
 func main() {
 	app := NewApp()
-	globalApp = app
 
-	width, height := getScreenSize()
+	width, height := app.platform.GetScreenSize()
 	err := wails.Run(&options.App{
 		Title:            "FloatingWindow",
 		Width:            width,
@@ -73,8 +50,19 @@ func main() {
 		OnStartup:  app.startup,
 		OnShutdown: app.shutdown,
 		OnDomReady: func(ctx context.Context) {
+			// 読み込み (読み込み直し) たページのローダーがプラグインを読み込めるようにする
+			app.resetPluginLoader()
+
 			// ウィンドウ設定を適用
-			C.SetupMainWindow()
+			app.platform.SetupMainWindow()
+
+			// 設定ファイルとプラグインのグローバルホットキーを登録
+			app.registerHotkeys()
+			app.registerPluginHotkeys()
+
+			// 設定ファイルのキージェスチャーとマウスジェスチャーを読み込む
+			app.registerKeyGestures()
+			app.registerMouseGestures()
 
 			// ショートカット監視を開始
 			app.startShortcutMonitoring()
@@ -85,21 +73,10 @@ func main() {
 			}
 
 			// 定期的にゴースト状態を確認するタイマーを開始
-			go func() {
-				ticker := time.NewTicker(100 * time.Millisecond)
-				defer ticker.Stop()
-
-				var lastGhostId string
-
-				for range ticker.C {
-					ghostId := C.GoString(C.GetLastGhostId())
-					if ghostId != lastGhostId {
-						lastGhostId = ghostId
-						log.Printf("Ghost changed: %s", ghostId)
-						runtime.EventsEmit(ctx, "switch-ghost", ghostId)
-					}
-				}
-			}()
+			app.startGhostMonitoring()
+
+			// プラグインの追加・更新・削除を監視
+			app.startPluginWatching()
 		},
 		Bind: []interface{}{app},
 	})
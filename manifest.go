@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 対応しているマニフェストの最新の版
+// manifestVersion を省略したマニフェストは版 1 として扱う
+const currentManifestVersion = 1
+
+// ValidationIssue の種類
+const (
+	issueInvalidJSON        = "invalid_json"
+	issueInvalidType        = "invalid_type"
+	issueRequired           = "required"
+	issueInvalidFormat      = "invalid_format"
+	issueUnsupportedVersion = "unsupported_version"
+	issueUnknownField       = "unknown_field"
+	issueNotFound           = "not_found"
+	issueIncompatibleEngine = "incompatible_engine"
+	issueUnsatisfiedDep     = "unsatisfied_dependency"
+	issueDependencyCycle    = "dependency_cycle"
+	issueShadowed           = "shadowed"
+)
+
+// ValidationIssue はプラグインの検証で見つかった問題
+// Path はマニフェスト内の位置を表すJSONパス ("$.id" など)、ファイルに関する問題では空
+type ValidationIssue struct {
+	Path    string `json:"path"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (i ValidationIssue) String() string {
+	if i.Path == "" {
+		return i.Message
+	}
+	return i.Path + ": " + i.Message
+}
+
+// joinIssues は問題を "; " で区切った1行の文字列にする
+func joinIssues(issues []ValidationIssue) string {
+	messages := make([]string, len(issues))
+	for i, issue := range issues {
+		messages[i] = issue.String()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// プラグインIDの書式 (英数字を "-", "_", "." で区切ったもの)
+var pluginIDPattern = regexp.MustCompile(`^[A-Za-z0-9]+(?:[._-][A-Za-z0-9]+)*$`)
+
+// プラグインIDの最大の長さ
+const maxPluginIDLength = 64
+
+// manifestField はマニフェストの項目の定義
+type manifestField struct {
+	required bool
+	// validate は値を検証して問題を返す (path はこの項目のJSONパス)
+	validate func(path string, value any) []ValidationIssue
+	// warn は値についての警告を返す (プラグインは無効にならない、省略可)
+	warn func(path string, value any) []ValidationIssue
+}
+
+// manifestSchemas は manifestVersion ごとの項目の定義
+var manifestSchemas = map[int]map[string]manifestField{
+	1: {
+		"manifestVersion": {validate: validateManifestVersionField},
+		"id":              {required: true, validate: validatePluginIDField},
+		"name":            {required: true, validate: validateNonEmptyStringField},
+		"version":         {required: true, validate: validateSemverField},
+		"description":     {validate: validateStringField},
+		"author":          {validate: validateStringField},
+		"shortcut":        {validate: validateShortcutField},
+		"icon":            {validate: validateStringField},
+		"engines":         {validate: validateEnginesField},
+		"dependencies":    {validate: validateDependenciesField},
+		"permissions":     {validate: validatePermissionsField, warn: warnUnknownPermissions},
+		"settings":        {validate: validateSettingsField},
+	},
+}
+
+// validateManifest は manifest.json の内容をスキーマに従って検証する
+// 検証に失敗した項目があっても、読み取れた範囲のマニフェストを返す
+func validateManifest(data []byte) (manifest GhostManifest, errs, warnings []ValidationIssue) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var raw any
+	if err := decoder.Decode(&raw); err != nil {
+		return manifest, []ValidationIssue{{Path: "$", Code: issueInvalidJSON, Message: fmt.Sprintf("invalid JSON: %v", err)}}, nil
+	}
+	if decoder.More() {
+		return manifest, []ValidationIssue{{Path: "$", Code: issueInvalidJSON, Message: "invalid JSON: unexpected data after the manifest object"}}, nil
+	}
+	fields, ok := raw.(map[string]any)
+	if !ok {
+		return manifest, []ValidationIssue{typeIssue("$", "an object", raw)}, nil
+	}
+
+	// 版を決めてから、その版のスキーマで検証する
+	version := currentManifestVersion
+	if value, ok := fields["manifestVersion"]; ok {
+		if issues := validateManifestVersionField("$.manifestVersion", value); len(issues) > 0 {
+			return manifest, issues, nil
+		}
+		n, _ := value.(json.Number).Int64()
+		version = int(n)
+	} else {
+		warnings = append(warnings, ValidationIssue{
+			Path:    "$.manifestVersion",
+			Code:    issueRequired,
+			Message: fmt.Sprintf("manifestVersion is missing; assuming version %d", currentManifestVersion),
+		})
+	}
+	schema := manifestSchemas[version]
+
+	// JSONパスの順序が安定するよう項目名の順に検証する
+	names := make([]string, 0, len(schema)+len(fields))
+	for name := range schema {
+		names = append(names, name)
+	}
+	for name := range fields {
+		if _, ok := schema[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := jsonPath("$", name)
+		field, known := schema[name]
+		value, present := fields[name]
+		switch {
+		case !known:
+			warnings = append(warnings, ValidationIssue{Path: path, Code: issueUnknownField, Message: fmt.Sprintf("unknown field %q is ignored", name)})
+		case !present && field.required:
+			errs = append(errs, ValidationIssue{Path: path, Code: issueRequired, Message: fmt.Sprintf("%s is required", name)})
+		case present:
+			errs = append(errs, field.validate(path, value)...)
+			if field.warn != nil {
+				warnings = append(warnings, field.warn(path, value)...)
+			}
+		}
+	}
+
+	// 型の合わない項目は空のまま、読み取れる項目だけを取り出す (エラーは検証済み)
+	json.Unmarshal(data, &manifest)
+	manifest.ManifestVersion = version
+	return manifest, errs, warnings
+}
+
+// JSONパスで "." の後にそのまま書けるキー
+var jsonPathIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// jsonPath は parent の子要素 key のJSONパスを返す
+func jsonPath(parent, key string) string {
+	if jsonPathIdentifier.MatchString(key) {
+		return parent + "." + key
+	}
+	return parent + "[" + strconv.Quote(key) + "]"
+}
+
+// typeIssue は値の型が期待と異なることを表す問題を返す
+func typeIssue(path, expected string, value any) ValidationIssue {
+	return ValidationIssue{Path: path, Code: issueInvalidType, Message: fmt.Sprintf("expected %s, got %s", expected, jsonTypeName(value))}
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number, float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func validateStringField(path string, value any) []ValidationIssue {
+	if _, ok := value.(string); !ok {
+		return []ValidationIssue{typeIssue(path, "a string", value)}
+	}
+	return nil
+}
+
+func validateNonEmptyStringField(path string, value any) []ValidationIssue {
+	s, ok := value.(string)
+	if !ok {
+		return []ValidationIssue{typeIssue(path, "a string", value)}
+	}
+	if strings.TrimSpace(s) == "" {
+		return []ValidationIssue{{Path: path, Code: issueRequired, Message: "must not be empty"}}
+	}
+	return nil
+}
+
+func validateManifestVersionField(path string, value any) []ValidationIssue {
+	n, ok := value.(json.Number)
+	if !ok {
+		return []ValidationIssue{typeIssue(path, "an integer", value)}
+	}
+	version, err := n.Int64()
+	if err != nil {
+		return []ValidationIssue{typeIssue(path, "an integer", value)}
+	}
+	if version < 1 || version > currentManifestVersion {
+		return []ValidationIssue{{
+			Path:    path,
+			Code:    issueUnsupportedVersion,
+			Message: fmt.Sprintf("unsupported manifestVersion %d (supported: 1 to %d)", version, currentManifestVersion),
+		}}
+	}
+	return nil
+}
+
+func validatePluginIDField(path string, value any) []ValidationIssue {
+	id, ok := value.(string)
+	if !ok {
+		return []ValidationIssue{typeIssue(path, "a string", value)}
+	}
+	if id == "" {
+		return []ValidationIssue{{Path: path, Code: issueRequired, Message: "must not be empty"}}
+	}
+	if len(id) > maxPluginIDLength || !pluginIDPattern.MatchString(id) {
+		return []ValidationIssue{{
+			Path:    path,
+			Code:    issueInvalidFormat,
+			Message: fmt.Sprintf("invalid plugin id %q: use up to %d letters and digits separated by '-', '_' or '.'", id, maxPluginIDLength),
+		}}
+	}
+	return nil
+}
+
+func validateSemverField(path string, value any) []ValidationIssue {
+	version, ok := value.(string)
+	if !ok {
+		return []ValidationIssue{typeIssue(path, "a string", value)}
+	}
+	if _, err := parseSemver(version); err != nil {
+		return []ValidationIssue{{Path: path, Code: issueInvalidFormat, Message: fmt.Sprintf("%v (expected MAJOR.MINOR.PATCH)", err)}}
+	}
+	return nil
+}
+
+func validateShortcutField(path string, value any) []ValidationIssue {
+	shortcut, ok := value.(string)
+	if !ok {
+		return []ValidationIssue{typeIssue(path, "a string", value)}
+	}
+	if shortcut == "" {
+		return nil
+	}
+	if _, _, err := parseShortcut(shortcut); err != nil {
+		return []ValidationIssue{{Path: path, Code: issueInvalidFormat, Message: err.Error()}}
+	}
+	return nil
+}
+
+// validateEnginesField は {"ghostcursor": ">=1.0.0"} の形式の engines を検証する
+// ホスト以外のエンジンの指定は読み込みに影響しない
+func validateEnginesField(path string, value any) []ValidationIssue {
+	engines, ok := value.(map[string]any)
+	if !ok {
+		return []ValidationIssue{typeIssue(path, "an object", value)}
+	}
+
+	var issues []ValidationIssue
+	for _, name := range sortedKeys(engines) {
+		issues = append(issues, validateSemverRangeField(jsonPath(path, name), engines[name])...)
+	}
+	return issues
+}
+
+// validateDependenciesField は {"plugin-id": "^1.0.0"} の形式の dependencies を検証する
+func validateDependenciesField(path string, value any) []ValidationIssue {
+	dependencies, ok := value.(map[string]any)
+	if !ok {
+		return []ValidationIssue{typeIssue(path, "an object", value)}
+	}
+
+	var issues []ValidationIssue
+	for _, id := range sortedKeys(dependencies) {
+		depPath := jsonPath(path, id)
+		if len(id) > maxPluginIDLength || !pluginIDPattern.MatchString(id) {
+			issues = append(issues, ValidationIssue{Path: depPath, Code: issueInvalidFormat, Message: fmt.Sprintf("invalid plugin id %q", id)})
+			continue
+		}
+		issues = append(issues, validateSemverRangeField(depPath, dependencies[id])...)
+	}
+	return issues
+}
+
+func validateSemverRangeField(path string, value any) []ValidationIssue {
+	constraint, ok := value.(string)
+	if !ok {
+		return []ValidationIssue{typeIssue(path, "a string", value)}
+	}
+	if _, err := parseSemverRange(constraint); err != nil {
+		return []ValidationIssue{{Path: path, Code: issueInvalidFormat, Message: fmt.Sprintf("%v (expected e.g. \">=1.0.0\" or \"^1.2.0\")", err)}}
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validatePermissionsField は ["clipboard.read", ...] の形式の permissions を検証する
+func validatePermissionsField(path string, value any) []ValidationIssue {
+	permissions, ok := value.([]any)
+	if !ok {
+		return []ValidationIssue{typeIssue(path, "an array", value)}
+	}
+
+	var issues []ValidationIssue
+	for i, item := range permissions {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		if _, ok := item.(string); !ok {
+			issues = append(issues, typeIssue(itemPath, "a string", item))
+		}
+	}
+	return issues
+}
+
+// warnUnknownPermissions は permissions のうちこのバージョンが知らない権限を警告する
+// 新しいバージョン向けのプラグインも読み込めるよう、知らない権限は無視する (その権限が必要なメソッドはない)
+func warnUnknownPermissions(path string, value any) []ValidationIssue {
+	permissions, _ := value.([]any)
+
+	var issues []ValidationIssue
+	for i, item := range permissions {
+		permission, ok := item.(string)
+		if !ok {
+			continue
+		}
+		if _, known := permissionDescriptions[Permission(permission)]; !known {
+			issues = append(issues, ValidationIssue{Path: fmt.Sprintf("%s[%d]", path, i), Code: issueUnknownField, Message: fmt.Sprintf("unknown permission %q is ignored", permission)})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateManifest(t *testing.T) {
+	type issue struct{ path, code string }
+	// base は manifestVersion, id, name, version がそろった正しいマニフェストの中身
+	const base = `"manifestVersion": 1, "id": "clock", "name": "Clock", "version": "1.0.0"`
+
+	tests := []struct {
+		name         string
+		manifest     string
+		wantErrs     []issue
+		wantWarnings []issue
+	}{
+		{name: "valid", manifest: `{` + base + `}`},
+		{
+			name:     "valid with every field",
+			manifest: `{` + base + `, "description": "d", "author": "a", "shortcut": "Alt+C", "icon": "icon.png", "engines": {"ghostcursor": ">=1.0.0"}, "dependencies": {"memo-pad": "^1.2.0"}, "permissions": ["clipboard.read"], "settings": []}`,
+		},
+		{name: "dotted id", manifest: `{"manifestVersion": 1, "id": "com.example.clock_2-b", "name": "Clock", "version": "1.0.0"}`},
+
+		// JSON として読めない、またはオブジェクトでない
+		{name: "invalid JSON", manifest: `{"id": "clock",`, wantErrs: []issue{{"$", issueInvalidJSON}}},
+		{name: "trailing data", manifest: `{` + base + `} {}`, wantErrs: []issue{{"$", issueInvalidJSON}}},
+		{name: "not an object", manifest: `["clock"]`, wantErrs: []issue{{"$", issueInvalidType}}},
+
+		// 必須の項目
+		{
+			name:     "missing required fields",
+			manifest: `{"manifestVersion": 1}`,
+			wantErrs: []issue{{"$.id", issueRequired}, {"$.name", issueRequired}, {"$.version", issueRequired}},
+		},
+		{name: "empty id", manifest: `{"manifestVersion": 1, "id": "", "name": "Clock", "version": "1.0.0"}`, wantErrs: []issue{{"$.id", issueRequired}}},
+		{name: "blank name", manifest: `{"manifestVersion": 1, "id": "clock", "name": "  ", "version": "1.0.0"}`, wantErrs: []issue{{"$.name", issueRequired}}},
+		{
+			name:         "missing manifestVersion",
+			manifest:     `{"id": "clock", "name": "Clock", "version": "1.0.0"}`,
+			wantWarnings: []issue{{"$.manifestVersion", issueRequired}},
+		},
+
+		// 型
+		{name: "id is a number", manifest: `{"manifestVersion": 1, "id": 42, "name": "Clock", "version": "1.0.0"}`, wantErrs: []issue{{"$.id", issueInvalidType}}},
+		{name: "name is null", manifest: `{"manifestVersion": 1, "id": "clock", "name": null, "version": "1.0.0"}`, wantErrs: []issue{{"$.name", issueInvalidType}}},
+		{name: "description is an object", manifest: `{` + base + `, "description": {}}`, wantErrs: []issue{{"$.description", issueInvalidType}}},
+		{name: "engines is a string", manifest: `{` + base + `, "engines": ">=1.0.0"}`, wantErrs: []issue{{"$.engines", issueInvalidType}}},
+		{name: "permissions is a string", manifest: `{` + base + `, "permissions": "clipboard.read"}`, wantErrs: []issue{{"$.permissions", issueInvalidType}}},
+		{name: "permission is a number", manifest: `{` + base + `, "permissions": ["clipboard.read", 3]}`, wantErrs: []issue{{"$.permissions[1]", issueInvalidType}}},
+		{name: "dependency range is a number", manifest: `{` + base + `, "dependencies": {"memo-pad": 1}}`, wantErrs: []issue{{`$.dependencies["memo-pad"]`, issueInvalidType}}},
+
+		// id の形式
+		{name: "id with a space", manifest: `{"manifestVersion": 1, "id": "my clock", "name": "Clock", "version": "1.0.0"}`, wantErrs: []issue{{"$.id", issueInvalidFormat}}},
+		{name: "id with a leading separator", manifest: `{"manifestVersion": 1, "id": "-clock", "name": "Clock", "version": "1.0.0"}`, wantErrs: []issue{{"$.id", issueInvalidFormat}}},
+		{name: "id with repeated separators", manifest: `{"manifestVersion": 1, "id": "clock..two", "name": "Clock", "version": "1.0.0"}`, wantErrs: []issue{{"$.id", issueInvalidFormat}}},
+		{name: "id with a path", manifest: `{"manifestVersion": 1, "id": "../clock", "name": "Clock", "version": "1.0.0"}`, wantErrs: []issue{{"$.id", issueInvalidFormat}}},
+		{name: "id with non-ASCII letters", manifest: `{"manifestVersion": 1, "id": "時計", "name": "Clock", "version": "1.0.0"}`, wantErrs: []issue{{"$.id", issueInvalidFormat}}},
+		{
+			name:     "id too long",
+			manifest: `{"manifestVersion": 1, "id": "` + strings.Repeat("a", maxPluginIDLength+1) + `", "name": "Clock", "version": "1.0.0"}`,
+			wantErrs: []issue{{"$.id", issueInvalidFormat}},
+		},
+		{name: "dependency with an invalid id", manifest: `{` + base + `, "dependencies": {"bad id": "^1.0.0"}}`, wantErrs: []issue{{`$.dependencies["bad id"]`, issueInvalidFormat}}},
+
+		// その他の形式
+		{name: "version without patch", manifest: `{"manifestVersion": 1, "id": "clock", "name": "Clock", "version": "1.0"}`, wantErrs: []issue{{"$.version", issueInvalidFormat}}},
+		{name: "version is a word", manifest: `{"manifestVersion": 1, "id": "clock", "name": "Clock", "version": "latest"}`, wantErrs: []issue{{"$.version", issueInvalidFormat}}},
+		{name: "invalid engine range", manifest: `{` + base + `, "engines": {"ghostcursor": "soon"}}`, wantErrs: []issue{{"$.engines.ghostcursor", issueInvalidFormat}}},
+		{name: "shortcut without a modifier", manifest: `{` + base + `, "shortcut": "C"}`, wantErrs: []issue{{"$.shortcut", issueInvalidFormat}}},
+		{
+			name:     "errors in field name order",
+			manifest: `{"manifestVersion": 1, "version": "x", "name": "", "id": "bad id"}`,
+			wantErrs: []issue{{"$.id", issueInvalidFormat}, {"$.name", issueRequired}, {"$.version", issueInvalidFormat}},
+		},
+
+		// 版
+		{name: "unknown manifestVersion", manifest: `{"manifestVersion": 2, "id": "clock", "name": "Clock", "version": "1.0.0"}`, wantErrs: []issue{{"$.manifestVersion", issueUnsupportedVersion}}},
+		{name: "manifestVersion zero", manifest: `{"manifestVersion": 0, "id": "clock", "name": "Clock", "version": "1.0.0"}`, wantErrs: []issue{{"$.manifestVersion", issueUnsupportedVersion}}},
+		// 版が分からなければ他の項目は検証しない
+		{name: "unknown manifestVersion skips the schema", manifest: `{"manifestVersion": 99}`, wantErrs: []issue{{"$.manifestVersion", issueUnsupportedVersion}}},
+		{name: "manifestVersion is a string", manifest: `{"manifestVersion": "1", "id": "clock", "name": "Clock", "version": "1.0.0"}`, wantErrs: []issue{{"$.manifestVersion", issueInvalidType}}},
+		{name: "manifestVersion is fractional", manifest: `{"manifestVersion": 1.5, "id": "clock", "name": "Clock", "version": "1.0.0"}`, wantErrs: []issue{{"$.manifestVersion", issueInvalidType}}},
+
+		// 警告だけでプラグインは無効にならない
+		{name: "unknown field", manifest: `{` + base + `, "colour": "red"}`, wantWarnings: []issue{{"$.colour", issueUnknownField}}},
+		{name: "unknown field needing quotes", manifest: `{` + base + `, "x-extra": true}`, wantWarnings: []issue{{`$["x-extra"]`, issueUnknownField}}},
+		{name: "unknown permission", manifest: `{` + base + `, "permissions": ["clipboard.read", "future.power"]}`, wantWarnings: []issue{{"$.permissions[1]", issueUnknownField}}},
+	}
+
+	collect := func(issues []ValidationIssue) []issue {
+		var got []issue
+		for _, i := range issues {
+			got = append(got, issue{i.Path, i.Code})
+		}
+		return got
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs, warnings := validateManifest([]byte(tt.manifest))
+			if got := collect(errs); !reflect.DeepEqual(got, tt.wantErrs) {
+				t.Errorf("errors = %v, want %v", got, tt.wantErrs)
+			}
+			if got := collect(warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("warnings = %v, want %v", got, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestValidateManifestKeepsReadableFields(t *testing.T) {
+	manifest, errs, _ := validateManifest([]byte(`{"manifestVersion": 1, "id": "clock", "name": "Clock", "version": "latest", "permissions": ["clipboard.read"]}`))
+	if len(errs) != 1 {
+		t.Fatalf("errors = %v, want only the version", errs)
+	}
+	if manifest.ID != "clock" || manifest.Name != "Clock" || manifest.ManifestVersion != 1 {
+		t.Errorf("manifest = %+v, want the readable fields", manifest)
+	}
+
+	// manifestVersion を省略すると現在の版として扱う
+	manifest, _, _ = validateManifest([]byte(`{"id": "clock", "name": "Clock", "version": "1.0.0"}`))
+	if manifest.ManifestVersion != currentManifestVersion {
+		t.Errorf("ManifestVersion = %d, want %d", manifest.ManifestVersion, currentManifestVersion)
+	}
+}
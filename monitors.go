@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// App が起動するバックグラウンド監視の名前
+const (
+	monitorMouse    = "mouse"
+	monitorShortcut = "shortcut"
+	monitorGhost    = "ghost"
+	monitorPlugins  = "plugins"
+)
+
+// monitorSupervisor はバックグラウンド監視ゴルーチンを context で起動・停止する
+// 監視ごとに名前を持ち、同じ名前の監視が二重に起動されることはない
+type monitorSupervisor struct {
+	mu       sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	monitors map[string]*runningMonitor
+	wg       sync.WaitGroup
+}
+
+type runningMonitor struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// newMonitorSupervisor は監視が一つも動いていない monitorSupervisor を生成する
+func newMonitorSupervisor() *monitorSupervisor {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &monitorSupervisor{
+		ctx:      ctx,
+		cancel:   cancel,
+		monitors: make(map[string]*runningMonitor),
+	}
+}
+
+// Start は run を name の監視としてゴルーチンで起動する
+// run は ctx がキャンセルされたら速やかに戻らなければならない
+// 同じ名前の監視が動いている場合や Shutdown 後は何もせず false を返す
+func (s *monitorSupervisor) Start(name string, run func(ctx context.Context)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx.Err() != nil {
+		return false
+	}
+	if _, ok := s.monitors[name]; ok {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	m := &runningMonitor{cancel: cancel, done: make(chan struct{})}
+	s.monitors[name] = m
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(m.done)
+		defer func() {
+			s.mu.Lock()
+			if s.monitors[name] == m {
+				delete(s.monitors, name)
+			}
+			s.mu.Unlock()
+		}()
+
+		run(ctx)
+	}()
+
+	fmt.Printf("Monitor started: %s\n", name)
+	return true
+}
+
+// Stop は name の監視を停止し、ゴルーチンが終了するまで待つ
+func (s *monitorSupervisor) Stop(name string) {
+	s.mu.Lock()
+	m, ok := s.monitors[name]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	m.cancel()
+	<-m.done
+	fmt.Printf("Monitor stopped: %s\n", name)
+}
+
+// Running は name の監視が動いているかを返す
+func (s *monitorSupervisor) Running(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.monitors[name]
+	return ok
+}
+
+// Done は Shutdown されると閉じられるチャネルを返す
+func (s *monitorSupervisor) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Shutdown はすべての監視を停止し、終了するまで待つ
+// 以降の Start は無視される
+func (s *monitorSupervisor) Shutdown() {
+	s.mu.Lock()
+	s.cancel()
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	fmt.Println("All monitors stopped")
+}
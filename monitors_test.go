@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMonitorSupervisorStart(t *testing.T) {
+	tests := []struct {
+		name     string
+		starts   []string
+		stop     []string
+		shutdown bool
+		// 最後に Start を試したときに起動できるか
+		restart string
+		want    bool
+	}{
+		{name: "new monitor", restart: monitorMouse, want: true},
+		{name: "duplicate name", starts: []string{monitorMouse}, restart: monitorMouse, want: false},
+		{name: "other name", starts: []string{monitorMouse}, restart: monitorGhost, want: true},
+		{name: "restart after stop", starts: []string{monitorMouse}, stop: []string{monitorMouse}, restart: monitorMouse, want: true},
+		{name: "after shutdown", starts: []string{monitorMouse}, shutdown: true, restart: monitorGhost, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMonitorSupervisor()
+			defer s.Shutdown()
+
+			block := func(ctx context.Context) { <-ctx.Done() }
+			for _, name := range tt.starts {
+				if !s.Start(name, block) {
+					t.Fatalf("Start(%s) = false", name)
+				}
+			}
+			for _, name := range tt.stop {
+				s.Stop(name)
+				if s.Running(name) {
+					t.Fatalf("%s is running after Stop", name)
+				}
+			}
+			if tt.shutdown {
+				s.Shutdown()
+			}
+
+			if got := s.Start(tt.restart, block); got != tt.want {
+				t.Errorf("Start(%s) = %v, want %v", tt.restart, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonitorSupervisorShutdownWaits(t *testing.T) {
+	s := newMonitorSupervisor()
+
+	var running atomic.Int32
+	for i := 0; i < 8; i++ {
+		started := make(chan struct{})
+		s.Start(fmt.Sprintf("monitor-%d", i), func(ctx context.Context) {
+			running.Add(1)
+			close(started)
+			<-ctx.Done()
+			// 終了処理に時間がかかっても Shutdown は待つ
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+		})
+		<-started
+	}
+
+	s.Shutdown()
+	if n := running.Load(); n != 0 {
+		t.Errorf("%d monitors still running after Shutdown", n)
+	}
+	select {
+	case <-s.Done():
+	default:
+		t.Error("Done is not closed after Shutdown")
+	}
+}
+
+// TestMonitorSupervisorConcurrent は go test -race で起動・停止の競合を検出する
+func TestMonitorSupervisorConcurrent(t *testing.T) {
+	s := newMonitorSupervisor()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("monitor-%d", i%4)
+			for j := 0; j < 50; j++ {
+				s.Start(name, func(ctx context.Context) { <-ctx.Done() })
+				s.Running(name)
+				if j%3 == 0 {
+					s.Stop(name)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	s.Shutdown()
+
+	for i := 0; i < 4; i++ {
+		if name := fmt.Sprintf("monitor-%d", i); s.Running(name) {
+			t.Errorf("%s is running after Shutdown", name)
+		}
+	}
+}
+
+// TestAppMonitorsShutdown は App の監視をフェイクの Platform で動かしながら、
+// 他のゴルーチンからの呼び出しと終了処理が競合しないことを確かめる
+func TestAppMonitorsShutdown(t *testing.T) {
+	t.Setenv(configDirEnv, t.TempDir())
+	env := NewFakeEnvironment()
+	app := env.App
+
+	app.startup(context.Background())
+	app.registerHotkeys()
+	app.startShortcutMonitoring()
+	app.startGhostMonitoring()
+	if err := app.StartKeyMonitoring(); err != nil {
+		t.Fatal(err)
+	}
+	// マウス・ショートカット・ゴーストの監視
+	if !env.Clock.WaitForTickers(3, fakeEventTimeout) {
+		t.Fatal("monitors did not start")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	drive := func(f func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ctx.Err() == nil; i++ {
+				f(i)
+			}
+		}()
+	}
+	drive(func(i int) { env.Clock.Advance(10 * time.Millisecond) })
+	drive(func(i int) { env.Platform.MoveMouse(float64(i%500), float64(i%300)) })
+	drive(func(i int) { app.SetGhostPos(float64(i), float64(i)); app.GetGhostPosX() })
+	drive(func(i int) { env.Platform.SetLastGhostId(fmt.Sprintf("ghost-%d", i%3)) })
+	drive(func(i int) { env.Platform.PressHotKey(1 + i%4) })
+	drive(func(i int) {
+		if i%2 == 0 {
+			env.Platform.SetPressedKeys("shift")
+		} else {
+			env.Platform.SetPressedKeys()
+		}
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	app.shutdown(context.Background())
+	cancel()
+	wg.Wait()
+
+	for _, name := range []string{monitorMouse, monitorShortcut, monitorGhost} {
+		if app.monitors.Running(name) {
+			t.Errorf("%s monitor is running after shutdown", name)
+		}
+	}
+	if env.Platform.KeyMonitoring() {
+		t.Error("key monitoring is running after shutdown")
+	}
+	if hotkeys := env.Platform.RegisteredHotKeys(); len(hotkeys) != 0 {
+		t.Errorf("hotkeys still registered after shutdown: %v", hotkeys)
+	}
+}
@@ -0,0 +1,630 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// マウスジェスチャー設定ファイル (設定ディレクトリ内)
+const mouseGestureConfigFile = "mousegestures.json"
+
+// 省略時の設定値
+const (
+	defaultMouseGestureThreshold   = 0.8
+	defaultMouseGestureMinDistance = 80.0
+	defaultMouseGestureIdle        = 150 * time.Millisecond
+	defaultMouseGestureMaxDuration = 2 * time.Second
+	defaultFlickMaxDuration        = 250 * time.Millisecond
+)
+
+// 認識の前にストロークを揃える点の数
+const mouseGestureResamplePoints = 64
+
+// ストロークが動いたとみなす最小の移動量 (px)
+const mouseGestureJitter = 2.0
+
+// 縦横の短い方がこの割合以下のストロークは、縦横比を保ったまま大きさを揃える
+const mouseGestureLineRatio = 0.3
+
+// 回転を無視するテンプレートで最もよく重なる角度を探す範囲と精度
+const (
+	mouseGestureAngleRange     = math.Pi / 4
+	mouseGestureAnglePrecision = math.Pi / 90
+)
+
+// GesturePoint はストロークやテンプレートの点
+// 座標はスクリーンと同じく右が +X、下が +Y
+type GesturePoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// GestureTemplate はマウスジェスチャーの形
+// 同じ名前のテンプレートを複数定義すると、どれかに当てはまればその名前で認識される
+type GestureTemplate struct {
+	Name   string         `json:"name"`
+	Points []GesturePoint `json:"points"`
+	// 向きを無視して形だけで比べる (円など、描き始める位置が決まらないもの)
+	RotationInvariant bool `json:"rotationInvariant,omitempty"`
+	// この時間以内に描かれたストロークにだけ当てはまる (フリックなど)
+	MaxDurationMs int `json:"maxDurationMs,omitempty"`
+}
+
+// MouseGestureConfig は mousegestures.json の内容
+// 省略した項目は既定値のまま使われる
+type MouseGestureConfig struct {
+	// ストロークを記録する間押し続ける修飾キー (空にすると常に記録する)
+	Modifiers []string `json:"modifiers"`
+	// テンプレートに当てはまったとみなす一致度 (0〜1)
+	Threshold float64 `json:"threshold"`
+	// ジェスチャーとして扱うストロークの最小の長さ (px)
+	MinDistance float64 `json:"minDistance"`
+	// カーソルがこの時間止まったらストロークの終わりとみなす
+	IdleMs int `json:"idleMs"`
+	// これより長くかかったストロークは無視する
+	MaxDurationMs int `json:"maxDurationMs"`
+	// 組み込みのテンプレートに追加するテンプレート
+	// 組み込みと同じ名前のものは組み込みを置き換える
+	Templates []GestureTemplate `json:"templates,omitempty"`
+}
+
+// GestureEvent は gesture-event のペイロード
+type GestureEvent struct {
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+	// 描き始めから描き終わりまでの時間
+	DurationMs int64         `json:"durationMs"`
+	Start      MousePosition `json:"start"`
+	End        MousePosition `json:"end"`
+}
+
+// GestureMatch はストロークを認識した結果
+type GestureMatch struct {
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+}
+
+// defaultMouseGestureConfig は設定ファイルがない場合の設定 (Control を押しながら描く)
+func defaultMouseGestureConfig() MouseGestureConfig {
+	return MouseGestureConfig{
+		Modifiers:     []string{"control"},
+		Threshold:     defaultMouseGestureThreshold,
+		MinDistance:   defaultMouseGestureMinDistance,
+		IdleMs:        int(defaultMouseGestureIdle / time.Millisecond),
+		MaxDurationMs: int(defaultMouseGestureMaxDuration / time.Millisecond),
+	}
+}
+
+// builtinGestureTemplates は組み込みのテンプレート (L字、円、ジグザグ、上下左右のフリック)
+func builtinGestureTemplates() []GestureTemplate {
+	flick := int(defaultFlickMaxDuration / time.Millisecond)
+	zigzag := []GesturePoint{{0, 0}, {25, 50}, {50, 0}, {75, 50}, {100, 0}}
+
+	return []GestureTemplate{
+		{Name: "L", Points: []GesturePoint{{0, 0}, {0, 100}, {60, 100}}},
+		{Name: "circle", Points: circlePoints(false), RotationInvariant: true},
+		{Name: "circle", Points: circlePoints(true), RotationInvariant: true},
+		{Name: "zigzag", Points: zigzag},
+		{Name: "zigzag", Points: reversePoints(zigzag)},
+		{Name: "flick-right", Points: []GesturePoint{{0, 0}, {100, 0}}, MaxDurationMs: flick},
+		{Name: "flick-left", Points: []GesturePoint{{100, 0}, {0, 0}}, MaxDurationMs: flick},
+		{Name: "flick-up", Points: []GesturePoint{{0, 100}, {0, 0}}, MaxDurationMs: flick},
+		{Name: "flick-down", Points: []GesturePoint{{0, 0}, {0, 100}}, MaxDurationMs: flick},
+	}
+}
+
+// circlePoints は上から描き始める円の点を返す (画面上で時計回り、counterclockwise なら反時計回り)
+func circlePoints(counterclockwise bool) []GesturePoint {
+	const steps = 32
+	points := make([]GesturePoint, 0, steps+1)
+	for i := 0; i <= steps; i++ {
+		angle := 2 * math.Pi * float64(i) / steps
+		if counterclockwise {
+			angle = -angle
+		}
+		angle -= math.Pi / 2
+		points = append(points, GesturePoint{X: 50 + 50*math.Cos(angle), Y: 50 + 50*math.Sin(angle)})
+	}
+	return points
+}
+
+func reversePoints(points []GesturePoint) []GesturePoint {
+	reversed := make([]GesturePoint, len(points))
+	for i, p := range points {
+		reversed[len(points)-1-i] = p
+	}
+	return reversed
+}
+
+// loadMouseGestureConfig は設定ファイルからマウスジェスチャーの設定を読み込む
+// ファイルがない場合や読み込めない場合は既定の設定を返す
+func loadMouseGestureConfig() (MouseGestureConfig, error) {
+	config := defaultMouseGestureConfig()
+	if _, err := readConfigFile(mouseGestureConfigFile, &config); err != nil {
+		return defaultMouseGestureConfig(), err
+	}
+	return config, nil
+}
+
+// mergeGestureTemplates は組み込みのテンプレートに templates を加える
+// templates と同じ名前の組み込みのテンプレートは使わない
+func mergeGestureTemplates(templates []GestureTemplate) []GestureTemplate {
+	overridden := make(map[string]bool)
+	for _, t := range templates {
+		overridden[t.Name] = true
+	}
+
+	var merged []GestureTemplate
+	for _, t := range builtinGestureTemplates() {
+		if !overridden[t.Name] {
+			merged = append(merged, t)
+		}
+	}
+	return append(merged, templates...)
+}
+
+// compiledTemplate は比較用に正規化したテンプレート
+type compiledTemplate struct {
+	template GestureTemplate
+	points   []GesturePoint
+}
+
+// MouseGestureRecognizer は記録済みの点列をテンプレートと比べて認識する
+// 点列は一定の数に打ち直し、大きさと位置を揃えてから各点の平均距離で比べる
+type MouseGestureRecognizer struct {
+	templates []compiledTemplate
+	threshold float64
+}
+
+// NewMouseGestureRecognizer は templates を認識する MouseGestureRecognizer を生成する
+// 不正なテンプレートは除外し、その理由をまとめたエラーを返す
+func NewMouseGestureRecognizer(templates []GestureTemplate, threshold float64) (*MouseGestureRecognizer, error) {
+	r := &MouseGestureRecognizer{threshold: threshold}
+
+	var errs []error
+	for i, t := range templates {
+		if err := t.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("template #%d (%s): %w", i+1, t.Name, err))
+			continue
+		}
+		r.templates = append(r.templates, compiledTemplate{
+			template: t,
+			points:   normalizeStroke(t.Points, t.RotationInvariant),
+		})
+	}
+	return r, errors.Join(errs...)
+}
+
+// validate はテンプレートを検証する
+func (t GestureTemplate) validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return errors.New("name is empty")
+	}
+	if len(t.Points) < 2 {
+		return errors.New("template needs at least 2 points")
+	}
+	if pathLength(t.Points) == 0 {
+		return errors.New("template has no length")
+	}
+	if t.MaxDurationMs < 0 {
+		return errors.New("maxDurationMs must not be negative")
+	}
+	return nil
+}
+
+// Recognize は duration かけて描かれた points に最もよく当てはまるテンプレートを返す
+// 一致度がしきい値に届かない場合は false を返す
+func (r *MouseGestureRecognizer) Recognize(points []GesturePoint, duration time.Duration) (GestureMatch, bool) {
+	if len(points) < 2 || pathLength(points) == 0 {
+		return GestureMatch{}, false
+	}
+
+	// テンプレートの種類に合わせて、向きを揃えたものと揃えないものを用意する
+	candidate := normalizeStroke(points, false)
+	rotated := normalizeStroke(points, true)
+
+	var best GestureMatch
+	for _, t := range r.templates {
+		if t.template.MaxDurationMs > 0 && duration > time.Duration(t.template.MaxDurationMs)*time.Millisecond {
+			continue
+		}
+
+		var distance float64
+		if t.template.RotationInvariant {
+			distance = distanceAtBestAngle(rotated, t.points)
+		} else {
+			distance = strokeDistance(candidate, t.points)
+		}
+
+		score := 1 - distance
+		if score > best.Score {
+			best = GestureMatch{Name: t.template.Name, Score: score}
+		}
+	}
+
+	if best.Name == "" || best.Score < r.threshold {
+		return best, false
+	}
+	return best, true
+}
+
+// normalizeStroke は点列を等間隔の点に打ち直し、大きさを 1 に揃えて重心を原点に移す
+// rotate が true の場合は重心から始点への向きが 0 になるよう回転させる
+func normalizeStroke(points []GesturePoint, rotate bool) []GesturePoint {
+	normalized := resample(points, mouseGestureResamplePoints)
+	if rotate {
+		c := centroid(normalized)
+		first := normalized[0]
+		normalized = rotateBy(normalized, -math.Atan2(first.Y-c.Y, first.X-c.X))
+	}
+	normalized = scaleToUnit(normalized)
+	return translateToOrigin(normalized)
+}
+
+// resample は点列を経路に沿って等間隔の n 点に打ち直す
+func resample(points []GesturePoint, n int) []GesturePoint {
+	interval := pathLength(points) / float64(n-1)
+	resampled := make([]GesturePoint, 0, n)
+	resampled = append(resampled, points[0])
+
+	src := append([]GesturePoint(nil), points...)
+	var acc float64
+	for i := 1; i < len(src); i++ {
+		d := distance(src[i-1], src[i])
+		if acc+d >= interval && d > 0 {
+			t := (interval - acc) / d
+			q := GesturePoint{
+				X: src[i-1].X + t*(src[i].X-src[i-1].X),
+				Y: src[i-1].Y + t*(src[i].Y-src[i-1].Y),
+			}
+			resampled = append(resampled, q)
+			// q を次の区間の始点として残りを続ける
+			src = append(src[:i], append([]GesturePoint{q}, src[i:]...)...)
+			acc = 0
+		} else {
+			acc += d
+		}
+	}
+
+	// 丸め誤差で足りない場合は終点で埋める
+	for len(resampled) < n {
+		resampled = append(resampled, points[len(points)-1])
+	}
+	return resampled[:n]
+}
+
+// scaleToUnit は縦横それぞれが 1 になるよう拡大縮小する
+// 細長いストローク (フリックなど) は直線がつぶれないよう縦横比を保って長い方の辺を 1 にする
+func scaleToUnit(points []GesturePoint) []GesturePoint {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range points {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+
+	width, height := maxX-minX, maxY-minY
+	size := math.Max(width, height)
+	if size == 0 {
+		return points
+	}
+	if math.Min(width, height)/size <= mouseGestureLineRatio {
+		width, height = size, size
+	}
+
+	scaled := make([]GesturePoint, len(points))
+	for i, p := range points {
+		scaled[i] = GesturePoint{X: p.X / width, Y: p.Y / height}
+	}
+	return scaled
+}
+
+func translateToOrigin(points []GesturePoint) []GesturePoint {
+	c := centroid(points)
+	translated := make([]GesturePoint, len(points))
+	for i, p := range points {
+		translated[i] = GesturePoint{X: p.X - c.X, Y: p.Y - c.Y}
+	}
+	return translated
+}
+
+func rotateBy(points []GesturePoint, angle float64) []GesturePoint {
+	c := centroid(points)
+	cos, sin := math.Cos(angle), math.Sin(angle)
+	rotated := make([]GesturePoint, len(points))
+	for i, p := range points {
+		dx, dy := p.X-c.X, p.Y-c.Y
+		rotated[i] = GesturePoint{X: dx*cos - dy*sin + c.X, Y: dx*sin + dy*cos + c.Y}
+	}
+	return rotated
+}
+
+// distanceAtBestAngle は candidate を ±45° の範囲で回転させたときの最小の距離を黄金分割探索で求める
+func distanceAtBestAngle(candidate, template []GesturePoint) float64 {
+	phi := (math.Sqrt(5) - 1) / 2
+	a, b := -mouseGestureAngleRange, mouseGestureAngleRange
+
+	x1 := phi*a + (1-phi)*b
+	f1 := strokeDistance(rotateBy(candidate, x1), template)
+	x2 := (1-phi)*a + phi*b
+	f2 := strokeDistance(rotateBy(candidate, x2), template)
+
+	for math.Abs(b-a) > mouseGestureAnglePrecision {
+		if f1 < f2 {
+			b, x2, f2 = x2, x1, f1
+			x1 = phi*a + (1-phi)*b
+			f1 = strokeDistance(rotateBy(candidate, x1), template)
+		} else {
+			a, x1, f1 = x1, x2, f2
+			x2 = (1-phi)*a + phi*b
+			f2 = strokeDistance(rotateBy(candidate, x2), template)
+		}
+	}
+	return math.Min(f1, f2)
+}
+
+// strokeDistance は正規化済みのストロークどうしの違いを 0〜1 で返す
+// 対応する点の位置の違いと、各点での進む向きの違いを同じ重みで足し合わせる
+func strokeDistance(a, b []GesturePoint) float64 {
+	// 正規化後の大きさは 1 なので、半対角線の長さで割って 0〜1 にする
+	position := pathDistance(a, b) / (0.5 * math.Sqrt2)
+	return (math.Min(position, 1) + directionDistance(a, b)) / 2
+}
+
+// directionDistance は対応する区間の向きの違いの平均を返す (同じ向きで 0、逆向きで 1)
+func directionDistance(a, b []GesturePoint) float64 {
+	var sum float64
+	for i := 1; i < len(a); i++ {
+		ax, ay := a[i].X-a[i-1].X, a[i].Y-a[i-1].Y
+		bx, by := b[i].X-b[i-1].X, b[i].Y-b[i-1].Y
+		la, lb := math.Hypot(ax, ay), math.Hypot(bx, by)
+		if la == 0 || lb == 0 {
+			continue
+		}
+		sum += (1 - (ax*bx+ay*by)/(la*lb)) / 2
+	}
+	return sum / float64(len(a)-1)
+}
+
+// pathDistance は対応する点どうしの距離の平均を返す
+func pathDistance(a, b []GesturePoint) float64 {
+	var sum float64
+	for i := range a {
+		sum += distance(a[i], b[i])
+	}
+	return sum / float64(len(a))
+}
+
+func pathLength(points []GesturePoint) float64 {
+	var length float64
+	for i := 1; i < len(points); i++ {
+		length += distance(points[i-1], points[i])
+	}
+	return length
+}
+
+func centroid(points []GesturePoint) GesturePoint {
+	var c GesturePoint
+	for _, p := range points {
+		c.X += p.X
+		c.Y += p.Y
+	}
+	n := float64(len(points))
+	return GesturePoint{X: c.X / n, Y: c.Y / n}
+}
+
+func distance(a, b GesturePoint) float64 {
+	return math.Hypot(b.X-a.X, b.Y-a.Y)
+}
+
+// strokeSample はストロークの記録中に受け取ったカーソル位置
+type strokeSample struct {
+	point GesturePoint
+	at    time.Time
+}
+
+// MouseGestureTracker はカーソル位置の定期的なサンプルをストロークに区切って認識する
+// 修飾キーが設定されている場合は、それを押している間だけストロークを記録する
+type MouseGestureTracker struct {
+	mu         sync.Mutex
+	config     MouseGestureConfig
+	modifiers  HotkeyModifiers
+	recognizer *MouseGestureRecognizer
+
+	// 修飾キーが押されているか (修飾キーが設定されていない場合は常に true)
+	recording bool
+	stroke    []strokeSample
+	// ストロークの最後にカーソルが動いた時刻
+	lastMove time.Time
+}
+
+// NewMouseGestureTracker は config に従ってストロークを認識する MouseGestureTracker を生成する
+// 不正な設定やテンプレートがあった場合も、有効な部分だけで動く MouseGestureTracker とエラーを返す
+func NewMouseGestureTracker(config MouseGestureConfig) (*MouseGestureTracker, error) {
+	t := &MouseGestureTracker{}
+	err := t.SetConfig(config)
+	return t, err
+}
+
+// SetConfig は設定を置き換え、記録中のストロークを破棄する
+// 不正な設定値は既定値に、不正なテンプレートは除外したうえでエラーを返す
+func (t *MouseGestureTracker) SetConfig(config MouseGestureConfig) error {
+	var errs []error
+	defaults := defaultMouseGestureConfig()
+
+	modifiers, err := parseHotkeyModifiers(config.Modifiers)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("modifiers: %w", err))
+		config.Modifiers = defaults.Modifiers
+		modifiers, _ = parseHotkeyModifiers(defaults.Modifiers)
+	}
+	if config.Threshold < 0 || config.Threshold > 1 {
+		errs = append(errs, fmt.Errorf("threshold must be between 0 and 1, got %v", config.Threshold))
+		config.Threshold = defaults.Threshold
+	}
+	if config.Threshold == 0 {
+		config.Threshold = defaults.Threshold
+	}
+	if config.MinDistance <= 0 {
+		config.MinDistance = defaults.MinDistance
+	}
+	if config.IdleMs <= 0 {
+		config.IdleMs = defaults.IdleMs
+	}
+	if config.MaxDurationMs <= 0 {
+		config.MaxDurationMs = defaults.MaxDurationMs
+	}
+
+	recognizer, err := NewMouseGestureRecognizer(mergeGestureTemplates(config.Templates), config.Threshold)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.config = config
+	t.modifiers = modifiers
+	t.recognizer = recognizer
+	t.recording = modifiers == 0
+	t.stroke = nil
+	return errors.Join(errs...)
+}
+
+// Config は使用中の設定を返す
+// Templates には組み込みのものを含めた、認識に使うすべてのテンプレートが入る
+func (t *MouseGestureTracker) Config() MouseGestureConfig {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	config := t.config
+	config.Modifiers = append([]string(nil), t.config.Modifiers...)
+	config.Templates = make([]GestureTemplate, len(t.recognizer.templates))
+	for i, compiled := range t.recognizer.templates {
+		config.Templates[i] = compiled.template
+	}
+	return config
+}
+
+// Recognize は記録済みの点列を使用中のテンプレートで認識する
+func (t *MouseGestureTracker) Recognize(points []GesturePoint, duration time.Duration) (GestureMatch, bool) {
+	t.mu.Lock()
+	recognizer := t.recognizer
+	t.mu.Unlock()
+	return recognizer.Recognize(points, duration)
+}
+
+// FeedKeys はカンマ区切りのキー状態 (KeyStateCallback と同じ形式) を受け取る
+// 修飾キーが離されたら記録中のストロークを認識する
+func (t *MouseGestureTracker) FeedKeys(keys string, at time.Time) (GestureEvent, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.modifiers == 0 {
+		return GestureEvent{}, false
+	}
+
+	held := pressedModifiers(keys)&t.modifiers == t.modifiers
+	if held == t.recording {
+		return GestureEvent{}, false
+	}
+
+	t.recording = held
+	if held {
+		t.stroke = nil
+		return GestureEvent{}, false
+	}
+	return t.finishLocked()
+}
+
+// FeedPosition は時刻 at のカーソル位置を受け取る
+// カーソルが止まってストロークが終わったら認識する
+func (t *MouseGestureTracker) FeedPosition(pos MousePosition, at time.Time) (GestureEvent, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.recording {
+		return GestureEvent{}, false
+	}
+
+	point := GesturePoint{X: pos.X, Y: pos.Y}
+	if len(t.stroke) == 0 {
+		t.stroke = []strokeSample{{point: point, at: at}}
+		return GestureEvent{}, false
+	}
+
+	last := t.stroke[len(t.stroke)-1]
+	if distance(last.point, point) < mouseGestureJitter {
+		if len(t.stroke) == 1 {
+			// 動き出すまでは描き始めの時刻を進めておく
+			t.stroke[0].at = at
+			return GestureEvent{}, false
+		}
+		if at.Sub(t.lastMove) >= time.Duration(t.config.IdleMs)*time.Millisecond {
+			event, ok := t.finishLocked()
+			t.stroke = []strokeSample{{point: point, at: at}}
+			return event, ok
+		}
+		return GestureEvent{}, false
+	}
+
+	t.stroke = append(t.stroke, strokeSample{point: point, at: at})
+	t.lastMove = at
+
+	// 長すぎるストロークはジェスチャーではないとみなして、ここから記録し直す
+	if at.Sub(t.stroke[0].at) > time.Duration(t.config.MaxDurationMs)*time.Millisecond {
+		t.stroke = []strokeSample{{point: point, at: at}}
+	}
+	return GestureEvent{}, false
+}
+
+// finishLocked は記録中のストロークを認識して破棄する
+func (t *MouseGestureTracker) finishLocked() (GestureEvent, bool) {
+	stroke := t.stroke
+	t.stroke = nil
+	if len(stroke) < 2 {
+		return GestureEvent{}, false
+	}
+
+	points := make([]GesturePoint, len(stroke))
+	for i, s := range stroke {
+		points[i] = s.point
+	}
+	if pathLength(points) < t.config.MinDistance {
+		return GestureEvent{}, false
+	}
+
+	first, last := stroke[0], stroke[len(stroke)-1]
+	duration := last.at.Sub(first.at)
+	if duration > time.Duration(t.config.MaxDurationMs)*time.Millisecond {
+		return GestureEvent{}, false
+	}
+
+	match, ok := t.recognizer.Recognize(points, duration)
+	if !ok {
+		return GestureEvent{}, false
+	}
+	return GestureEvent{
+		Name:       match.Name,
+		Score:      match.Score,
+		DurationMs: duration.Milliseconds(),
+		Start:      MousePosition{X: first.point.X, Y: first.point.Y},
+		End:        MousePosition{X: last.point.X, Y: last.point.Y},
+	}, true
+}
+
+// pressedModifiers はキー状態に含まれる修飾キーを返す (左右は区別しない)
+func pressedModifiers(keys string) HotkeyModifiers {
+	var mods HotkeyModifiers
+	for _, key := range strings.Split(keys, ",") {
+		name, ok := keyGestureLogicalNames[strings.TrimSpace(key)]
+		if !ok {
+			continue
+		}
+		mods |= hotkeyModifierAliases[name]
+	}
+	return mods
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mouseGestureFixture は testdata/mousegestures に記録したストローク
+// カーソル位置を intervalMs ごとにサンプルしたもので、gesture が空なら何にも当てはまらない
+type mouseGestureFixture struct {
+	Gesture    string         `json:"gesture"`
+	IntervalMs int            `json:"intervalMs"`
+	Points     []GesturePoint `json:"points"`
+}
+
+func loadMouseGestureFixtures(t *testing.T) map[string]mouseGestureFixture {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join("testdata", "mousegestures", "*.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no fixtures: %v", err)
+	}
+
+	fixtures := make(map[string]mouseGestureFixture, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fixture mouseGestureFixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		fixtures[strings.TrimSuffix(filepath.Base(path), ".json")] = fixture
+	}
+	return fixtures
+}
+
+// replayStroke は fixture の点を時刻つきで tracker に送る
+// 修飾キーが設定されていれば押している間に描き、そうでなければ止まったところで区切る
+func replayStroke(tracker *MouseGestureTracker, fixture mouseGestureFixture, holdModifier bool) (GestureEvent, bool) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	step := time.Duration(fixture.IntervalMs) * time.Millisecond
+
+	if holdModifier {
+		tracker.FeedKeys("lcontrol", at)
+	}
+	for _, p := range fixture.Points {
+		at = at.Add(step)
+		if event, ok := tracker.FeedPosition(MousePosition{X: p.X, Y: p.Y}, at); ok {
+			return event, true
+		}
+	}
+	if holdModifier {
+		return tracker.FeedKeys("", at.Add(step))
+	}
+
+	// カーソルを止めてストロークを終わらせる
+	last := fixture.Points[len(fixture.Points)-1]
+	for i := 0; i < 20; i++ {
+		at = at.Add(step)
+		if event, ok := tracker.FeedPosition(MousePosition{X: last.X, Y: last.Y}, at); ok {
+			return event, true
+		}
+	}
+	return GestureEvent{}, false
+}
+
+func TestMouseGestureFixtures(t *testing.T) {
+	modes := []struct {
+		name      string
+		modifiers []string
+	}{
+		{"modifier", []string{"control"}},
+		{"idle", []string{}},
+	}
+
+	for name, fixture := range loadMouseGestureFixtures(t) {
+		for _, mode := range modes {
+			t.Run(name+"/"+mode.name, func(t *testing.T) {
+				config := defaultMouseGestureConfig()
+				config.Modifiers = mode.modifiers
+				tracker, err := NewMouseGestureTracker(config)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				event, ok := replayStroke(tracker, fixture, len(mode.modifiers) > 0)
+				if fixture.Gesture == "" {
+					if ok {
+						t.Errorf("recognized %q (score %.2f), want nothing", event.Name, event.Score)
+					}
+					return
+				}
+				if !ok {
+					t.Fatalf("not recognized, want %q", fixture.Gesture)
+				}
+				if event.Name != fixture.Gesture {
+					t.Errorf("recognized %q (score %.2f), want %q", event.Name, event.Score, fixture.Gesture)
+				}
+				if first := fixture.Points[0]; event.Start.X != first.X || event.Start.Y != first.Y {
+					t.Errorf("start = %+v, want %+v", event.Start, first)
+				}
+			})
+		}
+	}
+}
+
+func TestMouseGestureTrackerIgnoresStrokes(t *testing.T) {
+	fixtures := loadMouseGestureFixtures(t)
+	circle := fixtures["circle-clockwise"]
+
+	tests := []struct {
+		name   string
+		config func(*MouseGestureConfig)
+		hold   bool
+	}{
+		{
+			name: "modifier not held",
+		},
+		{
+			name:   "shorter than the minimum distance",
+			config: func(c *MouseGestureConfig) { c.MinDistance = 10000 },
+			hold:   true,
+		},
+		{
+			name:   "slower than the maximum duration",
+			config: func(c *MouseGestureConfig) { c.MaxDurationMs = 100 },
+			hold:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := defaultMouseGestureConfig()
+			if tt.config != nil {
+				tt.config(&config)
+			}
+			tracker, err := NewMouseGestureTracker(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.hold {
+				if event, ok := replayStroke(tracker, circle, true); ok {
+					t.Errorf("recognized %q", event.Name)
+				}
+				return
+			}
+			// 修飾キーを押していなければ記録しない
+			at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			for i, p := range circle.Points {
+				if event, ok := tracker.FeedPosition(MousePosition{X: p.X, Y: p.Y}, at.Add(time.Duration(i)*20*time.Millisecond)); ok {
+					t.Fatalf("recognized %q", event.Name)
+				}
+			}
+			if event, ok := tracker.FeedKeys("", at.Add(time.Second)); ok {
+				t.Errorf("recognized %q", event.Name)
+			}
+		})
+	}
+}
+
+func TestMouseGestureUserTemplates(t *testing.T) {
+	fixtures := loadMouseGestureFixtures(t)
+
+	tests := []struct {
+		name      string
+		templates []GestureTemplate
+		fixture   string
+		want      string
+		err       string
+	}{
+		{
+			name:      "user template replaces the builtin one",
+			templates: []GestureTemplate{{Name: "L", Points: []GesturePoint{{0, 0}, {100, 0}, {100, 100}}}},
+			fixture:   "l-shape",
+			want:      "",
+		},
+		{
+			// 記録したストロークと同じ縦横比なので、組み込みの L よりよく当てはまる
+			name:      "user template is recognized",
+			templates: []GestureTemplate{{Name: "corner", Points: []GesturePoint{{0, 0}, {0, 180}, {110, 180}}}},
+			fixture:   "l-shape",
+			want:      "corner",
+		},
+		{
+			name: "invalid templates are skipped",
+			templates: []GestureTemplate{
+				{Name: "", Points: []GesturePoint{{0, 0}, {1, 1}}},
+				{Name: "dot", Points: []GesturePoint{{5, 5}, {5, 5}}},
+			},
+			fixture: "zigzag",
+			want:    "zigzag",
+			err:     "template has no length",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := defaultMouseGestureConfig()
+			config.Templates = tt.templates
+			tracker, err := NewMouseGestureTracker(config)
+			if tt.err == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("error = %v, want %q", err, tt.err)
+			}
+
+			event, ok := replayStroke(tracker, fixtures[tt.fixture], true)
+			if tt.want == "" {
+				// 組み込みの L が残っていれば L と認識される
+				if ok && event.Name == "L" {
+					t.Errorf("builtin template was used: %q", event.Name)
+				}
+				return
+			}
+			if !ok || event.Name != tt.want {
+				t.Errorf("recognized %q (%v), want %q", event.Name, ok, tt.want)
+			}
+		})
+	}
+}
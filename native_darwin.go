@@ -0,0 +1,23 @@
+//go:build darwin
+
+package main
+
+// macOSのネイティブ実装 (Objective-C) をビルドに含める
+// //export を含むファイルでは定義を書けないため、実装の取り込みはこのファイルに分離している
+
+/*
+#cgo CFLAGS: -x objective-c -I${SRCDIR}/backend/darwin/utils -I${SRCDIR}/backend/darwin/mouse -I${SRCDIR}/backend/darwin/keyboard -I${SRCDIR}/backend/darwin/window -I${SRCDIR}/backend/darwin/logger
+#cgo LDFLAGS: -framework Cocoa -framework Carbon
+#include "utils.h"
+#include "mouse.h"
+#include "keyboard.h"
+#include "window.h"
+#include "logger.h"
+
+#include "backend/darwin/utils/utils.m"
+#include "backend/darwin/mouse/mouse.m"
+#include "backend/darwin/keyboard/keyboard.m"
+#include "backend/darwin/window/window.m"
+#include "backend/darwin/logger/logger.m"
+*/
+import "C"
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// プラグインへの許可を保存する設定ファイル
+const permissionConfigFile = "permissions.json"
+
+// Permission はプラグインがマニフェストの permissions で宣言する権限
+type Permission string
+
+const (
+	PermissionClipboardRead  Permission = "clipboard.read"
+	PermissionClipboardWrite Permission = "clipboard.write"
+	PermissionKeyboard       Permission = "keyboard.simulate"
+	PermissionScreenCapture  Permission = "screen.capture"
+	PermissionMemoOpen       Permission = "memo.open"
+	PermissionFSRead         Permission = "fs.read"
+	PermissionFSWrite        Permission = "fs.write"
+)
+
+// 権限の説明 (許可を求めるダイアログに表示する)
+var permissionDescriptions = map[Permission]string{
+	PermissionClipboardRead:  "read the clipboard",
+	PermissionClipboardWrite: "write to the clipboard",
+	PermissionKeyboard:       "type keys into other applications",
+	PermissionScreenCapture:  "take screenshots",
+	PermissionMemoOpen:       "open the memo app",
+	PermissionFSRead:         "read files in its own install and data directories",
+	PermissionFSWrite:        "write files in its own data directory",
+}
+
+// ErrPermissionDenied はプラグインに権限がないことを表す
+var ErrPermissionDenied = errors.New("permission denied")
+
+// PermissionRequest は初めて権限を使うプラグインについてユーザーに確認する内容
+type PermissionRequest struct {
+	PluginID   string
+	PluginName string
+	Permission Permission
+}
+
+// PermissionGrant は保存されている許可・拒否
+type PermissionGrant struct {
+	PluginID   string     `json:"pluginId"`
+	Permission Permission `json:"permission"`
+	Granted    bool       `json:"granted"`
+}
+
+// permissionConfig は permissions.json の内容 (プラグインID → 権限 → 許可したか)
+type permissionConfig struct {
+	Plugins map[string]map[Permission]bool `json:"plugins"`
+}
+
+// PermissionManager はプラグインの権限を確認し、初めて使うときにユーザーの許可を求めて結果を保存する
+type PermissionManager struct {
+	mu     sync.Mutex
+	loaded bool
+	grants map[string]map[Permission]bool
+
+	// 同じ確認を何度も表示しないよう、確認は一度に一つずつ行う
+	promptMu sync.Mutex
+	// ユーザーに許可を求める (許可されたら true)
+	prompt func(request PermissionRequest) (bool, error)
+}
+
+// NewPermissionManager は prompt で許可を求める PermissionManager を生成する
+func NewPermissionManager(prompt func(request PermissionRequest) (bool, error)) *PermissionManager {
+	return &PermissionManager{prompt: prompt}
+}
+
+// Check は plugin が permission を使えるかを確認する
+// マニフェストで宣言していない権限は使えず、宣言していても保存された許可がなければユーザーに求める
+func (m *PermissionManager) Check(plugin PluginRecord, permission Permission) error {
+	if !plugin.Valid {
+		return fmt.Errorf("%w: plugin %q is not valid", ErrPermissionDenied, plugin.ID)
+	}
+	if !slices.Contains(plugin.Manifest.Permissions, string(permission)) {
+		return fmt.Errorf("%w: plugin %q does not declare %q in its manifest", ErrPermissionDenied, plugin.ID, permission)
+	}
+
+	if granted, decided := m.grant(plugin.ID, permission); decided {
+		return permissionResult(plugin.ID, permission, granted)
+	}
+
+	m.promptMu.Lock()
+	defer m.promptMu.Unlock()
+	// 待っている間に同じ確認が済んでいればその結果を使う
+	if granted, decided := m.grant(plugin.ID, permission); decided {
+		return permissionResult(plugin.ID, permission, granted)
+	}
+
+	granted, err := m.prompt(PermissionRequest{PluginID: plugin.ID, PluginName: plugin.Manifest.Name, Permission: permission})
+	if err != nil {
+		return fmt.Errorf("%w: failed to ask for %q for plugin %q: %v", ErrPermissionDenied, permission, plugin.ID, err)
+	}
+	if err := m.set(plugin.ID, permission, granted); err != nil {
+		fmt.Printf("Error saving permission %s for plugin %s: %v\n", permission, plugin.ID, err)
+	}
+	return permissionResult(plugin.ID, permission, granted)
+}
+
+func permissionResult(pluginID string, permission Permission, granted bool) error {
+	if !granted {
+		return fmt.Errorf("%w: %q was not granted to plugin %q", ErrPermissionDenied, permission, pluginID)
+	}
+	return nil
+}
+
+// Grants は保存されている許可・拒否をプラグインIDと権限の順に返す
+func (m *PermissionManager) Grants() []PermissionGrant {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ensureLoaded()
+
+	grants := []PermissionGrant{}
+	for pluginID, permissions := range m.grants {
+		for permission, granted := range permissions {
+			grants = append(grants, PermissionGrant{PluginID: pluginID, Permission: permission, Granted: granted})
+		}
+	}
+	sort.Slice(grants, func(i, j int) bool {
+		if grants[i].PluginID != grants[j].PluginID {
+			return grants[i].PluginID < grants[j].PluginID
+		}
+		return grants[i].Permission < grants[j].Permission
+	})
+	return grants
+}
+
+// Revoke は保存されている許可・拒否を取り消す (次に使うときに改めて確認する)
+func (m *PermissionManager) Revoke(pluginID string, permission Permission) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ensureLoaded()
+
+	if _, ok := m.grants[pluginID][permission]; !ok {
+		return fmt.Errorf("no saved decision for %q of plugin %q", permission, pluginID)
+	}
+	delete(m.grants[pluginID], permission)
+	if len(m.grants[pluginID]) == 0 {
+		delete(m.grants, pluginID)
+	}
+	return m.save()
+}
+
+// Clear は pluginID のプラグインについて保存されている許可・拒否をすべて取り消す
+func (m *PermissionManager) Clear(pluginID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ensureLoaded()
+
+	if _, ok := m.grants[pluginID]; !ok {
+		return nil
+	}
+	delete(m.grants, pluginID)
+	return m.save()
+}
+
+func (m *PermissionManager) grant(pluginID string, permission Permission) (granted, decided bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ensureLoaded()
+
+	granted, decided = m.grants[pluginID][permission]
+	return granted, decided
+}
+
+func (m *PermissionManager) set(pluginID string, permission Permission, granted bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ensureLoaded()
+
+	if m.grants[pluginID] == nil {
+		m.grants[pluginID] = make(map[Permission]bool)
+	}
+	m.grants[pluginID][permission] = granted
+	return m.save()
+}
+
+// ensureLoaded は初めて参照されたときに設定ファイルを読み込む (m.mu を持って呼ぶ)
+func (m *PermissionManager) ensureLoaded() {
+	if m.loaded {
+		return
+	}
+	m.loaded = true
+
+	var config permissionConfig
+	if _, err := readConfigFile(permissionConfigFile, &config); err != nil {
+		// 読めない場合は何も許可していないものとして扱う
+		fmt.Printf("Error loading plugin permissions: %v\n", err)
+	}
+	m.grants = config.Plugins
+	if m.grants == nil {
+		m.grants = make(map[string]map[Permission]bool)
+	}
+}
+
+// save は許可・拒否を設定ファイルに書き込む (m.mu を持って呼ぶ)
+func (m *PermissionManager) save() error {
+	return writeConfigFile(permissionConfigFile, permissionConfig{Plugins: m.grants})
+}
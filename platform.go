@@ -0,0 +1,81 @@
+package main
+
+import "errors"
+
+// errPlatformUnsupported はネイティブ実装が存在しない環境で返されるエラー
+var errPlatformUnsupported = errors.New("operation is not supported on this platform")
+
+// Platform はOS固有のネイティブ機能をまとめたインターフェース
+// App はこのインターフェースを通してのみネイティブ層にアクセスする
+type Platform interface {
+	MouseController
+	KeyboardController
+	ClipboardController
+	WindowController
+	ScreenController
+	HotkeyController
+}
+
+// MouseController はマウスカーソルとマウスイベントを扱う
+type MouseController interface {
+	// GetMousePosX はスクリーン左上を原点としたカーソルのX座標を返す
+	GetMousePosX() float64
+	// GetMousePosY はスクリーン左上を原点としたカーソルのY座標を返す
+	GetMousePosY() float64
+	// EnableMouseEvents はメインウィンドウがマウスイベントを受け取るようにする
+	EnableMouseEvents()
+	// DisableMouseEvents はメインウィンドウへのマウスイベントを透過させる
+	DisableMouseEvents()
+}
+
+// KeyboardController はキー入力のシミュレーションと監視を扱う
+type KeyboardController interface {
+	// SimulateKeyPresses はカンマ区切りのキー名 (例: "lcommand,c") を同時押しする
+	SimulateKeyPresses(keyString string)
+	// GetPressedKeys は現在押されているキーをカンマ区切りで返す
+	GetPressedKeys() string
+	// StartKeyMonitoring はキー状態の監視を開始し、変化があるたびに callback を呼び出す
+	StartKeyMonitoring(callback func(keys string)) error
+	// StopKeyMonitoring はキー状態の監視を停止する
+	StopKeyMonitoring()
+}
+
+// ClipboardController はクリップボードの読み書きを扱う
+type ClipboardController interface {
+	ReadClipboard() (string, error)
+	WriteClipboard(text string) error
+}
+
+// WindowController はメインウィンドウとアクティブアプリの管理を扱う
+type WindowController interface {
+	// SetupMainWindow はフローティングウィンドウの設定とホットキーを受け取る準備を行う
+	SetupMainWindow()
+	// ReturnFocusToPreviousWindow は直前にアクティブだったアプリにフォーカスを戻す
+	ReturnFocusToPreviousWindow()
+	// StopMonitoring はアクティブウィンドウの監視を停止する
+	StopMonitoring()
+	// GetLastGhostId はネイティブ側で最後に選択されたゴーストIDを返す
+	GetLastGhostId() string
+	// OpenMemoApp はOS標準のメモアプリを開く
+	OpenMemoApp() error
+}
+
+// ScreenController はスクリーン情報とキャプチャを扱う
+type ScreenController interface {
+	// GetScreenSize はメインスクリーンのサイズを返す
+	GetScreenSize() (width, height int)
+	// CaptureScreen は範囲選択式のスクリーンショットを path に保存する
+	CaptureScreen(path string) error
+}
+
+// HotkeyController はグローバルホットキーの登録と検出結果を扱う
+type HotkeyController interface {
+	// RegisterHotKey は modifiers + key をグローバルホットキーとして登録し、押されたときに id を通知する
+	// key は SimulateKeyPresses と同じキー名 (例: "1", "space")
+	RegisterHotKey(id int, modifiers HotkeyModifiers, key string) error
+	// UnregisterHotKey は id のグローバルホットキーの登録を解除する
+	UnregisterHotKey(id int)
+	// SetHotKeyHandler はホットキーが押されるたびに呼び出される handler を設定する (nil で解除)
+	// handler はネイティブ層のスレッドから呼び出される
+	SetHotKeyHandler(handler func(id int))
+}
@@ -0,0 +1,237 @@
+//go:build darwin
+
+package main
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa -framework Carbon
+#import <Cocoa/Cocoa.h>
+#import <Carbon/Carbon.h>
+void EnableMouseEvents();
+void DisableMouseEvents();
+void ReturnFocusToPreviousWindow();
+void SetupMainWindow(void);
+void StopMonitoring(void);
+const char* GetLastGhostId(void);
+void GetMainScreenSize(int *width, int *height);
+void SimulateKeyPresses(const char* keyString);
+char* GetPressedKeysString(void);
+void StartKeyMonitoring(const char* callbackName);
+void StopKeyMonitoring(void);
+double GetMousePosX(void);
+double GetMousePosY(void);
+int RegisterHotKeyWithID(int hotKeyID, UInt32 modifiers, const char* keyName);
+void UnregisterHotKeyWithID(int hotKeyID);
+void ClipboardSetText(const char* text);
+char* ClipboardGetText(void);
+void FreeMemory(void* ptr);
+*/
+import "C"
+import (
+	"fmt"
+	"os/exec"
+	"sync"
+	"unsafe"
+)
+
+// darwinPlatform は backend/darwin のObjective-C実装を呼び出す Platform
+type darwinPlatform struct{}
+
+func newPlatform() Platform {
+	return &darwinPlatform{}
+}
+
+func (p *darwinPlatform) GetMousePosX() float64 {
+	return float64(C.GetMousePosX())
+}
+
+func (p *darwinPlatform) GetMousePosY() float64 {
+	return float64(C.GetMousePosY())
+}
+
+func (p *darwinPlatform) EnableMouseEvents() {
+	C.EnableMouseEvents()
+}
+
+func (p *darwinPlatform) DisableMouseEvents() {
+	C.DisableMouseEvents()
+}
+
+func (p *darwinPlatform) SimulateKeyPresses(keyString string) {
+	cKeyString := C.CString(keyString)
+	defer C.free(unsafe.Pointer(cKeyString))
+	C.SimulateKeyPresses(cKeyString)
+}
+
+func (p *darwinPlatform) GetPressedKeys() string {
+	// C関数を呼び出して押されているキーの文字列を取得
+	cKeysString := C.GetPressedKeysString()
+	// 関数終了時にメモリを解放
+	defer C.free(unsafe.Pointer(cKeysString))
+
+	return C.GoString(cKeysString)
+}
+
+// キー状態の変化を受け取るハンドラ
+// cgoのコールバックからはインスタンスを辿れないため、パッケージ変数で保持する
+var (
+	keyStateHandlerMu sync.Mutex
+	keyStateHandler   func(keys string)
+
+	hotKeyHandlerMu sync.Mutex
+	hotKeyHandler   func(id int)
+)
+
+// HotKeyCallback は、登録したホットキーが押されるたびにC言語から呼び出されるコールバック関数
+//
+//export HotKeyCallback
+func HotKeyCallback(cHotKeyID C.int) {
+	hotKeyHandlerMu.Lock()
+	handler := hotKeyHandler
+	hotKeyHandlerMu.Unlock()
+
+	if handler != nil {
+		handler(int(cHotKeyID))
+	}
+}
+
+// KeyStateCallback は、キーの状態が変化したときにC言語から呼び出されるコールバック関数
+//
+//export KeyStateCallback
+func KeyStateCallback(cKeysString *C.char) {
+	// C文字列をGo文字列に変換
+	keysString := C.GoString(cKeysString)
+
+	keyStateHandlerMu.Lock()
+	handler := keyStateHandler
+	keyStateHandlerMu.Unlock()
+
+	if handler != nil {
+		handler(keysString)
+	}
+}
+
+func (p *darwinPlatform) StartKeyMonitoring(callback func(keys string)) error {
+	keyStateHandlerMu.Lock()
+	keyStateHandler = callback
+	keyStateHandlerMu.Unlock()
+
+	// コールバック関数名をC文字列に変換
+	cCallbackName := C.CString("KeyStateCallback")
+	defer C.free(unsafe.Pointer(cCallbackName))
+
+	// キー監視を開始
+	C.StartKeyMonitoring(cCallbackName)
+
+	return nil
+}
+
+func (p *darwinPlatform) StopKeyMonitoring() {
+	C.StopKeyMonitoring()
+
+	keyStateHandlerMu.Lock()
+	keyStateHandler = nil
+	keyStateHandlerMu.Unlock()
+}
+
+func (p *darwinPlatform) ReadClipboard() (string, error) {
+	// ネイティブAPIを呼び出す
+	cstr := C.ClipboardGetText()
+
+	// 関数終了時にメモリを解放
+	defer C.FreeMemory(unsafe.Pointer(cstr))
+
+	return C.GoString(cstr), nil
+}
+
+func (p *darwinPlatform) WriteClipboard(text string) error {
+	// 文字列をC文字列に変換
+	cText := C.CString(text)
+	// 関数終了時にメモリを解放
+	defer C.free(unsafe.Pointer(cText))
+
+	// ネイティブAPIでクリップボードに書き込み
+	C.ClipboardSetText(cText)
+
+	return nil
+}
+
+func (p *darwinPlatform) SetupMainWindow() {
+	C.SetupMainWindow()
+}
+
+func (p *darwinPlatform) ReturnFocusToPreviousWindow() {
+	C.ReturnFocusToPreviousWindow()
+}
+
+func (p *darwinPlatform) StopMonitoring() {
+	C.StopMonitoring()
+}
+
+func (p *darwinPlatform) GetLastGhostId() string {
+	return C.GoString(C.GetLastGhostId())
+}
+
+func (p *darwinPlatform) OpenMemoApp() error {
+	return exec.Command("open", "-a", "Notes").Run()
+}
+
+func (p *darwinPlatform) GetScreenSize() (width, height int) {
+	var w, h C.int
+	C.GetMainScreenSize(&w, &h)
+	return int(w), int(h)
+}
+
+func (p *darwinPlatform) CaptureScreen(path string) error {
+	return exec.Command("screencapture", "-i", path).Run()
+}
+
+// darwinModifierMasks は HotkeyModifiers に対応するCarbonの修飾キーマスク
+var darwinModifierMasks = []struct {
+	mod  HotkeyModifiers
+	mask C.UInt32
+}{
+	{ModControl, C.controlKey},
+	{ModOption, C.optionKey},
+	{ModShift, C.shiftKey},
+	{ModCommand, C.cmdKey},
+}
+
+// RegisterHotKeyWithID がキー名を解決できなかった場合の戻り値
+const darwinHotKeyUnknownKey = -1
+
+// eventHotKeyExistsErr (Carbon) は同じ組み合わせが登録済みの場合の OSStatus
+const darwinHotKeyExistsErr = -9878
+
+func (p *darwinPlatform) RegisterHotKey(id int, modifiers HotkeyModifiers, key string) error {
+	var mask C.UInt32
+	for _, m := range darwinModifierMasks {
+		if modifiers&m.mod != 0 {
+			mask |= m.mask
+		}
+	}
+
+	cKey := C.CString(key)
+	defer C.free(unsafe.Pointer(cKey))
+
+	switch status := int(C.RegisterHotKeyWithID(C.int(id), mask, cKey)); status {
+	case 0:
+		return nil
+	case darwinHotKeyUnknownKey:
+		return fmt.Errorf("unknown key %q", key)
+	case darwinHotKeyExistsErr:
+		return errHotkeyInUse
+	default:
+		return fmt.Errorf("RegisterEventHotKey failed (OSStatus %d)", status)
+	}
+}
+
+func (p *darwinPlatform) UnregisterHotKey(id int) {
+	C.UnregisterHotKeyWithID(C.int(id))
+}
+
+func (p *darwinPlatform) SetHotKeyHandler(handler func(id int)) {
+	hotKeyHandlerMu.Lock()
+	defer hotKeyHandlerMu.Unlock()
+	hotKeyHandler = handler
+}
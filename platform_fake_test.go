@@ -0,0 +1,443 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakePlatform はテスト用のインメモリ Platform
+// 仮想カーソル・仮想キー状態・仮想クリップボード・仮想アクティブアプリを持ち、
+// テストから状態を操作してネイティブ層からの入力を再現できる
+type FakePlatform struct {
+	mu sync.Mutex
+
+	// マウス
+	mouseX             float64
+	mouseY             float64
+	mouseEventsEnabled bool
+
+	// キーボード
+	pressedKeys   string
+	keyCallback   func(keys string)
+	simulatedKeys []string
+
+	// クリップボード
+	clipboard string
+
+	// ウィンドウ
+	windowReady     bool
+	monitoring      bool
+	frontmostApp    string
+	focusReturnedTo []string
+	lastGhostID     string
+	memoOpened      int
+
+	// スクリーン
+	screenWidth  int
+	screenHeight int
+	screenshots  []string
+
+	// ホットキー
+	hotkeys        map[int]string
+	blockedHotkeys map[string]bool
+	hotkeyHandler  func(id int)
+}
+
+// NewFakePlatform は 1440x900 のスクリーンを持つ FakePlatform を生成する
+func NewFakePlatform() *FakePlatform {
+	return &FakePlatform{
+		screenWidth:    1440,
+		screenHeight:   900,
+		lastGhostID:    "default",
+		hotkeys:        make(map[int]string),
+		blockedHotkeys: make(map[string]bool),
+	}
+}
+
+// --- テストからの操作 ---
+
+// MoveMouse は仮想カーソルを移動する
+func (p *FakePlatform) MoveMouse(x, y float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mouseX = x
+	p.mouseY = y
+}
+
+// SetPressedKeys は仮想キー状態を更新し、監視中であればキー状態コールバックを呼び出す
+func (p *FakePlatform) SetPressedKeys(keys ...string) {
+	keysString := strings.Join(keys, ",")
+
+	p.mu.Lock()
+	changed := keysString != p.pressedKeys
+	p.pressedKeys = keysString
+	callback := p.keyCallback
+	p.mu.Unlock()
+
+	if changed && callback != nil {
+		callback(keysString)
+	}
+}
+
+// PressHotKey は id のグローバルホットキーが押されたことにし、ホットキーのハンドラを呼び出す
+func (p *FakePlatform) PressHotKey(id int) {
+	p.mu.Lock()
+	handler := p.hotkeyHandler
+	p.mu.Unlock()
+
+	if handler != nil {
+		handler(id)
+	}
+}
+
+// BlockHotKey は modifiers + key が他のアプリケーションに登録済みであることにする
+func (p *FakePlatform) BlockHotKey(modifiers HotkeyModifiers, key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.blockedHotkeys[formatHotkey(modifiers, key)] = true
+}
+
+// RegisteredHotKeys は登録中のホットキーを ID ごとに "Option+1" の形式で返す
+func (p *FakePlatform) RegisteredHotKeys() map[int]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	hotkeys := make(map[int]string, len(p.hotkeys))
+	for id, hotkey := range p.hotkeys {
+		hotkeys[id] = hotkey
+	}
+	return hotkeys
+}
+
+// SetClipboard は仮想クリップボードの内容を設定する
+func (p *FakePlatform) SetClipboard(text string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clipboard = text
+}
+
+// Clipboard は仮想クリップボードの内容を返す
+func (p *FakePlatform) Clipboard() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.clipboard
+}
+
+// SetFrontmostApp は仮想的な最前面アプリを設定する
+func (p *FakePlatform) SetFrontmostApp(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.frontmostApp = name
+}
+
+// FrontmostApp は仮想的な最前面アプリを返す
+func (p *FakePlatform) FrontmostApp() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.frontmostApp
+}
+
+// FocusReturns は ReturnFocusToPreviousWindow でフォーカスが戻されたアプリの履歴を返す
+func (p *FakePlatform) FocusReturns() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.focusReturnedTo...)
+}
+
+// SetLastGhostId はネイティブ側で選択されたゴーストIDを設定する
+func (p *FakePlatform) SetLastGhostId(ghostID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastGhostID = ghostID
+}
+
+// SetScreenSize は仮想スクリーンのサイズを設定する
+func (p *FakePlatform) SetScreenSize(width, height int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.screenWidth = width
+	p.screenHeight = height
+}
+
+// SimulatedKeyPresses は SimulateKeyPresses に渡されたキー文字列の履歴を返す
+func (p *FakePlatform) SimulatedKeyPresses() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.simulatedKeys...)
+}
+
+// MouseEventsEnabled はメインウィンドウがマウスイベントを受け取る状態かを返す
+func (p *FakePlatform) MouseEventsEnabled() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.mouseEventsEnabled
+}
+
+// KeyMonitoring はキー状態の監視中かを返す
+func (p *FakePlatform) KeyMonitoring() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.keyCallback != nil
+}
+
+// WindowReady は SetupMainWindow が呼ばれ、監視が停止されていないかを返す
+func (p *FakePlatform) WindowReady() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.windowReady && p.monitoring
+}
+
+// Screenshots は CaptureScreen に渡された保存先の履歴を返す
+func (p *FakePlatform) Screenshots() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.screenshots...)
+}
+
+// MemoOpened は OpenMemoApp が呼ばれた回数を返す
+func (p *FakePlatform) MemoOpened() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.memoOpened
+}
+
+// --- Platform の実装 ---
+
+func (p *FakePlatform) GetMousePosX() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.mouseX
+}
+
+func (p *FakePlatform) GetMousePosY() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.mouseY
+}
+
+func (p *FakePlatform) EnableMouseEvents() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mouseEventsEnabled = true
+}
+
+func (p *FakePlatform) DisableMouseEvents() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mouseEventsEnabled = false
+}
+
+func (p *FakePlatform) SimulateKeyPresses(keyString string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.simulatedKeys = append(p.simulatedKeys, keyString)
+}
+
+func (p *FakePlatform) GetPressedKeys() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pressedKeys
+}
+
+func (p *FakePlatform) StartKeyMonitoring(callback func(keys string)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keyCallback = callback
+	return nil
+}
+
+func (p *FakePlatform) StopKeyMonitoring() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keyCallback = nil
+}
+
+func (p *FakePlatform) ReadClipboard() (string, error) {
+	return p.Clipboard(), nil
+}
+
+func (p *FakePlatform) WriteClipboard(text string) error {
+	p.SetClipboard(text)
+	return nil
+}
+
+func (p *FakePlatform) SetupMainWindow() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.windowReady = true
+	p.monitoring = true
+}
+
+func (p *FakePlatform) ReturnFocusToPreviousWindow() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.frontmostApp != "" {
+		p.focusReturnedTo = append(p.focusReturnedTo, p.frontmostApp)
+	}
+}
+
+func (p *FakePlatform) StopMonitoring() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.monitoring = false
+}
+
+func (p *FakePlatform) GetLastGhostId() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastGhostID
+}
+
+func (p *FakePlatform) OpenMemoApp() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.memoOpened++
+	return nil
+}
+
+func (p *FakePlatform) GetScreenSize() (width, height int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.screenWidth, p.screenHeight
+}
+
+func (p *FakePlatform) CaptureScreen(path string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.screenshots = append(p.screenshots, path)
+	return nil
+}
+
+func (p *FakePlatform) RegisterHotKey(id int, modifiers HotkeyModifiers, key string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	hotkey := formatHotkey(modifiers, key)
+	if p.blockedHotkeys[hotkey] {
+		return errHotkeyInUse
+	}
+	p.hotkeys[id] = hotkey
+	return nil
+}
+
+func (p *FakePlatform) UnregisterHotKey(id int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.hotkeys, id)
+}
+
+func (p *FakePlatform) SetHotKeyHandler(handler func(id int)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.hotkeyHandler = handler
+}
+
+// RecordedEvent は EventRecorder が記録したイベント
+type RecordedEvent struct {
+	Name string
+	Data []interface{}
+}
+
+// EventRecorder はフロントエンドに送信されたイベントを記録する
+// App の emitter に Emit を設定して使う
+type EventRecorder struct {
+	mu      sync.Mutex
+	events  []RecordedEvent
+	changed chan struct{}
+}
+
+// NewEventRecorder は空の EventRecorder を生成する
+func NewEventRecorder() *EventRecorder {
+	return &EventRecorder{changed: make(chan struct{})}
+}
+
+// Emit は runtime.EventsEmit と同じシグネチャでイベントを記録する
+func (r *EventRecorder) Emit(ctx context.Context, name string, data ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, RecordedEvent{Name: name, Data: data})
+
+	// 待機中の WaitFor を起こす
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// Events は記録されたすべてのイベントを返す
+func (r *EventRecorder) Events() []RecordedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedEvent(nil), r.events...)
+}
+
+// EventsNamed は name のイベントだけを返す
+func (r *EventRecorder) EventsNamed(name string) []RecordedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []RecordedEvent
+	for _, e := range r.events {
+		if e.Name == name {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// WaitFor は name のイベントが count 件以上記録されるまで最大 timeout 待つ
+func (r *EventRecorder) WaitFor(name string, count int, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		r.mu.Lock()
+		n := 0
+		for _, e := range r.events {
+			if e.Name == name {
+				n++
+			}
+		}
+		changed := r.changed
+		r.mu.Unlock()
+
+		if n >= count {
+			return true
+		}
+
+		select {
+		case <-changed:
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+// Reset は記録されたイベントを消去する
+func (r *EventRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
+
+// FakeEnvironment はフェイクのネイティブ層・時計・イベント記録に接続された App をまとめたもの
+type FakeEnvironment struct {
+	App      *App
+	Platform *FakePlatform
+	Clock    *FakeClock
+	Events   *EventRecorder
+}
+
+// NewFakeEnvironment はテスト用の App と、それを操作するためのフェイク一式を生成する
+func NewFakeEnvironment() *FakeEnvironment {
+	platform := NewFakePlatform()
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	events := NewEventRecorder()
+
+	app := NewAppWithPlatform(platform)
+	app.clock = clock
+	app.emitter = events.Emit
+
+	return &FakeEnvironment{
+		App:      app,
+		Platform: platform,
+		Clock:    clock,
+		Events:   events,
+	}
+}
@@ -0,0 +1,573 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"tmp/backend/linux/x11"
+)
+
+// ロックキーの状態に関係なくホットキーを受け取るため、これらの修飾キーの組み合わせもグラブする
+// Mod2 は一般的に NumLock に割り当てられている
+var linuxIgnoredModifiers = []uint16{0, x11.ModMaskLock, x11.ModMask2, x11.ModMaskLock | x11.ModMask2}
+
+func newPlatform() Platform {
+	p, err := newX11Platform("")
+	if err != nil {
+		fmt.Printf("X11 backend is not available, native features are disabled: %v\n", err)
+		return &unsupportedPlatform{}
+	}
+	return p
+}
+
+type linuxHotKey struct {
+	keycode   x11.Keycode
+	modifiers uint16
+}
+
+// x11Platform はX11サーバーと直接通信する Platform
+type x11Platform struct {
+	conn   *x11.Conn
+	screen x11.Screen
+	// セレクションの受け渡しに使う不可視ウィンドウ
+	window x11.Window
+	// キー入力の合成に使う (サーバーが XTEST に対応していない場合は nil)
+	xtest *x11.XTest
+
+	atomClipboard  x11.Atom
+	atomUTF8String x11.Atom
+	atomTargets    x11.Atom
+	atomText       x11.Atom
+	atomIncr       x11.Atom
+	atomTransfer   x11.Atom
+
+	// キーコードとキー名の対応
+	keycodeNames map[x11.Keycode]string
+	nameKeycodes map[x11.Keysym]x11.Keycode
+
+	// クリップボード
+	clipboardMu      sync.Mutex
+	clipboardText    string
+	clipboardOwned   bool
+	clipboardReadMu  sync.Mutex
+	selectionNotifyC chan *x11.SelectionNotifyEvent
+
+	// ホットキー
+	hotkeyMu      sync.Mutex
+	hotkeys       map[linuxHotKey]int
+	hotkeyHandler func(id int)
+
+	// キー監視
+	monitorMu   sync.Mutex
+	monitorStop chan struct{}
+	monitorDone chan struct{}
+}
+
+// newX11Platform は display (空の場合は $DISPLAY) のX11サーバーに接続する
+func newX11Platform(display string) (*x11Platform, error) {
+	conn, err := x11.Dial(display)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &x11Platform{
+		conn:             conn,
+		screen:           conn.DefaultScreen(),
+		hotkeys:          make(map[linuxHotKey]int),
+		selectionNotifyC: make(chan *x11.SelectionNotifyEvent, 1),
+	}
+
+	if err := p.init(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go p.eventLoop()
+	return p, nil
+}
+
+func (p *x11Platform) init() error {
+	atoms := []struct {
+		name string
+		atom *x11.Atom
+	}{
+		{"CLIPBOARD", &p.atomClipboard},
+		{"UTF8_STRING", &p.atomUTF8String},
+		{"TARGETS", &p.atomTargets},
+		{"TEXT", &p.atomText},
+		{"INCR", &p.atomIncr},
+		{"GHOSTCURSOR_SELECTION", &p.atomTransfer},
+	}
+	for _, a := range atoms {
+		atom, err := p.conn.InternAtom(a.name, false)
+		if err != nil {
+			return fmt.Errorf("failed to intern atom %s: %w", a.name, err)
+		}
+		*a.atom = atom
+	}
+
+	window, err := p.conn.CreateWindow(p.screen.Root, -10, -10, 1, 1, x11.WindowClassInputOnly)
+	if err != nil {
+		return fmt.Errorf("failed to create selection window: %w", err)
+	}
+	p.window = window
+
+	if err := p.loadKeyboardMapping(); err != nil {
+		return err
+	}
+
+	xtest, err := p.conn.NewXTest()
+	if err != nil {
+		fmt.Printf("Key simulation is disabled: %v\n", err)
+	} else {
+		p.xtest = xtest
+	}
+	return nil
+}
+
+// loadKeyboardMapping はサーバーのキーボード配列からキーコードとキー名の対応表を作る
+func (p *x11Platform) loadKeyboardMapping() error {
+	count := int(p.conn.MaxKeycode) - int(p.conn.MinKeycode) + 1
+	mapping, err := p.conn.GetKeyboardMapping(p.conn.MinKeycode, uint8(count))
+	if err != nil {
+		return fmt.Errorf("failed to get keyboard mapping: %w", err)
+	}
+
+	names := make(map[x11.Keysym]string, len(linuxKeys))
+	for _, k := range linuxKeys {
+		names[k.keysym] = k.name
+	}
+
+	p.keycodeNames = make(map[x11.Keycode]string)
+	p.nameKeycodes = make(map[x11.Keysym]x11.Keycode)
+	per := mapping.KeysymsPerKeycode
+	if per == 0 || len(mapping.Keysyms) < count*per {
+		return errors.New("keyboard mapping is empty")
+	}
+	keycodeAt := func(i int) x11.Keycode { return x11.Keycode(int(mapping.FirstKeycode) + i) }
+
+	// 押下状態の表示には修飾なしのキーシンボルを使う
+	for i := 0; i < count; i++ {
+		if name, ok := names[mapping.Keysyms[i*per]]; ok {
+			p.keycodeNames[keycodeAt(i)] = name
+		}
+	}
+
+	// 同じキーシンボルが複数のキーにある場合は修飾なしで入力できるものを優先する
+	for col := 0; col < per; col++ {
+		for i := 0; i < count; i++ {
+			sym := mapping.Keysyms[i*per+col]
+			if _, ok := p.nameKeycodes[sym]; sym != 0 && !ok {
+				p.nameKeycodes[sym] = keycodeAt(i)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *x11Platform) keycodeForName(name string) (x11.Keycode, bool) {
+	sym, ok := linuxKeysymForName(name)
+	if !ok {
+		return 0, false
+	}
+	keycode, ok := p.nameKeycodes[sym]
+	return keycode, ok
+}
+
+// eventLoop はサーバーからのイベントを処理する
+func (p *x11Platform) eventLoop() {
+	for ev := range p.conn.Events() {
+		switch ev := ev.(type) {
+		case *x11.KeyEvent:
+			if ev.Code == x11.KeyPress {
+				p.handleHotKey(ev)
+			}
+		case *x11.SelectionRequestEvent:
+			p.handleSelectionRequest(ev)
+		case *x11.SelectionClearEvent:
+			if ev.Selection == p.atomClipboard {
+				p.clipboardMu.Lock()
+				p.clipboardOwned = false
+				p.clipboardMu.Unlock()
+			}
+		case *x11.SelectionNotifyEvent:
+			select {
+			case p.selectionNotifyC <- ev:
+			default:
+			}
+		case *x11.Error:
+			fmt.Printf("X11 error: %v\n", ev)
+		}
+	}
+	fmt.Println("X11 connection closed")
+}
+
+func (p *x11Platform) GetMousePosX() float64 {
+	pos, err := p.conn.QueryPointer(p.screen.Root)
+	if err != nil {
+		return 0
+	}
+	return float64(pos.RootX)
+}
+
+func (p *x11Platform) GetMousePosY() float64 {
+	pos, err := p.conn.QueryPointer(p.screen.Root)
+	if err != nil {
+		return 0
+	}
+	return float64(pos.RootY)
+}
+
+// マウスイベントの透過にはウィンドウマネージャとの連携が必要なため未対応
+func (p *x11Platform) EnableMouseEvents() {}
+
+func (p *x11Platform) DisableMouseEvents() {}
+
+func (p *x11Platform) SimulateKeyPresses(keyString string) {
+	if p.xtest == nil {
+		fmt.Println("Key simulation is not available")
+		return
+	}
+
+	var keycodes []x11.Keycode
+	for _, name := range strings.Split(keyString, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		keycode, ok := p.keycodeForName(name)
+		if !ok {
+			fmt.Printf("Unknown key: %s\n", name)
+			continue
+		}
+		keycodes = append(keycodes, keycode)
+	}
+	if len(keycodes) == 0 {
+		fmt.Println("No valid keys to simulate")
+		return
+	}
+
+	// macOS版と同じく、順に押してから逆順に離す
+	for _, keycode := range keycodes {
+		p.xtest.FakeKey(keycode, true)
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	for i := len(keycodes) - 1; i >= 0; i-- {
+		p.xtest.FakeKey(keycodes[i], false)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := p.conn.Sync(); err != nil {
+		fmt.Printf("Failed to simulate key presses: %v\n", err)
+	}
+}
+
+func (p *x11Platform) GetPressedKeys() string {
+	keymap, err := p.conn.QueryKeymap()
+	if err != nil {
+		return ""
+	}
+
+	pressed := make(map[string]bool)
+	for keycode, name := range p.keycodeNames {
+		if keymap[keycode/8]&(1<<(keycode%8)) != 0 {
+			pressed[name] = true
+		}
+	}
+
+	var names []string
+	for _, k := range linuxKeys {
+		if pressed[k.name] {
+			names = append(names, k.name)
+		}
+	}
+
+	return strings.Join(names, ",")
+}
+
+func (p *x11Platform) StartKeyMonitoring(callback func(keys string)) error {
+	p.monitorMu.Lock()
+	defer p.monitorMu.Unlock()
+
+	if p.monitorStop != nil {
+		fmt.Println("Key monitoring is already running")
+		return nil
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	p.monitorStop = stop
+	p.monitorDone = done
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+
+		previous := ""
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				current := p.GetPressedKeys()
+				if current != previous {
+					previous = current
+					callback(current)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (p *x11Platform) StopKeyMonitoring() {
+	p.monitorMu.Lock()
+	stop, done := p.monitorStop, p.monitorDone
+	p.monitorStop, p.monitorDone = nil, nil
+	p.monitorMu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (p *x11Platform) ReadClipboard() (string, error) {
+	// 自分が所有している場合はサーバーを経由せずに返す
+	p.clipboardMu.Lock()
+	if p.clipboardOwned {
+		text := p.clipboardText
+		p.clipboardMu.Unlock()
+		return text, nil
+	}
+	p.clipboardMu.Unlock()
+
+	p.clipboardReadMu.Lock()
+	defer p.clipboardReadMu.Unlock()
+
+	owner, err := p.conn.GetSelectionOwner(p.atomClipboard)
+	if err != nil {
+		return "", err
+	}
+	if owner == x11.None {
+		return "", nil
+	}
+
+	// 前回のタイムアウトで残った通知を捨てる
+	select {
+	case <-p.selectionNotifyC:
+	default:
+	}
+
+	if err := p.conn.ConvertSelection(p.window, p.atomClipboard, p.atomUTF8String, p.atomTransfer, x11.CurrentTime); err != nil {
+		return "", err
+	}
+
+	var notify *x11.SelectionNotifyEvent
+	select {
+	case notify = <-p.selectionNotifyC:
+	case <-time.After(time.Second):
+		return "", errors.New("timed out waiting for clipboard owner")
+	}
+	if notify.Property == x11.None {
+		// UTF-8 に変換できない内容 (画像など)
+		return "", nil
+	}
+
+	value, err := p.conn.GetProperty(true, p.window, p.atomTransfer, x11.None, 0, 1<<22)
+	if err != nil {
+		return "", err
+	}
+	if value.Type == p.atomIncr {
+		return "", errors.New("clipboard content is too large")
+	}
+	return string(value.Value), nil
+}
+
+func (p *x11Platform) WriteClipboard(text string) error {
+	p.clipboardMu.Lock()
+	p.clipboardText = text
+	p.clipboardOwned = true
+	p.clipboardMu.Unlock()
+
+	if err := p.conn.SetSelectionOwner(p.window, p.atomClipboard, x11.CurrentTime); err != nil {
+		return err
+	}
+	owner, err := p.conn.GetSelectionOwner(p.atomClipboard)
+	if err != nil {
+		return err
+	}
+	if owner != p.window {
+		p.clipboardMu.Lock()
+		p.clipboardOwned = false
+		p.clipboardMu.Unlock()
+		return errors.New("failed to take clipboard ownership")
+	}
+	return nil
+}
+
+// handleSelectionRequest は他のアプリからのクリップボード要求に応える
+func (p *x11Platform) handleSelectionRequest(ev *x11.SelectionRequestEvent) {
+	notify := &x11.SelectionNotifyEvent{
+		Time:      ev.Time,
+		Requestor: ev.Requestor,
+		Selection: ev.Selection,
+		Target:    ev.Target,
+		Property:  x11.None,
+	}
+
+	// 古いクライアントは property に None を指定してくる
+	property := ev.Property
+	if property == x11.None {
+		property = ev.Target
+	}
+
+	p.clipboardMu.Lock()
+	text, owned := p.clipboardText, p.clipboardOwned
+	p.clipboardMu.Unlock()
+
+	if owned && ev.Selection == p.atomClipboard {
+		var err error
+		switch ev.Target {
+		case p.atomTargets:
+			targets := []x11.Atom{p.atomTargets, p.atomUTF8String, x11.AtomString, p.atomText}
+			err = p.conn.ChangeProperty(x11.PropModeReplace, ev.Requestor, property, x11.AtomAtom, 32, x11.EncodeAtoms(targets))
+			notify.Property = property
+		case p.atomUTF8String, p.atomText:
+			err = p.conn.ChangeProperty(x11.PropModeReplace, ev.Requestor, property, p.atomUTF8String, 8, []byte(text))
+			notify.Property = property
+		case x11.AtomString:
+			err = p.conn.ChangeProperty(x11.PropModeReplace, ev.Requestor, property, x11.AtomString, 8, []byte(text))
+			notify.Property = property
+		}
+		if err != nil {
+			notify.Property = x11.None
+		}
+	}
+
+	if err := p.conn.SendSelectionNotify(notify); err != nil {
+		fmt.Printf("Failed to answer selection request: %v\n", err)
+	}
+}
+
+// ホットキーはルートウィンドウのイベントとして eventLoop で受け取るため、ウィンドウ側の準備は不要
+func (p *x11Platform) SetupMainWindow() {}
+
+// linuxModifierMasks は HotkeyModifiers に対応するX11の修飾キーマスク
+var linuxModifierMasks = []struct {
+	mod  HotkeyModifiers
+	mask uint16
+}{
+	{ModControl, x11.ModMaskControl},
+	{ModOption, x11.ModMask1},
+	{ModShift, x11.ModMaskShift},
+	{ModCommand, x11.ModMask4},
+}
+
+func (p *x11Platform) RegisterHotKey(id int, modifiers HotkeyModifiers, key string) error {
+	var mask uint16
+	for _, m := range linuxModifierMasks {
+		if modifiers&m.mod != 0 {
+			mask |= m.mask
+		}
+	}
+
+	err := p.grabHotKey(id, mask, key)
+	var xerr *x11.Error
+	if errors.As(err, &xerr) && xerr.Code == x11.BadAccess {
+		// 他のクライアントが同じ組み合わせをグラブしている
+		return errHotkeyInUse
+	}
+	return err
+}
+
+func (p *x11Platform) UnregisterHotKey(id int) {
+	p.hotkeyMu.Lock()
+	defer p.hotkeyMu.Unlock()
+
+	for hotkey, hotkeyID := range p.hotkeys {
+		if hotkeyID != id {
+			continue
+		}
+		for _, ignored := range linuxIgnoredModifiers {
+			p.conn.UngrabKey(hotkey.keycode, p.screen.Root, hotkey.modifiers|ignored)
+		}
+		delete(p.hotkeys, hotkey)
+	}
+}
+
+// grabHotKey はルートウィンドウ上で modifiers + key をグラブし、押されたときに id を通知する
+func (p *x11Platform) grabHotKey(id int, modifiers uint16, key string) error {
+	keycode, ok := p.keycodeForName(key)
+	if !ok {
+		return fmt.Errorf("unknown key %q", key)
+	}
+
+	for i, ignored := range linuxIgnoredModifiers {
+		if err := p.conn.GrabKey(true, p.screen.Root, modifiers|ignored, keycode, x11.GrabModeAsync, x11.GrabModeAsync); err != nil {
+			// 途中まで成功したグラブを解除する
+			for _, undo := range linuxIgnoredModifiers[:i] {
+				p.conn.UngrabKey(keycode, p.screen.Root, modifiers|undo)
+			}
+			return err
+		}
+	}
+
+	p.hotkeyMu.Lock()
+	p.hotkeys[linuxHotKey{keycode: keycode, modifiers: modifiers}] = id
+	p.hotkeyMu.Unlock()
+	return nil
+}
+
+// linuxHotKeyModifiers はキーイベントの状態からホットキーの修飾キーだけを取り出す
+// ロックキーやマウスボタン (Button1Mask など) の状態はホットキーの照合に使わない
+func linuxHotKeyModifiers(state uint16) uint16 {
+	var mask uint16
+	for _, m := range linuxModifierMasks {
+		mask |= m.mask
+	}
+	return state & mask
+}
+
+func (p *x11Platform) handleHotKey(ev *x11.KeyEvent) {
+	modifiers := linuxHotKeyModifiers(ev.State)
+
+	p.hotkeyMu.Lock()
+	id, ok := p.hotkeys[linuxHotKey{keycode: ev.Detail, modifiers: modifiers}]
+	handler := p.hotkeyHandler
+	p.hotkeyMu.Unlock()
+
+	if ok && handler != nil {
+		handler(id)
+	}
+}
+
+// アクティブウィンドウの切り替えはウィンドウマネージャに依存するため未対応
+func (p *x11Platform) ReturnFocusToPreviousWindow() {}
+
+func (p *x11Platform) StopMonitoring() {}
+
+func (p *x11Platform) GetLastGhostId() string { return "default" }
+
+func (p *x11Platform) OpenMemoApp() error { return errPlatformUnsupported }
+
+func (p *x11Platform) GetScreenSize() (width, height int) {
+	return int(p.screen.WidthInPixels), int(p.screen.HeightInPixels)
+}
+
+func (p *x11Platform) CaptureScreen(path string) error { return errPlatformUnsupported }
+
+func (p *x11Platform) SetHotKeyHandler(handler func(id int)) {
+	p.hotkeyMu.Lock()
+	defer p.hotkeyMu.Unlock()
+	p.hotkeyHandler = handler
+}
@@ -0,0 +1,193 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"tmp/backend/linux/x11"
+)
+
+// X11 のマウスボタンの状態 (KeyEvent.State の Button1Mask〜Button5Mask)
+const (
+	testButton1Mask uint16 = 1 << 8
+	testButton5Mask uint16 = 1 << 12
+)
+
+func TestLinuxHotKeyModifiers(t *testing.T) {
+	tests := []struct {
+		name  string
+		state uint16
+		want  uint16
+	}{
+		{"option", x11.ModMask1, x11.ModMask1},
+		{"all modifiers", x11.ModMaskShift | x11.ModMaskControl | x11.ModMask1 | x11.ModMask4, x11.ModMaskShift | x11.ModMaskControl | x11.ModMask1 | x11.ModMask4},
+		{"caps lock and num lock", x11.ModMask1 | x11.ModMaskLock | x11.ModMask2, x11.ModMask1},
+		{"mouse button held", x11.ModMask1 | testButton1Mask, x11.ModMask1},
+		{"mouse buttons and unused modifiers", x11.ModMaskControl | testButton5Mask | x11.ModMask3 | x11.ModMask5, x11.ModMaskControl},
+		{"none", testButton1Mask | x11.ModMaskLock, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := linuxHotKeyModifiers(tt.state); got != tt.want {
+				t.Errorf("linuxHotKeyModifiers(%#x) = %#x, want %#x", tt.state, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestX11HandleHotKey(t *testing.T) {
+	const keycode x11.Keycode = 10
+	p := &x11Platform{hotkeys: map[linuxHotKey]int{{keycode: keycode, modifiers: x11.ModMask1}: 1}}
+
+	var pressed []int
+	p.SetHotKeyHandler(func(id int) { pressed = append(pressed, id) })
+
+	states := []uint16{
+		x11.ModMask1,
+		x11.ModMask1 | x11.ModMaskLock,
+		x11.ModMask1 | testButton1Mask,
+		x11.ModMask1 | x11.ModMask2 | testButton5Mask,
+		// 修飾キーが違うものは当てはまらない
+		x11.ModMask1 | x11.ModMaskShift,
+		testButton1Mask,
+	}
+	for _, state := range states {
+		p.handleHotKey(&x11.KeyEvent{Detail: keycode, State: state})
+	}
+	if len(pressed) != 4 {
+		t.Errorf("handler called %d times, want 4", len(pressed))
+	}
+}
+
+// startXvfb はテスト用のXvfbを起動してそのディスプレイ名を返す
+// Xvfb がインストールされていない場合はテストをスキップする
+func startXvfb(t *testing.T) string {
+	t.Helper()
+	path, err := exec.LookPath("Xvfb")
+	if err != nil {
+		t.Skip("Xvfb is not installed")
+	}
+
+	// 空いているディスプレイ番号は Xvfb に選ばせ、-displayfd で受け取る
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	cmd := exec.Command(path, "-displayfd", "3", "-nolisten", "tcp", "-screen", "0", "1024x768x24")
+	cmd.ExtraFiles = []*os.File{w}
+	if err := cmd.Start(); err != nil {
+		w.Close()
+		t.Fatalf("failed to start Xvfb: %v", err)
+	}
+	w.Close()
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	displayNum := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(r).ReadString('\n')
+		displayNum <- strings.TrimSpace(line)
+	}()
+	select {
+	case n := <-displayNum:
+		if n == "" {
+			t.Fatal("Xvfb did not report a display")
+		}
+		return ":" + n
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for Xvfb")
+	}
+	return ""
+}
+
+func TestX11PlatformXvfb(t *testing.T) {
+	display := startXvfb(t)
+
+	p, err := newX11Platform(display)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.conn.Close()
+	if p.xtest == nil {
+		t.Fatal("XTEST is not available on Xvfb")
+	}
+
+	t.Run("screen size", func(t *testing.T) {
+		if w, h := p.GetScreenSize(); w != 1024 || h != 768 {
+			t.Errorf("GetScreenSize() = %dx%d, want 1024x768", w, h)
+		}
+		if x, y := p.GetMousePosX(), p.GetMousePosY(); x < 0 || x >= 1024 || y < 0 || y >= 768 {
+			t.Errorf("mouse position (%v, %v) is outside the screen", x, y)
+		}
+	})
+
+	t.Run("clipboard", func(t *testing.T) {
+		if err := p.WriteClipboard("ghost"); err != nil {
+			t.Fatal(err)
+		}
+
+		// 別のクライアントからはセレクションの受け渡しで読む
+		other, err := newX11Platform(display)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer other.conn.Close()
+		text, err := other.ReadClipboard()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text != "ghost" {
+			t.Errorf("ReadClipboard() = %q, want %q", text, "ghost")
+		}
+	})
+
+	t.Run("hotkey", func(t *testing.T) {
+		pressed := make(chan int, 4)
+		p.SetHotKeyHandler(func(id int) { pressed <- id })
+		defer p.SetHotKeyHandler(nil)
+
+		if err := p.RegisterHotKey(7, ModOption, "1"); err != nil {
+			t.Fatal(err)
+		}
+		defer p.UnregisterHotKey(7)
+
+		p.SimulateKeyPresses("loption,1")
+		select {
+		case id := <-pressed:
+			if id != 7 {
+				t.Errorf("hotkey id = %d, want 7", id)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Option+1 was not delivered")
+		}
+	})
+
+	t.Run("pressed keys", func(t *testing.T) {
+		keycode, ok := p.keycodeForName("lshift")
+		if !ok {
+			t.Fatal("no keycode for lshift")
+		}
+		p.xtest.FakeKey(keycode, true)
+		p.conn.Sync()
+		got := p.GetPressedKeys()
+		p.xtest.FakeKey(keycode, false)
+		p.conn.Sync()
+
+		if got != "lshift" {
+			t.Errorf("GetPressedKeys() = %q while holding lshift", got)
+		}
+		if got := p.GetPressedKeys(); got != "" {
+			t.Errorf("GetPressedKeys() = %q after releasing", got)
+		}
+	})
+}
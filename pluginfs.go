@@ -0,0 +1,235 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// プラグインがファイルAPIで使えるディレクトリ
+const (
+	// プラグインのインストール先 (読み取り専用)
+	PluginFSInstall = "install"
+	// プラグインごとの書き込み可能なデータディレクトリ (~/.config/ghostcursor/plugin-data/<id>)
+	PluginFSData = "data"
+)
+
+// プラグインのファイルAPIで読み書きできる1ファイルの大きさの上限
+const maxPluginFileSize = 16 << 20
+
+// ErrOutsideSandbox はプラグインが使えるディレクトリの外を指すパスを表す
+var ErrOutsideSandbox = errors.New("path is outside the plugin sandbox")
+
+// pluginDataDir は id のプラグインのデータディレクトリを返す
+func pluginDataDir(id string) (string, error) {
+	if issues := validatePluginIDField("$.id", id); len(issues) > 0 {
+		return "", fmt.Errorf("invalid plugin id %q: %s", id, joinIssues(issues))
+	}
+	return configPath(filepath.Join("plugin-data", id))
+}
+
+// sandboxPath は root からの相対パス rel を、シンボリックリンクを解決した実際のパスにする
+// 絶対パス、root の外に出る .. 、root の外を指すシンボリックリンクは拒否する
+// rel が存在しない場合は、存在する親ディレクトリまでを解決する (書き込み先に使う)
+func sandboxPath(root, rel string) (string, error) {
+	if rel == "" {
+		rel = "."
+	}
+	if strings.Contains(rel, `\`) {
+		return "", fmt.Errorf("%w: %q contains a backslash", ErrOutsideSandbox, rel)
+	}
+	name := filepath.FromSlash(rel)
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("%w: %q", ErrOutsideSandbox, rel)
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	path, err := resolveExistingPath(filepath.Join(realRoot, name))
+	if err != nil {
+		return "", err
+	}
+	if !isWithinDir(realRoot, path) {
+		return "", fmt.Errorf("%w: %q resolves to %s", ErrOutsideSandbox, rel, path)
+	}
+	return path, nil
+}
+
+// confinePath は path (絶対パス) が roots のいずれかの中にあれば、シンボリックリンクを解決したパスを返す
+func confinePath(path string, roots []string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("%w: %q is not an absolute path", ErrOutsideSandbox, path)
+	}
+	resolved, err := resolveExistingPath(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	for _, root := range roots {
+		realRoot, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			continue
+		}
+		if isWithinDir(realRoot, resolved) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s is not inside a plugin directory", ErrOutsideSandbox, path)
+}
+
+// resolveExistingPath は path のうち存在する部分のシンボリックリンクを解決し、存在しない残りをそのまま付ける
+func resolveExistingPath(path string) (string, error) {
+	var rest []string
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		rest = append([]string{filepath.Base(path)}, rest...)
+		path = parent
+	}
+}
+
+// isWithinDir は path が dir 自身かその中にあるかを返す (どちらもシンボリックリンクを解決済みであること)
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// installSandboxPath は sandboxPath と同じく root (プラグインのインストール先) からの相対パス rel を解決し、
+// 署名の対象にならないパス (隠しディレクトリ、node_modules、署名ファイル) を拒否する
+// シンボリックリンクで対象外のファイルを指す場合も、解決したパスで判断するため拒否される
+func installSandboxPath(root, rel string) (string, error) {
+	path, err := sandboxPath(root, rel)
+	if err != nil {
+		return "", err
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.Rel(realRoot, path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	isDir := err == nil && info.IsDir()
+	if !pluginSignatureCovers(filepath.ToSlash(resolved), isDir) {
+		return "", fmt.Errorf("%w: %q is not covered by the plugin signature", ErrOutsideSandbox, rel)
+	}
+	return path, nil
+}
+
+// listInstallDir は root (インストール先) からの相対パス dir の内容のうち、署名の対象になるものを返す
+func listInstallDir(root, dir string) ([]DirectoryEntry, error) {
+	path, err := installSandboxPath(root, dir)
+	if err != nil {
+		return nil, err
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(realRoot, path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := listSandboxDir(root, dir)
+	if err != nil {
+		return nil, err
+	}
+	covered := make([]DirectoryEntry, 0, len(entries))
+	for _, entry := range entries {
+		if pluginSignatureCovers(filepath.ToSlash(filepath.Join(rel, entry.Name)), entry.IsDirectory) {
+			covered = append(covered, entry)
+		}
+	}
+	return covered, nil
+}
+
+// readInstallFile は root (インストール先) からの相対パス name のファイルを読み込む
+// 署名の対象にならないファイルは読み込まない
+func readInstallFile(root, name string) ([]byte, error) {
+	if _, err := installSandboxPath(root, name); err != nil {
+		return nil, err
+	}
+	return readSandboxFile(root, name)
+}
+
+// listSandboxDir は root からの相対パス dir の内容を返す
+func listSandboxDir(root, dir string) ([]DirectoryEntry, error) {
+	path, err := sandboxPath(root, dir)
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]DirectoryEntry, 0, len(files))
+	for _, file := range files {
+		entries = append(entries, DirectoryEntry{Name: file.Name(), IsDirectory: file.IsDir()})
+	}
+	return entries, nil
+}
+
+// readSandboxFile は root からの相対パス name のファイルを読み込む
+func readSandboxFile(root, name string) ([]byte, error) {
+	path, err := sandboxPath(root, name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", name)
+	}
+	if info.Size() > maxPluginFileSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", name, maxPluginFileSize)
+	}
+	return os.ReadFile(path)
+}
+
+// writeSandboxFile は root からの相対パス name にファイルを書き込む (親ディレクトリは作成する)
+func writeSandboxFile(root, name string, data []byte) error {
+	if len(data) > maxPluginFileSize {
+		return fmt.Errorf("%s is larger than %d bytes", name, maxPluginFileSize)
+	}
+	if filepath.Clean(name) == "." {
+		return fmt.Errorf("%q is not a file name", name)
+	}
+	path, err := sandboxPath(root, name)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Lstat(path); err == nil && !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", name)
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// removeSandboxFile は root からの相対パス name のファイルか空のディレクトリを削除する
+func removeSandboxFile(root, name string) error {
+	if filepath.Clean(name) == "." {
+		return fmt.Errorf("%q is not a file name", name)
+	}
+	path, err := sandboxPath(root, name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPluginSignatureCovers(t *testing.T) {
+	tests := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{".", true, true},
+		{"index.js", false, true},
+		{"dist/index.js", false, true},
+		{"dist", true, true},
+		{"signature.json", false, false},
+		{"dist/signature.json", false, true},
+		{"node_modules", true, false},
+		{"node_modules/lib/index.js", false, false},
+		{".hidden/index.js", false, false},
+		{"dist/.cache", true, false},
+		{".env", false, true},
+	}
+	for _, tt := range tests {
+		if got := pluginSignatureCovers(tt.rel, tt.isDir); got != tt.want {
+			t.Errorf("pluginSignatureCovers(%q, %v) = %v, want %v", tt.rel, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestInstallSandboxRejectsUnsignedFiles(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"manifest.json":              `{"id": "p"}`,
+		"index.js":                   "export default {};",
+		"signature.json":             "{}",
+		"node_modules/lib/index.js":  "unsigned",
+		".hidden/index.js":           "unsigned",
+		"dist/settings.js":           "export default {};",
+		"node_modules/dist/extra.js": "unsigned",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 署名の対象内から対象外を指すシンボリックリンク
+	if err := os.Symlink(filepath.Join(root, "node_modules", "lib"), filepath.Join(root, "lib")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"index.js", false},
+		{"dist/settings.js", false},
+		{"signature.json", true},
+		{"node_modules/lib/index.js", true},
+		{".hidden/index.js", true},
+		{"lib/index.js", true},
+	}
+	for _, tt := range tests {
+		_, err := readInstallFile(root, tt.name)
+		if tt.wantErr {
+			if !errors.Is(err, ErrOutsideSandbox) {
+				t.Errorf("readInstallFile(%q) error = %v, want ErrOutsideSandbox", tt.name, err)
+			}
+		} else if err != nil {
+			t.Errorf("readInstallFile(%q): %v", tt.name, err)
+		}
+	}
+
+	entries, err := listInstallDir(root, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	for _, hidden := range []string{"node_modules", ".hidden", "signature.json"} {
+		if slices.Contains(names, hidden) {
+			t.Errorf("listInstallDir listed %s: %v", hidden, names)
+		}
+	}
+	if _, err := listInstallDir(root, "node_modules"); !errors.Is(err, ErrOutsideSandbox) {
+		t.Errorf("listInstallDir(node_modules) error = %v, want ErrOutsideSandbox", err)
+	}
+
+	if got := findPluginModule(root, "index"); got != filepath.Join(root, "index.js") {
+		t.Errorf("findPluginModule(index) = %q", got)
+	}
+	if got := findPluginModule(root, "extra"); got != "" {
+		t.Errorf("findPluginModule(extra) = %q, want none", got)
+	}
+}
+
+func TestSandboxPathRejectsTraversal(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rel := range []string{"..", "../secret.txt", "sub/../../secret.txt", "sub/../..", "/etc/passwd", `..\secret.txt`, `sub\file.txt`} {
+		if path, err := sandboxPath(root, rel); !errors.Is(err, ErrOutsideSandbox) {
+			t.Errorf("sandboxPath(%q) = %q, %v, want ErrOutsideSandbox", rel, path, err)
+		}
+	}
+
+	valid := map[string]string{
+		"":                 realRoot,
+		".":                realRoot,
+		"sub/../file.txt":  filepath.Join(realRoot, "file.txt"),
+		"sub/new/file.txt": filepath.Join(realRoot, "sub", "new", "file.txt"),
+	}
+	for rel, want := range valid {
+		if path, err := sandboxPath(root, rel); err != nil || path != want {
+			t.Errorf("sandboxPath(%q) = %q, %v, want %q", rel, path, err, want)
+		}
+	}
+}
+
+func TestDataSandboxRejectsSymlinkEscape(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"out":      outside,
+		"secret":   secret,
+		"relative": filepath.Join("..", filepath.Base(outside), "secret.txt"),
+		// 存在しない外のファイルを指すリンクに書き込むと、外にファイルが作られる
+		"dangling": filepath.Join(outside, "created.txt"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 中を指すリンクは使える
+	if err := os.WriteFile(filepath.Join(root, "notes.txt"), []byte("notes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("notes.txt", filepath.Join(root, "inside")); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := readSandboxFile(root, "inside"); err != nil || string(data) != "notes" {
+		t.Errorf("readSandboxFile(inside) = %q, %v", data, err)
+	}
+
+	for _, name := range []string{"out/secret.txt", "secret", "relative"} {
+		if _, err := readSandboxFile(root, name); !errors.Is(err, ErrOutsideSandbox) {
+			t.Errorf("readSandboxFile(%q) error = %v, want ErrOutsideSandbox", name, err)
+		}
+		if err := removeSandboxFile(root, name); !errors.Is(err, ErrOutsideSandbox) {
+			t.Errorf("removeSandboxFile(%q) error = %v, want ErrOutsideSandbox", name, err)
+		}
+	}
+	if _, err := listSandboxDir(root, "out"); !errors.Is(err, ErrOutsideSandbox) {
+		t.Errorf("listSandboxDir(out) error = %v, want ErrOutsideSandbox", err)
+	}
+	for _, name := range []string{"out/new.txt", "out/sub/new.txt", "secret", "relative", "dangling"} {
+		if err := writeSandboxFile(root, name, []byte("overwritten")); err == nil {
+			t.Errorf("writeSandboxFile(%q) succeeded", name)
+		}
+	}
+
+	if data, _ := os.ReadFile(secret); string(data) != "secret" {
+		t.Errorf("file outside the sandbox was changed: %q", data)
+	}
+	entries, err := os.ReadDir(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("files were created outside the sandbox: %v", entries)
+	}
+}
+
+func TestPluginFilesInstallDirIsReadOnly(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv(configDirEnv, configDir)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(pluginPathEnv, "")
+	installDir := filepath.Join(configDir, "plugins", "notes")
+	if err := os.MkdirAll(installDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"manifest.json": `{"manifestVersion": 1, "id": "notes", "name": "Notes", "version": "1.0.0", "permissions": ["fs.read", "fs.write"]}`,
+		"index.js":      "export default {};",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(installDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	app := NewFakeEnvironment().App
+	app.permissions = NewPermissionManager(func(PermissionRequest) (bool, error) { return true, nil })
+	entry, err := app.LoadPlugin(openTestLoader(t, app), "notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := entry.Token
+	dataDir, err := pluginDataDir("notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 書き込みは常にデータディレクトリに行われる
+	if err := app.WritePluginFile(token, "index.js", "hijacked"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dataDir, "index.js")); string(data) != "hijacked" {
+		t.Errorf("data/index.js = %q, want the written content", data)
+	}
+
+	// データディレクトリからインストール先に出るパスやリンクは使えない
+	if err := os.Symlink(installDir, filepath.Join(dataDir, "install")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"../../plugins/notes/index.js", "install/index.js", "install/new.js", filepath.Join(installDir, "index.js")} {
+		if err := app.WritePluginFile(token, name, "hijacked"); !errors.Is(err, ErrOutsideSandbox) {
+			t.Errorf("WritePluginFile(%q) error = %v, want ErrOutsideSandbox", name, err)
+		}
+		if err := app.DeletePluginFile(token, name); !errors.Is(err, ErrOutsideSandbox) {
+			t.Errorf("DeletePluginFile(%q) error = %v, want ErrOutsideSandbox", name, err)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(installDir, "index.js")); string(data) != files["index.js"] {
+		t.Errorf("installed index.js was changed: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(installDir, "new.js")); !os.IsNotExist(err) {
+		t.Errorf("a file was created in the install directory: %v", err)
+	}
+
+	// インストール先は読み取れるが、外には出られない
+	if content, err := app.ReadPluginFile(token, PluginFSInstall, "index.js"); err != nil || content != files["index.js"] {
+		t.Errorf("ReadPluginFile(install, index.js) = %q, %v", content, err)
+	}
+	for _, name := range []string{"..", "../../plugin-data/notes/index.js", "/etc/passwd"} {
+		if _, err := app.ReadPluginFile(token, PluginFSInstall, name); !errors.Is(err, ErrOutsideSandbox) {
+			t.Errorf("ReadPluginFile(install, %q) error = %v, want ErrOutsideSandbox", name, err)
+		}
+	}
+	if _, err := app.ReadPluginFile(token, "config", "permissions.json"); err == nil {
+		t.Error("ReadPluginFile accepted an unknown area")
+	}
+}
@@ -0,0 +1,618 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// プラグインのトークンのバイト数
+const pluginTokenSize = 32
+
+// 追加のプラグインディレクトリを指定する環境変数 (複数の場合は ":" 区切り、Windows では ";")
+const pluginPathEnv = "GHOSTCURSOR_PLUGIN_PATH"
+
+// 追加のプラグインディレクトリを指定する設定ファイル
+const pluginRootsConfigFile = "plugin-roots.json"
+
+// システム全体にインストールされたプラグインのディレクトリ
+const systemPluginDir = "/opt/ghostcursor/plugins"
+
+// pluginRootsConfig は plugin-roots.json の内容
+type pluginRootsConfig struct {
+	Roots []string `json:"roots"`
+}
+
+// PluginRecord はスキャンして解決したプラグインの情報
+type PluginRecord struct {
+	ID       string        `json:"id"`
+	Manifest GhostManifest `json:"manifest"`
+	// プラグインが見つかったプラグインディレクトリ
+	Root string `json:"root"`
+	// プラグイン自身のディレクトリ
+	Dir string `json:"dir"`
+	// 読み込むモジュール (index.js/ts、旧式のプラグインでは content.js/ts)
+	EntryFile string `json:"entryFile"`
+	// 旧式のプラグインの background.js/ts
+	BackgroundFile string `json:"backgroundFile,omitempty"`
+	// アイコンファイルの絶対パス (マニフェストの icon がURLの場合は空)
+	IconPath string            `json:"iconPath,omitempty"`
+	Valid    bool              `json:"valid"`
+	Errors   []ValidationIssue `json:"errors"`
+	Warnings []ValidationIssue `json:"warnings"`
+	// 署名の確認結果
+	Signature PluginSignature `json:"signature"`
+	// 有効かどうかと、ユーザーが決めた並び順での位置 (スキャンの結果にはなく、App が埋める)
+	Enabled  bool `json:"enabled"`
+	Position int  `json:"position"`
+}
+
+// PluginRegistry はプラグインディレクトリをスキャンした結果をキャッシュする
+// 同じIDのプラグインが複数ある場合は、優先順位の高いディレクトリで先に見つかったものを使う
+type PluginRegistry struct {
+	mu sync.RWMutex
+	// スキャンするプラグインディレクトリを優先順に返す
+	roots func() []string
+
+	scanned     bool
+	records     []PluginRecord
+	byID        map[string]int
+	validations []PluginValidationResult
+	// スキャンしたときの各プラグインのディレクトリの内容 (変更の検出に使う)
+	fingerprints map[string]string
+	// 読み込んだプラグインに発行したトークン (トークン → プラグインID)
+	tokens map[string]string
+	// プラグインを読み込めるホストのローダーのキー (ページを読み込むたびに最初に OpenLoader を呼んだものだけが持つ)
+	loaderKey string
+	// ページが読み込まれてから、まだキーを発行していないか
+	loaderArmed bool
+}
+
+// PluginChangeKind はプラグインの変更の種類
+type PluginChangeKind string
+
+const (
+	PluginAdded   PluginChangeKind = "added"
+	PluginUpdated PluginChangeKind = "updated"
+	PluginRemoved PluginChangeKind = "removed"
+)
+
+// PluginChange は前回のスキャンからのプラグインの変更
+// 削除された場合の Plugin は削除前の情報
+type PluginChange struct {
+	Kind   PluginChangeKind
+	Plugin PluginRecord
+}
+
+// NewPluginRegistry は roots のプラグインディレクトリをスキャンする PluginRegistry を生成する
+// スキャンは最初に参照されたときに行われる
+func NewPluginRegistry(roots func() []string) *PluginRegistry {
+	return &PluginRegistry{roots: roots}
+}
+
+// Plugins はスキャン済みのプラグインを返す
+// IDが重複したプラグインは含まれないが、マニフェストが読めないなどIDのないプラグインは無効として含まれる
+func (r *PluginRegistry) Plugins() []PluginRecord {
+	r.ensureScanned()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return clonePluginRecords(r.records)
+}
+
+// Lookup は id のプラグインを返す
+func (r *PluginRegistry) Lookup(id string) (PluginRecord, bool) {
+	r.ensureScanned()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	i, ok := r.byID[id]
+	if !ok {
+		return PluginRecord{}, false
+	}
+	return clonePluginRecords(r.records[i : i+1])[0], true
+}
+
+// OpenLoader はプラグインを読み込むためのキーを発行する
+// キーは ResetLoader でページが読み込まれたことを知らされた後に一度だけ発行し、次の ResetLoader まではエラーになる
+// ホストのローダーはプラグインのコードを実行する前に受け取る
+func (r *PluginRegistry) OpenLoader() (string, error) {
+	buf := make([]byte, pluginTokenSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate plugin loader key: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.loaderArmed {
+		return "", fmt.Errorf("%w: plugin loader is already open or the page is not ready", ErrPermissionDenied)
+	}
+	r.loaderArmed = false
+	r.loaderKey = hex.EncodeToString(buf)
+	return r.loaderKey, nil
+}
+
+// ResetLoader はフロントエンドのページが (読み込み直しも含めて) 読み込まれたときに呼び、次の OpenLoader でキーを発行できるようにする
+// 以前のページのキーと、そのキーで読み込んだプラグインのトークンは使えなくなる
+func (r *PluginRegistry) ResetLoader() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loaderKey = ""
+	r.loaderArmed = true
+	clear(r.tokens)
+}
+
+// IsLoader は key が OpenLoader で発行したキーかを返す
+func (r *PluginRegistry) IsLoader(key string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.loaderKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(r.loaderKey)) == 1
+}
+
+// IssueToken は id のプラグインを読み込むときに、そのプラグインからの呼び出しを識別するトークンを発行する
+// 以前に発行したトークンは使えなくなる
+func (r *PluginRegistry) IssueToken(id string) (string, error) {
+	r.ensureScanned()
+
+	buf := make([]byte, pluginTokenSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate plugin token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	r.RevokeToken(id)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i, ok := r.byID[id]; !ok || !r.records[i].Valid {
+		return "", fmt.Errorf("plugin %q not found or not valid", id)
+	}
+	if r.tokens == nil {
+		r.tokens = make(map[string]string)
+	}
+	r.tokens[token] = id
+	return token, nil
+}
+
+// RevokeToken は id のプラグインに発行したトークンを使えなくする
+func (r *PluginRegistry) RevokeToken(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for token, owner := range r.tokens {
+		if owner == id {
+			delete(r.tokens, token)
+		}
+	}
+}
+
+// LookupToken はトークンを発行したプラグインを返す
+func (r *PluginRegistry) LookupToken(token string) (PluginRecord, bool) {
+	r.mu.RLock()
+	id, ok := r.tokens[token]
+	r.mu.RUnlock()
+	if !ok {
+		return PluginRecord{}, false
+	}
+	return r.Lookup(id)
+}
+
+// Validations はスキャンしたすべてのプラグインディレクトリの検証結果を返す (IDが重複したものも含む)
+func (r *PluginRegistry) Validations() []PluginValidationResult {
+	r.ensureScanned()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]PluginValidationResult{}, r.validations...)
+}
+
+// Rescan はプラグインディレクトリをスキャンし直してキャッシュを置き換え、前回のスキャンからの変更を返す
+// 最初のスキャンでは変更を返さない
+func (r *PluginRegistry) Rescan() []PluginChange {
+	roots := r.roots()
+	return r.rescan(roots, fingerprintPlugins(roots))
+}
+
+// Sync はプラグインのディレクトリに変更があった場合だけスキャンし直し、変更を返す
+func (r *PluginRegistry) Sync() []PluginChange {
+	roots := r.roots()
+	fingerprints := fingerprintPlugins(roots)
+
+	r.mu.RLock()
+	unchanged := r.scanned && maps.Equal(fingerprints, r.fingerprints)
+	r.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+	return r.rescan(roots, fingerprints)
+}
+
+func (r *PluginRegistry) rescan(roots []string, fingerprints map[string]string) []PluginChange {
+	records, validations := scanPlugins(roots)
+
+	byID := make(map[string]int, len(records))
+	for i, record := range records {
+		if record.ID != "" {
+			byID[record.ID] = i
+		}
+	}
+
+	r.mu.Lock()
+	var changes []PluginChange
+	if r.scanned {
+		changes = diffPlugins(r.records, r.byID, r.fingerprints, records, byID, fingerprints)
+	}
+	r.scanned = true
+	r.records = records
+	r.byID = byID
+	r.validations = validations
+	r.fingerprints = fingerprints
+	// 削除されたり無効になったりしたプラグインのトークンは使えなくする
+	for token, id := range r.tokens {
+		if i, ok := byID[id]; !ok || !records[i].Valid {
+			delete(r.tokens, token)
+		}
+	}
+	r.mu.Unlock()
+
+	fmt.Printf("Plugin registry scanned: %d plugins, %d changes\n", len(records), len(changes))
+	return changes
+}
+
+// diffPlugins は2回のスキャン結果をIDで突き合わせて変更を返す (IDのないプラグインは対象外)
+// 削除、追加・更新の順で、追加・更新はスキャン結果の順に並ぶ
+func diffPlugins(oldRecords []PluginRecord, oldByID map[string]int, oldFingerprints map[string]string,
+	newRecords []PluginRecord, newByID map[string]int, newFingerprints map[string]string) []PluginChange {
+	var changes []PluginChange
+	for _, old := range oldRecords {
+		if _, ok := newByID[old.ID]; old.ID != "" && !ok {
+			changes = append(changes, PluginChange{Kind: PluginRemoved, Plugin: old})
+		}
+	}
+
+	for _, record := range newRecords {
+		if record.ID == "" {
+			continue
+		}
+		i, ok := oldByID[record.ID]
+		if !ok {
+			changes = append(changes, PluginChange{Kind: PluginAdded, Plugin: record})
+			continue
+		}
+		old := oldRecords[i]
+		if !reflect.DeepEqual(old, record) || oldFingerprints[old.Dir] != newFingerprints[record.Dir] {
+			changes = append(changes, PluginChange{Kind: PluginUpdated, Plugin: record})
+		}
+	}
+	return changes
+}
+
+// fingerprintPlugins は roots の直下の各ディレクトリについて、含まれるファイルの名前・サイズ・更新時刻から計算した値を返す
+func fingerprintPlugins(roots []string) map[string]string {
+	fingerprints := make(map[string]string)
+	for _, root := range roots {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if isPluginDirEntry(entry) {
+				dir := filepath.Join(root, entry.Name())
+				fingerprints[dir] = fingerprintDir(dir)
+			}
+		}
+	}
+	return fingerprints
+}
+
+func fingerprintDir(dir string) string {
+	h := fnv.New64a()
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			// 依存パッケージや隠しディレクトリの変更ではプラグインを読み込み直さない
+			if path != dir && skippedPluginDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		fmt.Fprintf(h, "%s\x00%d\x00%d\n", rel, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+func (r *PluginRegistry) ensureScanned() {
+	r.mu.RLock()
+	scanned := r.scanned
+	r.mu.RUnlock()
+	if !scanned {
+		r.Rescan()
+	}
+}
+
+// scanPlugins は roots の直下のディレクトリをプラグインとして読み込み、依存関係を解決した読み込み順に並べる
+func scanPlugins(roots []string) ([]PluginRecord, []PluginValidationResult) {
+	var records []PluginRecord
+	var validations []PluginValidationResult
+	// 最初に見つかったプラグイン (ID → validations と records でのインデックス)
+	type firstFound struct {
+		dir        string
+		validation int
+		record     int
+	}
+	seen := make(map[string]firstFound)
+
+	trust, err := loadPluginTrust()
+	if err != nil {
+		fmt.Printf("Error loading plugin trust settings: %v\n", err)
+	}
+
+	for _, root := range roots {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			fmt.Printf("Error: Failed to read directory %s: %v\n", root, err)
+			continue
+		}
+
+		// os.ReadDir は名前順なので、同じディレクトリ内の優先順位も安定する
+		for _, entry := range entries {
+			if !isPluginDirEntry(entry) {
+				continue
+			}
+
+			dir := filepath.Join(root, entry.Name())
+			validation := validatePlugin(dir)
+			applyPluginTrust(&validation, trust)
+			validations = append(validations, validation)
+
+			record := resolvePlugin(root, dir, validation)
+			if record.ID != "" {
+				if first, ok := seen[record.ID]; ok {
+					// 優先順位の低いほうは読み込まず、両方の検証結果に警告を残す
+					fmt.Printf("Skipping plugin %s at %s: shadowed by %s\n", record.ID, dir, first.dir)
+					shadowed := &validations[len(validations)-1]
+					shadowed.ShadowedBy = first.dir
+					shadowed.Warnings = append(shadowed.Warnings, ValidationIssue{Path: "$.id", Code: issueShadowed,
+						Message: fmt.Sprintf("plugin %q is not loaded because %s has the same id and takes precedence", record.ID, first.dir)})
+					issue := ValidationIssue{Path: "$.id", Code: issueShadowed,
+						Message: fmt.Sprintf("plugin %q at %s has the same id and is not loaded", record.ID, dir)}
+					validations[first.validation].Warnings = append(validations[first.validation].Warnings, issue)
+					records[first.record].Warnings = append(records[first.record].Warnings, issue)
+					continue
+				}
+				seen[record.ID] = firstFound{dir: dir, validation: len(validations) - 1, record: len(records)}
+			}
+			records = append(records, record)
+		}
+	}
+
+	host, _ := parseSemver(hostVersion)
+	return resolvePluginLoadOrder(records, validations, host), validations
+}
+
+// isPluginDirEntry はプラグインディレクトリ直下のエントリがプラグインかを返す
+// 隠しディレクトリはインストール中の一時ディレクトリなどに使うので対象外
+func isPluginDirEntry(entry fs.DirEntry) bool {
+	return entry.IsDir() && !strings.HasPrefix(entry.Name(), ".")
+}
+
+// resolvePlugin は検証結果からマニフェスト、モジュール、アイコンのパスを解決する
+func resolvePlugin(root, dir string, validation PluginValidationResult) PluginRecord {
+	record := PluginRecord{
+		Manifest: validation.manifest,
+		Root:     root,
+		Dir:      dir,
+		// アイコンが見つからないだけのプラグインは警告として従来どおり読み込む
+		Valid:     validation.IsValid,
+		Errors:    append([]ValidationIssue{}, validation.Errors...),
+		Warnings:  append([]ValidationIssue{}, validation.Warnings...),
+		Signature: validation.Signature,
+	}
+	record.ID = record.Manifest.ID
+
+	if record.EntryFile = findPluginModule(dir, "index"); record.EntryFile == "" {
+		record.EntryFile = findPluginModule(dir, "content")
+		record.BackgroundFile = findPluginModule(dir, "background")
+	}
+	record.IconPath = resolvePluginIcon(dir, record.Manifest.Icon)
+	return record
+}
+
+// findPluginModule は dist/ とプラグインのディレクトリから moduleName の .js/.ts を探す
+func findPluginModule(dir, moduleName string) string {
+	candidates := []string{
+		"dist/" + moduleName + ".js",
+		moduleName + ".js",
+		"dist/" + moduleName + ".ts",
+		moduleName + ".ts",
+	}
+	for _, name := range candidates {
+		path, err := installSandboxPath(dir, name)
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// resolvePluginIcon はマニフェストの icon をファイルのパスに解決する
+// 見つからない場合は assets/ も探す
+func resolvePluginIcon(dir, icon string) string {
+	if icon == "" || strings.HasPrefix(icon, "http") || strings.HasPrefix(icon, "data:") {
+		return ""
+	}
+
+	// プラグインのディレクトリの外にあるアイコンは使わない
+	for _, name := range []string{icon, filepath.Join("assets", filepath.Base(icon))} {
+		path, err := installSandboxPath(dir, filepath.ToSlash(name))
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// pluginDirectories は存在するプラグインディレクトリを優先順に返す
+// 同じIDのプラグインは先のディレクトリのものが使われる。ユーザーのディレクトリがシステムのディレクトリより優先される
+//  1. ~/.config/ghostcursor/plugins (InstallPlugin のインストール先)
+//  2. ~/ghostcursor/ghosts
+//  3. 環境変数 GHOSTCURSOR_PLUGIN_PATH のディレクトリ (書いた順)
+//  4. plugin-roots.json の roots のディレクトリ (書いた順)
+//  5. /opt/ghostcursor/plugins
+func pluginDirectories() []string {
+	existing := []string{}
+	for _, dir := range pluginRootCandidates() {
+		info, err := os.Stat(dir)
+		switch {
+		case err == nil && info.IsDir():
+			existing = append(existing, dir)
+		case err == nil:
+			fmt.Printf("Not a directory: %s\n", dir)
+		case !os.IsNotExist(err):
+			fmt.Printf("Error checking directory %s: %v\n", dir, err)
+		}
+	}
+	return existing
+}
+
+// pluginRootCandidates は存在しないものも含めたプラグインディレクトリを優先順に返す
+// 同じディレクトリが複数回指定された場合は最初のものだけを返す
+func pluginRootCandidates() []string {
+	var candidates []string
+	if dir, err := configDir(); err == nil {
+		candidates = append(candidates, filepath.Join(dir, "plugins"))
+	} else {
+		fmt.Printf("Error getting config directory: %v\n", err)
+	}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(homeDir, "ghostcursor", "ghosts"))
+	} else {
+		fmt.Printf("Error getting user home directory: %v\n", err)
+	}
+	candidates = append(candidates, extraPluginDirectories()...)
+	candidates = append(candidates, systemPluginDir)
+
+	var roots []string
+	listed := make(map[string]bool)
+	for _, dir := range candidates {
+		if !listed[dir] {
+			listed[dir] = true
+			roots = append(roots, dir)
+		}
+	}
+	return roots
+}
+
+// pluginRootsCache は plugin-roots.json の内容のキャッシュ
+// ファイルの更新時刻と大きさが変わるまで読み直さないため、壊れたファイルのエラーも変わるまで一度だけ表示される
+var pluginRootsCache struct {
+	mu      sync.Mutex
+	loaded  bool
+	path    string
+	exists  bool
+	modTime time.Time
+	size    int64
+	roots   []string
+}
+
+// loadPluginRoots は plugin-roots.json の roots を返す
+func loadPluginRoots() []string {
+	path, err := configPath(pluginRootsConfigFile)
+	if err != nil {
+		fmt.Printf("Error loading extra plugin directories: %v\n", err)
+		return nil
+	}
+	info, statErr := os.Stat(path)
+	exists := statErr == nil
+	var modTime time.Time
+	var size int64
+	if exists {
+		modTime, size = info.ModTime(), info.Size()
+	}
+
+	c := &pluginRootsCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loaded && c.path == path && c.exists == exists && c.modTime.Equal(modTime) && c.size == size {
+		return slices.Clone(c.roots)
+	}
+
+	var config pluginRootsConfig
+	if _, err := readConfigFile(pluginRootsConfigFile, &config); err != nil {
+		fmt.Printf("Error loading extra plugin directories: %v\n", err)
+	}
+	c.loaded, c.path, c.exists, c.modTime, c.size = true, path, exists, modTime, size
+	c.roots = config.Roots
+	return slices.Clone(c.roots)
+}
+
+// extraPluginDirectories は環境変数と plugin-roots.json で追加されたプラグインディレクトリを返す
+// 相対パスは無視し、~/ はホームディレクトリにする
+func extraPluginDirectories() []string {
+	var roots []string
+	if value := os.Getenv(pluginPathEnv); value != "" {
+		roots = append(roots, filepath.SplitList(value)...)
+	}
+	roots = append(roots, loadPluginRoots()...)
+
+	var dirs []string
+	for _, root := range roots {
+		if rest, ok := strings.CutPrefix(root, "~/"); ok {
+			homeDir, err := os.UserHomeDir()
+			if err != nil {
+				fmt.Printf("Error getting user home directory: %v\n", err)
+				continue
+			}
+			root = filepath.Join(homeDir, rest)
+		}
+		if root == "" {
+			continue
+		}
+		if !filepath.IsAbs(root) {
+			fmt.Printf("Ignoring relative plugin directory: %s\n", root)
+			continue
+		}
+		dirs = append(dirs, filepath.Clean(root))
+	}
+	return dirs
+}
+
+func clonePluginRecords(records []PluginRecord) []PluginRecord {
+	cloned := make([]PluginRecord, len(records))
+	for i, record := range records {
+		record.Manifest.Engines = maps.Clone(record.Manifest.Engines)
+		record.Manifest.Dependencies = maps.Clone(record.Manifest.Dependencies)
+		record.Manifest.Permissions = append([]string(nil), record.Manifest.Permissions...)
+		record.Manifest.Settings = append([]SettingField(nil), record.Manifest.Settings...)
+		record.Errors = append([]ValidationIssue{}, record.Errors...)
+		record.Warnings = append([]ValidationIssue{}, record.Warnings...)
+		cloned[i] = record
+	}
+	return cloned
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPluginLoaderReload(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv(configDirEnv, configDir)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(pluginPathEnv, "")
+	writeTestPlugin(t, filepath.Join(configDir, "plugins", "clock"), "clock", "1.0.0")
+
+	env := NewFakeEnvironment()
+	env.App.startup(context.Background())
+	t.Cleanup(func() { env.App.shutdown(context.Background()) })
+	app := env.App
+
+	// ページが読み込まれるまではキーを発行しない
+	if _, err := app.OpenPluginLoader(); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("OpenPluginLoader before dom ready: err = %v, want ErrPermissionDenied", err)
+	}
+
+	app.resetPluginLoader()
+	if events := env.Events.EventsNamed(EventPluginLoaderReady); len(events) != 1 {
+		t.Fatalf("plugin-loader-ready emitted %d times, want 1", len(events))
+	}
+	firstKey, err := app.OpenPluginLoader()
+	if err != nil {
+		t.Fatalf("OpenPluginLoader: %v", err)
+	}
+	// プラグインのコードから呼ばれてもキーは得られない
+	if _, err := app.OpenPluginLoader(); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("second OpenPluginLoader: err = %v, want ErrPermissionDenied", err)
+	}
+	entry, err := app.LoadPlugin(firstKey, "clock")
+	if err != nil {
+		t.Fatalf("LoadPlugin: %v", err)
+	}
+	if _, err := app.StorageList(entry.Token); err != nil {
+		t.Fatalf("StorageList with the issued token: %v", err)
+	}
+
+	// ページを読み込み直すと、以前のキーとトークンは使えなくなり、新しいキーを受け取れる
+	app.resetPluginLoader()
+	if _, err := app.LoadPlugin(firstKey, "clock"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("LoadPlugin with the old key: err = %v, want ErrPermissionDenied", err)
+	}
+	if _, err := app.StorageList(entry.Token); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("StorageList with a token of the old page: err = %v, want ErrPermissionDenied", err)
+	}
+	secondKey, err := app.OpenPluginLoader()
+	if err != nil {
+		t.Fatalf("OpenPluginLoader after reload: %v", err)
+	}
+	if secondKey == firstKey {
+		t.Error("the reloaded page got the same key")
+	}
+	if _, err := app.OpenPluginLoader(); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("OpenPluginLoader after the reloaded page took the key: err = %v, want ErrPermissionDenied", err)
+	}
+	entry, err = app.LoadPlugin(secondKey, "clock")
+	if err != nil {
+		t.Fatalf("LoadPlugin after reload: %v", err)
+	}
+	if _, err := app.StorageList(entry.Token); err != nil {
+		t.Errorf("StorageList with the new token: %v", err)
+	}
+}
+
+func TestHostOnlyMethods(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv(configDirEnv, configDir)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(pluginPathEnv, "")
+	writeTestPlugin(t, filepath.Join(configDir, "plugins", "clock"), "clock", "1.0.0")
+	writeTestPlugin(t, filepath.Join(configDir, "plugins", "memo"), "memo", "1.0.0")
+
+	app := NewFakeEnvironment().App
+	key := openTestLoader(t, app)
+	clock, err := app.LoadPlugin(key, "clock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// プラグインが持っているのは自分のトークンだけで、ローダーのキーは持っていない
+	calls := map[string]func(key string) error{
+		"RevokePluginPermission": func(key string) error {
+			return app.RevokePluginPermission(key, "memo", string(PermissionClipboardRead))
+		},
+		"EnablePlugin": func(key string) error {
+			_, err := app.EnablePlugin(key, "memo")
+			return err
+		},
+		"DisablePlugin": func(key string) error {
+			_, err := app.DisablePlugin(key, "memo")
+			return err
+		},
+		"ReorderPlugins": func(key string) error {
+			_, err := app.ReorderPlugins(key, []string{"memo", "clock"})
+			return err
+		},
+		"InstallPlugin": func(key string) error {
+			_, err := app.InstallPlugin(key, filepath.Join(t.TempDir(), "missing.zip"))
+			return err
+		},
+		"UninstallPlugin": func(key string) error {
+			return app.UninstallPlugin(key, "memo")
+		},
+		"RollbackPlugin": func(key string) error {
+			_, err := app.RollbackPlugin(key, "memo")
+			return err
+		},
+		"InstallFromStore": func(key string) error {
+			_, err := app.InstallFromStore(key, "memo")
+			return err
+		},
+	}
+	for name, call := range calls {
+		for _, bad := range []string{"", clock.Token, "0000"} {
+			if err := call(bad); !errors.Is(err, ErrPermissionDenied) {
+				t.Errorf("%s with key %q: err = %v, want ErrPermissionDenied", name, bad, err)
+			}
+		}
+	}
+	if _, ok := app.plugins.Lookup("memo"); !ok {
+		t.Fatal("memo was uninstalled without the loader key")
+	}
+	if record := app.pluginWithState("memo"); !record.Enabled || record.Position != 1 {
+		t.Fatalf("memo state changed without the loader key: enabled %v, position %d", record.Enabled, record.Position)
+	}
+
+	// ホストのキーなら呼べる
+	if _, err := app.DisablePlugin(key, "memo"); err != nil {
+		t.Errorf("DisablePlugin with the loader key: %v", err)
+	}
+	if _, err := app.ReorderPlugins(key, []string{"memo", "clock"}); err != nil {
+		t.Errorf("ReorderPlugins with the loader key: %v", err)
+	}
+}
+
+func TestPluginLogsAreScopedToToken(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv(configDirEnv, configDir)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(pluginPathEnv, "")
+	writeTestPlugin(t, filepath.Join(configDir, "plugins", "clock"), "clock", "1.0.0")
+	writeTestPlugin(t, filepath.Join(configDir, "plugins", "memo"), "memo", "1.0.0")
+
+	app := NewFakeEnvironment().App
+	key := openTestLoader(t, app)
+	tokens := make(map[string]string)
+	for _, id := range []string{"clock", "memo"} {
+		entry, err := app.LoadPlugin(key, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens[id] = entry.Token
+	}
+
+	if err := app.WritePluginLog(tokens["clock"], LogLevelInfo, "tick"); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.WritePluginLog(tokens["memo"], LogLevelWarn, "saved"); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]string{"clock": "[clock] [INFO] tick", "memo": "[memo] [WARN] saved"} {
+		lines, err := app.ReadPluginLogs(tokens[id], 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) != 1 || !strings.HasSuffix(lines[0], want) {
+			t.Errorf("ReadPluginLogs(%s) = %q, want one line ending with %q", id, lines, want)
+		}
+	}
+
+	if err := app.ClearPluginLogs(tokens["memo"]); err != nil {
+		t.Fatal(err)
+	}
+	if lines, _ := app.ReadPluginLogs(tokens["clock"], 0); len(lines) != 1 {
+		t.Errorf("clearing memo's log changed clock's log: %q", lines)
+	}
+
+	// トークンのない呼び出しはどのプラグインのログも扱えない
+	for _, bad := range []string{"", "clock", key} {
+		if _, err := app.ReadPluginLogs(bad, 0); !errors.Is(err, ErrPermissionDenied) {
+			t.Errorf("ReadPluginLogs(%q): err = %v, want ErrPermissionDenied", bad, err)
+		}
+		if err := app.WritePluginLog(bad, LogLevelInfo, "spoofed"); !errors.Is(err, ErrPermissionDenied) {
+			t.Errorf("WritePluginLog(%q): err = %v, want ErrPermissionDenied", bad, err)
+		}
+		if err := app.ClearPluginLogs(bad); !errors.Is(err, ErrPermissionDenied) {
+			t.Errorf("ClearPluginLogs(%q): err = %v, want ErrPermissionDenied", bad, err)
+		}
+	}
+}
+
+func TestPluginRootPrecedence(t *testing.T) {
+	configDir := t.TempDir()
+	homeDir := t.TempDir()
+	t.Setenv(configDirEnv, configDir)
+	t.Setenv("HOME", homeDir)
+	envFirst, envSecond := t.TempDir(), t.TempDir()
+	t.Setenv(pluginPathEnv, envFirst+string(filepath.ListSeparator)+envSecond)
+	configured := t.TempDir()
+	// 環境変数と同じディレクトリは先に指定されたほうの位置で1回だけ使う
+	roots := `{"roots": ["` + filepath.ToSlash(configured) + `", "` + filepath.ToSlash(envFirst) + `"]}`
+	if err := os.WriteFile(filepath.Join(configDir, pluginRootsConfigFile), []byte(roots), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	userDir := filepath.Join(configDir, "plugins")
+	ghostsDir := filepath.Join(homeDir, "ghostcursor", "ghosts")
+	candidates := pluginRootCandidates()
+	want := []string{userDir, ghostsDir, envFirst, envSecond, configured, systemPluginDir}
+	if !slices.Equal(candidates, want) {
+		t.Fatalf("pluginRootCandidates() = %v, want %v", candidates, want)
+	}
+
+	// /opt には書き込めないので、同じ位置に一時ディレクトリを置く
+	systemDir := t.TempDir()
+	scanRoots := append(slices.Clone(candidates[:len(candidates)-1]), systemDir)
+
+	// 同じIDのプラグインをいくつかのディレクトリに置き、バージョンでどれが使われたかを見分ける
+	placements := []struct {
+		root, dirName, id, version string
+	}{
+		{userDir, "clock", "clock", "1.0.0"},
+		{ghostsDir, "clock", "clock", "2.0.0"},
+		{envFirst, "clock", "clock", "3.0.0"},
+		{configured, "clock", "clock", "5.0.0"},
+		{systemDir, "clock", "clock", "6.0.0"},
+
+		{ghostsDir, "memo", "memo", "2.0.0"},
+		{envSecond, "memo", "memo", "4.0.0"},
+		{systemDir, "memo", "memo", "6.0.0"},
+
+		{envSecond, "calc", "calc", "4.0.0"},
+		{envFirst, "calc", "calc", "3.0.0"},
+
+		{configured, "weather", "weather", "5.0.0"},
+		{systemDir, "weather", "weather", "6.0.0"},
+
+		{systemDir, "system-only", "system-only", "6.0.0"},
+
+		// 同じディレクトリの中では名前順で先のものが使われる
+		{envSecond, "b-timer", "timer", "4.1.0"},
+		{envSecond, "a-timer", "timer", "4.0.0"},
+	}
+	for _, p := range placements {
+		writeTestPlugin(t, filepath.Join(p.root, p.dirName), p.id, p.version)
+	}
+
+	wantWinners := map[string]string{
+		"clock":       filepath.Join(userDir, "clock"),
+		"memo":        filepath.Join(ghostsDir, "memo"),
+		"calc":        filepath.Join(envFirst, "calc"),
+		"weather":     filepath.Join(configured, "weather"),
+		"system-only": filepath.Join(systemDir, "system-only"),
+		"timer":       filepath.Join(envSecond, "a-timer"),
+	}
+	wantVersions := map[string]string{"clock": "1.0.0", "memo": "2.0.0", "calc": "3.0.0", "weather": "5.0.0", "system-only": "6.0.0", "timer": "4.0.0"}
+
+	records, validations := scanPlugins(scanRoots)
+	if len(records) != len(wantWinners) {
+		t.Errorf("loaded %d plugins, want %d", len(records), len(wantWinners))
+	}
+	shadowedCount := make(map[string]int)
+	for _, p := range placements {
+		dir := filepath.Join(p.root, p.dirName)
+		if dir != wantWinners[p.id] {
+			shadowedCount[p.id]++
+		}
+	}
+	for _, record := range records {
+		if record.Dir != wantWinners[record.ID] || record.Manifest.Version != wantVersions[record.ID] {
+			t.Errorf("plugin %s loaded from %s (%s), want %s (%s)", record.ID, record.Dir, record.Manifest.Version, wantWinners[record.ID], wantVersions[record.ID])
+		}
+		if got := countIssues(record.Warnings, issueShadowed); got != shadowedCount[record.ID] {
+			t.Errorf("plugin %s has %d shadowed warnings, want %d", record.ID, got, shadowedCount[record.ID])
+		}
+	}
+
+	// 診断画面の結果: 使われないものは使われるディレクトリを ShadowedBy に持ち、使われるものは隠したものの数だけ警告を持つ
+	if len(validations) != len(placements) {
+		t.Fatalf("got %d validation results, want %d", len(validations), len(placements))
+	}
+	for _, validation := range validations {
+		id := validation.manifest.ID
+		winner := wantWinners[id]
+		if validation.PluginPath == winner {
+			if validation.ShadowedBy != "" {
+				t.Errorf("%s is loaded but ShadowedBy = %s", validation.PluginPath, validation.ShadowedBy)
+			}
+			if got := countIssues(validation.Warnings, issueShadowed); got != shadowedCount[id] {
+				t.Errorf("%s has %d shadowed warnings, want %d", validation.PluginPath, got, shadowedCount[id])
+			}
+			continue
+		}
+		if validation.ShadowedBy != winner {
+			t.Errorf("%s ShadowedBy = %q, want %s", validation.PluginPath, validation.ShadowedBy, winner)
+		}
+		if got := countIssues(validation.Warnings, issueShadowed); got != 1 {
+			t.Errorf("%s has %d shadowed warnings, want 1", validation.PluginPath, got)
+		}
+		if !validation.IsValid {
+			t.Errorf("shadowed plugin %s should still validate: %v", validation.PluginPath, validation.Errors)
+		}
+	}
+
+	// 存在しないディレクトリ (ここでは /opt) は読み込む対象に含めない
+	if dirs := pluginDirectories(); !slices.Equal(dirs, candidates[:len(candidates)-1]) && !slices.Equal(dirs, candidates) {
+		t.Errorf("pluginDirectories() = %v", dirs)
+	}
+}
+
+// countIssues は issues のうち code のものの数を返す
+func countIssues(issues []ValidationIssue, code string) int {
+	n := 0
+	for _, issue := range issues {
+		if issue.Code == code {
+			n++
+		}
+	}
+	return n
+}
+
+func TestReorderPluginsSkipsPluginsWithoutID(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv(configDirEnv, configDir)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(pluginPathEnv, "")
+	pluginDir := filepath.Join(configDir, "plugins")
+	writeTestPlugin(t, filepath.Join(pluginDir, "clock"), "clock", "1.0.0")
+	writeTestPlugin(t, filepath.Join(pluginDir, "memo"), "memo", "1.0.0")
+	// マニフェストが読めないプラグインにはIDがない
+	broken := filepath.Join(pluginDir, "broken")
+	writeTestPlugin(t, broken, "broken", "1.0.0")
+	if err := os.WriteFile(filepath.Join(broken, "manifest.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	env := NewFakeEnvironment()
+	env.App.startup(context.Background())
+	t.Cleanup(func() { env.App.shutdown(context.Background()) })
+	app := env.App
+	key := openTestLoader(t, app)
+	if _, err := app.ReorderPlugins(key, []string{"memo", "clock"}); err != nil {
+		t.Fatal(err)
+	}
+	events := env.Events.EventsNamed(EventPluginsReordered)
+	if len(events) != 1 {
+		t.Fatalf("plugins-reordered emitted %d times, want 1", len(events))
+	}
+	if order := events[0].Data[0].([]string); !slices.Equal(order, []string{"memo", "clock"}) {
+		t.Errorf("plugins-reordered = %q, want [memo clock]", order)
+	}
+
+	if _, err := app.ReorderPlugins(key, []string{"clock", "clock"}); err == nil {
+		t.Error("ReorderPlugins accepted a duplicated id")
+	}
+	if len(env.Events.EventsNamed(EventPluginsReordered)) != 1 {
+		t.Error("plugins-reordered was emitted for a rejected order")
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// プラグインの有効・無効と並び順を保存する設定ファイル
+const pluginStateConfigFile = "plugin-state.json"
+
+// pluginStateConfig は plugin-state.json の内容
+type pluginStateConfig struct {
+	// 無効にしたプラグインのID
+	Disabled []string `json:"disabled"`
+	// ユーザーが決めたゴーストの並び順 (先頭が既定のゴースト)
+	Order []string `json:"order"`
+}
+
+// PluginStateStore はプラグインの有効・無効と並び順を保存する
+// 保存されていないプラグインは有効で、並び順に含まれないものは読み込み順で後ろに並ぶ
+type PluginStateStore struct {
+	mu       sync.Mutex
+	loaded   bool
+	disabled map[string]bool
+	order    []string
+}
+
+// NewPluginStateStore は PluginStateStore を生成する
+func NewPluginStateStore() *PluginStateStore {
+	return &PluginStateStore{}
+}
+
+// Enabled は id のプラグインが有効かを返す
+func (s *PluginStateStore) Enabled(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureLoaded()
+
+	return !s.disabled[id]
+}
+
+// SetEnabled は id のプラグインの有効・無効を変更して保存し、変わったかを返す
+func (s *PluginStateStore) SetEnabled(id string, enabled bool) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureLoaded()
+
+	if !s.disabled[id] == enabled {
+		return false, nil
+	}
+	if enabled {
+		delete(s.disabled, id)
+	} else {
+		s.disabled[id] = true
+	}
+	if err := s.save(); err != nil {
+		// 保存できなければ元に戻す
+		if enabled {
+			s.disabled[id] = true
+		} else {
+			delete(s.disabled, id)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Reorder は ids の順にゴーストを並べて保存する
+// ids にないプラグインは以前の並び順のまま ids の後ろに並ぶ
+func (s *PluginStateStore) Reorder(ids []string) error {
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return fmt.Errorf("plugin %q appears more than once", id)
+		}
+		seen[id] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureLoaded()
+
+	order := slices.Clone(ids)
+	for _, id := range s.order {
+		if !seen[id] {
+			order = append(order, id)
+		}
+	}
+	previous := s.order
+	s.order = order
+	if err := s.save(); err != nil {
+		s.order = previous
+		return err
+	}
+	return nil
+}
+
+// Forget は id のプラグインの有効・無効と並び順を削除する
+func (s *PluginStateStore) Forget(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureLoaded()
+
+	index := slices.Index(s.order, id)
+	if !s.disabled[id] && index < 0 {
+		return nil
+	}
+	disabled := s.disabled[id]
+	previous := s.order
+	delete(s.disabled, id)
+	if index >= 0 {
+		s.order = slices.Delete(slices.Clone(s.order), index, index+1)
+	}
+	if err := s.save(); err != nil {
+		if disabled {
+			s.disabled[id] = true
+		}
+		s.order = previous
+		return err
+	}
+	return nil
+}
+
+// Apply は records (読み込み順) に有効・無効と並び順の位置を埋めたものを返す
+// 読み込み順は変えない。並び順に含まれないプラグインの位置は、読み込み順のまま並び順のプラグインの後ろになる
+// 無効にしたプラグインに依存するプラグインは、依存関係を満たさないものとして無効になる
+func (s *PluginStateStore) Apply(records []PluginRecord) []PluginRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureLoaded()
+
+	rank := make(map[string]int, len(s.order))
+	for i, id := range s.order {
+		rank[id] = i
+	}
+	positions := make([]int, len(records))
+	for i := range positions {
+		positions[i] = i
+	}
+	sort.SliceStable(positions, func(i, j int) bool {
+		ri, iok := rank[records[positions[i]].ID]
+		rj, jok := rank[records[positions[j]].ID]
+		if iok != jok {
+			return iok
+		}
+		return iok && ri < rj
+	})
+
+	applied := clonePluginRecords(records)
+	for position, i := range positions {
+		applied[i].Enabled = !s.disabled[applied[i].ID]
+		applied[i].Position = position
+	}
+	checkDisabledDependencies(applied)
+	return applied
+}
+
+// ensureLoaded は初めて参照されたときに設定ファイルを読み込む (s.mu を持って呼ぶ)
+func (s *PluginStateStore) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+
+	var config pluginStateConfig
+	if _, err := readConfigFile(pluginStateConfigFile, &config); err != nil {
+		// 読めない場合はすべて有効で読み込み順とする
+		fmt.Printf("Error loading plugin state: %v\n", err)
+	}
+	s.disabled = make(map[string]bool, len(config.Disabled))
+	for _, id := range config.Disabled {
+		s.disabled[id] = true
+	}
+	s.order = config.Order
+}
+
+// save は状態を設定ファイルに書き込む (s.mu を持って呼ぶ)
+func (s *PluginStateStore) save() error {
+	return writeConfigFile(pluginStateConfigFile, pluginStateConfig{
+		Disabled: sortedKeys(s.disabled),
+		Order:    s.order,
+	})
+}
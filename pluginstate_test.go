@@ -0,0 +1,96 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// statePlugin は依存先 deps を持つ有効なプラグインの情報を作る
+func statePlugin(id string, deps ...string) PluginRecord {
+	record := PluginRecord{ID: id, Valid: true}
+	if len(deps) > 0 {
+		record.Manifest.Dependencies = make(map[string]string, len(deps))
+		for _, dep := range deps {
+			record.Manifest.Dependencies[dep] = "^1.0.0"
+		}
+	}
+	return record
+}
+
+func TestPluginStateStoreApply(t *testing.T) {
+	// 読み込み順 (依存先が先): base <- ui <- theme, clock は依存なし
+	records := []PluginRecord{
+		statePlugin("base"),
+		statePlugin("clock"),
+		statePlugin("ui", "base"),
+		statePlugin("theme", "ui"),
+	}
+
+	tests := []struct {
+		name      string
+		order     []string
+		disabled  []string
+		positions map[string]int
+		invalid   []string
+	}{
+		{
+			name:      "no state",
+			positions: map[string]int{"base": 0, "clock": 1, "ui": 2, "theme": 3},
+		},
+		{
+			name:      "user order does not change load order",
+			order:     []string{"theme", "clock"},
+			positions: map[string]int{"theme": 0, "clock": 1, "base": 2, "ui": 3},
+		},
+		{
+			name:      "disabled dependency invalidates dependents",
+			disabled:  []string{"base"},
+			positions: map[string]int{"base": 0, "clock": 1, "ui": 2, "theme": 3},
+			invalid:   []string{"ui", "theme"},
+		},
+		{
+			name:      "disabled leaf keeps dependencies valid",
+			order:     []string{"ui"},
+			disabled:  []string{"theme"},
+			positions: map[string]int{"ui": 0, "base": 1, "clock": 2, "theme": 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(configDirEnv, t.TempDir())
+			store := NewPluginStateStore()
+			if tt.order != nil {
+				if err := store.Reorder(tt.order); err != nil {
+					t.Fatalf("Reorder: %v", err)
+				}
+			}
+			for _, id := range tt.disabled {
+				if _, err := store.SetEnabled(id, false); err != nil {
+					t.Fatalf("SetEnabled(%s): %v", id, err)
+				}
+			}
+
+			applied := store.Apply(records)
+			for i, record := range applied {
+				if record.ID != records[i].ID {
+					t.Fatalf("load order changed: got %s at %d, want %s", record.ID, i, records[i].ID)
+				}
+				if got, want := record.Position, tt.positions[record.ID]; got != want {
+					t.Errorf("%s: position = %d, want %d", record.ID, got, want)
+				}
+				if got, want := record.Enabled, !slices.Contains(tt.disabled, record.ID); got != want {
+					t.Errorf("%s: enabled = %v, want %v", record.ID, got, want)
+				}
+				if got, want := record.Valid, !slices.Contains(tt.invalid, record.ID); got != want {
+					t.Errorf("%s: valid = %v, want %v (errors: %v)", record.ID, got, want, record.Errors)
+				}
+			}
+			for _, record := range records {
+				if !record.Valid || len(record.Errors) > 0 {
+					t.Fatalf("Apply modified the input record %s", record.ID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// プラグインディレクトリの変更が落ち着いてからスキャンし直すまでの時間
+// (コピーや展開の途中で何度もスキャンしないようにする)
+const pluginWatchDebounce = 300 * time.Millisecond
+
+// pluginWatcher はプラグインディレクトリと plugin-roots.json の変更をファイルシステムの通知で監視する
+// 通知はディレクトリごとなので、プラグインディレクトリの中のディレクトリもすべて監視する
+// まだ存在しないプラグインディレクトリは、作られたことがわかるように親ディレクトリを監視する
+type pluginWatcher struct {
+	watcher *fsnotify.Watcher
+	// 監視するプラグインディレクトリ (存在しないものも含む) を返す
+	roots func() []string
+
+	current []string
+	watched map[string]bool
+}
+
+// newPluginWatcher は roots のプラグインディレクトリを監視する pluginWatcher を生成する
+func newPluginWatcher(roots func() []string) (*pluginWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start watching plugin directories: %w", err)
+	}
+	w := &pluginWatcher{watcher: watcher, roots: roots, watched: make(map[string]bool)}
+	w.Refresh()
+	return w, nil
+}
+
+// Events はファイルシステムの変更の通知を返す
+func (w *pluginWatcher) Events() <-chan fsnotify.Event {
+	return w.watcher.Events
+}
+
+// Errors は監視中のエラーを返す
+func (w *pluginWatcher) Errors() <-chan error {
+	return w.watcher.Errors
+}
+
+// Close は監視を終了する
+func (w *pluginWatcher) Close() error {
+	return w.watcher.Close()
+}
+
+// Refresh はプラグインディレクトリを取得し直し、追加・削除されたディレクトリの監視を更新する
+func (w *pluginWatcher) Refresh() {
+	w.current = w.roots()
+
+	dirs := make(map[string]bool)
+	if path, err := configPath(pluginRootsConfigFile); err == nil {
+		dirs[filepath.Dir(path)] = true
+	}
+	for _, root := range w.current {
+		if info, err := os.Stat(root); err != nil || !info.IsDir() {
+			dirs[filepath.Dir(root)] = true
+			continue
+		}
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			// fingerprintDir と同じく、依存パッケージや隠しディレクトリの変更は見ない
+			if path != root && skippedPluginDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			dirs[path] = true
+			return nil
+		})
+	}
+
+	for dir := range w.watched {
+		if !dirs[dir] {
+			// 削除されたディレクトリの監視は自動で外れているため、エラーは無視する
+			w.watcher.Remove(dir)
+			delete(w.watched, dir)
+		}
+	}
+	for dir := range dirs {
+		if w.watched[dir] {
+			continue
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		if err := w.watcher.Add(dir); err != nil {
+			fmt.Printf("Error watching %s: %v\n", dir, err)
+			continue
+		}
+		w.watched[dir] = true
+	}
+}
+
+// Relevant は path の変更でプラグインをスキャンし直す必要があるかを返す
+// 親ディレクトリや設定ディレクトリの、プラグインと関係のないファイルの変更は無視する
+func (w *pluginWatcher) Relevant(path string) bool {
+	if config, err := configPath(pluginRootsConfigFile); err == nil && path == config {
+		return true
+	}
+	for _, root := range w.current {
+		if path == root || isWithinDir(root, path) {
+			return true
+		}
+	}
+	return false
+}
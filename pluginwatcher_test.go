@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// writeTestPlugin は dir に manifest.json と index.js だけのプラグインを作る
+func writeTestPlugin(t *testing.T, dir, id, version string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"manifest.json": `{"id": "` + id + `", "name": "` + id + `", "version": "` + version + `"}`,
+		"index.js":      "export default {};",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadPluginRoots(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv(configDirEnv, configDir)
+	path := filepath.Join(configDir, pluginRootsConfigFile)
+
+	steps := []struct {
+		name    string
+		content string // 空なら削除する
+		want    []string
+	}{
+		{name: "missing", want: nil},
+		{name: "created", content: `{"roots": ["/a"]}`, want: []string{"/a"}},
+		{name: "changed", content: `{"roots": ["/a", "/b"]}`, want: []string{"/a", "/b"}},
+		{name: "broken", content: `{"roots": [`, want: nil},
+		{name: "fixed", content: `{"roots": ["/c"]}`, want: []string{"/c"}},
+		{name: "removed", want: nil},
+	}
+
+	modTime := time.Now()
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if step.content == "" {
+				os.Remove(path)
+			} else {
+				if err := os.WriteFile(path, []byte(step.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				// 更新時刻の精度が粗いファイルシステムでも変更がわかるようにずらす
+				modTime = modTime.Add(time.Second)
+				os.Chtimes(path, modTime, modTime)
+			}
+
+			// 変わるまでは何度呼んでも同じ内容を返す
+			for i := 0; i < 2; i++ {
+				if got := loadPluginRoots(); !slices.Equal(got, step.want) {
+					t.Errorf("loadPluginRoots() = %v, want %v", got, step.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPluginWatcherRelevant(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv(configDirEnv, configDir)
+	root := filepath.Join(configDir, "plugins")
+
+	w, err := newPluginWatcher(func() []string { return []string{root} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{root, true},
+		{filepath.Join(root, "clock"), true},
+		{filepath.Join(root, "clock", "index.js"), true},
+		{filepath.Join(configDir, pluginRootsConfigFile), true},
+		{filepath.Join(configDir, pluginStateConfigFile), false},
+		{filepath.Join(configDir, "plugins-old"), false},
+	}
+	for _, tt := range tests {
+		if got := w.Relevant(tt.path); got != tt.want {
+			t.Errorf("Relevant(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestPluginHotReload(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv(configDirEnv, configDir)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(pluginPathEnv, "")
+
+	env := NewFakeEnvironment()
+	env.App.startup(context.Background())
+	t.Cleanup(func() { env.App.shutdown(context.Background()) })
+	env.App.ListPlugins()
+	env.App.startPluginWatching()
+
+	// 監視を始める前に作った空のディレクトリにも監視が付くのを待つ
+	time.Sleep(100 * time.Millisecond)
+
+	// まだ存在しないプラグインディレクトリを作ってプラグインを置く
+	root := filepath.Join(configDir, "plugins")
+	writeTestPlugin(t, filepath.Join(root, "clock"), "clock", "1.0.0")
+	if !env.Events.WaitFor(EventPluginAdded, 1, 5*time.Second) {
+		t.Fatal("plugin-added was not emitted for a new plugin root")
+	}
+
+	writeTestPlugin(t, filepath.Join(root, "clock"), "clock", "1.1.0")
+	if !env.Events.WaitFor(EventPluginUpdated, 1, 5*time.Second) {
+		t.Fatal("plugin-updated was not emitted")
+	}
+	if record, _ := env.App.plugins.Lookup("clock"); record.Manifest.Version != "1.1.0" {
+		t.Errorf("version = %q after update, want 1.1.0", record.Manifest.Version)
+	}
+
+	// plugin-roots.json で追加したディレクトリも監視する
+	extra := t.TempDir()
+	writeTestPlugin(t, filepath.Join(extra, "memo"), "memo", "1.0.0")
+	rootsConfig := `{"roots": ["` + filepath.ToSlash(extra) + `"]}`
+	if err := os.WriteFile(filepath.Join(configDir, pluginRootsConfigFile), []byte(rootsConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !env.Events.WaitFor(EventPluginAdded, 2, 5*time.Second) {
+		t.Fatal("plugin-added was not emitted for a root added to plugin-roots.json")
+	}
+	writeTestPlugin(t, filepath.Join(extra, "memo"), "memo", "2.0.0")
+	if !env.Events.WaitFor(EventPluginUpdated, 2, 5*time.Second) {
+		t.Fatal("plugin-updated was not emitted for a plugin in the added root")
+	}
+
+	if err := os.RemoveAll(filepath.Join(root, "clock")); err != nil {
+		t.Fatal(err)
+	}
+	if !env.Events.WaitFor(EventPluginRemoved, 1, 5*time.Second) {
+		t.Fatal("plugin-removed was not emitted")
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// プラグインの呼び出し回数を数える期間
+const pluginQuotaWindow = time.Minute
+
+// defaultPluginQuotas はプラグインごとに pluginQuotaWindow の間に使える回数
+var defaultPluginQuotas = map[Permission]int{
+	PermissionClipboardRead:  60,
+	PermissionClipboardWrite: 60,
+	PermissionKeyboard:       120,
+	PermissionScreenCapture:  10,
+	PermissionMemoOpen:       10,
+	PermissionFSRead:         600,
+	PermissionFSWrite:        300,
+}
+
+// ErrQuotaExceeded はプラグインが一定時間に使える回数を超えたことを表す
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+type quotaKey struct {
+	pluginID   string
+	permission Permission
+}
+
+type quotaUsage struct {
+	start time.Time
+	count int
+}
+
+// PluginQuota はプラグインと権限の組ごとに、一定時間に使える回数を制限する
+type PluginQuota struct {
+	mu     sync.Mutex
+	clock  Clock
+	window time.Duration
+	limits map[Permission]int
+	usage  map[quotaKey]*quotaUsage
+}
+
+// NewPluginQuota は window の間に limits の回数まで使える PluginQuota を生成する
+// limits にない権限は制限しない
+func NewPluginQuota(clock Clock, window time.Duration, limits map[Permission]int) *PluginQuota {
+	return &PluginQuota{clock: clock, window: window, limits: limits, usage: make(map[quotaKey]*quotaUsage)}
+}
+
+// Allow は pluginID のプラグインが permission をもう一度使えるかを確認し、使えるなら回数を数える
+func (q *PluginQuota) Allow(pluginID string, permission Permission) error {
+	limit, ok := q.limits[permission]
+	if !ok {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.clock.Now()
+	key := quotaKey{pluginID: pluginID, permission: permission}
+	usage := q.usage[key]
+	if usage == nil || now.Sub(usage.start) >= q.window {
+		usage = &quotaUsage{start: now}
+		q.usage[key] = usage
+	}
+	if usage.count >= limit {
+		return fmt.Errorf("%w: plugin %q used %q %d times in %v", ErrQuotaExceeded, pluginID, permission, usage.count, q.window)
+	}
+	usage.count++
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPluginQuotaRefill(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	quota := NewPluginQuota(clock, time.Minute, map[Permission]int{PermissionClipboardRead: 3, PermissionKeyboard: 1})
+
+	allow := func(pluginID string, permission Permission, want bool) {
+		t.Helper()
+		err := quota.Allow(pluginID, permission)
+		if want && err != nil {
+			t.Errorf("Allow(%s, %s): %v", pluginID, permission, err)
+		}
+		if !want && !errors.Is(err, ErrQuotaExceeded) {
+			t.Errorf("Allow(%s, %s) error = %v, want ErrQuotaExceeded", pluginID, permission, err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		allow("clock", PermissionClipboardRead, true)
+	}
+	allow("clock", PermissionClipboardRead, false)
+
+	// プラグインと権限の組ごとに数える
+	allow("memo", PermissionClipboardRead, true)
+	allow("clock", PermissionKeyboard, true)
+	allow("clock", PermissionKeyboard, false)
+	// 制限のない権限
+	for i := 0; i < 10; i++ {
+		allow("clock", PermissionClipboardWrite, true)
+	}
+
+	// 期間が終わるまでは使えない (拒否した呼び出しも期間を延ばさない)
+	clock.Advance(59 * time.Second)
+	allow("clock", PermissionClipboardRead, false)
+	clock.Advance(time.Second)
+	for i := 0; i < 3; i++ {
+		allow("clock", PermissionClipboardRead, true)
+	}
+	allow("clock", PermissionClipboardRead, false)
+
+	// memo の期間は最初に使ったときから始まっている
+	allow("memo", PermissionClipboardRead, true)
+	allow("memo", PermissionClipboardRead, true)
+	allow("memo", PermissionClipboardRead, true)
+	allow("memo", PermissionClipboardRead, false)
+	clock.Advance(time.Minute)
+	allow("memo", PermissionClipboardRead, true)
+	allow("clock", PermissionKeyboard, true)
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// セマンティックバージョン (https://semver.org/) の書式
+var semverPattern = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// Semver はセマンティックバージョン
+type Semver struct {
+	Major, Minor, Patch int
+	// プレリリース識別子 (例: "beta.1" なら ["beta", "1"])
+	Prerelease []string
+	// ビルドメタデータ (比較には使わない)
+	Build string
+}
+
+// parseSemver は "1.2.3"、"1.0.0-beta.1+build.5" の形式のバージョンを解析する
+func parseSemver(version string) (Semver, error) {
+	m := semverPattern.FindStringSubmatch(version)
+	if m == nil {
+		return Semver{}, fmt.Errorf("invalid semantic version %q", version)
+	}
+
+	var v Semver
+	var err error
+	if v.Major, err = strconv.Atoi(m[1]); err != nil {
+		return Semver{}, fmt.Errorf("invalid semantic version %q: %w", version, err)
+	}
+	if v.Minor, err = strconv.Atoi(m[2]); err != nil {
+		return Semver{}, fmt.Errorf("invalid semantic version %q: %w", version, err)
+	}
+	if v.Patch, err = strconv.Atoi(m[3]); err != nil {
+		return Semver{}, fmt.Errorf("invalid semantic version %q: %w", version, err)
+	}
+	if m[4] != "" {
+		v.Prerelease = strings.Split(m[4], ".")
+	}
+	v.Build = m[5]
+	return v, nil
+}
+
+// String は "1.2.3-beta.1+build" の形式でバージョンを表す
+func (v Semver) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare は v が other より前なら負、同じなら 0、後なら正の値を返す
+// プレリリースは同じ番号の正式リリースより前になる
+func (v Semver) Compare(other Semver) int {
+	if c := compareInt(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+
+	switch {
+	case len(v.Prerelease) == 0 && len(other.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(other.Prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.Prerelease) && i < len(other.Prerelease); i++ {
+		if c := comparePrerelease(v.Prerelease[i], other.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(v.Prerelease), len(other.Prerelease))
+}
+
+// comparePrerelease はプレリリース識別子を比べる (数字だけのものは数値として比べ、英字を含むものより前になる)
+func comparePrerelease(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareInt(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// semverComparator はバージョンの範囲を表す比較 (">=1.2.3" など) のひとつ
+type semverComparator struct {
+	op      string
+	version Semver
+}
+
+func (c semverComparator) matches(v Semver) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return cmp == 0
+}
+
+// SemverRange はバージョンの範囲
+// "||" で区切った条件のいずれかを満たせば範囲に含まれ、空白で区切った比較はすべてを満たす必要がある
+type SemverRange struct {
+	raw  string
+	sets [][]semverComparator
+}
+
+// 範囲の比較演算子 (長いものから順に試す)
+var semverRangeOps = []string{">=", "<=", ">", "<", "=", "^", "~"}
+
+// parseSemverRange は ">=1.2.0"、"^1.2.0"、"~1.2.0 || >=2.0.0-beta.1"、"*" の形式の範囲を解析する
+// "^1.2.3" は ">=1.2.3 <2.0.0"、"~1.2.3" は ">=1.2.3 <1.3.0"、演算子のないバージョンはそのバージョンだけを表す
+func parseSemverRange(constraint string) (SemverRange, error) {
+	r := SemverRange{raw: strings.TrimSpace(constraint)}
+	if r.raw == "" {
+		return SemverRange{}, fmt.Errorf("empty version range")
+	}
+
+	for _, alternative := range strings.Split(r.raw, "||") {
+		terms := strings.Fields(alternative)
+		if len(terms) == 0 {
+			return SemverRange{}, fmt.Errorf("invalid version range %q: empty alternative", constraint)
+		}
+
+		var set []semverComparator
+		for _, term := range terms {
+			if term == "*" {
+				continue
+			}
+			comparators, err := parseSemverComparator(term)
+			if err != nil {
+				return SemverRange{}, fmt.Errorf("invalid version range %q: %w", constraint, err)
+			}
+			set = append(set, comparators...)
+		}
+		r.sets = append(r.sets, set)
+	}
+	return r, nil
+}
+
+// parseSemverComparator は "^1.2.3" のような比較をひとつ解析し、等価な比較の並びにする
+func parseSemverComparator(term string) ([]semverComparator, error) {
+	op := ""
+	for _, candidate := range semverRangeOps {
+		if strings.HasPrefix(term, candidate) {
+			op = candidate
+			break
+		}
+	}
+	v, err := parseSemver(strings.TrimPrefix(term, op))
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "^":
+		upper := Semver{Major: v.Major + 1}
+		if v.Major == 0 && v.Minor == 0 {
+			upper = Semver{Patch: v.Patch + 1}
+		} else if v.Major == 0 {
+			upper = Semver{Minor: v.Minor + 1}
+		}
+		return []semverComparator{{">=", v}, {"<", upper}}, nil
+	case "~":
+		return []semverComparator{{">=", v}, {"<", Semver{Major: v.Major, Minor: v.Minor + 1}}}, nil
+	case "":
+		op = "="
+	}
+	return []semverComparator{{op, v}}, nil
+}
+
+// Contains は v が範囲に含まれるかを返す
+func (r SemverRange) Contains(v Semver) bool {
+	for _, set := range r.sets {
+		matched := true
+		for _, c := range set {
+			if !c.matches(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// String は解析する前の範囲の文字列を返す
+func (r SemverRange) String() string {
+	return r.raw
+}
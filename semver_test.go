@@ -0,0 +1,138 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func mustParseSemver(t *testing.T, version string) Semver {
+	t.Helper()
+	v, err := parseSemver(version)
+	if err != nil {
+		t.Fatalf("parseSemver(%q): %v", version, err)
+	}
+	return v
+}
+
+func TestParseSemver(t *testing.T) {
+	valid := map[string]Semver{
+		"0.0.0":                 {},
+		"1.2.3":                 {Major: 1, Minor: 2, Patch: 3},
+		"10.20.30":              {Major: 10, Minor: 20, Patch: 30},
+		"1.0.0-beta.1":          {Major: 1, Prerelease: []string{"beta", "1"}},
+		"1.0.0-0.3.7":           {Major: 1, Prerelease: []string{"0", "3", "7"}},
+		"1.0.0-x-y.1+build.5":   {Major: 1, Prerelease: []string{"x-y", "1"}, Build: "build.5"},
+		"1.0.0+20130313144700":  {Major: 1, Build: "20130313144700"},
+		"2.0.0-rc.1+exp.sha.ff": {Major: 2, Prerelease: []string{"rc", "1"}, Build: "exp.sha.ff"},
+	}
+	for version, want := range valid {
+		got, err := parseSemver(version)
+		if err != nil {
+			t.Errorf("parseSemver(%q): %v", version, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("parseSemver(%q) = %+v, want %+v", version, got, want)
+		}
+		if got.String() != version {
+			t.Errorf("parseSemver(%q).String() = %q", version, got.String())
+		}
+	}
+
+	for _, version := range []string{"", "1", "1.0", "1.0.0.0", "v1.0.0", "01.0.0", "1.02.0", "1.0.0-", "1.0.0-01", "1.0.0-beta..1", "1.0.0+", "1.0.0 ", "latest"} {
+		if _, err := parseSemver(version); err == nil {
+			t.Errorf("parseSemver(%q) succeeded, want an error", version)
+		}
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	// semver.org の優先順位の例に、番号とビルドメタデータの比較を加えたもの (昇順)
+	ordered := []string{
+		"0.9.9",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"1.10.0",
+		"2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a, b := mustParseSemver(t, ordered[i]), mustParseSemver(t, ordered[j])
+			if got, want := a.Compare(b), compareInt(i, j); got != want {
+				t.Errorf("%s.Compare(%s) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+
+	// ビルドメタデータは比較に使わない
+	if c := mustParseSemver(t, "1.0.0+a").Compare(mustParseSemver(t, "1.0.0+b")); c != 0 {
+		t.Errorf("1.0.0+a.Compare(1.0.0+b) = %d, want 0", c)
+	}
+}
+
+func TestSemverRange(t *testing.T) {
+	tests := []struct {
+		constraint string
+		contains   []string
+		excludes   []string
+	}{
+		{constraint: "1.2.3", contains: []string{"1.2.3", "1.2.3+build"}, excludes: []string{"1.2.4", "1.2.3-rc.1"}},
+		{constraint: "=1.2.3", contains: []string{"1.2.3"}, excludes: []string{"1.2.2"}},
+		{constraint: ">1.2.3", contains: []string{"1.2.4", "2.0.0"}, excludes: []string{"1.2.3", "1.2.3-rc.1"}},
+		{constraint: ">=1.2.3", contains: []string{"1.2.3", "9.0.0"}, excludes: []string{"1.2.2", "1.2.3-rc.1"}},
+		{constraint: "<1.2.3", contains: []string{"1.2.2", "1.2.3-rc.1"}, excludes: []string{"1.2.3"}},
+		{constraint: "<=1.2.3", contains: []string{"1.2.3", "0.0.1"}, excludes: []string{"1.2.4"}},
+
+		// ^ はいちばん左の 0 でない番号を上げない範囲
+		{constraint: "^1.2.3", contains: []string{"1.2.3", "1.2.9", "1.9.0"}, excludes: []string{"1.2.2", "2.0.0", "0.9.0"}},
+		{constraint: "^0.2.3", contains: []string{"0.2.3", "0.2.9"}, excludes: []string{"0.2.2", "0.3.0", "1.0.0"}},
+		{constraint: "^0.0.3", contains: []string{"0.0.3"}, excludes: []string{"0.0.2", "0.0.4", "0.1.0"}},
+		{constraint: "^1.0.0-beta.2", contains: []string{"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0", "1.5.0"}, excludes: []string{"1.0.0-beta.1", "1.0.0-alpha", "2.0.0"}},
+
+		// ~ はパッチの番号だけを上げる範囲
+		{constraint: "~1.2.3", contains: []string{"1.2.3", "1.2.99"}, excludes: []string{"1.2.2", "1.3.0"}},
+		{constraint: "~0.1.0", contains: []string{"0.1.0", "0.1.5"}, excludes: []string{"0.2.0"}},
+
+		// 空白で区切った比較はすべて、|| で区切った条件はいずれかを満たす
+		{constraint: ">=1.2.0 <1.5.0", contains: []string{"1.2.0", "1.4.9"}, excludes: []string{"1.1.9", "1.5.0"}},
+		{constraint: "<1.0.0 || >=3.0.0", contains: []string{"0.5.0", "3.0.0", "4.1.0"}, excludes: []string{"1.0.0", "2.9.9"}},
+		{constraint: "~1.2.0 || >=2.0.0-beta.1", contains: []string{"1.2.5", "2.0.0-beta.1", "2.0.0-beta.2", "2.0.0"}, excludes: []string{"1.3.0", "2.0.0-alpha"}},
+		{constraint: "  >=1.0.0   <2.0.0  ", contains: []string{"1.0.0"}, excludes: []string{"2.0.0"}},
+		{constraint: "*", contains: []string{"0.0.0", "1.0.0-rc.1", "99.0.0"}},
+		{constraint: "* || 1.0.0", contains: []string{"5.0.0"}},
+	}
+
+	for _, tt := range tests {
+		r, err := parseSemverRange(tt.constraint)
+		if err != nil {
+			t.Errorf("parseSemverRange(%q): %v", tt.constraint, err)
+			continue
+		}
+		for _, version := range tt.contains {
+			if !r.Contains(mustParseSemver(t, version)) {
+				t.Errorf("%q should contain %s", tt.constraint, version)
+			}
+		}
+		for _, version := range tt.excludes {
+			if r.Contains(mustParseSemver(t, version)) {
+				t.Errorf("%q should not contain %s", tt.constraint, version)
+			}
+		}
+	}
+}
+
+func TestParseSemverRangeErrors(t *testing.T) {
+	for _, constraint := range []string{"", "   ", "||", ">=1.0.0 ||", "|| 1.0.0", "^1.2", "~1", ">= 1.0.0", "1.x", ">>1.0.0", "=>1.0.0", "latest", "1.0.0 - 2.0.0"} {
+		if r, err := parseSemverRange(constraint); err == nil {
+			t.Errorf("parseSemverRange(%q) = %v, want an error", constraint, r.sets)
+		}
+	}
+}
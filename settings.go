@@ -0,0 +1,464 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"sync"
+)
+
+// プラグインの設定値を保存する設定ファイル
+const pluginSettingsConfigFile = "plugin-settings.json"
+
+// SettingType はマニフェストの settings で宣言する設定項目の型
+type SettingType string
+
+const (
+	SettingString  SettingType = "string"
+	SettingNumber  SettingType = "number"
+	SettingInteger SettingType = "integer"
+	SettingBoolean SettingType = "boolean"
+	// "Alt+1" などのショートカット
+	SettingShortcut SettingType = "shortcut"
+	// 文字列の配列
+	SettingStringList SettingType = "stringList"
+)
+
+// SettingField はマニフェストの settings の1項目 (設定画面の1行)
+type SettingField struct {
+	Key         string      `json:"key"`
+	Type        SettingType `json:"type"`
+	Title       string      `json:"title,omitempty"`
+	Description string      `json:"description,omitempty"`
+	// 省略時は型の空の値 (enum があればその先頭)
+	Default any `json:"default,omitempty"`
+	// 選べる値 (string, number, integer のみ)
+	Enum []any `json:"enum,omitempty"`
+	// 値の範囲 (number, integer のみ)
+	Minimum *float64 `json:"minimum,omitempty"`
+	Maximum *float64 `json:"maximum,omitempty"`
+}
+
+// PluginSettings はプラグインの設定項目と現在の値
+type PluginSettings struct {
+	PluginID string         `json:"pluginId"`
+	Fields   []SettingField `json:"fields"`
+	// すべての項目の値 (保存されていない項目は既定値)
+	Values map[string]any `json:"values"`
+}
+
+// PluginSettingsChangedEvent は plugin-settings-changed のペイロード
+type PluginSettingsChangedEvent struct {
+	PluginID string         `json:"pluginId"`
+	Values   map[string]any `json:"values"`
+	// 値が変わった項目のキー
+	Changed []string `json:"changed"`
+}
+
+// pluginSettingsConfig は plugin-settings.json の内容 (プラグインID → キー → 値)
+// ユーザーが変更した値だけを保存する
+type pluginSettingsConfig struct {
+	Plugins map[string]map[string]any `json:"plugins"`
+}
+
+// 設定項目のキーの書式
+var settingKeyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]*$`)
+
+// 設定項目に書ける項目
+var settingFieldProperties = []string{"key", "type", "title", "description", "default", "enum", "minimum", "maximum"}
+
+// validateSettingsField は [{"key": "endpoint", "type": "string", ...}] の形式の settings を検証する
+func validateSettingsField(path string, value any) []ValidationIssue {
+	items, ok := value.([]any)
+	if !ok {
+		return []ValidationIssue{typeIssue(path, "an array", value)}
+	}
+
+	var issues []ValidationIssue
+	keys := make(map[string]bool)
+	for i, item := range items {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		field, fieldIssues := parseSettingField(itemPath, item)
+		issues = append(issues, fieldIssues...)
+		if field.Key == "" {
+			continue
+		}
+		if keys[field.Key] {
+			issues = append(issues, ValidationIssue{Path: jsonPath(itemPath, "key"), Code: issueInvalidFormat, Message: fmt.Sprintf("duplicate setting key %q", field.Key)})
+		}
+		keys[field.Key] = true
+	}
+	return issues
+}
+
+// parseSettingField はマニフェストの settings の1項目を検証して SettingField にする
+func parseSettingField(path string, value any) (SettingField, []ValidationIssue) {
+	var field SettingField
+	properties, ok := value.(map[string]any)
+	if !ok {
+		return field, []ValidationIssue{typeIssue(path, "an object", value)}
+	}
+
+	var issues []ValidationIssue
+	for _, name := range sortedKeys(properties) {
+		if !slices.Contains(settingFieldProperties, name) {
+			issues = append(issues, ValidationIssue{Path: jsonPath(path, name), Code: issueUnknownField, Message: fmt.Sprintf("unknown setting property %q", name)})
+		}
+	}
+
+	keyPath := jsonPath(path, "key")
+	if key, present := properties["key"]; !present {
+		issues = append(issues, ValidationIssue{Path: keyPath, Code: issueRequired, Message: "key is required"})
+	} else if s, ok := key.(string); !ok {
+		issues = append(issues, typeIssue(keyPath, "a string", key))
+	} else if !settingKeyPattern.MatchString(s) {
+		issues = append(issues, ValidationIssue{Path: keyPath, Code: issueInvalidFormat, Message: fmt.Sprintf("invalid setting key %q: start with a letter and use letters, digits, '_', '-' or '.'", s)})
+	} else {
+		field.Key = s
+	}
+
+	typePath := jsonPath(path, "type")
+	switch t := properties["type"].(type) {
+	case nil:
+		issues = append(issues, ValidationIssue{Path: typePath, Code: issueRequired, Message: "type is required"})
+	case string:
+		switch SettingType(t) {
+		case SettingString, SettingNumber, SettingInteger, SettingBoolean, SettingShortcut, SettingStringList:
+			field.Type = SettingType(t)
+		default:
+			issues = append(issues, ValidationIssue{Path: typePath, Code: issueInvalidFormat, Message: fmt.Sprintf("unknown setting type %q", t)})
+		}
+	default:
+		issues = append(issues, typeIssue(typePath, "a string", t))
+	}
+
+	for _, name := range []string{"title", "description"} {
+		if v, present := properties[name]; present {
+			issues = append(issues, validateStringField(jsonPath(path, name), v)...)
+		}
+	}
+	field.Title, _ = properties["title"].(string)
+	field.Description, _ = properties["description"].(string)
+	if field.Type == "" {
+		return field, issues
+	}
+
+	numeric := field.Type == SettingNumber || field.Type == SettingInteger
+	for _, name := range []string{"minimum", "maximum"} {
+		v, present := properties[name]
+		if !present {
+			continue
+		}
+		n, ok := settingNumber(v)
+		switch {
+		case !numeric:
+			issues = append(issues, ValidationIssue{Path: jsonPath(path, name), Code: issueInvalidType, Message: fmt.Sprintf("%s is only allowed for number and integer settings", name)})
+		case !ok:
+			issues = append(issues, typeIssue(jsonPath(path, name), "a number", v))
+		case name == "minimum":
+			field.Minimum = &n
+		default:
+			field.Maximum = &n
+		}
+	}
+	if field.Minimum != nil && field.Maximum != nil && *field.Minimum > *field.Maximum {
+		issues = append(issues, ValidationIssue{Path: jsonPath(path, "minimum"), Code: issueInvalidFormat, Message: "minimum is greater than maximum"})
+	}
+
+	if v, present := properties["enum"]; present {
+		enumPath := jsonPath(path, "enum")
+		values, ok := v.([]any)
+		switch {
+		case field.Type != SettingString && !numeric:
+			issues = append(issues, ValidationIssue{Path: enumPath, Code: issueInvalidType, Message: "enum is only allowed for string, number and integer settings"})
+		case !ok:
+			issues = append(issues, typeIssue(enumPath, "an array", v))
+		case len(values) == 0:
+			issues = append(issues, ValidationIssue{Path: enumPath, Code: issueRequired, Message: "enum must not be empty"})
+		default:
+			// 選択肢は範囲だけを確認する (enum 自身との比較はしない)
+			rangeOnly := field
+			for i, item := range values {
+				normalized, err := normalizeSettingValue(rangeOnly, item)
+				if err != nil {
+					issues = append(issues, ValidationIssue{Path: fmt.Sprintf("%s[%d]", enumPath, i), Code: issueInvalidFormat, Message: err.Error()})
+					continue
+				}
+				field.Enum = append(field.Enum, normalized)
+			}
+		}
+	}
+
+	if v, present := properties["default"]; present {
+		normalized, err := normalizeSettingValue(field, v)
+		if err != nil {
+			issues = append(issues, ValidationIssue{Path: jsonPath(path, "default"), Code: issueInvalidFormat, Message: err.Error()})
+		} else {
+			field.Default = normalized
+		}
+	}
+	return field, issues
+}
+
+// settingNumber はJSONの数値を float64 にする
+func settingNumber(value any) (float64, bool) {
+	switch n := value.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// normalizeSettingValue は value が field の値として正しいかを確認し、保存する形 (数値は float64、stringList は []string) にする
+func normalizeSettingValue(field SettingField, value any) (any, error) {
+	var normalized any
+	switch field.Type {
+	case SettingString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %s", jsonTypeName(value))
+		}
+		normalized = s
+	case SettingShortcut:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a shortcut string, got %s", jsonTypeName(value))
+		}
+		if s != "" {
+			if _, _, err := parseShortcut(s); err != nil {
+				return nil, err
+			}
+		}
+		normalized = s
+	case SettingBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected a boolean, got %s", jsonTypeName(value))
+		}
+		normalized = b
+	case SettingNumber, SettingInteger:
+		n, ok := settingNumber(value)
+		if !ok || math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, fmt.Errorf("expected a number, got %s", jsonTypeName(value))
+		}
+		if field.Type == SettingInteger && n != math.Trunc(n) {
+			return nil, fmt.Errorf("expected an integer, got %v", n)
+		}
+		if field.Minimum != nil && n < *field.Minimum {
+			return nil, fmt.Errorf("%v is less than the minimum %v", n, *field.Minimum)
+		}
+		if field.Maximum != nil && n > *field.Maximum {
+			return nil, fmt.Errorf("%v is greater than the maximum %v", n, *field.Maximum)
+		}
+		normalized = n
+	case SettingStringList:
+		items, ok := value.([]any)
+		if !ok {
+			if list, isList := value.([]string); isList {
+				return slices.Clone(list), nil
+			}
+			return nil, fmt.Errorf("expected an array of strings, got %s", jsonTypeName(value))
+		}
+		list := make([]string, len(items))
+		for i, item := range items {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("item %d: expected a string, got %s", i, jsonTypeName(item))
+			}
+			list[i] = s
+		}
+		normalized = list
+	default:
+		return nil, fmt.Errorf("unknown setting type %q", field.Type)
+	}
+
+	if len(field.Enum) > 0 && !slices.Contains(field.Enum, normalized) {
+		return nil, fmt.Errorf("%v is not one of the allowed values %v", normalized, field.Enum)
+	}
+	return normalized, nil
+}
+
+// defaultSettingValue は field の既定値を返す
+func defaultSettingValue(field SettingField) any {
+	if field.Default != nil {
+		if value, err := normalizeSettingValue(field, field.Default); err == nil {
+			return value
+		}
+	}
+	if len(field.Enum) > 0 {
+		return field.Enum[0]
+	}
+	switch field.Type {
+	case SettingNumber, SettingInteger:
+		// 範囲があれば範囲内の値にする
+		if field.Minimum != nil && *field.Minimum > 0 {
+			return *field.Minimum
+		}
+		if field.Maximum != nil && *field.Maximum < 0 {
+			return *field.Maximum
+		}
+		return float64(0)
+	case SettingBoolean:
+		return false
+	case SettingStringList:
+		return []string{}
+	}
+	return ""
+}
+
+// SettingsStore はプラグインの設定値を保存する
+type SettingsStore struct {
+	mu     sync.Mutex
+	loaded bool
+	values map[string]map[string]any
+}
+
+// NewSettingsStore は SettingsStore を生成する
+func NewSettingsStore() *SettingsStore {
+	return &SettingsStore{}
+}
+
+// Get は plugin のすべての設定項目の値を返す
+// 保存された値がマニフェストの定義に合わなくなっていれば既定値を使う
+func (s *SettingsStore) Get(plugin PluginRecord) PluginSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureLoaded()
+
+	return s.settings(plugin)
+}
+
+// Set は plugin の設定項目のうち values にあるものを変更して保存し、変更後の設定と値が変わった項目のキーを返す
+// 値が null の項目は既定値に戻す。1つでも正しくない値があれば何も変更しない
+func (s *SettingsStore) Set(plugin PluginRecord, values map[string]any) (PluginSettings, []string, error) {
+	fields := make(map[string]SettingField, len(plugin.Manifest.Settings))
+	for _, field := range plugin.Manifest.Settings {
+		fields[field.Key] = field
+	}
+
+	updates := make(map[string]any, len(values))
+	for _, key := range sortedKeys(values) {
+		field, ok := fields[key]
+		if !ok {
+			return PluginSettings{}, nil, fmt.Errorf("plugin %q has no setting %q", plugin.ID, key)
+		}
+		if values[key] == nil {
+			updates[key] = nil
+			continue
+		}
+		value, err := normalizeSettingValue(field, values[key])
+		if err != nil {
+			return PluginSettings{}, nil, fmt.Errorf("invalid value for setting %q of plugin %q: %w", key, plugin.ID, err)
+		}
+		updates[key] = value
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureLoaded()
+
+	before := s.settings(plugin)
+	stored := maps.Clone(s.values[plugin.ID])
+	if stored == nil {
+		stored = make(map[string]any)
+	}
+	for key, value := range updates {
+		if value == nil {
+			delete(stored, key)
+		} else {
+			stored[key] = value
+		}
+	}
+
+	all := maps.Clone(s.values)
+	if len(stored) == 0 {
+		delete(all, plugin.ID)
+	} else {
+		all[plugin.ID] = stored
+	}
+	if err := writeConfigFile(pluginSettingsConfigFile, pluginSettingsConfig{Plugins: all}); err != nil {
+		return PluginSettings{}, nil, err
+	}
+	s.values = all
+
+	after := s.settings(plugin)
+	var changed []string
+	for _, field := range plugin.Manifest.Settings {
+		if !settingValuesEqual(before.Values[field.Key], after.Values[field.Key]) {
+			changed = append(changed, field.Key)
+		}
+	}
+	return after, changed, nil
+}
+
+// Clear は pluginID のプラグインの保存された値をすべて削除する
+func (s *SettingsStore) Clear(pluginID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureLoaded()
+
+	if _, ok := s.values[pluginID]; !ok {
+		return nil
+	}
+	all := maps.Clone(s.values)
+	delete(all, pluginID)
+	if err := writeConfigFile(pluginSettingsConfigFile, pluginSettingsConfig{Plugins: all}); err != nil {
+		return err
+	}
+	s.values = all
+	return nil
+}
+
+// settings は plugin の設定項目と値を返す (s.mu を持って呼ぶ)
+func (s *SettingsStore) settings(plugin PluginRecord) PluginSettings {
+	stored := s.values[plugin.ID]
+	values := make(map[string]any, len(plugin.Manifest.Settings))
+	for _, field := range plugin.Manifest.Settings {
+		value := defaultSettingValue(field)
+		if v, ok := stored[field.Key]; ok {
+			if normalized, err := normalizeSettingValue(field, v); err == nil {
+				value = normalized
+			} else {
+				fmt.Printf("Ignoring saved setting %s of plugin %s: %v\n", field.Key, plugin.ID, err)
+			}
+		}
+		values[field.Key] = value
+	}
+	return PluginSettings{PluginID: plugin.ID, Fields: slices.Clone(plugin.Manifest.Settings), Values: values}
+}
+
+// ensureLoaded は初めて参照されたときに設定ファイルを読み込む (s.mu を持って呼ぶ)
+func (s *SettingsStore) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+
+	var config pluginSettingsConfig
+	if _, err := readConfigFile(pluginSettingsConfigFile, &config); err != nil {
+		// 読めない場合はすべて既定値として扱う
+		fmt.Printf("Error loading plugin settings: %v\n", err)
+	}
+	s.values = config.Plugins
+	if s.values == nil {
+		s.values = make(map[string]map[string]any)
+	}
+}
+
+func settingValuesEqual(a, b any) bool {
+	if listA, ok := a.([]string); ok {
+		listB, ok := b.([]string)
+		return ok && slices.Equal(listA, listB)
+	}
+	return a == b
+}
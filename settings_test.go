@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// decodeManifestJSON はマニフェストと同じく数値を json.Number として読み込む
+func decodeManifestJSON(t *testing.T, data string) any {
+	t.Helper()
+	decoder := json.NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		t.Fatalf("invalid test JSON %s: %v", data, err)
+	}
+	return value
+}
+
+func TestValidateSettingsField(t *testing.T) {
+	type issue struct{ path, code string }
+	tests := []struct {
+		name     string
+		settings string
+		want     []issue
+	}{
+		{
+			name: "valid",
+			settings: `[
+				{"key": "endpoint", "type": "string", "title": "Endpoint", "default": "http://localhost"},
+				{"key": "mode", "type": "string", "enum": ["fast", "slow"], "default": "slow"},
+				{"key": "count", "type": "integer", "minimum": 1, "maximum": 10, "default": 3},
+				{"key": "ratio", "type": "number", "enum": [0.5, 1.5]},
+				{"key": "enabled", "type": "boolean", "default": true},
+				{"key": "hotkey", "type": "shortcut", "default": "Alt+1"},
+				{"key": "tags", "type": "stringList", "default": ["a", "b"]}
+			]`,
+		},
+		{name: "not an array", settings: `{"key": "a"}`, want: []issue{{"$.settings", issueInvalidType}}},
+		{name: "item not an object", settings: `["a"]`, want: []issue{{"$.settings[0]", issueInvalidType}}},
+		{name: "missing key", settings: `[{"type": "string"}]`, want: []issue{{"$.settings[0].key", issueRequired}}},
+		{name: "key not a string", settings: `[{"key": 1, "type": "string"}]`, want: []issue{{"$.settings[0].key", issueInvalidType}}},
+		{name: "bad key format", settings: `[{"key": "1st", "type": "string"}]`, want: []issue{{"$.settings[0].key", issueInvalidFormat}}},
+		{name: "duplicate key", settings: `[{"key": "a", "type": "string"}, {"key": "a", "type": "number"}]`, want: []issue{{"$.settings[1].key", issueInvalidFormat}}},
+		{name: "missing type", settings: `[{"key": "a"}]`, want: []issue{{"$.settings[0].type", issueRequired}}},
+		{name: "type not a string", settings: `[{"key": "a", "type": 1}]`, want: []issue{{"$.settings[0].type", issueInvalidType}}},
+		{name: "unknown type", settings: `[{"key": "a", "type": "color"}]`, want: []issue{{"$.settings[0].type", issueInvalidFormat}}},
+		{name: "unknown property", settings: `[{"key": "a", "type": "string", "placeholder": "x"}]`, want: []issue{{"$.settings[0].placeholder", issueUnknownField}}},
+		{name: "title not a string", settings: `[{"key": "a", "type": "string", "title": 1}]`, want: []issue{{"$.settings[0].title", issueInvalidType}}},
+		{name: "enum on boolean", settings: `[{"key": "a", "type": "boolean", "enum": [true]}]`, want: []issue{{"$.settings[0].enum", issueInvalidType}}},
+		{name: "enum not an array", settings: `[{"key": "a", "type": "string", "enum": "x"}]`, want: []issue{{"$.settings[0].enum", issueInvalidType}}},
+		{name: "empty enum", settings: `[{"key": "a", "type": "string", "enum": []}]`, want: []issue{{"$.settings[0].enum", issueRequired}}},
+		{name: "enum item of the wrong type", settings: `[{"key": "a", "type": "integer", "enum": [1, "two", 2.5]}]`, want: []issue{{"$.settings[0].enum[1]", issueInvalidFormat}, {"$.settings[0].enum[2]", issueInvalidFormat}}},
+		{name: "enum item out of range", settings: `[{"key": "a", "type": "number", "maximum": 1, "enum": [0.5, 2]}]`, want: []issue{{"$.settings[0].enum[1]", issueInvalidFormat}}},
+		{name: "default not in enum", settings: `[{"key": "a", "type": "string", "enum": ["x", "y"], "default": "z"}]`, want: []issue{{"$.settings[0].default", issueInvalidFormat}}},
+		{name: "default of the wrong type", settings: `[{"key": "a", "type": "boolean", "default": "yes"}]`, want: []issue{{"$.settings[0].default", issueInvalidFormat}}},
+		{name: "default out of range", settings: `[{"key": "a", "type": "integer", "minimum": 1, "default": 0}]`, want: []issue{{"$.settings[0].default", issueInvalidFormat}}},
+		{name: "default not an integer", settings: `[{"key": "a", "type": "integer", "default": 1.5}]`, want: []issue{{"$.settings[0].default", issueInvalidFormat}}},
+		{name: "default not a shortcut", settings: `[{"key": "a", "type": "shortcut", "default": "Hyper+Q"}]`, want: []issue{{"$.settings[0].default", issueInvalidFormat}}},
+		{name: "default list with a number", settings: `[{"key": "a", "type": "stringList", "default": ["x", 1]}]`, want: []issue{{"$.settings[0].default", issueInvalidFormat}}},
+		{name: "minimum on string", settings: `[{"key": "a", "type": "string", "minimum": 1}]`, want: []issue{{"$.settings[0].minimum", issueInvalidType}}},
+		{name: "maximum not a number", settings: `[{"key": "a", "type": "number", "maximum": "10"}]`, want: []issue{{"$.settings[0].maximum", issueInvalidType}}},
+		{name: "minimum greater than maximum", settings: `[{"key": "a", "type": "number", "minimum": 5, "maximum": 1}]`, want: []issue{{"$.settings[0].minimum", issueInvalidFormat}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []issue
+			for _, i := range validateSettingsField("$.settings", decodeManifestJSON(t, tt.settings)) {
+				got = append(got, issue{i.Path, i.Code})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("issues = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// settingsTestPlugin はマニフェストの settings を検証して読み込んだプラグインの情報を作る
+func settingsTestPlugin(t *testing.T, id, settings string) PluginRecord {
+	t.Helper()
+	data := `{"manifestVersion": 1, "id": "` + id + `", "name": "` + id + `", "version": "1.0.0", "settings": ` + settings + `}`
+	manifest, errs, _ := validateManifest([]byte(data))
+	if len(errs) > 0 {
+		t.Fatalf("invalid test manifest: %s", joinIssues(errs))
+	}
+	return PluginRecord{ID: id, Manifest: manifest, Valid: true}
+}
+
+const testSettingsSchema = `[
+	{"key": "endpoint", "type": "string", "default": "http://localhost"},
+	{"key": "mode", "type": "string", "enum": ["fast", "slow"]},
+	{"key": "count", "type": "integer", "minimum": 5, "maximum": 10},
+	{"key": "enabled", "type": "boolean"},
+	{"key": "hotkey", "type": "shortcut"},
+	{"key": "tags", "type": "stringList"}
+]`
+
+func TestSettingsStoreDefaults(t *testing.T) {
+	t.Setenv(configDirEnv, t.TempDir())
+	plugin := settingsTestPlugin(t, "clock", testSettingsSchema)
+
+	got := NewSettingsStore().Get(plugin).Values
+	want := map[string]any{
+		"endpoint": "http://localhost",
+		// enum があれば先頭、範囲があれば範囲内の値になる
+		"mode":    "fast",
+		"count":   float64(5),
+		"enabled": false,
+		"hotkey":  "",
+		"tags":    []string{},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("default values = %#v, want %#v", got, want)
+	}
+}
+
+func TestSettingsStoreSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]any
+		wantErr string
+		want    map[string]any
+		changed []string
+	}{
+		{
+			name:    "valid values",
+			values:  map[string]any{"mode": "slow", "count": float64(7), "enabled": true, "hotkey": "Alt+2", "tags": []any{"a", "b"}},
+			want:    map[string]any{"mode": "slow", "count": float64(7), "enabled": true, "hotkey": "Alt+2", "tags": []string{"a", "b"}},
+			changed: []string{"mode", "count", "enabled", "hotkey", "tags"},
+		},
+		{
+			name:    "same as default",
+			values:  map[string]any{"endpoint": "http://localhost"},
+			want:    map[string]any{"endpoint": "http://localhost"},
+			changed: nil,
+		},
+		{name: "unknown key", values: map[string]any{"mode": "slow", "color": "red"}, wantErr: `has no setting "color"`},
+		{name: "wrong type", values: map[string]any{"enabled": "yes"}, wantErr: "expected a boolean"},
+		{name: "not in enum", values: map[string]any{"mode": "medium"}, wantErr: "not one of the allowed values"},
+		{name: "below minimum", values: map[string]any{"count": float64(4)}, wantErr: "less than the minimum"},
+		{name: "not an integer", values: map[string]any{"count": 5.5}, wantErr: "expected an integer"},
+		{name: "bad shortcut", values: map[string]any{"hotkey": "Hyper+Q"}, wantErr: "hotkey"},
+		{name: "list with a number", values: map[string]any{"tags": []any{"a", float64(1)}}, wantErr: "expected a string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(configDirEnv, t.TempDir())
+			plugin := settingsTestPlugin(t, "clock", testSettingsSchema)
+			store := NewSettingsStore()
+			before := store.Get(plugin).Values
+
+			settings, changed, err := store.Set(plugin, tt.values)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Set error = %v, want %q", err, tt.wantErr)
+				}
+				// 1つでも正しくない値があれば何も変更しない
+				if got := NewSettingsStore().Get(plugin).Values; !reflect.DeepEqual(got, before) {
+					t.Errorf("values after a rejected Set = %#v, want %#v", got, before)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+			if !reflect.DeepEqual(changed, tt.changed) {
+				t.Errorf("changed = %v, want %v", changed, tt.changed)
+			}
+			for key, want := range tt.want {
+				if got := settings.Values[key]; !reflect.DeepEqual(got, want) {
+					t.Errorf("%s = %#v, want %#v", key, got, want)
+				}
+			}
+			// 保存した値は読み込み直しても同じ
+			if reloaded := NewSettingsStore().Get(plugin).Values; !reflect.DeepEqual(reloaded, settings.Values) {
+				t.Errorf("reloaded values = %#v, want %#v", reloaded, settings.Values)
+			}
+		})
+	}
+}
+
+func TestSettingsStoreResetToDefault(t *testing.T) {
+	t.Setenv(configDirEnv, t.TempDir())
+	plugin := settingsTestPlugin(t, "clock", testSettingsSchema)
+	store := NewSettingsStore()
+
+	if _, _, err := store.Set(plugin, map[string]any{"endpoint": "http://example.com", "count": float64(8)}); err != nil {
+		t.Fatal(err)
+	}
+	settings, changed, err := store.Set(plugin, map[string]any{"endpoint": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Values["endpoint"] != "http://localhost" || settings.Values["count"] != float64(8) {
+		t.Errorf("values after resetting endpoint = %#v", settings.Values)
+	}
+	if !reflect.DeepEqual(changed, []string{"endpoint"}) {
+		t.Errorf("changed = %v, want [endpoint]", changed)
+	}
+}
+
+func TestPluginSettingsByID(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv(configDirEnv, configDir)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(pluginPathEnv, "")
+	dir := filepath.Join(configDir, "plugins", "clock")
+	writeTestPlugin(t, dir, "clock", "1.0.0")
+	manifest := `{"manifestVersion": 1, "id": "clock", "name": "clock", "version": "1.0.0", "settings": ` + testSettingsSchema + `}`
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	env := NewFakeEnvironment()
+	env.App.startup(context.Background())
+	t.Cleanup(func() { env.App.shutdown(context.Background()) })
+	app := env.App
+	key := openTestLoader(t, app)
+	entry, err := app.LoadPlugin(key, "clock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// プラグインのトークンではアプリの設定画面用のメソッドは呼べない
+	if _, err := app.GetPluginSettingsByID(entry.Token, "clock"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("GetPluginSettingsByID with a plugin token: err = %v, want ErrPermissionDenied", err)
+	}
+	if _, err := app.SetPluginSettingsByID(entry.Token, "clock", map[string]any{"mode": "slow"}); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("SetPluginSettingsByID with a plugin token: err = %v, want ErrPermissionDenied", err)
+	}
+	if _, err := app.GetPluginSettingsByID(key, "missing"); err == nil {
+		t.Error("GetPluginSettingsByID for a missing plugin succeeded")
+	}
+
+	settings, err := app.GetPluginSettingsByID(key, "clock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(settings.Fields) != 6 || settings.Values["mode"] != "fast" {
+		t.Fatalf("settings = %+v", settings)
+	}
+
+	// 同じスキーマで検証する
+	if _, err := app.SetPluginSettingsByID(key, "clock", map[string]any{"mode": "medium"}); err == nil {
+		t.Error("SetPluginSettingsByID accepted a value outside the enum")
+	}
+	if _, err := app.SetPluginSettingsByID(key, "clock", map[string]any{"color": "red"}); err == nil {
+		t.Error("SetPluginSettingsByID accepted an unknown key")
+	}
+	if _, err := app.SetPluginSettingsByID(key, "clock", map[string]any{"mode": "slow"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// アプリの画面で変えた値はプラグインにも通知され、プラグインから読める
+	events := env.Events.EventsNamed(EventPluginSettingsChanged)
+	if len(events) != 1 {
+		t.Fatalf("plugin-settings-changed emitted %d times, want 1", len(events))
+	}
+	if event := events[0].Data[0].(PluginSettingsChangedEvent); event.PluginID != "clock" || !reflect.DeepEqual(event.Changed, []string{"mode"}) {
+		t.Errorf("plugin-settings-changed = %+v", event)
+	}
+	fromPlugin, err := app.GetPluginSettings(entry.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromPlugin.Values["mode"] != "slow" {
+		t.Errorf("plugin sees mode = %v, want slow", fromPlugin.Values["mode"])
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 信頼する発行者と署名の扱いを書く設定ファイル
+const pluginTrustConfigFile = "plugin-trust.json"
+
+// プラグインのディレクトリ直下に置く署名ファイル
+const pluginSignatureFile = "signature.json"
+
+// 署名する内容の先頭に付ける文字列 (形式を変えるときは版を上げる)
+const pluginSignaturePrefix = "ghostcursor-plugin-signature-v1\n"
+
+// SignaturePolicy は署名が確認できないプラグインの扱い
+type SignaturePolicy string
+
+const (
+	// 署名を確認するが、結果にかかわらず読み込む
+	SignaturePolicyAllow SignaturePolicy = "allow"
+	// 署名が確認できないプラグインは警告を付けて読み込む
+	SignaturePolicyWarn SignaturePolicy = "warn"
+	// 署名が確認できないプラグインは読み込まない
+	SignaturePolicyBlock SignaturePolicy = "block"
+)
+
+// SignatureStatus はプラグインの署名の確認結果
+type SignatureStatus string
+
+const (
+	SignatureValid SignatureStatus = "valid"
+	// signature.json がない
+	SignatureUnsigned SignatureStatus = "unsigned"
+	// 信頼する発行者の一覧にない発行者の署名
+	SignatureUntrusted SignatureStatus = "untrusted"
+	// 署名が一致しない (ファイルが改ざんされた) か、署名ファイルが壊れている
+	SignatureInvalid SignatureStatus = "invalid"
+)
+
+// 署名に関する ValidationIssue の種類
+const issueSignature = "signature"
+
+// PluginSignature はプラグインの署名の確認結果
+type PluginSignature struct {
+	Status    SignatureStatus `json:"status"`
+	Publisher string          `json:"publisher,omitempty"`
+	Message   string          `json:"message,omitempty"`
+}
+
+// TrustedPublisher は署名を信頼する発行者
+type TrustedPublisher struct {
+	Name string `json:"name"`
+	// ed25519 の公開鍵 (base64)
+	PublicKey string `json:"publicKey"`
+}
+
+// pluginTrust は plugin-trust.json の内容
+type pluginTrust struct {
+	Policy     SignaturePolicy    `json:"policy"`
+	Publishers []TrustedPublisher `json:"publishers"`
+}
+
+// pluginSignatureFileContent は signature.json の内容
+type pluginSignatureFileContent struct {
+	Publisher string `json:"publisher"`
+	// ed25519 の署名 (base64)
+	Signature string `json:"signature"`
+}
+
+// loadPluginTrust は設定ファイルから信頼する発行者と署名の扱いを読み込む
+// ファイルがない場合は信頼する発行者なしで警告だけにする
+func loadPluginTrust() (pluginTrust, error) {
+	trust := pluginTrust{Policy: SignaturePolicyWarn}
+	if _, err := readConfigFile(pluginTrustConfigFile, &trust); err != nil {
+		return pluginTrust{Policy: SignaturePolicyWarn}, err
+	}
+
+	switch trust.Policy {
+	case SignaturePolicyAllow, SignaturePolicyWarn, SignaturePolicyBlock:
+	case "":
+		trust.Policy = SignaturePolicyWarn
+	default:
+		// 設定の誤りで署名の確認が緩くならないよう、最も厳しい扱いにする
+		err := fmt.Errorf("unknown signature policy %q in %s; blocking unverified plugins", trust.Policy, pluginTrustConfigFile)
+		trust.Policy = SignaturePolicyBlock
+		return trust, err
+	}
+	return trust, nil
+}
+
+// applyPluginTrust はプラグインの署名を確認し、結果と署名の扱いに応じた問題を validation に追加する
+func applyPluginTrust(validation *PluginValidationResult, trust pluginTrust) {
+	signature := verifyPluginSignature(validation.PluginPath, trust.Publishers)
+	validation.Signature = signature
+	if signature.Status == SignatureValid {
+		return
+	}
+
+	issue := ValidationIssue{Code: issueSignature, Message: signature.Message}
+	switch trust.Policy {
+	case SignaturePolicyBlock:
+		validation.Errors = append(validation.Errors, issue)
+		validation.IsValid = false
+	case SignaturePolicyWarn:
+		validation.Warnings = append(validation.Warnings, issue)
+	}
+}
+
+// verifyPluginSignature は dir の signature.json を publishers の公開鍵で確認する
+func verifyPluginSignature(dir string, publishers []TrustedPublisher) PluginSignature {
+	data, err := os.ReadFile(filepath.Join(dir, pluginSignatureFile))
+	if os.IsNotExist(err) {
+		return PluginSignature{Status: SignatureUnsigned, Message: "plugin is not signed"}
+	}
+	if err != nil {
+		return PluginSignature{Status: SignatureInvalid, Message: fmt.Sprintf("failed to read %s: %v", pluginSignatureFile, err)}
+	}
+
+	var content pluginSignatureFileContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return PluginSignature{Status: SignatureInvalid, Message: fmt.Sprintf("invalid %s: %v", pluginSignatureFile, err)}
+	}
+	signature, err := base64.StdEncoding.DecodeString(content.Signature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return PluginSignature{Status: SignatureInvalid, Publisher: content.Publisher, Message: fmt.Sprintf("invalid signature in %s", pluginSignatureFile)}
+	}
+
+	var publicKey ed25519.PublicKey
+	for _, publisher := range publishers {
+		if publisher.Name != content.Publisher {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(publisher.PublicKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return PluginSignature{Status: SignatureInvalid, Publisher: content.Publisher, Message: fmt.Sprintf("invalid public key for publisher %q in %s", publisher.Name, pluginTrustConfigFile)}
+		}
+		publicKey = key
+		break
+	}
+	if publicKey == nil {
+		return PluginSignature{Status: SignatureUntrusted, Publisher: content.Publisher, Message: fmt.Sprintf("signed by untrusted publisher %q", content.Publisher)}
+	}
+
+	message, err := pluginSignatureMessage(dir)
+	if err != nil {
+		return PluginSignature{Status: SignatureInvalid, Publisher: content.Publisher, Message: err.Error()}
+	}
+	if !ed25519.Verify(publicKey, message, signature) {
+		return PluginSignature{Status: SignatureInvalid, Publisher: content.Publisher, Message: "signature does not match the plugin files; the plugin may have been modified"}
+	}
+	return PluginSignature{Status: SignatureValid, Publisher: content.Publisher}
+}
+
+// skippedPluginDir はプラグインの中の name のディレクトリを署名と変更の検知から除くかを返す (隠しディレクトリと node_modules)
+func skippedPluginDir(name string) bool {
+	return name == "node_modules" || strings.HasPrefix(name, ".")
+}
+
+// pluginSignatureCovers はプラグインのディレクトリからの相対パス rel (/ 区切り) が署名の対象かを返す
+// 署名ファイル自身と、除かれたディレクトリの中 (isDir なら rel 自身も) は対象にならない
+// 対象にならないファイルはプラグインのインストール先から読み込ませない
+func pluginSignatureCovers(rel string, isDir bool) bool {
+	if rel == "." {
+		return isDir
+	}
+	if rel == pluginSignatureFile && !isDir {
+		return false
+	}
+	parts := strings.Split(rel, "/")
+	if !isDir {
+		parts = parts[:len(parts)-1]
+	}
+	for _, name := range parts {
+		if skippedPluginDir(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// pluginSignatureMessage はプラグインのファイルから署名する内容を作る
+// 署名の対象になるすべてのファイル (pluginSignatureCovers) について "SHA-256(16進数) 相対パス" の行を filepath.WalkDir の順 (名前順) に並べる
+func pluginSignatureMessage(dir string) ([]byte, error) {
+	var lines []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		rel = filepath.ToSlash(rel)
+		if !pluginSignatureCovers(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return fmt.Errorf("%s is not a regular file", rel)
+		}
+
+		sum, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, sum+" "+rel+"\n")
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash plugin files: %w", err)
+	}
+
+	return []byte(pluginSignaturePrefix + strings.Join(lines, "")), nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// プラグインのストレージを置くディレクトリ (設定ディレクトリからの相対パス、プラグインごとに <id>.json)
+const pluginStorageDir = "plugin-storage"
+
+// プラグインごとのストレージの上限
+const (
+	maxStorageKeyLength = 256
+	maxStorageValueSize = 1 << 20
+	// キーと値の合計のバイト数
+	maxStorageSize = 5 << 20
+	maxStorageKeys = 1000
+)
+
+// ErrStorageQuotaExceeded はプラグインのストレージの上限を超えることを表す
+var ErrStorageQuotaExceeded = errors.New("storage quota exceeded")
+
+// PluginStorage はプラグインごとのキーと文字列の値を設定ディレクトリのファイルに保存する
+type PluginStorage struct {
+	mu sync.Mutex
+	// 読み込んだプラグインのストレージ (プラグインID → キー → 値)
+	cache map[string]map[string]string
+}
+
+// NewPluginStorage は PluginStorage を生成する
+func NewPluginStorage() *PluginStorage {
+	return &PluginStorage{cache: make(map[string]map[string]string)}
+}
+
+// Get は pluginID のプラグインの key の値を返す
+func (s *PluginStorage) Get(pluginID, key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.load(pluginID)
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := values[key]
+	return value, ok, nil
+}
+
+// Set は pluginID のプラグインの key に value を保存する
+func (s *PluginStorage) Set(pluginID, key, value string) error {
+	if key == "" || len(key) > maxStorageKeyLength {
+		return fmt.Errorf("invalid storage key: must be 1 to %d bytes", maxStorageKeyLength)
+	}
+	if len(value) > maxStorageValueSize {
+		return fmt.Errorf("%w: value for %q is larger than %d bytes", ErrStorageQuotaExceeded, key, maxStorageValueSize)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.load(pluginID)
+	if err != nil {
+		return err
+	}
+	old, exists := values[key]
+	if !exists && len(values) >= maxStorageKeys {
+		return fmt.Errorf("%w: plugin %q already has %d keys", ErrStorageQuotaExceeded, pluginID, maxStorageKeys)
+	}
+	size := storageSize(values) + len(key) + len(value)
+	if exists {
+		size -= len(key) + len(old)
+	}
+	if size > maxStorageSize {
+		return fmt.Errorf("%w: plugin %q would use %d bytes (max %d)", ErrStorageQuotaExceeded, pluginID, size, maxStorageSize)
+	}
+
+	updated := maps.Clone(values)
+	updated[key] = value
+	return s.save(pluginID, updated)
+}
+
+// Delete は pluginID のプラグインの key を削除する (なければ何もしない)
+func (s *PluginStorage) Delete(pluginID, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.load(pluginID)
+	if err != nil {
+		return err
+	}
+	if _, ok := values[key]; !ok {
+		return nil
+	}
+
+	updated := maps.Clone(values)
+	delete(updated, key)
+	return s.save(pluginID, updated)
+}
+
+// List は pluginID のプラグインのキーを名前順に返す
+func (s *PluginStorage) List(pluginID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.load(pluginID)
+	if err != nil {
+		return nil, err
+	}
+	return sortedKeys(values), nil
+}
+
+// Clear は pluginID のプラグインのストレージをファイルごと削除する
+func (s *PluginStorage) Clear(pluginID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name, err := storageFileName(pluginID)
+	if err != nil {
+		return err
+	}
+	path, err := configPath(name)
+	if err != nil {
+		return err
+	}
+	delete(s.cache, pluginID)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear storage of plugin %s: %w", pluginID, err)
+	}
+	return nil
+}
+
+// load は pluginID のプラグインのストレージを返す (s.mu を持って呼ぶ)
+// 返した map は変更しないこと
+func (s *PluginStorage) load(pluginID string) (map[string]string, error) {
+	if values, ok := s.cache[pluginID]; ok {
+		return values, nil
+	}
+
+	name, err := storageFileName(pluginID)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	if _, err := readConfigFile(name, &values); err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = make(map[string]string)
+	}
+	s.cache[pluginID] = values
+	return values, nil
+}
+
+// save は values を pluginID のプラグインのストレージとして書き込む (s.mu を持って呼ぶ)
+// 書き込みに失敗した場合は以前の内容のままにする
+func (s *PluginStorage) save(pluginID string, values map[string]string) error {
+	name, err := storageFileName(pluginID)
+	if err != nil {
+		return err
+	}
+	if err := writeConfigFile(name, values); err != nil {
+		return fmt.Errorf("failed to save storage of plugin %s: %w", pluginID, err)
+	}
+	s.cache[pluginID] = values
+	return nil
+}
+
+// storageFileName は pluginID のプラグインのストレージのファイル名 (設定ディレクトリからの相対パス) を返す
+func storageFileName(pluginID string) (string, error) {
+	if issues := validatePluginIDField("$.id", pluginID); len(issues) > 0 {
+		return "", fmt.Errorf("invalid plugin id %q: %s", pluginID, joinIssues(issues))
+	}
+	return filepath.Join(pluginStorageDir, pluginID+".json"), nil
+}
+
+func storageSize(values map[string]string) int {
+	size := 0
+	for key, value := range values {
+		size += len(key) + len(value)
+	}
+	return size
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPluginStorageIsolation(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv(configDirEnv, configDir)
+	storage := NewPluginStorage()
+
+	for id, value := range map[string]string{"clock": "12:00", "memo": "buy milk"} {
+		if err := storage.Set(id, "shared", value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := storage.Set("memo", "only-memo", "x"); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(storage *PluginStorage) {
+		t.Helper()
+		for id, want := range map[string]string{"clock": "12:00", "memo": "buy milk"} {
+			if value, ok, err := storage.Get(id, "shared"); err != nil || !ok || value != want {
+				t.Errorf("Get(%s, shared) = %q, %v, %v, want %q", id, value, ok, err, want)
+			}
+		}
+		if _, ok, _ := storage.Get("clock", "only-memo"); ok {
+			t.Error("clock can read a key of memo")
+		}
+		if keys, _ := storage.List("clock"); !reflect.DeepEqual(keys, []string{"shared"}) {
+			t.Errorf("List(clock) = %v", keys)
+		}
+	}
+	check(storage)
+	// ファイルから読み込んでも同じ
+	check(NewPluginStorage())
+
+	// プラグインIDでファイルの外を指すことはできない
+	for _, id := range []string{"", "../memo", "memo/../clock", "clock.json/.."} {
+		if err := storage.Set(id, "key", "value"); err == nil {
+			t.Errorf("Set with plugin id %q succeeded", id)
+		}
+	}
+
+	if err := storage.Clear("memo"); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := storage.List("memo"); len(keys) != 0 {
+		t.Errorf("List(memo) after Clear = %v", keys)
+	}
+	if value, ok, _ := storage.Get("clock", "shared"); !ok || value != "12:00" {
+		t.Errorf("clearing memo changed clock: %q, %v", value, ok)
+	}
+	if _, err := os.Stat(filepath.Join(configDir, pluginStorageDir, "memo.json")); !os.IsNotExist(err) {
+		t.Errorf("storage file of memo remains after Clear: %v", err)
+	}
+}
+
+func TestPluginStorageQuota(t *testing.T) {
+	t.Setenv(configDirEnv, t.TempDir())
+	storage := NewPluginStorage()
+
+	for _, key := range []string{"", strings.Repeat("k", maxStorageKeyLength+1)} {
+		if err := storage.Set("clock", key, "value"); err == nil {
+			t.Errorf("Set with a %d byte key succeeded", len(key))
+		}
+	}
+	if err := storage.Set("clock", "big", strings.Repeat("a", maxStorageValueSize+1)); !errors.Is(err, ErrStorageQuotaExceeded) {
+		t.Errorf("Set with a value over the limit: err = %v, want ErrStorageQuotaExceeded", err)
+	}
+
+	// キーと値の合計が上限ちょうどになるまでは保存できる
+	full := strings.Repeat("a", maxStorageValueSize)
+	for i := 1; i <= 4; i++ {
+		if err := storage.Set("clock", fmt.Sprintf("k%d", i), full); err != nil {
+			t.Fatalf("Set(k%d): %v", i, err)
+		}
+	}
+	used := 4 * (2 + maxStorageValueSize)
+	if err := storage.Set("clock", "k5", strings.Repeat("a", maxStorageSize-used-2+1)); !errors.Is(err, ErrStorageQuotaExceeded) {
+		t.Fatalf("Set over the total size: err = %v, want ErrStorageQuotaExceeded", err)
+	}
+	if _, ok, _ := storage.Get("clock", "k5"); ok {
+		t.Error("a rejected value was saved")
+	}
+	if err := storage.Set("clock", "k5", strings.Repeat("a", maxStorageSize-used-2)); err != nil {
+		t.Fatalf("Set up to the total size: %v", err)
+	}
+
+	// 上書きは以前の値を除いて数える
+	if err := storage.Set("clock", "k1", full); err != nil {
+		t.Errorf("overwriting with the same size: %v", err)
+	}
+	if err := storage.Set("clock", "k1", full+"a"); !errors.Is(err, ErrStorageQuotaExceeded) {
+		t.Errorf("overwriting with a larger value: err = %v, want ErrStorageQuotaExceeded", err)
+	}
+	if err := storage.Delete("clock", "k1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Set("clock", "k6", "small"); err != nil {
+		t.Errorf("Set after Delete freed space: %v", err)
+	}
+
+	// 上限はプラグインごと
+	if err := storage.Set("memo", "k1", full); err != nil {
+		t.Errorf("another plugin is limited by clock's usage: %v", err)
+	}
+}
+
+func TestPluginStorageKeyLimit(t *testing.T) {
+	t.Setenv(configDirEnv, t.TempDir())
+	storage := NewPluginStorage()
+
+	for i := 0; i < maxStorageKeys; i++ {
+		if err := storage.Set("clock", fmt.Sprintf("key-%d", i), "v"); err != nil {
+			t.Fatalf("Set(key-%d): %v", i, err)
+		}
+	}
+	if err := storage.Set("clock", "one-more", "v"); !errors.Is(err, ErrStorageQuotaExceeded) {
+		t.Errorf("Set over the key limit: err = %v, want ErrStorageQuotaExceeded", err)
+	}
+	if err := storage.Set("clock", "key-0", "updated"); err != nil {
+		t.Errorf("overwriting at the key limit: %v", err)
+	}
+}
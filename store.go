@@ -0,0 +1,320 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// プラグインストアの設定ファイル
+const storeConfigFile = "store.json"
+
+// プラグインストアの索引のURLを上書きする環境変数
+const storeURLEnv = "GHOSTCURSOR_STORE_URL"
+
+const (
+	// 取得した索引を使い回す時間
+	storeIndexTTL = 10 * time.Minute
+	// ストアへのリクエストのタイムアウト
+	storeRequestTimeout = 60 * time.Second
+	// 索引とアーカイブの大きさの上限
+	maxStoreIndexSize    = 16 << 20
+	maxStoreDownloadSize = 64 << 20
+)
+
+// storeConfig は store.json の内容
+type storeConfig struct {
+	IndexURL string `json:"indexUrl"`
+}
+
+// StorePlugin はプラグインストアの索引に載っているプラグイン
+type StorePlugin struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Version     string            `json:"version"`
+	Description string            `json:"description"`
+	Author      string            `json:"author"`
+	Icon        string            `json:"icon,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Engines     map[string]string `json:"engines,omitempty"`
+	// アーカイブのURL (索引のURLからの相対パスでもよい) とそのSHA-256 (16進数)
+	DownloadURL string `json:"downloadUrl"`
+	SHA256      string `json:"sha256"`
+	Size        int64  `json:"size,omitempty"`
+	// インストール済みのバージョン (索引にはなく、App が埋める)
+	InstalledVersion string `json:"installedVersion,omitempty"`
+}
+
+// storeIndex はプラグインストアの索引 (JSON) の形式
+type storeIndex struct {
+	Plugins []StorePlugin `json:"plugins"`
+}
+
+// PluginUpdate はストアに新しい版があるインストール済みのプラグイン
+type PluginUpdate struct {
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	InstalledVersion string `json:"installedVersion"`
+	LatestVersion    string `json:"latestVersion"`
+}
+
+// StoreClient はプラグインストアの索引を取得し、アーカイブをダウンロードする
+type StoreClient struct {
+	client *http.Client
+	clock  Clock
+	// 索引のURLを返す (設定ファイルの変更を反映するため毎回呼ぶ)
+	indexURL func() (string, error)
+
+	mu        sync.Mutex
+	index     []StorePlugin
+	indexFrom string
+	fetchedAt time.Time
+}
+
+// NewStoreClient は indexURL の索引を client で取得する StoreClient を生成する
+func NewStoreClient(client *http.Client, indexURL func() (string, error), clock Clock) *StoreClient {
+	return &StoreClient{client: client, indexURL: indexURL, clock: clock}
+}
+
+// loadStoreIndexURL は環境変数または store.json からプラグインストアの索引のURLを読み込む
+func loadStoreIndexURL() (string, error) {
+	if u := os.Getenv(storeURLEnv); u != "" {
+		return u, nil
+	}
+
+	var config storeConfig
+	if _, err := readConfigFile(storeConfigFile, &config); err != nil {
+		return "", err
+	}
+	if config.IndexURL == "" {
+		return "", fmt.Errorf("plugin store is not configured: set indexUrl in %s or %s", storeConfigFile, storeURLEnv)
+	}
+	return config.IndexURL, nil
+}
+
+// Index はストアの索引を返す
+// refresh が false なら、同じURLから storeIndexTTL 以内に取得したものを使い回す
+func (c *StoreClient) Index(refresh bool) ([]StorePlugin, error) {
+	indexURL, err := c.indexURL()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !refresh && c.index != nil && c.indexFrom == indexURL && c.clock.Now().Sub(c.fetchedAt) < storeIndexTTL {
+		return cloneStorePlugins(c.index), nil
+	}
+
+	index, err := c.fetchIndex(indexURL)
+	if err != nil {
+		return nil, err
+	}
+	c.index = index
+	c.indexFrom = indexURL
+	c.fetchedAt = c.clock.Now()
+	return cloneStorePlugins(index), nil
+}
+
+// Search は ID、名前、説明、作者、タグのいずれかに query を含むプラグインを返す (大文字と小文字は区別しない)
+func (c *StoreClient) Search(query string) ([]StorePlugin, error) {
+	index, err := c.Index(false)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	results := []StorePlugin{}
+	for _, plugin := range index {
+		if query == "" || storePluginMatches(plugin, query) {
+			results = append(results, plugin)
+		}
+	}
+	return results, nil
+}
+
+func storePluginMatches(plugin StorePlugin, query string) bool {
+	fields := append([]string{plugin.ID, plugin.Name, plugin.Description, plugin.Author}, plugin.Tags...)
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup は索引から id のプラグインを返す
+func (c *StoreClient) Lookup(id string) (StorePlugin, error) {
+	index, err := c.Index(false)
+	if err != nil {
+		return StorePlugin{}, err
+	}
+	for _, plugin := range index {
+		if plugin.ID == id {
+			return plugin, nil
+		}
+	}
+	return StorePlugin{}, fmt.Errorf("plugin %q not found in the store", id)
+}
+
+// Download はプラグインのアーカイブを一時ファイルにダウンロードし、SHA-256 が索引と一致すればそのパスを返す
+// 呼び出し側は使い終わったら一時ファイルを削除する
+func (c *StoreClient) Download(plugin StorePlugin) (string, error) {
+	c.mu.Lock()
+	base := c.indexFrom
+	c.mu.Unlock()
+
+	downloadURL, err := resolveStoreURL(base, plugin.DownloadURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid download URL for plugin %s: %w", plugin.ID, err)
+	}
+	resp, err := c.get(downloadURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download plugin %s: %w", plugin.ID, err)
+	}
+	defer resp.Body.Close()
+
+	tmp, err := os.CreateTemp("", "ghostcursor-"+plugin.ID+"-*.zip")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	path := tmp.Name()
+
+	h := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(resp.Body, maxStoreDownloadSize+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = verifyStoreDownload(plugin, written, h)
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to download plugin %s: %w", plugin.ID, err)
+	}
+	return path, nil
+}
+
+// verifyStoreDownload はダウンロードしたアーカイブの大きさとハッシュを索引と比べる
+func verifyStoreDownload(plugin StorePlugin, written int64, h hash.Hash) error {
+	if written > maxStoreDownloadSize {
+		return fmt.Errorf("archive is larger than %d bytes", maxStoreDownloadSize)
+	}
+	if plugin.Size > 0 && written != plugin.Size {
+		return fmt.Errorf("size mismatch: expected %d bytes, got %d", plugin.Size, written)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(sum, plugin.SHA256) {
+		return fmt.Errorf("checksum mismatch: expected sha256 %s, got %s", strings.ToLower(plugin.SHA256), sum)
+	}
+	return nil
+}
+
+func (c *StoreClient) fetchIndex(indexURL string) ([]StorePlugin, error) {
+	if _, err := resolveStoreURL("", indexURL); err != nil {
+		return nil, fmt.Errorf("invalid plugin store URL: %w", err)
+	}
+	resp, err := c.get(indexURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch plugin store index: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxStoreIndexSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin store index: %w", err)
+	}
+	if len(data) > maxStoreIndexSize {
+		return nil, fmt.Errorf("plugin store index is larger than %d bytes", maxStoreIndexSize)
+	}
+
+	var index storeIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse plugin store index: %w", err)
+	}
+
+	// 不正なエントリは読み飛ばし、同じIDは最初のものを使う
+	plugins := []StorePlugin{}
+	seen := make(map[string]bool)
+	for _, plugin := range index.Plugins {
+		if err := validateStorePlugin(plugin); err != nil {
+			fmt.Printf("Skipping plugin store entry %q: %v\n", plugin.ID, err)
+			continue
+		}
+		if seen[plugin.ID] {
+			fmt.Printf("Skipping duplicate plugin store entry %q\n", plugin.ID)
+			continue
+		}
+		seen[plugin.ID] = true
+		plugins = append(plugins, plugin)
+	}
+	fmt.Printf("Fetched plugin store index from %s: %d plugins\n", indexURL, len(plugins))
+	return plugins, nil
+}
+
+func validateStorePlugin(plugin StorePlugin) error {
+	if issues := validatePluginIDField("$.id", plugin.ID); len(issues) > 0 {
+		return errors.New(joinIssues(issues))
+	}
+	if _, err := parseSemver(plugin.Version); err != nil {
+		return err
+	}
+	if plugin.DownloadURL == "" {
+		return errors.New("downloadUrl is missing")
+	}
+	if sum, err := hex.DecodeString(plugin.SHA256); err != nil || len(sum) != sha256.Size {
+		return fmt.Errorf("invalid sha256 %q", plugin.SHA256)
+	}
+	return nil
+}
+
+func (c *StoreClient) get(u string) (*http.Response, error) {
+	resp, err := c.client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return resp, nil
+}
+
+// resolveStoreURL は base からの相対URLを解決する (http と https だけを許可する)
+func resolveStoreURL(base, ref string) (string, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	if base != "" {
+		b, err := url.Parse(base)
+		if err != nil {
+			return "", err
+		}
+		u = b.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("unsupported URL %q: only http and https are allowed", u)
+	}
+	return u.String(), nil
+}
+
+func cloneStorePlugins(plugins []StorePlugin) []StorePlugin {
+	cloned := make([]StorePlugin, len(plugins))
+	for i, plugin := range plugins {
+		plugin.Tags = append([]string(nil), plugin.Tags...)
+		plugin.Engines = maps.Clone(plugin.Engines)
+		cloned[i] = plugin
+	}
+	return cloned
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// storeStandIn は索引とアーカイブを返すプラグインストアの代わりのHTTPサーバー
+type storeStandIn struct {
+	server   *httptest.Server
+	index    storeIndex
+	archives map[string][]byte
+	requests atomic.Int32
+}
+
+func newStoreStandIn(t *testing.T) *storeStandIn {
+	t.Helper()
+	s := &storeStandIn{archives: make(map[string][]byte)}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ghosts/index.json" {
+			s.requests.Add(1)
+			json.NewEncoder(w).Encode(s.index)
+			return
+		}
+		archive, ok := s.archives[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(archive)
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *storeStandIn) indexURL() string {
+	return s.server.URL + "/ghosts/index.json"
+}
+
+// addPlugin は id のプラグインのアーカイブを path で配り、索引に載せる
+func (s *storeStandIn) addPlugin(t *testing.T, id, version, path string) StorePlugin {
+	t.Helper()
+	archive := pluginArchive(t, id, version)
+	s.archives[path] = archive
+	sum := sha256.Sum256(archive)
+	plugin := StorePlugin{
+		ID:          id,
+		Name:        strings.ToUpper(id[:1]) + id[1:],
+		Version:     version,
+		DownloadURL: strings.TrimPrefix(path, "/ghosts/"),
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+	s.index.Plugins = append(s.index.Plugins, plugin)
+	return plugin
+}
+
+// pluginArchive は manifest.json と index.js だけのプラグインのアーカイブを作る
+func pluginArchive(t *testing.T, id, version string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := map[string]string{
+		"manifest.json": `{"id": "` + id + `", "name": "` + id + `", "version": "` + version + `"}`,
+		"index.js":      "export default {};",
+	}
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newTestStoreClient(indexURL string, clock Clock) *StoreClient {
+	return NewStoreClient(&http.Client{Timeout: 5 * time.Second}, func() (string, error) { return indexURL, nil }, clock)
+}
+
+func TestStoreClientIndex(t *testing.T) {
+	store := newStoreStandIn(t)
+	store.addPlugin(t, "clock", "1.2.0", "/ghosts/clock.zip")
+	good := store.index.Plugins[0]
+
+	invalid := []StorePlugin{
+		{ID: "Bad ID", Version: "1.0.0", DownloadURL: "a.zip", SHA256: good.SHA256},
+		{ID: "noversion", Version: "latest", DownloadURL: "a.zip", SHA256: good.SHA256},
+		{ID: "nourl", Version: "1.0.0", SHA256: good.SHA256},
+		{ID: "shortsum", Version: "1.0.0", DownloadURL: "a.zip", SHA256: "abcd"},
+		{ID: "clock", Version: "9.9.9", DownloadURL: "a.zip", SHA256: good.SHA256},
+	}
+	store.index.Plugins = append(store.index.Plugins, invalid...)
+
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	client := newTestStoreClient(store.indexURL(), clock)
+
+	tests := []struct {
+		name     string
+		advance  time.Duration
+		refresh  bool
+		requests int32
+	}{
+		{name: "first fetch", requests: 1},
+		{name: "cached", advance: time.Minute, requests: 1},
+		{name: "refresh", refresh: true, requests: 2},
+		{name: "expired", advance: storeIndexTTL, requests: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Advance(tt.advance)
+			index, err := client.Index(tt.refresh)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 不正なエントリと重複したIDは読み飛ばす
+			if len(index) != 1 || index[0].ID != "clock" || index[0].Version != "1.2.0" {
+				t.Errorf("index = %+v", index)
+			}
+			if got := store.requests.Load(); got != tt.requests {
+				t.Errorf("requests = %d, want %d", got, tt.requests)
+			}
+		})
+	}
+}
+
+func TestStoreClientSearch(t *testing.T) {
+	store := newStoreStandIn(t)
+	store.addPlugin(t, "clock", "1.0.0", "/ghosts/clock.zip")
+	store.addPlugin(t, "memo", "1.0.0", "/ghosts/memo.zip")
+	store.index.Plugins[0].Tags = []string{"time", "Utility"}
+	store.index.Plugins[1].Description = "Write notes"
+
+	client := newTestStoreClient(store.indexURL(), NewFakeClock(time.Now()))
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"clock", "memo"}},
+		{"CLOCK", []string{"clock"}},
+		{"utility", []string{"clock"}},
+		{" notes ", []string{"memo"}},
+		{"weather", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results, err := client.Search(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, plugin := range results {
+				ids = append(ids, plugin.ID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreClientDownload(t *testing.T) {
+	store := newStoreStandIn(t)
+	plugin := store.addPlugin(t, "clock", "1.0.0", "/ghosts/clock.zip")
+	archive := store.archives["/ghosts/clock.zip"]
+
+	tests := []struct {
+		name   string
+		modify func(*StorePlugin)
+		err    string
+	}{
+		{name: "relative URL"},
+		{name: "absolute URL", modify: func(p *StorePlugin) { p.DownloadURL = store.server.URL + "/ghosts/clock.zip" }},
+		{name: "uppercase checksum", modify: func(p *StorePlugin) { p.SHA256 = strings.ToUpper(p.SHA256) }},
+		{name: "matching size", modify: func(p *StorePlugin) { p.Size = int64(len(archive)) }},
+		{
+			name: "bad checksum",
+			modify: func(p *StorePlugin) {
+				sum := sha256.Sum256([]byte("tampered"))
+				p.SHA256 = hex.EncodeToString(sum[:])
+			},
+			err: "checksum mismatch",
+		},
+		{
+			name:   "checksum of another archive",
+			modify: func(p *StorePlugin) { p.DownloadURL = "memo.zip" },
+			err:    "checksum mismatch",
+		},
+		{name: "size mismatch", modify: func(p *StorePlugin) { p.Size = int64(len(archive)) + 1 }, err: "size mismatch"},
+		{name: "not found", modify: func(p *StorePlugin) { p.DownloadURL = "missing.zip" }, err: "404"},
+		{name: "unsupported scheme", modify: func(p *StorePlugin) { p.DownloadURL = "file:///etc/passwd" }, err: "only http and https"},
+	}
+	store.archives["/ghosts/memo.zip"] = pluginArchive(t, "memo", "1.0.0")
+
+	client := newTestStoreClient(store.indexURL(), NewFakeClock(time.Now()))
+	if _, err := client.Index(false); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := plugin
+			if tt.modify != nil {
+				tt.modify(&p)
+			}
+			path, err := client.Download(p)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error = %v, want %q", err, tt.err)
+				}
+				if path != "" {
+					t.Errorf("path = %q for a failed download", path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(path)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, archive) {
+				t.Error("downloaded archive differs from the served one")
+			}
+		})
+	}
+}
+
+func TestInstallFromStore(t *testing.T) {
+	t.Setenv(configDirEnv, t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(pluginPathEnv, "")
+
+	store := newStoreStandIn(t)
+	store.addPlugin(t, "clock", "1.0.0", "/ghosts/clock.zip")
+	store.addPlugin(t, "memo", "1.0.0", "/ghosts/memo.zip")
+	// 索引のハッシュと違うアーカイブを配る
+	store.archives["/ghosts/memo.zip"] = pluginArchive(t, "memo", "6.6.6")
+	t.Setenv(storeURLEnv, store.indexURL())
+
+	app := NewFakeEnvironment().App
+	key := openTestLoader(t, app)
+
+	if _, err := app.InstallFromStore(key, "memo"); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("InstallFromStore(memo) error = %v, want checksum mismatch", err)
+	}
+	if _, ok := app.plugins.Lookup("memo"); ok {
+		t.Fatal("plugin with a bad checksum was installed")
+	}
+
+	record, err := app.InstallFromStore(key, "clock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !record.Valid || record.Manifest.Version != "1.0.0" {
+		t.Fatalf("installed record = %+v", record)
+	}
+
+	// 新しい版が索引に載れば更新として報告される
+	store.index.Plugins = nil
+	store.addPlugin(t, "clock", "1.1.0", "/ghosts/clock-1.1.0.zip")
+	updates, err := app.CheckForUpdates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PluginUpdate{{ID: "clock", Name: "Clock", InstalledVersion: "1.0.0", LatestVersion: "1.1.0"}}
+	if !slices.Equal(updates, want) {
+		t.Errorf("updates = %+v, want %+v", updates, want)
+	}
+}